@@ -2,134 +2,437 @@ package calc
 
 import (
 	"gonum.org/v1/gonum/blas"
+	"gonum.org/v1/gonum/blas/blas32"
 	"gonum.org/v1/gonum/blas/blas64"
 )
 
-func blasConv2D(a NDArray, k NDArray, arr NDArray) {
-	kShape := k.Shape()
-	kh, kw, inf, kf := kShape[0], kShape[1], kShape[2], kShape[3]
-	adim := len(a.shape)
-
-	iWOff := a.shape[adim-1]
-	iHOff := iWOff * a.shape[adim-2]
-	iBOff := iHOff * a.shape[adim-3]
-
-	aWOff := arr.shape[adim-1]
-	aHOff := aWOff * arr.shape[adim-2]
-	aBOff := aHOff * arr.shape[adim-3]
-
-	kFOff := 1
-	kIFOff := kFOff * kf
-	kWOff := kIFOff * inf
-	kHOff := kWOff * kw
-
-	for aBIndex, iBIndex := 0, 0; aBIndex < len(arr.data); aBIndex, iBIndex = aBIndex+aBOff, iBIndex+iBOff {
-		for r := 0; r < arr.shape[adim-3]; r++ {
-			aDataIndex := aBIndex + r*aHOff
-			c := blas64.General{
-				Rows:   arr.shape[adim-2],
-				Cols:   kf,
-				Data:   arr.data[aDataIndex:],
-				Stride: kf,
+// a row-major matrix over either storage type, so the conv kernels can be shared between blas64 and blas32
+type general[T float] struct {
+	Rows, Cols int
+	Data       []T
+	Stride     int
+}
+
+// c += op(a) * op(b)
+func gemm[T float](tA blas.Transpose, tB blas.Transpose, a general[T], b general[T], c general[T]) {
+	switch cData := any(c.Data).(type) {
+	case []float64:
+		blas64.Gemm(tA, tB, 1.0,
+			blas64.General{Rows: a.Rows, Cols: a.Cols, Data: any(a.Data).([]float64), Stride: a.Stride},
+			blas64.General{Rows: b.Rows, Cols: b.Cols, Data: any(b.Data).([]float64), Stride: b.Stride},
+			1.0,
+			blas64.General{Rows: c.Rows, Cols: c.Cols, Data: cData, Stride: c.Stride},
+		)
+	case []float32:
+		blas32.Gemm(tA, tB, 1.0,
+			blas32.General{Rows: a.Rows, Cols: a.Cols, Data: any(a.Data).([]float32), Stride: a.Stride},
+			blas32.General{Rows: b.Rows, Cols: b.Cols, Data: any(b.Data).([]float32), Stride: b.Stride},
+			1.0,
+			blas32.General{Rows: c.Rows, Cols: c.Cols, Data: cData, Stride: c.Stride},
+		)
+	}
+}
+
+// Splits a convolution over any number of spatial dims into (h, w) planes. 1D convolutions get a unit h
+// dim, and every dim before the last two is walked here, calling f with the plane index into the input,
+// kernel and output for every kernel tap that lands inside the input. Only images [start, end) of the
+// batch are walked.
+func convPlanes(dims []convDim, start int, end int, f func(dh convDim, dw convDim, inPlane int, kPlane int, outPlane int)) {
+	if len(dims) == 1 {
+		dims = []convDim{{in: 1, out: 1, k: 1, stride: 1, dilation: 1}, dims[0]}
+	}
+	outer, dh, dw := dims[:len(dims)-2], dims[len(dims)-2], dims[len(dims)-1]
+
+	var walk func(d int, ip int, kp int, op int)
+	walk = func(d int, ip int, kp int, op int) {
+		if d == len(outer) {
+			f(dh, dw, ip, kp, op)
+			return
+		}
+		dim := outer[d]
+		for o := 0; o < dim.out; o++ {
+			for kk := 0; kk < dim.k; kk++ {
+				i := dim.pos(o, kk)
+				if i < 0 || i >= dim.in {
+					continue
+				}
+				walk(d+1, ip*dim.in+i, kp*dim.k+kk, op*dim.out+o)
+			}
+		}
+	}
+
+	for b := start; b < end; b++ {
+		walk(0, b, 0, b)
+	}
+}
+
+func convSizes(dims []convDim) (in int, k int, out int) {
+	in, k, out = 1, 1, 1
+	for _, d := range dims {
+		in *= d.in
+		k *= d.k
+		out *= d.out
+	}
+	return
+}
+
+// a, k and arr must all have the same dtype
+func blasConv(a NDArray, k NDArray, arr NDArray, dims []convDim, groups int) {
+	if arr.dtype == Float32 {
+		blasConvData(a.data32, k.data32, arr.data32, k.shape, dims, groups)
+	} else {
+		blasConvData(a.data, k.data, arr.data, k.shape, dims, groups)
+	}
+}
+
+func blasConvData[T float](a []T, k []T, arr []T, kShape []int, dims []convDim, groups int) {
+	inf, kf := kShape[len(dims)], kShape[len(dims)+1]
+	inSize, kSize, outSize := convSizes(dims)
+	batch := len(arr) / (outSize * kf)
+
+	// images write to separate outputs, so the batch is split across the pool
+	if ConvIm2Col() {
+		patches := convPatches(dims)
+		splitRange(batch, rowGrain([]int{batch, outSize * kf}), func(start int, end int) {
+			col := make([]T, outSize*kSize*inf)
+			for b := start; b < end; b++ {
+				im2colConvImage(a[b*inSize*inf*groups:], k, arr[b*outSize*kf:], col, patches, outSize, inf, kf, groups)
+			}
+		})
+		return
+	}
+	splitRange(batch, rowGrain([]int{batch, outSize * kf}), func(start int, end int) {
+		convPlanes(dims, start, end, func(dh convDim, dw convDim, ip int, kp int, op int) {
+			blasConv2DImage(
+				a[ip*dh.in*dw.in*inf*groups:],
+				k[kp*dh.k*dw.k*inf*kf:],
+				arr[op*dh.out*dw.out*kf:],
+				dh, dw, inf, kf, groups,
+			)
+		})
+	})
+}
+
+// convolves a single (h, w, inf * groups) image into a (h, w, kf) output
+func blasConv2DImage[T float](in []T, k []T, out []T, dh convDim, dw convDim, inf int, kf int, groups int) {
+	gkf := kf / groups
+	for r := 0; r < dh.out; r++ {
+		for h := 0; h < dh.k; h++ {
+			ir := dh.pos(r, h)
+			if ir < 0 || ir >= dh.in {
+				continue
 			}
-			for h := 0; h < kh; h++ {
-				for w := 0; w < kw; w++ {
-					iDataIndex := iBIndex + (h+r)*iHOff + w*iWOff
-					kIndex := kHOff*h + kWOff*w
-					a := blas64.General{
-						Rows:   arr.shape[adim-2],
+			for w := 0; w < dw.k; w++ {
+				c0, c1 := dw.outRange(w)
+				if c0 == c1 {
+					continue
+				}
+				iDataIndex := (ir*dw.in + dw.pos(c0, w)) * inf * groups
+				kIndex := (h*dw.k + w) * inf * kf
+				oDataIndex := (r*dw.out + c0) * kf
+				for g := 0; g < groups; g++ {
+					a := general[T]{
+						Rows:   c1 - c0,
 						Cols:   inf,
-						Data:   a.data[iDataIndex:],
-						Stride: inf,
+						Data:   in[iDataIndex+g*inf:],
+						Stride: inf * groups * dw.stride,
 					}
-					b := blas64.General{
+					b := general[T]{
 						Rows:   inf,
-						Cols:   kf,
-						Data:   k.data[kIndex:],
+						Cols:   gkf,
+						Data:   k[kIndex+g*gkf:],
 						Stride: kf,
 					}
-					blas64.Gemm(blas.NoTrans, blas.NoTrans, 1.0, a, b, 1.0, c)
+					c := general[T]{
+						Rows:   c1 - c0,
+						Cols:   gkf,
+						Data:   out[oDataIndex+g*gkf:],
+						Stride: kf,
+					}
+					gemm(blas.NoTrans, blas.NoTrans, a, b, c)
 				}
 			}
 		}
 	}
 }
 
-func blasInverseConv2D(a NDArray, g NDArray, arr NDArray) {
-	kShape := arr.Shape()
-	kh, kw, inf, kf := kShape[0], kShape[1], kShape[2], kShape[3]
+// a, g and arr must all have the same dtype
+func blasInverseConv(a NDArray, g NDArray, arr NDArray, dims []convDim, groups int) {
+	if arr.dtype == Float32 {
+		blasInverseConvData(a.data32, g.data32, arr.data32, arr.shape, dims, groups)
+	} else {
+		blasInverseConvData(a.data, g.data, arr.data, arr.shape, dims, groups)
+	}
+}
+
+func blasInverseConvData[T float](a []T, g []T, arr []T, kShape []int, dims []convDim, groups int) {
+	inf, kf := kShape[len(dims)], kShape[len(dims)+1]
+	inSize, kSize, outSize := convSizes(dims)
+	batch := len(g) / (outSize * kf)
+	useIm2Col := ConvIm2Col()
+	var patches []patch
+	if useIm2Col {
+		patches = convPatches(dims)
+	}
 
-	adim := len(a.shape)
+	// every image accumulates into the whole kernel, so each chunk of the batch gets its own copy of it,
+	// and the copies are summed in order. The first chunk uses arr, which is already zeroed.
+	chunks := reduceChunks(batch, rowGrain([]int{batch, outSize * kf}))
+	if chunks < 1 {
+		chunks = 1
+	}
+	partials := make([][]T, chunks)
+	parallelTasks(chunks, func(i int) {
+		k := arr
+		if i > 0 {
+			k = make([]T, len(arr))
+		}
+		start, end := i*batch/chunks, (i+1)*batch/chunks
+		if useIm2Col {
+			col := make([]T, outSize*kSize*inf)
+			for b := start; b < end; b++ {
+				im2colInverseConvImage(a[b*inSize*inf*groups:], g[b*outSize*kf:], k, col, patches, outSize, inf, kf, groups)
+			}
+		} else {
+			convPlanes(dims, start, end, func(dh convDim, dw convDim, ip int, kp int, op int) {
+				blasInverseConv2DImage(
+					a[ip*dh.in*dw.in*inf*groups:],
+					g[op*dh.out*dw.out*kf:],
+					k[kp*dh.k*dw.k*inf*kf:],
+					dh, dw, inf, kf, groups,
+				)
+			})
+		}
+		partials[i] = k
+	})
+	for _, p := range partials[1:] {
+		for i, v := range p {
+			arr[i] += v
+		}
+	}
+}
+
+// accumulates the kernel gradient for a single (h, w, inf * groups) image and its (h, w, kf) output gradient
+func blasInverseConv2DImage[T float](in []T, g []T, k []T, dh convDim, dw convDim, inf int, kf int, groups int) {
+	gkf := kf / groups
+	for r := 0; r < dh.out; r++ {
+		for h := 0; h < dh.k; h++ {
+			ir := dh.pos(r, h)
+			if ir < 0 || ir >= dh.in {
+				continue
+			}
+			for w := 0; w < dw.k; w++ {
+				c0, c1 := dw.outRange(w)
+				if c0 == c1 {
+					continue
+				}
+				iDataIndex := (ir*dw.in + dw.pos(c0, w)) * inf * groups
+				gDataIndex := (r*dw.out + c0) * kf
+				kIndex := (h*dw.k + w) * inf * kf
+				for gr := 0; gr < groups; gr++ {
+					a := general[T]{
+						Rows:   c1 - c0,
+						Cols:   inf,
+						Data:   in[iDataIndex+gr*inf:],
+						Stride: inf * groups * dw.stride,
+					}
+					b := general[T]{
+						Rows:   c1 - c0,
+						Cols:   gkf,
+						Data:   g[gDataIndex+gr*gkf:],
+						Stride: kf,
+					}
+					c := general[T]{
+						Rows:   inf,
+						Cols:   gkf,
+						Data:   k[kIndex+gr*gkf:],
+						Stride: kf,
+					}
+					gemm(blas.Trans, blas.NoTrans, a, b, c)
+				}
+			}
+		}
+	}
+}
 
-	iWOff := a.shape[adim-1]
-	iHOff := iWOff * a.shape[adim-2]
-	iBOff := iHOff * a.shape[adim-3]
+// a, k and arr must all have the same dtype
+func blasConvTranspose(a NDArray, k NDArray, arr NDArray, dims []convDim, groups int) {
+	if arr.dtype == Float32 {
+		blasConvTransposeData(a.data32, k.data32, arr.data32, k.shape, dims, groups)
+	} else {
+		blasConvTransposeData(a.data, k.data, arr.data, k.shape, dims, groups)
+	}
+}
 
-	gWOff := g.shape[adim-1]
-	gHOff := gWOff * g.shape[adim-2]
-	gBOff := gHOff * g.shape[adim-3]
+func blasConvTransposeData[T float](a []T, k []T, arr []T, kShape []int, dims []convDim, groups int) {
+	outf, kf := kShape[len(dims)], kShape[len(dims)+1]
+	outSize, kSize, inSize := convSizes(dims)
+	batch := len(a) / (inSize * kf)
 
-	kFOff := 1
-	kIFOff := kFOff * kf
-	kWOff := kIFOff * inf
-	kHOff := kWOff * kw
+	// the input of the forward convolution is the output here
+	if ConvIm2Col() {
+		patches := convPatches(dims)
+		splitRange(batch, rowGrain([]int{batch, inSize * kf}), func(start int, end int) {
+			col := make([]T, inSize*kSize*outf)
+			for b := start; b < end; b++ {
+				im2colConvTransposeImage(a[b*inSize*kf:], k, arr[b*outSize*outf*groups:], col, patches, inSize, outf, kf, groups)
+			}
+		})
+		return
+	}
+	splitRange(batch, rowGrain([]int{batch, inSize * kf}), func(start int, end int) {
+		convPlanes(dims, start, end, func(dh convDim, dw convDim, op int, kp int, ip int) {
+			blasConv2DTransposeImage(
+				a[ip*dh.out*dw.out*kf:],
+				k[kp*dh.k*dw.k*outf*kf:],
+				arr[op*dh.in*dw.in*outf*groups:],
+				dh, dw, outf, kf, groups,
+			)
+		})
+	})
+}
 
-	for iBIndex, gBIndex := 0, 0; iBIndex < len(a.data); iBIndex, gBIndex = iBIndex+iBOff, gBIndex+gBOff {
-		for r := 0; r < g.shape[adim-3]; r++ {
-			for c := 0; c < g.shape[adim-2]; c++ {
-				gDataIndex := gBIndex + r*gHOff + c*gWOff
-				y := blas64.Vector{
-					N:    kf,
-					Data: g.data[gDataIndex : gDataIndex+kf],
-					Inc:  1,
+// scatters a single (h, w, kf) image back through the kernel into a (h, w, outf * groups) output
+func blasConv2DTransposeImage[T float](in []T, k []T, out []T, dh convDim, dw convDim, outf int, kf int, groups int) {
+	gkf := kf / groups
+	for r := 0; r < dh.out; r++ {
+		for h := 0; h < dh.k; h++ {
+			or := dh.pos(r, h)
+			if or < 0 || or >= dh.in {
+				continue
+			}
+			for w := 0; w < dw.k; w++ {
+				c0, c1 := dw.outRange(w)
+				if c0 == c1 {
+					continue
 				}
-				for h := 0; h < kh; h++ {
-					iDataIndex := iBIndex + (r+h)*iHOff + c*iWOff
-					kIndex := h * kHOff
-					x := blas64.Vector{
-						N:    inf,
-						Data: a.data[iDataIndex : iDataIndex+inf*kw],
-						Inc:  1,
+				iDataIndex := (r*dw.out + c0) * kf
+				kIndex := (h*dw.k + w) * outf * kf
+				oDataIndex := (or*dw.in + dw.pos(c0, w)) * outf * groups
+				for g := 0; g < groups; g++ {
+					a := general[T]{
+						Rows:   c1 - c0,
+						Cols:   gkf,
+						Data:   in[iDataIndex+g*gkf:],
+						Stride: kf,
 					}
-					a := blas64.General{
-						Rows:   inf * kw,
-						Cols:   kf,
-						Data:   arr.data[kIndex : kIndex+inf*kf*kw],
+					b := general[T]{
+						Rows:   outf,
+						Cols:   gkf,
+						Data:   k[kIndex+g*gkf:],
 						Stride: kf,
 					}
-					blas64.Ger(1.0, x, y, a)
+					c := general[T]{
+						Rows:   c1 - c0,
+						Cols:   outf,
+						Data:   out[oDataIndex+g*outf:],
+						Stride: outf * groups * dw.stride,
+					}
+					gemm(blas.NoTrans, blas.Trans, a, b, c)
 				}
 			}
 		}
 	}
 }
 
+// Handles a MatMul over the last two axes where a isn't broadcast and b is either not broadcast or has
+// only unit leading dims. Transposed views are passed to blas as is, other views are packed first.
+// Returns false if the naive path is needed.
+func blasMatMul(a NDArray, b NDArray, a1 int, a2 int, arr NDArray) bool {
+	n := len(arr.shape)
+	if !arr.dtype.IsFloat() || n < 2 || len(a.shape) != n || len(b.shape) != n || a1 != n-2 || a2 != n-1 {
+		return false
+	}
+	m, k, cols := a.shape[n-2], a.shape[n-1], b.shape[n-1]
+	if m == 0 || k == 0 || cols == 0 {
+		return false
+	}
+	batch := 1
+	bBatched := false
+	for i := 0; i < n-2; i++ {
+		if a.shape[i] != arr.shape[i] {
+			return false
+		}
+		if b.shape[i] != 1 {
+			if b.shape[i] != arr.shape[i] {
+				return false
+			}
+			bBatched = true
+		}
+		batch *= arr.shape[i]
+	}
+
+	requirePacked(arr)
+	a, b = a.AsType(arr.dtype), b.AsType(arr.dtype)
+	aT, bT := a.transposedLast2(), b.transposedLast2()
+	if !aT {
+		a = a.Contiguous()
+	}
+	if !bT {
+		b = b.Contiguous()
+	}
+
+	arr.Fill(0.)
+	if arr.dtype == Float32 {
+		blasMatMulData(a.data32, b.data32, arr.data32, batch, bBatched, aT, bT, m, k, cols)
+	} else {
+		blasMatMulData(a.data, b.data, arr.data, batch, bBatched, aT, bT, m, k, cols)
+	}
+	return true
+}
+
+func blasMatMulData[T float](a []T, b []T, c []T, batch int, bBatched bool, aT bool, bT bool, m int, k int, n int) {
+	if !bBatched && !aT {
+		// every batch uses the same b, so they can be stacked into one tall matrix
+		m, batch = m*batch, 1
+	}
+	for i := 0; i < batch; i++ {
+		bi := i
+		if !bBatched {
+			bi = 0
+		}
+		tA, ga := blas.NoTrans, general[T]{Rows: m, Cols: k, Data: a[i*m*k:], Stride: k}
+		if aT {
+			tA, ga = blas.Trans, general[T]{Rows: k, Cols: m, Data: a[i*m*k:], Stride: m}
+		}
+		tB, gb := blas.NoTrans, general[T]{Rows: k, Cols: n, Data: b[bi*k*n:], Stride: n}
+		if bT {
+			tB, gb = blas.Trans, general[T]{Rows: n, Cols: k, Data: b[bi*k*n:], Stride: k}
+		}
+		gemm(tA, tB, ga, gb, general[T]{Rows: m, Cols: n, Data: c[i*m*n:], Stride: n})
+	}
+}
+
 func blasStddev(data []float64, stddev []float64) {
 	sz := len(stddev)
 	sz2 := len(data) / sz
-	for i := 0; i < sz; i++ {
-		stddev[i] = blas64.Nrm2(blas64.Vector{
-			N:    sz2,
-			Data: data[i:],
-			Inc:  sz,
-		}) / float64(sz2)
-	}
+	splitRange(sz, minGrain/(sz2+1), func(start int, end int) {
+		for i := start; i < end; i++ {
+			stddev[i] = blas64.Nrm2(blas64.Vector{
+				N:    sz2,
+				Data: data[i:],
+				Inc:  sz,
+			}) / float64(sz2)
+		}
+	})
 }
 
 func blasDVariance(a []float64, g []float64, stddev []float64, dVariance []float64) {
 	sz := len(stddev)
 	sz2 := len(a) / sz
 
-	for i := 0; i < sz; i++ {
-		s := stddev[i]
-		dVariance[i] = blas64.Dot(blas64.Vector{
-			N:    sz2,
-			Data: a[i:],
-			Inc:  sz,
-		}, blas64.Vector{
-			N:    sz2,
-			Data: g[i:],
-			Inc:  sz,
-		}) * -0.5 / (s * s * s)
-	}
+	splitRange(sz, minGrain/(sz2+1), func(start int, end int) {
+		for i := start; i < end; i++ {
+			s := stddev[i]
+			dVariance[i] = blas64.Dot(blas64.Vector{
+				N:    sz2,
+				Data: a[i:],
+				Inc:  sz,
+			}, blas64.Vector{
+				N:    sz2,
+				Data: g[i:],
+				Inc:  sz,
+			}) * -0.5 / (s * s * s)
+		}
+	})
 }
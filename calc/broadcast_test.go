@@ -0,0 +1,144 @@
+package calc_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// Shapes are aligned at their last axis, missing leading axes count as size 1, and size 1 axes stretch
+var broadcastCases = []struct {
+	a, b, out []int
+}{
+	{[]int{2, 3}, []int{2, 3}, []int{2, 3}},
+	{[]int{3, 4}, []int{4}, []int{3, 4}},
+	{[]int{4}, []int{3, 4}, []int{3, 4}},
+	{[]int{3, 1}, []int{4}, []int{3, 4}},
+	{[]int{2, 3, 4}, []int{3, 1}, []int{2, 3, 4}},
+	{[]int{2, 1, 4}, []int{3, 1}, []int{2, 3, 4}},
+	{[]int{1, 1}, []int{5, 1, 4}, []int{5, 1, 4}},
+	{[]int{1}, []int{2, 3}, []int{2, 3}},
+	{[]int{5}, []int{}, []int{5}},
+	{[]int{}, []int{2, 3}, []int{2, 3}},
+	{[]int{}, []int{}, []int{}},
+	{[]int{2, 1, 3, 1}, []int{4, 1, 5}, []int{2, 4, 3, 5}},
+}
+
+var incompatibleCases = [][2][]int{
+	{{3}, {4}},
+	{{2, 3}, {3, 2}},
+	{{2, 3, 4}, {3, 3}},
+}
+
+func TestBroadcastShape(t *testing.T) {
+	for _, c := range broadcastCases {
+		if got := calc.BroadcastShape(c.a, c.b); !calc.ShapeEqual(got, c.out) || len(got) != len(c.out) {
+			t.Errorf("BroadcastShape(%v, %v) = %v, want %v", c.a, c.b, got, c.out)
+		}
+	}
+	for _, c := range incompatibleCases {
+		if got := calc.BroadcastShape(c[0], c[1]); got != nil {
+			t.Errorf("BroadcastShape(%v, %v) = %v, want nil", c[0], c[1], got)
+		}
+	}
+}
+
+// the index into an operand of shape that index of the output reads
+func broadcastIndex(shape []int, index []int) []int {
+	index = index[len(index)-len(shape):]
+	out := make([]int, len(shape))
+	for i := range shape {
+		if shape[i] != 1 {
+			out[i] = index[i]
+		}
+	}
+	return out
+}
+
+func checkBroadcast(t *testing.T, name string, got calc.NDArray, out []int, want func(index []int) float64) {
+	t.Helper()
+	if len(got.Shape()) != len(out) || !calc.ShapeEqual(got.Shape(), out) {
+		t.Errorf("%s: shape %v, want %v", name, got.Shape(), out)
+		return
+	}
+	got.ForEach(func(dataIndex int, index []int, value float64) {
+		if w := want(index); math.Abs(value-w) > 1e-6 {
+			t.Errorf("%s: %v = %v, want %v", name, index, value, w)
+		}
+	})
+}
+
+func TestBroadcastBinaryOps(t *testing.T) {
+	ops := []struct {
+		name string
+		op   func(a calc.NDArray, b calc.NDArray) calc.NDArray
+		ref  func(x float64, y float64) float64
+	}{
+		{"Add", calc.NDArray.Add, func(x, y float64) float64 { return x + y }},
+		{"Mul", calc.NDArray.Mul, func(x, y float64) float64 { return x * y }},
+		{"Div", calc.NDArray.Div, func(x, y float64) float64 { return x / y }},
+		{"Minimum", calc.NDArray.Minimum, math.Min},
+		{"Maximum", calc.NDArray.Maximum, math.Max},
+		{"Greater", calc.NDArray.Greater, func(x, y float64) float64 {
+			if x > y {
+				return 1
+			}
+			return 0
+		}},
+		{"Equal", calc.NDArray.Equal, func(x, y float64) float64 {
+			if x == y {
+				return 1
+			}
+			return 0
+		}},
+	}
+
+	for _, c := range broadcastCases {
+		// small integers so Equal has matches and every dtype is exact
+		a := calc.RandomUniform(1, 4, c.a...).AsType(calc.Int64).AsType(calc.Float64)
+		b := calc.RandomUniform(1, 4, c.b...).AsType(calc.Int64).AsType(calc.Float64)
+		for _, op := range ops {
+			for _, dtype := range []calc.DType{calc.Float64, calc.Float32, calc.Int64} {
+				if op.name == "Div" && dtype == calc.Int64 {
+					continue
+				}
+				name := fmt.Sprintf("%s %s %v %v", op.name, dtype, c.a, c.b)
+				got := op.op(a.AsType(dtype), b.AsType(dtype))
+				checkBroadcast(t, name, got, c.out, func(index []int) float64 {
+					return op.ref(a.Get(broadcastIndex(c.a, index)), b.Get(broadcastIndex(c.b, index)))
+				})
+			}
+		}
+	}
+}
+
+func TestBroadcastWhere(t *testing.T) {
+	for _, c := range broadcastCases {
+		cond := calc.RandomUniform(0, 1, c.a...).Greater(calc.Constant(0.5))
+		x := calc.RandomNormal(0, 1, c.b...)
+		y := calc.RandomNormal(0, 1)
+		got := cond.Where(x, y)
+		checkBroadcast(t, fmt.Sprint("Where ", c.a, c.b), got, c.out, func(index []int) float64 {
+			if cond.Get(broadcastIndex(c.a, index)) != 0 {
+				return x.Get(broadcastIndex(c.b, index))
+			}
+			return y.Get(nil)
+		})
+	}
+}
+
+func TestBroadcastViews(t *testing.T) {
+	a := calc.RandomNormal(0, 1, 4, 3)
+	b := calc.RandomNormal(0, 1, 2, 3, 4)
+	view := a.Transpose(0, 1)
+	got := view.Add(b)
+	checkBroadcast(t, "Add view", got, []int{2, 3, 4}, func(index []int) float64 {
+		return a.Get([]int{index[2], index[1]}) + b.Get(index)
+	})
+	got = b.Mul(view.Reverse(0))
+	checkBroadcast(t, "Mul reversed view", got, []int{2, 3, 4}, func(index []int) float64 {
+		return a.Get([]int{index[2], 2 - index[1]}) * b.Get(index)
+	})
+}
@@ -0,0 +1,645 @@
+package calc
+
+import "fmt"
+
+type Padding int
+
+const (
+	// No padding, the kernel only visits positions fully inside the input
+	PaddingValid Padding = iota
+	// Pad so the output size is ceil(in / stride), with any odd padding at the end
+	PaddingSame
+	// Pad by the amounts in ConvOpts.Pads
+	PaddingExplicit
+)
+
+// Stride, dilation, padding and grouping for a convolution. The zero value is a stride 1 valid convolution.
+type ConvOpts struct {
+	// per spatial axis, defaulting to 1
+	Strides   []int
+	Dilations []int
+
+	Padding Padding
+	// (before, after) pairs per spatial axis, only used with PaddingExplicit
+	Pads []int
+
+	// Splits the input and output filters into this many groups, each convolved separately, with the
+	// kernel only covering the input filters of one group. Groups equal to the number of input filters
+	// is a depthwise convolution. Defaults to 1.
+	Groups int
+
+	// Extra size per spatial axis added to the end of the output of a transposed convolution. A strided
+	// convolution maps several input sizes to the same output size, this picks which one to transpose to.
+	OutputPadding []int
+}
+
+// resolved geometry of a single spatial axis of a convolution
+type convDim struct {
+	in       int
+	out      int
+	k        int
+	stride   int
+	dilation int
+	// padding before the first input element
+	pad int
+}
+
+func (o ConvOpts) stride(axis int) int {
+	if axis < len(o.Strides) && o.Strides[axis] > 0 {
+		return o.Strides[axis]
+	}
+	return 1
+}
+
+func (o ConvOpts) dilation(axis int) int {
+	if axis < len(o.Dilations) && o.Dilations[axis] > 0 {
+		return o.Dilations[axis]
+	}
+	return 1
+}
+
+func (o ConvOpts) groups() int {
+	if o.Groups > 0 {
+		return o.Groups
+	}
+	return 1
+}
+
+func (o ConvOpts) dim(op string, axis int, in int, k int) (convDim, error) {
+	d := convDim{in: in, k: k, stride: o.stride(axis), dilation: o.dilation(axis)}
+	if k <= 0 {
+		return d, &ShapeError{Op: op, Axis: -1, Reason: fmt.Sprintf("kernel size %d on spatial axis %d", k, axis)}
+	}
+
+	span := d.dilation*(k-1) + 1
+	switch o.Padding {
+	case PaddingSame:
+		d.out = (in + d.stride - 1) / d.stride
+		if total := (d.out-1)*d.stride + span - in; total > 0 {
+			d.pad = total / 2
+		}
+	case PaddingExplicit:
+		if len(o.Pads) < 2*axis+2 {
+			return d, &ShapeError{Op: op, Axis: -1, Reason: fmt.Sprintf("missing explicit padding for spatial axis %d in %v", axis, o.Pads)}
+		}
+		d.pad = o.Pads[2*axis]
+		d.out = (in+o.Pads[2*axis]+o.Pads[2*axis+1]-span)/d.stride + 1
+	default:
+		d.out = (in-span)/d.stride + 1
+	}
+	if d.out <= 0 {
+		return d, &ShapeError{
+			Op:     op,
+			Axis:   -1,
+			Reason: fmt.Sprintf("kernel of size %d (dilation %d) does not fit in input of size %d on spatial axis %d", k, d.dilation, in, axis),
+		}
+	}
+	return d, nil
+}
+
+// size of the input that a convolution with these options maps to an output of size out
+func (o ConvOpts) transposedSize(axis int, out int, k int) int {
+	stride, span := o.stride(axis), o.dilation(axis)*(k-1)+1
+	extra := 0
+	if axis < len(o.OutputPadding) {
+		extra = o.OutputPadding[axis]
+	}
+	switch o.Padding {
+	case PaddingSame:
+		return out*stride + extra
+	case PaddingExplicit:
+		return (out-1)*stride + span - o.Pads[2*axis] - o.Pads[2*axis+1] + extra
+	default:
+		return (out-1)*stride + span + extra
+	}
+}
+
+// input position for output position o and kernel offset kk
+func (d convDim) pos(o int, kk int) int {
+	return o*d.stride + kk*d.dilation - d.pad
+}
+
+// range of output positions [start, end) whose input position for kernel offset kk is in bounds
+func (d convDim) outRange(kk int) (int, int) {
+	off := kk*d.dilation - d.pad
+	start, end := 0, 0
+	if off < 0 {
+		start = (-off + d.stride - 1) / d.stride
+	}
+	if d.in-1-off >= 0 {
+		end = (d.in-1-off)/d.stride + 1
+	}
+	if end > d.out {
+		end = d.out
+	}
+	if start > end {
+		start = end
+	}
+	return start, end
+}
+
+func convOp(axes []int) string {
+	return fmt.Sprintf("Conv%dD", len(axes))
+}
+
+func convDims(op string, shape []int, axes []int, kernel []int, opts ConvOpts) ([]convDim, error) {
+	dims := make([]convDim, len(axes))
+	for i, ax := range axes {
+		d, err := opts.dim(op, i, shape[ax], kernel[i])
+		if err != nil {
+			return nil, err
+		}
+		dims[i] = d
+	}
+	return dims, nil
+}
+
+// checks the axes of shape and that the kernel has a size per spatial axis followed by two filter sizes
+func checkConvAxes(op string, shape []int, kShape []int, axes []int, fAxis int) error {
+	if err := CheckAxes(op, shape, append([]int{fAxis}, axes...)...); err != nil {
+		return err
+	}
+	if len(kShape) != len(axes)+2 {
+		return &ShapeError{Op: op, Axis: -1, Actual: kShape, Reason: fmt.Sprintf("kernel needs %d spatial axes and 2 filter axes", len(axes))}
+	}
+	return nil
+}
+
+// the number of output filters per group, checking that the filters split evenly into groups
+func checkGroups(op string, inFilters int, kernelInFilters int, outFilters int, opts ConvOpts) (int, error) {
+	groups := opts.groups()
+	if inFilters != kernelInFilters*groups || outFilters%groups != 0 {
+		return 0, &ShapeError{
+			Op:     op,
+			Axis:   -1,
+			Reason: fmt.Sprintf("can't split %d input and %d output filters into %d groups of %d input filters", inFilters, outFilters, groups, kernelInFilters),
+		}
+	}
+	return outFilters / groups, nil
+}
+
+// The output shape of convolving shape over the spatial axes with a kernel of kShape, which must be
+// (spatial..., filters / groups, outFilters)
+func CheckConv(shape []int, kShape []int, axes []int, fAxis int, opts ConvOpts) ([]int, error) {
+	op := convOp(axes)
+	if err := checkConvAxes(op, shape, kShape, axes, fAxis); err != nil {
+		return nil, err
+	}
+	dims, err := convDims(op, shape, axes, kShape[:len(axes)], opts)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkGroups(op, shape[fAxis], kShape[len(axes)], kShape[len(axes)+1], opts); err != nil {
+		return nil, err
+	}
+	outShape := append([]int{}, shape...)
+	for i, d := range dims {
+		outShape[axes[i]] = d.out
+	}
+	outShape[fAxis] = kShape[len(axes)+1]
+	return outShape, nil
+}
+
+// The kernel shape of the gradient of a convolution of shape over the spatial axes, with the given kernel
+// spatial sizes and output gradient of gShape
+func CheckInverseConv(shape []int, gShape []int, kernel []int, axes []int, fAxis int, opts ConvOpts) ([]int, error) {
+	op := "Inverse" + convOp(axes)
+	if err := CheckAxes(op, shape, append([]int{fAxis}, axes...)...); err != nil {
+		return nil, err
+	}
+	if len(gShape) != len(shape) || len(kernel) != len(axes) {
+		return nil, &ShapeError{Op: op, Axis: -1, Expected: shape, Actual: gShape, Reason: "gradient or kernel ranks don't match the input"}
+	}
+	kShape := append(append([]int{}, kernel...), shape[fAxis]/opts.groups(), gShape[fAxis])
+	outShape, err := CheckConv(shape, kShape, axes, fAxis, opts)
+	if err != nil {
+		return nil, err
+	}
+	if !ShapeEqual(outShape, gShape) {
+		return nil, &ShapeError{Op: op, Axis: -1, Expected: outShape, Actual: gShape, Reason: "gradient doesn't match the convolution output"}
+	}
+	return kShape, nil
+}
+
+// The output shape of the transposed convolution of shape over the spatial axes with a kernel of kShape,
+// which must be (spatial..., outFilters / groups, filters)
+func CheckConvTranspose(shape []int, kShape []int, axes []int, fAxis int, opts ConvOpts) ([]int, error) {
+	op := convOp(axes) + "Transpose"
+	if err := checkConvAxes(op, shape, kShape, axes, fAxis); err != nil {
+		return nil, err
+	}
+	if err := checkTransposeFilters(op, shape, kShape, fAxis); err != nil {
+		return nil, err
+	}
+	kernel, outf, kf := kShape[:len(axes)], kShape[len(axes)], kShape[len(axes)+1]
+	outShape := append([]int{}, shape...)
+	for i, ax := range axes {
+		outShape[ax] = opts.transposedSize(i, shape[ax], kernel[i])
+	}
+	outShape[fAxis] = outf * opts.groups()
+	if err := checkTransposeDims(op, shape, outShape, kernel, axes, opts); err != nil {
+		return nil, err
+	}
+	if _, err := checkGroups(op, outShape[fAxis], outf, kf, opts); err != nil {
+		return nil, err
+	}
+	return outShape, nil
+}
+
+// checks that the input has as many filters as the output of the forward convolution with kShape
+func checkTransposeFilters(op string, shape []int, kShape []int, fAxis int) error {
+	if kf := kShape[len(kShape)-1]; shape[fAxis] != kf {
+		return &ShapeError{Op: op, Axis: fAxis, Expected: kShape, Actual: shape, Reason: fmt.Sprintf("kernel has %d filters but input has %d", kf, shape[fAxis])}
+	}
+	return nil
+}
+
+// checks that convolving outShape gives the spatial sizes of shape, which a transposed convolution reverses
+func checkTransposeDims(op string, shape []int, outShape []int, kernel []int, axes []int, opts ConvOpts) error {
+	for _, ax := range axes {
+		if outShape[ax] <= 0 {
+			return &ShapeError{Op: op, Axis: ax, Actual: outShape, Reason: "output is empty"}
+		}
+	}
+	dims, err := convDims(op, outShape, axes, kernel, opts)
+	if err != nil {
+		return err
+	}
+	for i, d := range dims {
+		if d.out != shape[axes[i]] {
+			return &ShapeError{
+				Op:       op,
+				Axis:     axes[i],
+				Expected: shape,
+				Actual:   outShape,
+				Reason:   fmt.Sprintf("output doesn't convolve back to the input with %+v", opts),
+			}
+		}
+	}
+	return nil
+}
+
+// Conv*Shape and the like don't know the input filters of the kernel, so they only check the spatial
+// axes. The Check functions check everything.
+
+func convShape(shape []int, axes []int, fAxis int, kernel []int, kernelF int, opts ConvOpts) []int {
+	op := convOp(axes)
+	if err := CheckAxes(op, shape, append([]int{fAxis}, axes...)...); err != nil {
+		panic(err)
+	}
+	outShape := append([]int{}, shape...)
+	for i, d := range Must(convDims(op, shape, axes, kernel, opts)) {
+		outShape[axes[i]] = d.out
+	}
+	outShape[fAxis] = kernelF
+	return outShape
+}
+
+func convTransposeShape(shape []int, axes []int, fAxis int, kernel []int, kernelF int, opts ConvOpts) []int {
+	op := convOp(axes) + "Transpose"
+	if err := CheckAxes(op, shape, append([]int{fAxis}, axes...)...); err != nil {
+		panic(err)
+	}
+	outShape := append([]int{}, shape...)
+	for i, ax := range axes {
+		outShape[ax] = opts.transposedSize(i, shape[ax], kernel[i])
+	}
+	outShape[fAxis] = kernelF * opts.groups()
+	if err := checkTransposeDims(op, shape, outShape, kernel, axes, opts); err != nil {
+		panic(err)
+	}
+	return outShape
+}
+
+func inverseConvShape(shape []int, gShape []int, fAxis int, kernel []int, opts ConvOpts) []int {
+	return append(append([]int{}, kernel...), shape[fAxis]/opts.groups(), gShape[fAxis])
+}
+
+func Conv1DShape(shape []int, wAxis int, fAxis int, kernelW int, kernelF int, opts ConvOpts) []int {
+	return convShape(shape, []int{wAxis}, fAxis, []int{kernelW}, kernelF, opts)
+}
+
+func Conv2DShape(shape []int, hAxis int, wAxis int, fAxis int, kernelH int, kernelW int, kernelF int, opts ConvOpts) []int {
+	return convShape(shape, []int{hAxis, wAxis}, fAxis, []int{kernelH, kernelW}, kernelF, opts)
+}
+
+func Conv3DShape(shape []int, dAxis int, hAxis int, wAxis int, fAxis int, kernelD int, kernelH int, kernelW int, kernelF int, opts ConvOpts) []int {
+	return convShape(shape, []int{dAxis, hAxis, wAxis}, fAxis, []int{kernelD, kernelH, kernelW}, kernelF, opts)
+}
+
+func InverseConv1DShape(shape []int, gShape []int, wAxis int, fAxis int, kernelW int, opts ConvOpts) []int {
+	return inverseConvShape(shape, gShape, fAxis, []int{kernelW}, opts)
+}
+
+func InverseConv2DShape(shape []int, gShape []int, hAxis int, wAxis int, fAxis int, kernelH int, kernelW int, opts ConvOpts) []int {
+	return inverseConvShape(shape, gShape, fAxis, []int{kernelH, kernelW}, opts)
+}
+
+func InverseConv3DShape(shape []int, gShape []int, dAxis int, hAxis int, wAxis int, fAxis int, kernelD int, kernelH int, kernelW int, opts ConvOpts) []int {
+	return inverseConvShape(shape, gShape, fAxis, []int{kernelD, kernelH, kernelW}, opts)
+}
+
+// kernelF is the number of filters per group of the output (the input filters of the forward convolution)
+func Conv1DTransposeShape(shape []int, wAxis int, fAxis int, kernelW int, kernelF int, opts ConvOpts) []int {
+	return convTransposeShape(shape, []int{wAxis}, fAxis, []int{kernelW}, kernelF, opts)
+}
+
+// kernelF is the number of filters per group of the output (the input filters of the forward convolution)
+func Conv2DTransposeShape(shape []int, hAxis int, wAxis int, fAxis int, kernelH int, kernelW int, kernelF int, opts ConvOpts) []int {
+	return convTransposeShape(shape, []int{hAxis, wAxis}, fAxis, []int{kernelH, kernelW}, kernelF, opts)
+}
+
+// kernelF is the number of filters per group of the output (the input filters of the forward convolution)
+func Conv3DTransposeShape(shape []int, dAxis int, hAxis int, wAxis int, fAxis int, kernelD int, kernelH int, kernelW int, kernelF int, opts ConvOpts) []int {
+	return convTransposeShape(shape, []int{dAxis, hAxis, wAxis}, fAxis, []int{kernelD, kernelH, kernelW}, kernelF, opts)
+}
+
+// row-major element offsets for each axis of shape
+func offsets(shape []int) []int {
+	offs := make([]int, len(shape))
+	size := 1
+	for i := len(shape) - 1; i >= 0; i-- {
+		offs[i] = size
+		size *= shape[i]
+	}
+	return offs
+}
+
+// whether the spatial axes followed by the filter axis are the trailing axes, which is the layout blas can handle
+func channelsLast(shape []int, axes []int, fAxis int) bool {
+	if fAxis != len(shape)-1 {
+		return false
+	}
+	for i, ax := range axes {
+		if ax != len(shape)-1-len(axes)+i {
+			return false
+		}
+	}
+	return true
+}
+
+// Calls f for every kernel tap of the output position pos that lands inside the input, with the data index
+// of the input element (starting from ai) and the flattened spatial index into the kernel
+func convTaps(dims []convDim, axes []int, aOff []int, pos []int, ai int, f func(ai int, kPos int)) {
+	var walk func(d int, ai int, kPos int)
+	walk = func(d int, ai int, kPos int) {
+		if d == len(dims) {
+			f(ai, kPos)
+			return
+		}
+		dim := dims[d]
+		for kk := 0; kk < dim.k; kk++ {
+			ip := dim.pos(pos[d], kk)
+			if ip < 0 || ip >= dim.in {
+				continue
+			}
+			walk(d+1, ai+ip*aOff[axes[d]], kPos*dim.k+kk)
+		}
+	}
+	walk(0, ai, 0)
+}
+
+// k must be (spatial..., aFilters / groups, outFilters)
+func (a NDArray) convInto(k NDArray, axes []int, fAxis int, opts ConvOpts, arr NDArray) NDArray {
+	a, k = a.Contiguous(), k.Contiguous()
+	requirePacked(arr)
+	kShape := k.Shape()
+	inf, kf := kShape[len(axes)], kShape[len(axes)+1]
+	op := convOp(axes)
+	Must(CheckConv(a.shape, kShape, axes, fAxis, opts))
+	dims := Must(convDims(op, a.shape, axes, kShape[:len(axes)], opts))
+	gkf := Must(checkGroups(op, a.shape[fAxis], inf, kf, opts))
+
+	arr.Fill(0.)
+
+	if channelsLast(a.shape, axes, fAxis) && arr.dtype.IsFloat() {
+		blasConv(a.AsType(arr.dtype), k.AsType(arr.dtype), arr, dims, opts.groups())
+		return arr
+	}
+	if !both64(a, k) || arr.dtype != Float64 {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray {
+			return a.convInto(k.AsType(Float64), axes, fAxis, opts, arr)
+		})
+	}
+
+	aOff := offsets(a.shape)
+	pos := make([]int, len(axes))
+
+	arr.ForEach(func(dataIndex int, index []int, value float64) {
+		o := index[fAxis]
+		// start from the first filter of the group
+		index[fAxis] = o / gkf * inf
+		for i, ax := range axes {
+			pos[i], index[ax] = index[ax], 0
+		}
+
+		sum := 0.
+		convTaps(dims, axes, aOff, pos, a.dataIndex(index), func(ai int, kPos int) {
+			ki := kPos*inf*kf + o
+			for f := 0; f < inf; f++ {
+				sum += a.data[ai] * k.data[ki]
+				ai += aOff[fAxis]
+				ki += kf
+			}
+		})
+		arr.data[dataIndex] = sum
+	})
+	return arr
+}
+
+// kernel size is taken from arr, which must be (spatial..., aFilters / groups, gFilters)
+func (a NDArray) inverseConvInto(g NDArray, axes []int, fAxis int, opts ConvOpts, arr NDArray) NDArray {
+	a, g = a.Contiguous(), g.Contiguous()
+	requirePacked(arr)
+	kShape := arr.Shape()
+	inf, kf := kShape[len(axes)], kShape[len(axes)+1]
+	op := "Inverse" + convOp(axes)
+	Must(CheckInverseConv(a.shape, g.shape, kShape[:len(axes)], axes, fAxis, opts))
+	dims := Must(convDims(op, a.shape, axes, kShape[:len(axes)], opts))
+	gkf := Must(checkGroups(op, a.shape[fAxis], inf, kf, opts))
+
+	arr.Fill(0.)
+
+	if channelsLast(a.shape, axes, fAxis) && arr.dtype.IsFloat() {
+		blasInverseConv(a.AsType(arr.dtype), g.AsType(arr.dtype), arr, dims, opts.groups())
+		return arr
+	}
+	if !both64(a, g) || arr.dtype != Float64 {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray {
+			return a.inverseConvInto(g.AsType(Float64), axes, fAxis, opts, arr)
+		})
+	}
+
+	aOff := offsets(a.shape)
+	pos := make([]int, len(axes))
+
+	g.ForEach(func(dataIndex int, index []int, value float64) {
+		o := index[fAxis]
+		// start from the first filter of the group
+		index[fAxis] = o / gkf * inf
+		for i, ax := range axes {
+			pos[i], index[ax] = index[ax], 0
+		}
+
+		convTaps(dims, axes, aOff, pos, a.dataIndex(index), func(ai int, kPos int) {
+			ki := kPos*inf*kf + o
+			for f := 0; f < inf; f++ {
+				arr.data[ki] += a.data[ai] * value
+				ai += aOff[fAxis]
+				ki += kf
+			}
+		})
+	})
+
+	return arr
+}
+
+// output size is taken from arr, which is needed to disambiguate strided convolutions
+func (a NDArray) convTransposeInto(k NDArray, axes []int, fAxis int, opts ConvOpts, arr NDArray) NDArray {
+	a, k = a.Contiguous(), k.Contiguous()
+	requirePacked(arr)
+	kShape := k.Shape()
+	outf, kf := kShape[len(axes)], kShape[len(axes)+1]
+	op := convOp(axes) + "Transpose"
+	if err := checkConvAxes(op, a.shape, kShape, axes, fAxis); err != nil {
+		panic(err)
+	}
+	if err := checkTransposeFilters(op, a.shape, kShape, fAxis); err != nil {
+		panic(err)
+	}
+	if err := checkTransposeDims(op, a.shape, arr.shape, kShape[:len(axes)], axes, opts); err != nil {
+		panic(err)
+	}
+	dims := Must(convDims(op, arr.shape, axes, kShape[:len(axes)], opts))
+	gkf := Must(checkGroups(op, arr.shape[fAxis], outf, kf, opts))
+
+	arr.Fill(0.)
+
+	if channelsLast(a.shape, axes, fAxis) && arr.dtype.IsFloat() {
+		blasConvTranspose(a.AsType(arr.dtype), k.AsType(arr.dtype), arr, dims, opts.groups())
+		return arr
+	}
+	if !both64(a, k) || arr.dtype != Float64 {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray {
+			return a.convTransposeInto(k.AsType(Float64), axes, fAxis, opts, arr)
+		})
+	}
+
+	oOff := offsets(arr.shape)
+	pos := make([]int, len(axes))
+
+	a.ForEach(func(dataIndex int, index []int, value float64) {
+		o := index[fAxis]
+		index[fAxis] = o / gkf * outf
+		for i, ax := range axes {
+			pos[i], index[ax] = index[ax], 0
+		}
+
+		convTaps(dims, axes, oOff, pos, arr.dataIndex(index), func(oi int, kPos int) {
+			ki := kPos*outf*kf + o
+			for f := 0; f < outf; f++ {
+				arr.data[oi] += k.data[ki] * value
+				oi += oOff[fAxis]
+				ki += kf
+			}
+		})
+	})
+
+	return arr
+}
+
+// k must be (w, aFilters / groups, outFilters)
+func (a NDArray) Conv1D(k NDArray, wAxis int, fAxis int, opts ConvOpts) NDArray {
+	arr := ZerosOf(PromoteTypes(a.dtype, k.dtype), Must(CheckConv(a.shape, k.shape, []int{wAxis}, fAxis, opts))...)
+	return a.Conv1DInto(k, wAxis, fAxis, opts, arr)
+}
+
+func (a NDArray) Conv1DInto(k NDArray, wAxis int, fAxis int, opts ConvOpts, arr NDArray) NDArray {
+	return a.convInto(k, []int{wAxis}, fAxis, opts, arr)
+}
+
+// k must be (h, w, aFilters / groups, outFilters)
+func (a NDArray) Conv2D(k NDArray, hAxis int, wAxis int, fAxis int, opts ConvOpts) NDArray {
+	arr := ZerosOf(PromoteTypes(a.dtype, k.dtype), Must(CheckConv(a.shape, k.shape, []int{hAxis, wAxis}, fAxis, opts))...)
+	return a.Conv2DInto(k, hAxis, wAxis, fAxis, opts, arr)
+}
+
+func (a NDArray) Conv2DInto(k NDArray, hAxis int, wAxis int, fAxis int, opts ConvOpts, arr NDArray) NDArray {
+	return a.convInto(k, []int{hAxis, wAxis}, fAxis, opts, arr)
+}
+
+// k must be (d, h, w, aFilters / groups, outFilters)
+func (a NDArray) Conv3D(k NDArray, dAxis int, hAxis int, wAxis int, fAxis int, opts ConvOpts) NDArray {
+	arr := ZerosOf(PromoteTypes(a.dtype, k.dtype), Must(CheckConv(a.shape, k.shape, []int{dAxis, hAxis, wAxis}, fAxis, opts))...)
+	return a.Conv3DInto(k, dAxis, hAxis, wAxis, fAxis, opts, arr)
+}
+
+func (a NDArray) Conv3DInto(k NDArray, dAxis int, hAxis int, wAxis int, fAxis int, opts ConvOpts, arr NDArray) NDArray {
+	return a.convInto(k, []int{dAxis, hAxis, wAxis}, fAxis, opts, arr)
+}
+
+// Gradient of a convolution with respect to its kernel, given the input a and the output gradient g
+func (a NDArray) InverseConv1D(g NDArray, wAxis int, fAxis int, kernelW int, opts ConvOpts) NDArray {
+	arr := ZerosOf(PromoteTypes(a.dtype, g.dtype), Must(CheckInverseConv(a.shape, g.shape, []int{kernelW}, []int{wAxis}, fAxis, opts))...)
+	return a.InverseConv1DInto(g, wAxis, fAxis, opts, arr)
+}
+
+func (a NDArray) InverseConv1DInto(g NDArray, wAxis int, fAxis int, opts ConvOpts, arr NDArray) NDArray {
+	return a.inverseConvInto(g, []int{wAxis}, fAxis, opts, arr)
+}
+
+// Gradient of a convolution with respect to its kernel, given the input a and the output gradient g
+func (a NDArray) InverseConv2D(g NDArray, hAxis int, wAxis int, fAxis int, kernelH int, kernelW int, opts ConvOpts) NDArray {
+	arr := ZerosOf(PromoteTypes(a.dtype, g.dtype), Must(CheckInverseConv(a.shape, g.shape, []int{kernelH, kernelW}, []int{hAxis, wAxis}, fAxis, opts))...)
+	return a.InverseConv2DInto(g, hAxis, wAxis, fAxis, opts, arr)
+}
+
+func (a NDArray) InverseConv2DInto(g NDArray, hAxis int, wAxis int, fAxis int, opts ConvOpts, arr NDArray) NDArray {
+	return a.inverseConvInto(g, []int{hAxis, wAxis}, fAxis, opts, arr)
+}
+
+// Gradient of a convolution with respect to its kernel, given the input a and the output gradient g
+func (a NDArray) InverseConv3D(g NDArray, dAxis int, hAxis int, wAxis int, fAxis int, kernelD int, kernelH int, kernelW int, opts ConvOpts) NDArray {
+	arr := ZerosOf(PromoteTypes(a.dtype, g.dtype), Must(CheckInverseConv(a.shape, g.shape, []int{kernelD, kernelH, kernelW}, []int{dAxis, hAxis, wAxis}, fAxis, opts))...)
+	return a.InverseConv3DInto(g, dAxis, hAxis, wAxis, fAxis, opts, arr)
+}
+
+func (a NDArray) InverseConv3DInto(g NDArray, dAxis int, hAxis int, wAxis int, fAxis int, opts ConvOpts, arr NDArray) NDArray {
+	return a.inverseConvInto(g, []int{dAxis, hAxis, wAxis}, fAxis, opts, arr)
+}
+
+// Transposed convolution (the gradient of Conv1D with respect to its input).
+// k is (w, outFilters / groups, aFilters), the same kernel as the forward convolution.
+func (a NDArray) Conv1DTranspose(k NDArray, wAxis int, fAxis int, opts ConvOpts) NDArray {
+	arr := ZerosOf(PromoteTypes(a.dtype, k.dtype), Must(CheckConvTranspose(a.shape, k.shape, []int{wAxis}, fAxis, opts))...)
+	return a.Conv1DTransposeInto(k, wAxis, fAxis, opts, arr)
+}
+
+// The spatial size of the output is taken from arr, which is needed to disambiguate strided convolutions
+func (a NDArray) Conv1DTransposeInto(k NDArray, wAxis int, fAxis int, opts ConvOpts, arr NDArray) NDArray {
+	return a.convTransposeInto(k, []int{wAxis}, fAxis, opts, arr)
+}
+
+// Transposed convolution (the gradient of Conv2D with respect to its input).
+// k is (h, w, outFilters / groups, aFilters), the same kernel as the forward convolution.
+func (a NDArray) Conv2DTranspose(k NDArray, hAxis int, wAxis int, fAxis int, opts ConvOpts) NDArray {
+	arr := ZerosOf(PromoteTypes(a.dtype, k.dtype), Must(CheckConvTranspose(a.shape, k.shape, []int{hAxis, wAxis}, fAxis, opts))...)
+	return a.Conv2DTransposeInto(k, hAxis, wAxis, fAxis, opts, arr)
+}
+
+// The spatial size of the output is taken from arr, which is needed to disambiguate strided convolutions
+func (a NDArray) Conv2DTransposeInto(k NDArray, hAxis int, wAxis int, fAxis int, opts ConvOpts, arr NDArray) NDArray {
+	return a.convTransposeInto(k, []int{hAxis, wAxis}, fAxis, opts, arr)
+}
+
+// Transposed convolution (the gradient of Conv3D with respect to its input).
+// k is (d, h, w, outFilters / groups, aFilters), the same kernel as the forward convolution.
+func (a NDArray) Conv3DTranspose(k NDArray, dAxis int, hAxis int, wAxis int, fAxis int, opts ConvOpts) NDArray {
+	arr := ZerosOf(PromoteTypes(a.dtype, k.dtype), Must(CheckConvTranspose(a.shape, k.shape, []int{dAxis, hAxis, wAxis}, fAxis, opts))...)
+	return a.Conv3DTransposeInto(k, dAxis, hAxis, wAxis, fAxis, opts, arr)
+}
+
+// The spatial size of the output is taken from arr, which is needed to disambiguate strided convolutions
+func (a NDArray) Conv3DTransposeInto(k NDArray, dAxis int, hAxis int, wAxis int, fAxis int, opts ConvOpts, arr NDArray) NDArray {
+	return a.convTransposeInto(k, []int{dAxis, hAxis, wAxis}, fAxis, opts, arr)
+}
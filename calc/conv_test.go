@@ -0,0 +1,353 @@
+package calc_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+func optStride(opts calc.ConvOpts, i int) int {
+	if i < len(opts.Strides) && opts.Strides[i] > 0 {
+		return opts.Strides[i]
+	}
+	return 1
+}
+
+func optDilation(opts calc.ConvOpts, i int) int {
+	if i < len(opts.Dilations) && opts.Dilations[i] > 0 {
+		return opts.Dilations[i]
+	}
+	return 1
+}
+
+// output size and padding before the first element of spatial axis i, straight from the definition of each
+// padding mode
+func naiveConvDim(opts calc.ConvOpts, i int, in int, k int) (out int, pad int) {
+	s, span := optStride(opts, i), optDilation(opts, i)*(k-1)+1
+	switch opts.Padding {
+	case calc.PaddingSame:
+		out = (in + s - 1) / s
+		return out, max(0, (out-1)*s+span-in) / 2
+	case calc.PaddingExplicit:
+		return (in+opts.Pads[2*i]+opts.Pads[2*i+1]-span)/s + 1, opts.Pads[2*i]
+	}
+	return (in-span)/s + 1, 0
+}
+
+// Calls f for every element of the input, kernel and output that a convolution of an input of aShape by a
+// kernel of kShape (spatial..., aFilters / groups, outFilters) multiplies and adds together
+func convTerms(aShape []int, kShape []int, outShape []int, axes []int, fAxis int, opts calc.ConvOpts, f func(ai []int, ki []int, oi []int)) {
+	inf, kf := kShape[len(axes)], kShape[len(axes)+1]
+	groups := max(opts.Groups, 1)
+	pads := make([]int, len(axes))
+	for i, ax := range axes {
+		_, pads[i] = naiveConvDim(opts, i, aShape[ax], kShape[i])
+	}
+	ai := make([]int, len(aShape))
+	calc.Zeros(outShape...).ForEach(func(_ int, oi []int, _ float64) {
+		o := oi[fAxis]
+		calc.Zeros(kShape...).ForEach(func(_ int, ki []int, _ float64) {
+			if ki[len(axes)+1] != o {
+				return
+			}
+			copy(ai, oi)
+			ai[fAxis] = o/(kf/groups)*inf + ki[len(axes)]
+			for i, ax := range axes {
+				ai[ax] = oi[ax]*optStride(opts, i) + ki[i]*optDilation(opts, i) - pads[i]
+				if ai[ax] < 0 || ai[ax] >= aShape[ax] {
+					return
+				}
+			}
+			f(ai, ki, oi)
+		})
+	})
+}
+
+func naiveConvShape(aShape []int, kShape []int, axes []int, fAxis int, opts calc.ConvOpts) []int {
+	shape := append([]int{}, aShape...)
+	shape[fAxis] = kShape[len(axes)+1]
+	for i, ax := range axes {
+		shape[ax], _ = naiveConvDim(opts, i, aShape[ax], kShape[i])
+	}
+	return shape
+}
+
+func naiveConv(a calc.NDArray, k calc.NDArray, axes []int, fAxis int, opts calc.ConvOpts) calc.NDArray {
+	out := calc.Zeros(naiveConvShape(a.Shape(), k.Shape(), axes, fAxis, opts)...)
+	convTerms(a.Shape(), k.Shape(), out.Shape(), axes, fAxis, opts, func(ai []int, ki []int, oi []int) {
+		out.Set(oi, out.Get(oi)+a.Get(ai)*k.Get(ki))
+	})
+	return out
+}
+
+// the gradient of the kernel of a convolution of a with output gradient g
+func naiveInverseConv(a calc.NDArray, g calc.NDArray, kernel []int, axes []int, fAxis int, opts calc.ConvOpts) calc.NDArray {
+	kShape := append(append([]int{}, kernel...), a.Shape()[fAxis]/max(opts.Groups, 1), g.Shape()[fAxis])
+	out := calc.Zeros(kShape...)
+	convTerms(a.Shape(), kShape, g.Shape(), axes, fAxis, opts, func(ai []int, ki []int, oi []int) {
+		out.Set(ki, out.Get(ki)+a.Get(ai)*g.Get(oi))
+	})
+	return out
+}
+
+func conv(a calc.NDArray, k calc.NDArray, axes []int, fAxis int, opts calc.ConvOpts) calc.NDArray {
+	switch len(axes) {
+	case 1:
+		return a.Conv1D(k, axes[0], fAxis, opts)
+	case 2:
+		return a.Conv2D(k, axes[0], axes[1], fAxis, opts)
+	}
+	return a.Conv3D(k, axes[0], axes[1], axes[2], fAxis, opts)
+}
+
+func inverseConv(a calc.NDArray, g calc.NDArray, kernel []int, axes []int, fAxis int, opts calc.ConvOpts) calc.NDArray {
+	switch len(axes) {
+	case 1:
+		return a.InverseConv1D(g, axes[0], fAxis, kernel[0], opts)
+	case 2:
+		return a.InverseConv2D(g, axes[0], axes[1], fAxis, kernel[0], kernel[1], opts)
+	}
+	return a.InverseConv3D(g, axes[0], axes[1], axes[2], fAxis, kernel[0], kernel[1], kernel[2], opts)
+}
+
+// (batch, spatial..., filters) with the filters last, which takes the blas path, and the same input with
+// the filters moved first, which doesn't
+func convLayouts(a calc.NDArray) (last calc.NDArray, first calc.NDArray, axes []int, firstAxes []int) {
+	rank := len(a.Shape())
+	perm := []int{0, rank - 1}
+	for ax := 1; ax < rank-1; ax++ {
+		axes = append(axes, ax)
+		firstAxes = append(firstAxes, ax+1)
+		perm = append(perm, ax)
+	}
+	return a, a.Permute(perm...).Contiguous(), axes, firstAxes
+}
+
+type convCase struct {
+	name   string
+	aShape []int
+	kShape []int
+	opts   calc.ConvOpts
+}
+
+// Checks convolutions and their kernel gradients against naiveConv and naiveInverseConv in both layouts
+func checkConvs(t *testing.T, cases []convCase) {
+	t.Helper()
+	rng := calc.NewRNG(18)
+	for _, c := range cases {
+		a, k := rng.Normal(0, 1, c.aShape...), rng.Normal(0, 1, c.kShape...)
+		last, first, axes, firstAxes := convLayouts(a)
+		kernel := c.kShape[:len(axes)]
+		rank := len(c.aShape)
+
+		want := naiveConv(a, k, axes, rank-1, c.opts)
+		shape, err := calc.CheckConv(c.aShape, c.kShape, axes, rank-1, c.opts)
+		if err != nil || !calc.ShapeEqual(shape, want.Shape()) {
+			t.Errorf("%s: CheckConv = %v, %v, want %v", c.name, shape, err, want.Shape())
+			continue
+		}
+		checkClose(t, c.name, conv(last, k, axes, rank-1, c.opts), want)
+		wantFirst := naiveConv(first, k, firstAxes, 1, c.opts)
+		checkClose(t, c.name+" filters first", conv(first, k, firstAxes, 1, c.opts), wantFirst)
+
+		g := rng.Normal(0, 1, want.Shape()...)
+		checkClose(t, c.name+" inverse", inverseConv(last, g, kernel, axes, rank-1, c.opts), naiveInverseConv(a, g, kernel, axes, rank-1, c.opts))
+		gFirst := rng.Normal(0, 1, wantFirst.Shape()...)
+		checkClose(t, c.name+" inverse filters first", inverseConv(first, gFirst, kernel, firstAxes, 1, c.opts), naiveInverseConv(first, gFirst, kernel, firstAxes, 1, c.opts))
+	}
+}
+
+func TestConv2DOptions(t *testing.T) {
+	checkConvs(t, []convCase{
+		{"valid", []int{2, 5, 6, 3}, []int{3, 2, 3, 4}, calc.ConvOpts{}},
+		{"stride", []int{2, 7, 6, 3}, []int{3, 2, 3, 4}, calc.ConvOpts{Strides: []int{2, 3}}},
+		{"dilation", []int{2, 7, 6, 2}, []int{2, 2, 2, 3}, calc.ConvOpts{Dilations: []int{3, 2}}},
+		{"same", []int{2, 5, 6, 2}, []int{3, 2, 2, 3}, calc.ConvOpts{Padding: calc.PaddingSame}},
+		{"same odd padding", []int{1, 6, 6, 2}, []int{2, 4, 2, 3}, calc.ConvOpts{Padding: calc.PaddingSame}},
+		{"same strided", []int{2, 7, 8, 2}, []int{3, 3, 2, 2}, calc.ConvOpts{Padding: calc.PaddingSame, Strides: []int{2, 3}}},
+		{"same dilated", []int{1, 7, 6, 2}, []int{3, 2, 2, 2}, calc.ConvOpts{Padding: calc.PaddingSame, Dilations: []int{2, 3}}},
+		{"explicit", []int{2, 5, 6, 2}, []int{3, 3, 2, 3}, calc.ConvOpts{Padding: calc.PaddingExplicit, Pads: []int{1, 2, 0, 3}}},
+		{"everything", []int{2, 9, 8, 2}, []int{3, 2, 2, 3}, calc.ConvOpts{
+			Padding:   calc.PaddingExplicit,
+			Pads:      []int{2, 1, 1, 1},
+			Strides:   []int{2, 3},
+			Dilations: []int{2, 3},
+		}},
+	})
+
+	for _, c := range []struct {
+		name string
+		opts calc.ConvOpts
+	}{
+		{"too large", calc.ConvOpts{Dilations: []int{3, 1}}},
+		{"missing pads", calc.ConvOpts{Padding: calc.PaddingExplicit, Pads: []int{1, 1}}},
+	} {
+		if _, err := calc.CheckConv([]int{1, 5, 5, 1}, []int{3, 3, 1, 1}, []int{1, 2}, 3, c.opts); err == nil {
+			t.Errorf("CheckConv %s didn't fail", c.name)
+		}
+	}
+}
+
+func TestConv1D3D(t *testing.T) {
+	checkConvs(t, []convCase{
+		{"1D", []int{2, 7, 3}, []int{3, 3, 4}, calc.ConvOpts{}},
+		{"1D strided dilated", []int{2, 11, 2}, []int{3, 2, 3}, calc.ConvOpts{Strides: []int{2}, Dilations: []int{2}}},
+		{"1D same", []int{2, 6, 2}, []int{4, 2, 3}, calc.ConvOpts{Padding: calc.PaddingSame, Strides: []int{2}}},
+		{"1D explicit", []int{1, 5, 2}, []int{3, 2, 2}, calc.ConvOpts{Padding: calc.PaddingExplicit, Pads: []int{2, 1}}},
+		{"3D", []int{2, 4, 5, 4, 2}, []int{2, 3, 2, 2, 3}, calc.ConvOpts{}},
+		{"3D strided", []int{1, 5, 5, 6, 2}, []int{2, 2, 3, 2, 2}, calc.ConvOpts{Strides: []int{2, 1, 3}}},
+		{"3D same dilated", []int{1, 5, 4, 5, 2}, []int{2, 2, 2, 2, 2}, calc.ConvOpts{Padding: calc.PaddingSame, Dilations: []int{2, 1, 2}}},
+		{"3D explicit", []int{1, 3, 4, 3, 1}, []int{2, 3, 2, 1, 2}, calc.ConvOpts{Padding: calc.PaddingExplicit, Pads: []int{1, 0, 1, 1, 0, 2}}},
+	})
+}
+
+func TestGroupedConv(t *testing.T) {
+	checkConvs(t, []convCase{
+		{"2 groups", []int{2, 5, 4, 4}, []int{3, 2, 2, 6}, calc.ConvOpts{Groups: 2}},
+		{"3 groups strided", []int{2, 6, 5, 6}, []int{2, 2, 2, 3}, calc.ConvOpts{Groups: 3, Strides: []int{2, 2}}},
+		{"depthwise", []int{2, 5, 5, 3}, []int{3, 3, 1, 3}, calc.ConvOpts{Groups: 3, Padding: calc.PaddingSame}},
+		{"depthwise multiplier", []int{1, 5, 4, 2}, []int{2, 2, 1, 6}, calc.ConvOpts{Groups: 2, Dilations: []int{2, 1}}},
+		{"1D groups", []int{2, 6, 4}, []int{3, 2, 4}, calc.ConvOpts{Groups: 2, Padding: calc.PaddingExplicit, Pads: []int{1, 1}}},
+		{"3D groups", []int{1, 3, 4, 3, 4}, []int{2, 2, 2, 1, 4}, calc.ConvOpts{Groups: 4}},
+	})
+
+	for _, c := range []struct {
+		name           string
+		aShape, kShape []int
+		groups         int
+	}{
+		{"input filters", []int{1, 4, 4, 5}, []int{2, 2, 2, 4}, 2},
+		{"output filters", []int{1, 4, 4, 4}, []int{2, 2, 2, 3}, 2},
+		{"kernel filters", []int{1, 4, 4, 4}, []int{2, 2, 4, 4}, 2},
+	} {
+		_, err := calc.CheckConv(c.aShape, c.kShape, []int{1, 2}, 3, calc.ConvOpts{Groups: c.groups})
+		if _, ok := err.(*calc.ShapeError); !ok {
+			t.Errorf("CheckConv with %s that don't split into groups: got %v, want a ShapeError", c.name, err)
+		}
+	}
+}
+
+// the input size of spatial axis i of a convolution with an output of size out, straight from the
+// definition of each padding mode
+func naiveTransposedSize(opts calc.ConvOpts, i int, out int, k int) int {
+	s, span := optStride(opts, i), optDilation(opts, i)*(k-1)+1
+	extra := 0
+	if i < len(opts.OutputPadding) {
+		extra = opts.OutputPadding[i]
+	}
+	switch opts.Padding {
+	case calc.PaddingSame:
+		return out*s + extra
+	case calc.PaddingExplicit:
+		return (out-1)*s + span - opts.Pads[2*i] - opts.Pads[2*i+1] + extra
+	}
+	return (out-1)*s + span + extra
+}
+
+// the gradient of the input of a convolution by k with output gradient g, for an input of outShape
+func naiveConvTranspose(g calc.NDArray, k calc.NDArray, outShape []int, axes []int, fAxis int, opts calc.ConvOpts) calc.NDArray {
+	out := calc.Zeros(outShape...)
+	convTerms(outShape, k.Shape(), g.Shape(), axes, fAxis, opts, func(ai []int, ki []int, oi []int) {
+		out.Set(ai, out.Get(ai)+g.Get(oi)*k.Get(ki))
+	})
+	return out
+}
+
+func convTranspose(a calc.NDArray, k calc.NDArray, axes []int, fAxis int, opts calc.ConvOpts) calc.NDArray {
+	switch len(axes) {
+	case 1:
+		return a.Conv1DTranspose(k, axes[0], fAxis, opts)
+	case 2:
+		return a.Conv2DTranspose(k, axes[0], axes[1], fAxis, opts)
+	}
+	return a.Conv3DTranspose(k, axes[0], axes[1], axes[2], fAxis, opts)
+}
+
+// Checks transposed convolutions against naiveConvTranspose in both layouts. aShape is the input of the
+// transposed convolution, and kShape is (spatial..., outFilters / groups, aFilters).
+func checkConvTransposes(t *testing.T, cases []convCase) {
+	t.Helper()
+	rng := calc.NewRNG(22)
+	for _, c := range cases {
+		a, k := rng.Normal(0, 1, c.aShape...), rng.Normal(0, 1, c.kShape...)
+		last, first, axes, firstAxes := convLayouts(a)
+		rank := len(c.aShape)
+
+		outShape := append([]int{}, c.aShape...)
+		outShape[rank-1] = c.kShape[len(axes)] * max(c.opts.Groups, 1)
+		for i, ax := range axes {
+			outShape[ax] = naiveTransposedSize(c.opts, i, c.aShape[ax], c.kShape[i])
+		}
+		shape, err := calc.CheckConvTranspose(c.aShape, c.kShape, axes, rank-1, c.opts)
+		if err != nil || !calc.ShapeEqual(shape, outShape) {
+			t.Errorf("%s: CheckConvTranspose = %v, %v, want %v", c.name, shape, err, outShape)
+			continue
+		}
+		want := naiveConvTranspose(a, k, outShape, axes, rank-1, c.opts)
+		checkClose(t, c.name, convTranspose(last, k, axes, rank-1, c.opts), want)
+		wantFirst := naiveConvTranspose(first, k, convLayoutsShape(outShape), firstAxes, 1, c.opts)
+		checkClose(t, c.name+" filters first", convTranspose(first, k, firstAxes, 1, c.opts), wantFirst)
+	}
+}
+
+var convTransposeCases = []convCase{
+	{"2D", []int{2, 3, 4, 3}, []int{2, 3, 2, 3}, calc.ConvOpts{}},
+	{"2D strided", []int{2, 3, 3, 2}, []int{3, 2, 2, 2}, calc.ConvOpts{Strides: []int{2, 3}}},
+	{"2D output padding", []int{1, 3, 3, 2}, []int{3, 3, 2, 2}, calc.ConvOpts{Strides: []int{2, 2}, OutputPadding: []int{1, 0}}},
+	{"2D dilated", []int{1, 3, 4, 2}, []int{2, 2, 3, 2}, calc.ConvOpts{Dilations: []int{2, 3}}},
+	{"2D same", []int{2, 3, 4, 2}, []int{3, 2, 2, 2}, calc.ConvOpts{Padding: calc.PaddingSame, Strides: []int{2, 1}}},
+	{"2D explicit", []int{1, 4, 4, 2}, []int{3, 3, 2, 2}, calc.ConvOpts{Padding: calc.PaddingExplicit, Pads: []int{1, 0, 2, 1}, Strides: []int{2, 1}}},
+	{"2D groups", []int{2, 3, 3, 4}, []int{2, 2, 3, 4}, calc.ConvOpts{Groups: 2, Strides: []int{2, 2}}},
+	{"1D", []int{2, 4, 3}, []int{3, 2, 3}, calc.ConvOpts{Strides: []int{2}, Padding: calc.PaddingSame}},
+	{"3D", []int{1, 2, 3, 2, 2}, []int{2, 2, 2, 3, 2}, calc.ConvOpts{Strides: []int{2, 1, 2}}},
+}
+
+func TestConvTranspose(t *testing.T) {
+	checkConvTransposes(t, convTransposeCases)
+
+	rng := calc.NewRNG(22)
+	// a stride 2 convolution maps inputs of size 7 and 8 to 3, so Into picks by the size of arr
+	a, k := rng.Normal(0, 1, 1, 3, 3, 2), rng.Normal(0, 1, 3, 3, 2, 2)
+	opts := calc.ConvOpts{Strides: []int{2, 2}}
+	for _, size := range []int{7, 8} {
+		got := a.Conv2DTransposeInto(k, 1, 2, 3, opts, calc.Zeros(1, size, 8, 2))
+		checkClose(t, "Into", got, naiveConvTranspose(a, k, []int{1, size, 8, 2}, []int{1, 2}, 3, opts))
+	}
+	func() {
+		defer func() {
+			if _, ok := recover().(*calc.ShapeError); !ok {
+				t.Error("Conv2DTransposeInto an output that doesn't convolve back to the input didn't panic with a ShapeError")
+			}
+		}()
+		a.Conv2DTransposeInto(k, 1, 2, 3, opts, calc.Zeros(1, 9, 8, 2))
+	}()
+}
+
+// a (batch, spatial..., filters) shape with the filters moved first, like convLayouts
+func convLayoutsShape(shape []int) []int {
+	return append([]int{shape[0], shape[len(shape)-1]}, shape[1:len(shape)-1]...)
+}
+
+// The im2col path and the direct blas path split the batch across the pool, so they're checked with
+// batches that don't divide evenly between workers
+func TestConvIm2Col(t *testing.T) {
+	defer calc.SetWorkers(calc.Workers())
+	defer calc.SetConvIm2Col(calc.ConvIm2Col())
+	cases := []convCase{
+		{"2D", []int{5, 5, 6, 3}, []int{3, 2, 3, 4}, calc.ConvOpts{}},
+		{"2D strided same", []int{5, 7, 6, 2}, []int{3, 3, 2, 3}, calc.ConvOpts{Padding: calc.PaddingSame, Strides: []int{2, 3}}},
+		{"2D dilated explicit", []int{3, 7, 6, 2}, []int{2, 2, 2, 3}, calc.ConvOpts{Padding: calc.PaddingExplicit, Pads: []int{1, 0, 2, 1}, Dilations: []int{3, 2}}},
+		{"2D groups", []int{5, 5, 4, 4}, []int{3, 2, 2, 6}, calc.ConvOpts{Groups: 2}},
+		{"2D depthwise", []int{3, 5, 5, 3}, []int{3, 3, 1, 3}, calc.ConvOpts{Groups: 3, Padding: calc.PaddingSame}},
+		{"1D", []int{5, 9, 2}, []int{3, 2, 3}, calc.ConvOpts{Strides: []int{2}, Padding: calc.PaddingSame}},
+		{"3D", []int{3, 4, 5, 4, 2}, []int{2, 3, 2, 2, 3}, calc.ConvOpts{Strides: []int{1, 2, 1}}},
+	}
+	for _, im2col := range []bool{false, true} {
+		calc.SetConvIm2Col(im2col)
+		for _, workers := range []int{1, 3} {
+			calc.SetWorkers(workers)
+			checkConvs(t, cases)
+			checkConvTransposes(t, convTransposeCases)
+		}
+	}
+}
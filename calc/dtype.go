@@ -0,0 +1,257 @@
+package calc
+
+import "fmt"
+
+// Element type of an NDArray. Float64 is the zero value, so arrays default to it.
+//
+// Float32 arrays have native kernels for the hot paths (element-wise Add/Mul, ReLU, MatMul and the
+// convolutions, which use blas32). Int64 arrays have native element-wise Add/Mul and exact comparisons.
+// Everything else is computed in float64 and converted back.
+//
+// Bool arrays hold masks. They're returned by comparisons and read as 0/1 by everything else.
+type DType int
+
+const (
+	Float64 DType = iota
+	Float32
+	Int64
+	Bool
+)
+
+func (d DType) String() string {
+	switch d {
+	case Float64:
+		return "float64"
+	case Float32:
+		return "float32"
+	case Int64:
+		return "int64"
+	case Bool:
+		return "bool"
+	default:
+		return fmt.Sprintf("DType(%d)", int(d))
+	}
+}
+
+func (d DType) IsFloat() bool {
+	return d == Float64 || d == Float32
+}
+
+// The type that an operation between a and b is computed in. Types are ordered
+// bool < int64 < float32 < float64, which is the reverse of their declaration order.
+func PromoteTypes(a DType, b DType) DType {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func ZerosOf(dtype DType, shape ...int) NDArray {
+	size := 1
+	for _, s := range shape {
+		size *= s
+	}
+	switch dtype {
+	case Float64:
+		return Zeros(shape...)
+	case Float32:
+		return FromRaw32(shape, make([]float32, size))
+	case Int64:
+		return FromRawInt64(shape, make([]int64, size))
+	case Bool:
+		return FromRawBool(shape, make([]bool, size))
+	default:
+		panic(fmt.Sprintf("unknown dtype %s", dtype))
+	}
+}
+
+// Panics if data doesn't have the size of shape, see NewArray32
+func FromRaw32(shape []int, data []float32) NDArray {
+	return Must(NewArray32(shape, data))
+}
+
+// Panics if data doesn't have the size of shape, see NewArrayInt64
+func FromRawInt64(shape []int, data []int64) NDArray {
+	return Must(NewArrayInt64(shape, data))
+}
+
+// Panics if data doesn't have the size of shape, see NewArrayBool
+func FromRawBool(shape []int, data []bool) NDArray {
+	return Must(NewArrayBool(shape, data))
+}
+
+// a with the same dtype and storage, but no shape or cached offsets
+func (a NDArray) storage() NDArray {
+	return NDArray{
+		dtype:    a.dtype,
+		data:     a.data,
+		data32:   a.data32,
+		dataI64:  a.dataI64,
+		dataBool: a.dataBool,
+	}
+}
+
+func (a NDArray) DType() DType {
+	return a.dtype
+}
+
+func (a NDArray) size() int {
+	switch a.dtype {
+	case Float32:
+		return len(a.data32)
+	case Int64:
+		return len(a.dataI64)
+	case Bool:
+		return len(a.dataBool)
+	default:
+		return len(a.data)
+	}
+}
+
+// value at a data index, converted to float64
+func (a NDArray) at(i int) float64 {
+	switch a.dtype {
+	case Float32:
+		return float64(a.data32[i])
+	case Int64:
+		return float64(a.dataI64[i])
+	case Bool:
+		if a.dataBool[i] {
+			return 1.
+		}
+		return 0.
+	default:
+		return a.data[i]
+	}
+}
+
+func (a NDArray) setAt(i int, v float64) {
+	switch a.dtype {
+	case Float32:
+		a.data32[i] = float32(v)
+	case Int64:
+		a.dataI64[i] = int64(v)
+	case Bool:
+		a.dataBool[i] = v != 0
+	default:
+		a.data[i] = v
+	}
+}
+
+// Converts a to dtype, returning a itself if it already has that type
+func (a NDArray) AsType(dtype DType) NDArray {
+	if a.dtype == dtype {
+		return a
+	}
+	return a.AsTypeInto(ZerosOf(dtype, a.shape...))
+}
+
+// Floats are truncated when converted to Int64, and anything nonzero is true as a Bool
+func (a NDArray) AsTypeInto(arr NDArray) NDArray {
+	requirePacked(arr)
+	if !a.packed() {
+		if a.dtype == arr.dtype {
+			return a.copyInto(arr)
+		}
+		walkElems(a.layout(), func(aIndex int, i int) {
+			arr.setAt(i, a.at(aIndex))
+		})
+		return arr
+	}
+	switch {
+	case a.dtype == arr.dtype:
+		copyElems(arr, a)
+	case a.dtype == Float64 && arr.dtype == Float32:
+		for i, v := range a.data {
+			arr.data32[i] = float32(v)
+		}
+	case a.dtype == Float32 && arr.dtype == Float64:
+		for i, v := range a.data32 {
+			arr.data[i] = float64(v)
+		}
+	default:
+		for i := 0; i < a.size(); i++ {
+			arr.setAt(i, a.at(i))
+		}
+	}
+	return arr
+}
+
+func copyElems(dst NDArray, src NDArray) {
+	switch dst.dtype {
+	case Float32:
+		copy(dst.data32, src.data32)
+	case Int64:
+		copy(dst.dataI64, src.dataI64)
+	case Bool:
+		copy(dst.dataBool, src.dataBool)
+	default:
+		copy(dst.data, src.data)
+	}
+}
+
+// returns a function copying element in of src to element out of dst, which must have the same dtype
+func elemCopier(dst NDArray, src NDArray) func(out int, in int) {
+	if dst.dtype != src.dtype {
+		panic(fmt.Sprintf("can't copy %s elements into %s array", src.dtype, dst.dtype))
+	}
+	switch dst.dtype {
+	case Float32:
+		return func(out int, in int) { dst.data32[out] = src.data32[in] }
+	case Int64:
+		return func(out int, in int) { dst.dataI64[out] = src.dataI64[in] }
+	case Bool:
+		return func(out int, in int) { dst.dataBool[out] = src.dataBool[in] }
+	default:
+		return func(out int, in int) { dst.data[out] = src.data[in] }
+	}
+}
+
+// For ops without a native kernel: runs f on the float64 version of a and converts the result back
+func (a NDArray) via64(f func(a NDArray) NDArray) NDArray {
+	if a.dtype == Float64 {
+		return f(a)
+	}
+	return f(a.AsType(Float64)).AsType(a.dtype)
+}
+
+// Like via64 for *Into ops, converting the float64 result into arr
+func (a NDArray) via64Into(arr NDArray, f func(a NDArray, arr NDArray) NDArray) NDArray {
+	if a.dtype == Float64 && arr.dtype == Float64 {
+		return f(a, arr)
+	}
+	return f(a.AsType(Float64), Zeros(arr.shape...)).AsTypeInto(arr)
+}
+
+// storage types with native blas kernels
+type float interface {
+	float32 | float64
+}
+
+// storage types with native element-wise kernels
+type number interface {
+	float32 | float64 | int64
+}
+
+// Expands an array of class indices into a Float64 array with a trailing axis of size depth
+func (a NDArray) OneHot(depth int) NDArray {
+	shape := append(append([]int{}, a.shape...), depth)
+	return a.OneHotInto(Zeros(shape...))
+}
+
+// the depth is taken from the last axis of arr, which must be a Float64 array
+func (a NDArray) OneHotInto(arr NDArray) NDArray {
+	requirePacked(arr)
+	depth := arr.shape[len(arr.shape)-1]
+	arr.Fill(0.)
+	i := 0
+	a.ForEach(func(_ int, _ []int, value float64) {
+		c := int(value)
+		if c < 0 || c >= depth {
+			panic(fmt.Sprintf("class %d out of range for one-hot depth %d", c, depth))
+		}
+		arr.data[i*depth+c] = 1.
+		i++
+	})
+	return arr
+}
@@ -0,0 +1,129 @@
+package calc_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// checkClose for a float32 result, which must keep its dtype
+func checkClose32(t *testing.T, name string, got calc.NDArray, want calc.NDArray) {
+	t.Helper()
+	if got.DType() != calc.Float32 {
+		t.Errorf("%s: dtype %s, want float32", name, got.DType())
+	}
+	if !calc.ShapeEqual(got.Shape(), want.Shape()) {
+		t.Errorf("%s: shape %v, want %v", name, got.Shape(), want.Shape())
+		return
+	}
+	// loose enough for float32 rounding
+	want.ForEach(func(_ int, index []int, w float64) {
+		if g := got.Get(index); !(math.Abs(g-w) <= 1e-5*math.Max(1, math.Abs(w))) {
+			t.Errorf("%s: %v = %v, want %v", name, index, g, w)
+		}
+	})
+}
+
+func TestPromoteTypes(t *testing.T) {
+	order := []calc.DType{calc.Bool, calc.Int64, calc.Float32, calc.Float64}
+	for i, a := range order {
+		for j, b := range order {
+			if got, want := calc.PromoteTypes(a, b), order[max(i, j)]; got != want {
+				t.Errorf("PromoteTypes(%s, %s) = %s, want %s", a, b, got, want)
+			}
+		}
+	}
+}
+
+func TestFloat32(t *testing.T) {
+	rng := calc.NewRNG(24)
+	a, b := rng.Normal(0, 1, 3, 4), rng.Normal(0, 1, 4, 5)
+	a32, b32 := a.AsType(calc.Float32), b.AsType(calc.Float32)
+
+	a32.ForEach(func(_ int, index []int, v float64) {
+		if w := float64(float32(a.Get(index))); v != w {
+			t.Errorf("AsType float32 %v = %v, want %v", index, v, w)
+		}
+	})
+	checkSame(t, "AsType float32 and back", a32.AsType(calc.Float64).AsType(calc.Float32), a32)
+
+	checkClose32(t, "Add", a32.Add(a32), a.Add(a))
+	checkClose32(t, "Mul", a32.Mul(a32), a.Mul(a))
+	checkClose32(t, "MatMul", a32.MatMul(b32, 0, 1), a.MatMul(b, 0, 1))
+	checkClose32(t, "MatMul of a transposed view", b32.Transpose(0, 1).MatMul(a32.Transpose(0, 1), 0, 1), b.Transpose(0, 1).MatMul(a.Transpose(0, 1), 0, 1))
+	if got := a32.Add(a); got.DType() != calc.Float64 {
+		t.Errorf("float32 + float64 is %s", got.DType())
+	}
+	if got := a32.Add(calc.FromRawInt64([]int{1}, []int64{2})); got.DType() != calc.Float32 {
+		t.Errorf("float32 + int64 is %s", got.DType())
+	}
+
+	// values past float32 range round to infinity
+	big := calc.FromRaw([]int{2}, []float64{1e39, -1e39}).AsType(calc.Float32)
+	if !math.IsInf(big.Get([]int{0}), 1) || !math.IsInf(big.Get([]int{1}), -1) {
+		t.Errorf("AsType float32 of ±1e39 = %v", big)
+	}
+}
+
+func TestFloat32Conv(t *testing.T) {
+	rng := calc.NewRNG(25)
+	a, k := rng.Normal(0, 1, 2, 6, 5, 4), rng.Normal(0, 1, 3, 2, 2, 4)
+	a32, k32 := a.AsType(calc.Float32), k.AsType(calc.Float32)
+	opts := calc.ConvOpts{Strides: []int{2, 1}, Padding: calc.PaddingSame, Groups: 2}
+
+	want := naiveConv(a, k, []int{1, 2}, 3, opts)
+	checkClose32(t, "Conv2D", a32.Conv2D(k32, 1, 2, 3, opts), want)
+	// filters first doesn't take the blas path, and is computed in float64
+	first := a32.Permute(0, 3, 1, 2).Contiguous()
+	checkClose32(t, "Conv2D filters first", first.Conv2D(k32, 2, 3, 1, opts), want.Permute(0, 3, 1, 2).Contiguous())
+
+	g := rng.Normal(0, 1, want.Shape()...)
+	g32 := g.AsType(calc.Float32)
+	checkClose32(t, "InverseConv2D", a32.InverseConv2D(g32, 1, 2, 3, 3, 2, opts), naiveInverseConv(a, g, []int{3, 2}, []int{1, 2}, 3, opts))
+	checkClose32(t, "Conv2DTranspose", g32.Conv2DTransposeInto(k32, 1, 2, 3, opts, calc.ZerosOf(calc.Float32, a.Shape()...)), naiveConvTranspose(g, k, a.Shape(), []int{1, 2}, 3, opts))
+}
+
+func TestInt64AndBool(t *testing.T) {
+	// past 2^53, where float64 can't hold every integer
+	big := calc.FromRawInt64([]int{3}, []int64{1<<53 + 1, -7, 0})
+	sum := big.Add(calc.FromRawInt64([]int{1}, []int64{2}))
+	checkSame(t, "int64 Add", sum, calc.FromRawInt64([]int{3}, []int64{1<<53 + 3, -5, 2}))
+	// Get rounds to float64, but String shows every digit
+	if s := sum.String(); !strings.Contains(s, "9007199254740995") {
+		t.Errorf("int64 Add = %s, want 9007199254740995 first", s)
+	}
+	checkSame(t, "int64 Mul", big.Mul(calc.FromRawInt64([]int{3}, []int64{1, 3, 5})), calc.FromRawInt64([]int{3}, []int64{1<<53 + 1, -21, 0}))
+	checkSame(t, "int64 ReLU", big.ReLU(), calc.FromRawInt64([]int{3}, []int64{1<<53 + 1, 0, 0}))
+	checkSame(t, "int64 Equal", big.Equal(calc.FromRawInt64([]int{3}, []int64{1 << 53, -7, 0})), calc.FromRawBool([]int{3}, []bool{false, true, true}))
+	checkSame(t, "int64 Greater", big.Greater(calc.FromRawInt64([]int{3}, []int64{1 << 53, -7, -1})), calc.FromRawBool([]int{3}, []bool{true, false, true}))
+
+	floats := calc.FromRaw([]int{5}, []float64{1.9, -1.9, 0, 0.5, -0.0})
+	checkSame(t, "AsType int64 truncates", floats.AsType(calc.Int64), calc.FromRawInt64([]int{5}, []int64{1, -1, 0, 0, 0}))
+	checkSame(t, "AsType bool", floats.AsType(calc.Bool), calc.FromRawBool([]int{5}, []bool{true, true, false, true, false}))
+	checkSame(t, "float Equal within Epsilon", floats.Equal(floats.Add(calc.FromRaw([]int{1}, []float64{calc.Epsilon / 2}))), calc.FromRawBool([]int{5}, []bool{true, true, true, true, true}))
+
+	mask := calc.FromRawBool([]int{2, 3}, []bool{true, false, true, true, true, false})
+	checkSame(t, "Bool Sum", mask.Sum(1), calc.FromRawInt64([]int{2, 1}, []int64{2, 2}))
+	checkSame(t, "Bool AsType float64", mask.AsType(calc.Float64), calc.FromRaw([]int{2, 3}, []float64{1, 0, 1, 1, 1, 0}))
+	if got := mask.Add(mask); got.DType() != calc.Bool {
+		t.Errorf("bool + bool is %s", got.DType())
+	}
+	if got := mask.Add(calc.FromRawInt64([]int{1}, []int64{1})); got.DType() != calc.Int64 {
+		t.Errorf("bool + int64 is %s", got.DType())
+	}
+
+	labels := calc.FromRawInt64([]int{3}, []int64{2, 0, 1})
+	checkSame(t, "OneHot", labels.OneHot(3), calc.FromRaw([]int{3, 3}, []float64{0, 0, 1, 1, 0, 0, 0, 1, 0}))
+
+	filled := calc.ZerosOf(calc.Int64, 2)
+	filled.Fill(3.7)
+	checkSame(t, "int64 Fill", filled, calc.FromRawInt64([]int{2}, []int64{3, 3}))
+	if s := calc.FromRawInt64([]int{2}, []int64{1 << 60, -3}).String(); !strings.Contains(s, "1152921504606846976") || !strings.Contains(s, "-3") {
+		t.Errorf("int64 String = %s", s)
+	}
+	if s := mask.String(); !strings.Contains(s, "true") || !strings.Contains(s, "false") {
+		t.Errorf("bool String = %s", s)
+	}
+}
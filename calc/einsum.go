@@ -0,0 +1,289 @@
+package calc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/blas"
+)
+
+// Splits an einsum spec like "bij,bjk->bik" into the labels of each of n operands and of the output.
+// Labels are single letters. Without "->" the output is every label that appears exactly once, in
+// alphabetical order.
+func ParseEinsum(spec string, n int) (inputs []string, output string, err error) {
+	fail := func(format string, args ...any) ([]string, string, error) {
+		return nil, "", &ShapeError{Op: "Einsum", Axis: -1, Reason: fmt.Sprintf("%q ", spec) + fmt.Sprintf(format, args...)}
+	}
+	spec = strings.ReplaceAll(spec, " ", "")
+	in, out, explicit := strings.Cut(spec, "->")
+	inputs = strings.Split(in, ",")
+	if len(inputs) != n {
+		return fail("has %d operands, got %d", len(inputs), n)
+	}
+
+	counts := map[rune]int{}
+	for _, labels := range inputs {
+		for _, l := range labels {
+			if !(l >= 'a' && l <= 'z' || l >= 'A' && l <= 'Z') {
+				return fail("has invalid label %q", l)
+			}
+			counts[l]++
+		}
+	}
+
+	if !explicit {
+		var once []string
+		for l, c := range counts {
+			if c == 1 {
+				once = append(once, string(l))
+			}
+		}
+		sort.Strings(once)
+		return inputs, strings.Join(once, ""), nil
+	}
+
+	for i, l := range out {
+		if counts[l] == 0 {
+			return fail("has output label %q that isn't in any operand", l)
+		}
+		if strings.ContainsRune(out[:i], l) {
+			return fail("repeats output label %q", l)
+		}
+	}
+	return inputs, out, nil
+}
+
+// the size of every label, checking that they agree between operands
+func einsumSizes(spec string, inputs []string, shapes [][]int) (map[byte]int, error) {
+	sizes := map[byte]int{}
+	first := map[byte]int{}
+	for i, labels := range inputs {
+		if len(labels) != len(shapes[i]) {
+			return nil, &ShapeError{
+				Op:     "Einsum",
+				Axis:   -1,
+				Actual: shapes[i],
+				Reason: fmt.Sprintf("%q operand %d has %d labels", spec, i, len(labels)),
+			}
+		}
+		for j := range labels {
+			if s, ok := sizes[labels[j]]; ok && s != shapes[i][j] {
+				return nil, &ShapeError{
+					Op:       "Einsum",
+					Axis:     j,
+					Expected: shapes[first[labels[j]]],
+					Actual:   shapes[i],
+					Reason:   fmt.Sprintf("%q operand %d label %q has sizes %d and %d", spec, i, labels[j], s, shapes[i][j]),
+				}
+			}
+			if _, ok := sizes[labels[j]]; !ok {
+				sizes[labels[j]], first[labels[j]] = shapes[i][j], i
+			}
+		}
+	}
+	return sizes, nil
+}
+
+// The output shape of an einsum spec over operands of shapes
+func CheckEinsum(spec string, shapes ...[]int) ([]int, error) {
+	inputs, output, err := ParseEinsum(spec, len(shapes))
+	if err != nil {
+		return nil, err
+	}
+	sizes, err := einsumSizes(spec, inputs, shapes)
+	if err != nil {
+		return nil, err
+	}
+	shape := make([]int, len(output))
+	for i := range output {
+		shape[i] = sizes[output[i]]
+	}
+	return shape, nil
+}
+
+// Evaluates an einsum spec (see ParseEinsum) over the operands. A label repeated within an operand takes
+// its diagonal, and labels missing from the output are summed over. Operands are contracted left to
+// right, each pair as a batched blas Gemm, so the result is Float32 if every operand is and is computed
+// in float64 otherwise.
+func Einsum(spec string, operands ...NDArray) NDArray {
+	dtype := Bool
+	for _, o := range operands {
+		dtype = PromoteTypes(dtype, o.dtype)
+	}
+	shapes := make([][]int, len(operands))
+	for i, o := range operands {
+		shapes[i] = o.shape
+	}
+	return EinsumInto(spec, ZerosOf(dtype, Must(CheckEinsum(spec, shapes...))...), operands...)
+}
+
+func EinsumInto(spec string, arr NDArray, operands ...NDArray) NDArray {
+	requirePacked(arr)
+	shapes := make([][]int, len(operands))
+	for i, o := range operands {
+		shapes[i] = o.shape
+	}
+	Must(CheckEinsum(spec, shapes...))
+	inputs, output, _ := ParseEinsum(spec, len(operands))
+
+	dtype := Float32
+	for _, o := range operands {
+		if o.dtype != Float32 {
+			dtype = Float64
+		}
+	}
+	ops := make([]einsumOperand, len(operands))
+	for i, o := range operands {
+		ops[i] = einsumOperand{labels: inputs[i], arr: o.AsType(dtype)}.diagonal()
+	}
+
+	for len(ops) > 1 {
+		keep := output
+		for _, o := range ops[2:] {
+			keep += o.labels
+		}
+		a, b := ops[0], ops[1]
+		a = a.sumExcept(keep + b.labels)
+		b = b.sumExcept(keep + a.labels)
+		ops = append([]einsumOperand{a.contract(b, keep)}, ops[2:]...)
+	}
+
+	res := ops[0].sumExcept(output)
+	axes := make([]int, len(output))
+	for i := range output {
+		axes[i] = strings.IndexByte(res.labels, output[i])
+	}
+	return res.arr.permute(axes).AsTypeInto(arr)
+}
+
+type einsumOperand struct {
+	labels string
+	arr    NDArray
+}
+
+// Replaces axes with repeated labels by a view of their diagonal
+func (o einsumOperand) diagonal() einsumOperand {
+	arr := o.arr.strided()
+	var labels []byte
+	var shape, strides []int
+	for i, stride := range arr.stridesOf() {
+		if j := strings.IndexByte(string(labels), o.labels[i]); j >= 0 {
+			strides[j] += stride
+			continue
+		}
+		labels = append(labels, o.labels[i])
+		shape = append(shape, arr.shape[i])
+		strides = append(strides, stride)
+	}
+	if len(labels) == len(o.labels) {
+		return o
+	}
+	return einsumOperand{labels: string(labels), arr: arr.view(shape, strides, arr.offset)}
+}
+
+// Sums over every axis whose label isn't in keep
+func (o einsumOperand) sumExcept(keep string) einsumOperand {
+	var axes []int
+	var labels []byte
+	var shape []int
+	for i := range o.labels {
+		if strings.IndexByte(keep, o.labels[i]) < 0 {
+			axes = append(axes, i)
+		} else {
+			labels = append(labels, o.labels[i])
+			shape = append(shape, o.arr.shape[i])
+		}
+	}
+	if len(axes) == 0 {
+		return o
+	}
+	return einsumOperand{labels: string(labels), arr: o.arr.Sum(axes...).Reshape(shape...)}
+}
+
+// Contracts two operands that have no labels to sum over alone. Shared labels in keep are batch axes and
+// the rest are summed over.
+func (a einsumOperand) contract(b einsumOperand, keep string) einsumOperand {
+	var batch, summed, freeA, freeB []byte
+	for i := range a.labels {
+		l := a.labels[i]
+		switch {
+		case strings.IndexByte(b.labels, l) < 0:
+			freeA = append(freeA, l)
+		case strings.IndexByte(keep, l) >= 0:
+			batch = append(batch, l)
+		default:
+			summed = append(summed, l)
+		}
+	}
+	for i := range b.labels {
+		if strings.IndexByte(a.labels, b.labels[i]) < 0 {
+			freeB = append(freeB, b.labels[i])
+		}
+	}
+
+	aMat, nb, m, k := a.matrices(batch, freeA, summed)
+	bMat, _, _, n := b.matrices(batch, summed, freeB)
+
+	labels := string(batch) + string(freeA) + string(freeB)
+	var shape []int
+	for i := range labels {
+		shape = append(shape, a.labelSize(labels[i], b))
+	}
+	out := ZerosOf(aMat.dtype, shape...)
+	if m*k*n > 0 {
+		if out.dtype == Float32 {
+			batchGemm(aMat.data32, bMat.data32, out.data32, nb, m, k, n)
+		} else {
+			batchGemm(aMat.data, bMat.data, out.data, nb, m, k, n)
+		}
+	}
+	return einsumOperand{labels: labels, arr: out}
+}
+
+// o permuted to its batch, row and column labels, as a packed batch of matrices
+func (o einsumOperand) matrices(batch []byte, rows []byte, cols []byte) (arr NDArray, nb int, r int, c int) {
+	var axes []int
+	sizes := []int{1, 1, 1}
+	for g, group := range [][]byte{batch, rows, cols} {
+		for _, l := range group {
+			ax := strings.IndexByte(o.labels, l)
+			axes = append(axes, ax)
+			sizes[g] *= o.arr.shape[ax]
+		}
+	}
+	return o.arr.permute(axes).Contiguous(), sizes[0], sizes[1], sizes[2]
+}
+
+func (a einsumOperand) labelSize(l byte, b einsumOperand) int {
+	if i := strings.IndexByte(a.labels, l); i >= 0 {
+		return a.arr.shape[i]
+	}
+	return b.arr.shape[strings.IndexByte(b.labels, l)]
+}
+
+// c[i] = a[i] b[i] for nb packed m x k and k x n matrices, across the pool
+func batchGemm[T float](a []T, b []T, c []T, nb int, m int, k int, n int) {
+	splitRange(nb, minGrain/(m*k*n)+1, func(start int, end int) {
+		for i := start; i < end; i++ {
+			gemm(blas.NoTrans, blas.NoTrans,
+				general[T]{Rows: m, Cols: k, Data: a[i*m*k:], Stride: k},
+				general[T]{Rows: k, Cols: n, Data: b[i*k*n:], Stride: n},
+				general[T]{Rows: m, Cols: n, Data: c[i*m*n:], Stride: n},
+			)
+		}
+	})
+}
+
+// Returns a view with axis i of the result being axis axes[i] of a
+func (a NDArray) permute(axes []int) NDArray {
+	a = a.strided()
+	strides := a.stridesOf()
+	shape := make([]int, len(axes))
+	permuted := make([]int, len(axes))
+	for i, ax := range axes {
+		shape[i] = a.shape[ax]
+		permuted[i] = strides[ax]
+	}
+	return a.view(shape, permuted, a.offset)
+}
@@ -0,0 +1,98 @@
+package calc_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// einsum by looping over every assignment of the labels
+func naiveEinsum(spec string, operands ...calc.NDArray) calc.NDArray {
+	inputs, output, err := calc.ParseEinsum(spec, len(operands))
+	if err != nil {
+		panic(err)
+	}
+	sizes := map[byte]int{}
+	var labels []byte
+	for i, in := range inputs {
+		for j := range in {
+			if _, ok := sizes[in[j]]; !ok {
+				labels = append(labels, in[j])
+			}
+			sizes[in[j]] = operands[i].Shape()[j]
+		}
+	}
+	shape := make([]int, len(output))
+	for i := range output {
+		shape[i] = sizes[output[i]]
+	}
+	out := calc.Zeros(shape...)
+
+	values := map[byte]int{}
+	var walk func(l int)
+	walk = func(l int) {
+		if l < len(labels) {
+			for v := 0; v < sizes[labels[l]]; v++ {
+				values[labels[l]] = v
+				walk(l + 1)
+			}
+			return
+		}
+		prod := 1.
+		for i, in := range inputs {
+			index := make([]int, len(in))
+			for j := range in {
+				index[j] = values[in[j]]
+			}
+			prod *= operands[i].Get(index)
+		}
+		index := make([]int, len(output))
+		for i := range output {
+			index[i] = values[output[i]]
+		}
+		out.Set(index, out.Get(index)+prod)
+	}
+	walk(0)
+	return out
+}
+
+func TestEinsum(t *testing.T) {
+	rng := calc.NewRNG(8)
+	cases := []struct {
+		spec   string
+		shapes [][]int
+	}{
+		{"ij,jk->ik", [][]int{{3, 4}, {4, 2}}},
+		{"ij,jk", [][]int{{3, 4}, {4, 2}}},
+		{"bij,bjk->bik", [][]int{{2, 3, 4}, {2, 4, 5}}},
+		{"bqd,bkd->bqk", [][]int{{2, 3, 4}, {2, 5, 4}}},
+		{"bi,ijk,bk->bj", [][]int{{3, 4}, {4, 2, 5}, {3, 5}}},
+		{"ij->ji", [][]int{{3, 4}}},
+		{"ij->", [][]int{{3, 4}}},
+		{"i,j->ij", [][]int{{3}, {4}}},
+		{"ii->", [][]int{{3, 3}}},
+		{"ii->i", [][]int{{3, 3}}},
+		{"bii->bi", [][]int{{2, 3, 3}}},
+		{"ii,ij->j", [][]int{{3, 3}, {3, 4}}},
+		{"ijk,jil->kl", [][]int{{2, 3, 4}, {3, 2, 5}}},
+		{"b i j, b j -> b i", [][]int{{2, 3, 4}, {2, 4}}},
+	}
+	for _, c := range cases {
+		operands := make([]calc.NDArray, len(c.shapes))
+		for i, shape := range c.shapes {
+			operands[i] = rng.Normal(0, 1, shape...)
+		}
+		want := naiveEinsum(c.spec, operands...)
+		checkClose(t, c.spec, calc.Einsum(c.spec, operands...), want)
+
+		for i := range operands {
+			operands[i] = operands[i].AsType(calc.Float32)
+		}
+		got := calc.Einsum(c.spec, operands...)
+		if got.DType() != calc.Float32 {
+			t.Errorf("%s of float32 is %s", c.spec, got.DType())
+		}
+		// scaled so checkClose allows float32 rounding
+		checkClose(t, c.spec+" float32", got.AsType(calc.Float64).MulConstant(1e-4), want.MulConstant(1e-4))
+	}
+}
@@ -0,0 +1,211 @@
+package calc
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Describes arrays or shapes an op can't work with. The Check functions return one, and ops that
+// return arrays panic with one.
+type ShapeError struct {
+	Op string
+	// the axis at fault, or -1 when it isn't about one axis
+	Axis     int
+	Expected []int
+	Actual   []int
+	Reason   string
+}
+
+func (e *ShapeError) Error() string {
+	parts := []string{e.Op}
+	if e.Axis >= 0 {
+		parts = append(parts, fmt.Sprintf("axis %d", e.Axis))
+	}
+	if e.Reason != "" {
+		parts = append(parts, e.Reason)
+	}
+	if e.Expected != nil {
+		parts = append(parts, fmt.Sprintf("expected %v, got %v", e.Expected, e.Actual))
+	} else if e.Actual != nil {
+		parts = append(parts, fmt.Sprintf("shape %v", e.Actual))
+	}
+	return strings.Join(parts, ": ")
+}
+
+// Returns v, panicking with err if it isn't nil. It wraps the error-returning functions for the panicking
+// style, like calc.Must(calc.NewArray(shape, data)).
+func Must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func NewArray(shape []int, data []float64) (NDArray, error) {
+	if err := checkData("NewArray", shape, len(data)); err != nil {
+		return NDArray{}, err
+	}
+	return NDArray{shape: shape, data: data}, nil
+}
+
+func NewArray32(shape []int, data []float32) (NDArray, error) {
+	if err := checkData("NewArray32", shape, len(data)); err != nil {
+		return NDArray{}, err
+	}
+	return NDArray{shape: shape, dtype: Float32, data32: data}, nil
+}
+
+func NewArrayInt64(shape []int, data []int64) (NDArray, error) {
+	if err := checkData("NewArrayInt64", shape, len(data)); err != nil {
+		return NDArray{}, err
+	}
+	return NDArray{shape: shape, dtype: Int64, dataI64: data}, nil
+}
+
+func NewArrayBool(shape []int, data []bool) (NDArray, error) {
+	if err := checkData("NewArrayBool", shape, len(data)); err != nil {
+		return NDArray{}, err
+	}
+	return NDArray{shape: shape, dtype: Bool, dataBool: data}, nil
+}
+
+func checkData(op string, shape []int, n int) error {
+	for i, sz := range shape {
+		if sz < 0 {
+			return &ShapeError{Op: op, Axis: i, Actual: shape, Reason: "negative size"}
+		}
+	}
+	if size := shapeSize(shape); size != n {
+		return &ShapeError{Op: op, Axis: -1, Reason: fmt.Sprintf("shape %v needs %d elements but data has %d", shape, size, n)}
+	}
+	return nil
+}
+
+// Checks that every axis is in range for shape
+func CheckAxes(op string, shape []int, axes ...int) error {
+	for _, ax := range axes {
+		if ax < 0 || ax >= len(shape) {
+			return &ShapeError{Op: op, Axis: ax, Actual: shape, Reason: fmt.Sprintf("out of range for rank %d", len(shape))}
+		}
+	}
+	return nil
+}
+
+// The shape a and b broadcast to (see BroadcastShape)
+func CheckBroadcast(op string, aShape []int, bShape []int) ([]int, error) {
+	if shape := BroadcastShape(aShape, bShape); shape != nil {
+		return shape, nil
+	}
+	rank := max(len(aShape), len(bShape))
+	pa, pb := padShape(aShape, rank), padShape(bShape, rank)
+	axis := rank - 1
+	for pa[axis] == pb[axis] || pa[axis] == 1 || pb[axis] == 1 {
+		axis--
+	}
+	return nil, &ShapeError{
+		Op:       op,
+		Axis:     axis,
+		Expected: aShape,
+		Actual:   bShape,
+		Reason:   fmt.Sprintf("can't broadcast sizes %d and %d", pa[axis], pb[axis]),
+	}
+}
+
+// Checks that shape broadcasts to out without growing, as ops writing into out need
+func checkBroadcastTo(op string, shape []int, out []int) {
+	if len(shape) > len(out) {
+		panic(&ShapeError{Op: op, Axis: -1, Expected: out, Actual: shape, Reason: "operand has a higher rank than the output"})
+	}
+	padded := padShape(shape, len(out))
+	for i := range out {
+		if padded[i] != out[i] && padded[i] != 1 {
+			panic(&ShapeError{Op: op, Axis: i, Expected: out, Actual: shape, Reason: "operand doesn't broadcast to the output"})
+		}
+	}
+}
+
+// The shape of a matrix product over axes a1 and a2 (see MatMulShape)
+func CheckMatMul(aShape []int, bShape []int, a1 int, a2 int) ([]int, error) {
+	if len(aShape) != len(bShape) {
+		return nil, &ShapeError{Op: "MatMul", Axis: -1, Expected: aShape, Actual: bShape, Reason: "ranks differ"}
+	}
+	if err := CheckAxes("MatMul", aShape, a1, a2); err != nil {
+		return nil, err
+	}
+	if a1 == a2 {
+		return nil, &ShapeError{Op: "MatMul", Axis: a1, Reason: "row and column axes are the same"}
+	}
+	if aShape[a2] != bShape[a1] {
+		return nil, &ShapeError{
+			Op:       "MatMul",
+			Axis:     a1,
+			Expected: aShape,
+			Actual:   bShape,
+			Reason:   fmt.Sprintf("a has %d columns on axis %d but b has %d rows", aShape[a2], a2, bShape[a1]),
+		}
+	}
+	tAShape := append([]int{}, aShape...)
+	tBShape := append([]int{}, bShape...)
+	tAShape[a1], tAShape[a2], tBShape[a1], tBShape[a2] = 1, 1, 1, 1
+	outShape, err := CheckBroadcast("MatMul", tAShape, tBShape)
+	if err != nil {
+		return nil, err
+	}
+	outShape[a1] = aShape[a1]
+	outShape[a2] = bShape[a2]
+	return outShape, nil
+}
+
+// Checks that an array of shape can be reshaped to newShape
+func CheckReshape(shape []int, newShape []int) error {
+	for i, sz := range newShape {
+		if sz < 0 {
+			return &ShapeError{Op: "Reshape", Axis: i, Actual: newShape, Reason: "negative size"}
+		}
+	}
+	if shapeSize(shape) != shapeSize(newShape) {
+		return &ShapeError{
+			Op:       "Reshape",
+			Axis:     -1,
+			Expected: shape,
+			Actual:   newShape,
+			Reason:   fmt.Sprintf("sizes %d and %d differ", shapeSize(shape), shapeSize(newShape)),
+		}
+	}
+	return nil
+}
+
+// The shape of shapes joined along axis, which must match on every other axis
+func CheckConcat(axis int, shapes ...[]int) ([]int, error) {
+	if len(shapes) == 0 {
+		return nil, &ShapeError{Op: "Concat", Axis: axis, Reason: "nothing to concatenate"}
+	}
+	if err := CheckAxes("Concat", shapes[0], axis); err != nil {
+		return nil, err
+	}
+	out := append([]int{}, shapes[0]...)
+	for _, shape := range shapes[1:] {
+		if len(shape) != len(out) {
+			return nil, &ShapeError{Op: "Concat", Axis: -1, Expected: shapes[0], Actual: shape, Reason: "ranks differ"}
+		}
+		for i := range shape {
+			if i == axis {
+				out[i] += shape[i]
+			} else if shape[i] != out[i] {
+				return nil, &ShapeError{Op: "Concat", Axis: i, Expected: shapes[0], Actual: shape, Reason: "sizes differ off the concatenated axis"}
+			}
+		}
+	}
+	return out, nil
+}
+
+// Checks that [start, end) is a valid range of axis
+func CheckSlice(shape []int, axis int, start int, end int) error {
+	if err := CheckAxes("Slice", shape, axis); err != nil {
+		return err
+	}
+	if start < 0 || end < start || end > shape[axis] {
+		return &ShapeError{Op: "Slice", Axis: axis, Actual: shape, Reason: fmt.Sprintf("range [%d, %d) is out of bounds", start, end)}
+	}
+	return nil
+}
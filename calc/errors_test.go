@@ -0,0 +1,161 @@
+package calc_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+var shapeErrorCases = []struct {
+	name  string
+	check func() error
+}{
+	{"Conv kernel larger than input", func() error {
+		_, err := calc.CheckConv([]int{1, 3, 3, 2}, []int{5, 5, 2, 4}, []int{1, 2}, 3, calc.ConvOpts{})
+		return err
+	}},
+	{"Conv dilated kernel larger than input", func() error {
+		_, err := calc.CheckConv([]int{1, 5, 2}, []int{3, 2, 4}, []int{1}, 2, calc.ConvOpts{Dilations: []int{3}})
+		return err
+	}},
+	{"Conv input filters", func() error {
+		_, err := calc.CheckConv([]int{1, 5, 5, 3}, []int{3, 3, 2, 4}, []int{1, 2}, 3, calc.ConvOpts{})
+		return err
+	}},
+	{"Conv groups", func() error {
+		_, err := calc.CheckConv([]int{1, 5, 5, 4}, []int{3, 3, 2, 3}, []int{1, 2}, 3, calc.ConvOpts{Groups: 2})
+		return err
+	}},
+	{"Conv missing explicit padding", func() error {
+		_, err := calc.CheckConv([]int{1, 5, 5, 1}, []int{3, 3, 1, 1}, []int{1, 2}, 3, calc.ConvOpts{Padding: calc.PaddingExplicit, Pads: []int{1, 1}})
+		return err
+	}},
+	{"Conv kernel rank", func() error {
+		_, err := calc.CheckConv([]int{1, 5, 5, 1}, []int{3, 1, 1}, []int{1, 2}, 3, calc.ConvOpts{})
+		return err
+	}},
+	{"Conv axis", func() error {
+		_, err := calc.CheckConv([]int{1, 5, 1}, []int{3, 1, 1}, []int{3}, 2, calc.ConvOpts{})
+		return err
+	}},
+	{"ConvTranspose filters", func() error {
+		_, err := calc.CheckConvTranspose([]int{1, 5, 3}, []int{3, 2, 4}, []int{1}, 2, calc.ConvOpts{})
+		return err
+	}},
+	{"ConvTranspose output padding", func() error {
+		_, err := calc.CheckConvTranspose([]int{1, 5, 4}, []int{3, 2, 4}, []int{1}, 2, calc.ConvOpts{Strides: []int{2}, OutputPadding: []int{2}})
+		return err
+	}},
+	{"InverseConv gradient shape", func() error {
+		_, err := calc.CheckInverseConv([]int{1, 5, 2}, []int{1, 4, 3}, []int{3}, []int{1}, 2, calc.ConvOpts{})
+		return err
+	}},
+	{"Einsum operands", func() error {
+		_, err := calc.CheckEinsum("ij,jk->ik", []int{2, 3})
+		return err
+	}},
+	{"Einsum label sizes", func() error {
+		_, err := calc.CheckEinsum("ij,jk->ik", []int{2, 3}, []int{4, 5})
+		return err
+	}},
+	{"Einsum label count", func() error {
+		_, err := calc.CheckEinsum("ijk->i", []int{2, 3})
+		return err
+	}},
+	{"Einsum invalid label", func() error {
+		_, err := calc.CheckEinsum("i1->i", []int{2, 3})
+		return err
+	}},
+	{"Einsum unknown output label", func() error {
+		_, err := calc.CheckEinsum("ij->k", []int{2, 3})
+		return err
+	}},
+	{"Einsum repeated output label", func() error {
+		_, err := calc.CheckEinsum("ij->ii", []int{2, 3})
+		return err
+	}},
+	{"Matrices rank", func() error { return calc.CheckMatrices("QR", []int{3}) }},
+	{"Inverse not square", func() error { return calc.CheckSquareMatrices("Inverse", []int{2, 3, 4}) }},
+	{"Solve rows", func() error { return calc.CheckSolve([]int{3, 3}, []int{4, 2}) }},
+	{"Solve batch", func() error { return calc.CheckSolve([]int{2, 3, 3}, []int{5, 3, 2}) }},
+	{"Gather rank", func() error { return calc.CheckGather([]int{3, 4}, []int{3}, 1) }},
+	{"Gather other axis", func() error { return calc.CheckGather([]int{3, 4}, []int{2, 4}, 1) }},
+	{"Scatter values", func() error {
+		_, err := calc.CheckScatter([]int{3, 2}, []int{3, 3}, 1, 5)
+		return err
+	}},
+	{"IndexSelect rank", func() error {
+		_, err := calc.CheckIndexSelect([]int{3, 4}, []int{2, 2}, 0)
+		return err
+	}},
+	{"IndexAdd values", func() error {
+		_, err := calc.CheckIndexAdd([]int{3, 4}, []int{2}, 0, 5)
+		return err
+	}},
+	{"TopK too many", func() error {
+		_, err := calc.CheckTopK([]int{3, 4}, 5, 1)
+		return err
+	}},
+	{"TopK axis", func() error {
+		_, err := calc.CheckTopK([]int{3, 4}, 1, 2)
+		return err
+	}},
+	{"Pad rank", func() error {
+		_, err := calc.CheckPad([]int{3, 4}, [][2]int{{1, 1}}, calc.PadConstant)
+		return err
+	}},
+	{"Pad negative width", func() error {
+		_, err := calc.CheckPad([]int{3}, [][2]int{{-1, 1}}, calc.PadConstant)
+		return err
+	}},
+	{"Pad mode", func() error {
+		_, err := calc.CheckPad([]int{3}, [][2]int{{1, 1}}, calc.PadMode(7))
+		return err
+	}},
+	{"Pad reflect empty axis", func() error {
+		_, err := calc.CheckPad([]int{0}, [][2]int{{1, 0}}, calc.PadReflect)
+		return err
+	}},
+}
+
+func TestShapeErrors(t *testing.T) {
+	for _, c := range shapeErrorCases {
+		var se *calc.ShapeError
+		if err := c.check(); !errors.As(err, &se) {
+			t.Errorf("%s: got %v, want a ShapeError", c.name, err)
+		}
+	}
+}
+
+func TestShapeErrorPanics(t *testing.T) {
+	ops := []struct {
+		name string
+		op   func()
+	}{
+		{"Conv2D kernel larger than input", func() {
+			calc.Zeros(1, 3, 3, 2).Conv2D(calc.Zeros(5, 5, 2, 4), 1, 2, 3, calc.ConvOpts{})
+		}},
+		{"Conv2D groups", func() {
+			calc.Zeros(1, 5, 5, 3).Conv2D(calc.Zeros(3, 3, 1, 4), 1, 2, 3, calc.ConvOpts{Groups: 2})
+		}},
+		{"Einsum label sizes", func() {
+			calc.Einsum("ij,jk->ik", calc.Zeros(2, 3), calc.Zeros(4, 5))
+		}},
+		{"Inverse not square", func() { calc.Zeros(2, 3).Inverse() }},
+		{"Det rank", func() { calc.Zeros(3).Det() }},
+		{"Solve", func() { calc.Zeros(3, 3).Solve(calc.Zeros(2, 1)) }},
+		{"Gather", func() { calc.Zeros(3, 4).Gather(calc.ZerosOf(calc.Int64, 3), 1) }},
+		{"TopK", func() { calc.Zeros(3, 4).TopK(5, 1) }},
+	}
+	for _, c := range ops {
+		func() {
+			defer func() {
+				if _, ok := recover().(*calc.ShapeError); !ok {
+					t.Errorf("%s didn't panic with a ShapeError", c.name)
+				}
+			}()
+			c.op()
+		}()
+	}
+}
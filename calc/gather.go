@@ -0,0 +1,216 @@
+package calc
+
+import "fmt"
+
+// Indexing along an axis by an Int64 (or any integer valued) array of the same rank. Every axis but the
+// indexed one must have the same size in both.
+
+func checkIndexShape(op string, aShape []int, iShape []int, axis int) error {
+	if err := CheckAxes(op, aShape, axis); err != nil {
+		return err
+	}
+	if len(aShape) != len(iShape) {
+		return &ShapeError{Op: op, Axis: -1, Expected: aShape, Actual: iShape, Reason: "indices have a different rank"}
+	}
+	for i := range aShape {
+		if i != axis && aShape[i] != iShape[i] {
+			return &ShapeError{Op: op, Axis: i, Expected: aShape, Actual: iShape, Reason: "indices differ off the indexed axis"}
+		}
+	}
+	return nil
+}
+
+// Checks that indices of iShape can gather from shape along axis
+func CheckGather(shape []int, iShape []int, axis int) error {
+	return checkIndexShape("Gather", shape, iShape, axis)
+}
+
+// The shape of scattering values of shape by indices of iShape along axis into an output with the given
+// size on axis
+func CheckScatter(shape []int, iShape []int, axis int, size int) ([]int, error) {
+	if !ShapeEqual(shape, iShape) {
+		return nil, &ShapeError{Op: "Scatter", Axis: -1, Expected: iShape, Actual: shape, Reason: "values don't match the indices"}
+	}
+	if err := CheckAxes("Scatter", iShape, axis); err != nil {
+		return nil, err
+	}
+	out := append([]int{}, iShape...)
+	out[axis] = size
+	return out, nil
+}
+
+func checkIndex(idx int, n int) int {
+	if idx < 0 || idx >= n {
+		panic(fmt.Sprintf("index %d out of range for axis of size %d", idx, n))
+	}
+	return idx
+}
+
+func (a NDArray) Gather(indices NDArray, axis int) NDArray {
+	arr := ZerosOf(a.dtype, indices.shape...)
+	return a.GatherInto(indices, axis, arr)
+}
+
+// arr[..., i, ...] = a[..., indices[..., i, ...], ...] along axis
+func (a NDArray) GatherInto(indices NDArray, axis int, arr NDArray) NDArray {
+	requirePacked(arr)
+	if err := CheckGather(a.shape, indices.shape, axis); err != nil {
+		panic(err)
+	}
+	a = a.AsType(arr.dtype)
+	cp := elemCopier(arr, a)
+	n := a.shape[axis]
+	walkLanes(axis, func(starts []int, steps [][]int) {
+		for i, step := range steps[0] {
+			idx := checkIndex(int(indices.at(starts[0]+step)), n)
+			cp(starts[2]+steps[2][i], starts[1]+steps[1][idx])
+		}
+	}, indices.layout(), a.layout(), arr.layout())
+	return arr
+}
+
+// Adds a into arr[..., indices[..., i, ...], ...] along axis, where a has the shape of indices. This is
+// the gradient of Gather.
+func (a NDArray) ScatterAddInto(indices NDArray, axis int, arr NDArray) NDArray {
+	requirePacked(arr)
+	if err := checkIndexShape("Scatter", arr.shape, indices.shape, axis); err != nil {
+		panic(err)
+	}
+	Must(CheckScatter(a.shape, indices.shape, axis, arr.shape[axis]))
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr64 NDArray) NDArray {
+			// via64Into starts from zeros
+			arr.AsTypeInto(arr64)
+			return a.ScatterAddInto(indices, axis, arr64)
+		})
+	}
+	n := arr.shape[axis]
+	walkLanes(axis, func(starts []int, steps [][]int) {
+		for i, step := range steps[0] {
+			idx := checkIndex(int(indices.at(starts[0]+step)), n)
+			arr.data[starts[2]+steps[2][idx]] += a.data[starts[1]+steps[1][i]]
+		}
+	}, indices.layout(), a.layout(), arr.layout())
+	return arr
+}
+
+func (a NDArray) Scatter(indices NDArray, axis int, size int) NDArray {
+	arr := ZerosOf(a.dtype, Must(CheckScatter(a.shape, indices.shape, axis, size))...)
+	return a.ScatterInto(indices, axis, arr)
+}
+
+// arr[..., indices[..., i, ...], ...] = a[..., i, ...] along axis, where a has the shape of indices.
+// Elements of arr that aren't indexed are left as they are, and the last of any repeated index wins.
+func (a NDArray) ScatterInto(indices NDArray, axis int, arr NDArray) NDArray {
+	requirePacked(arr)
+	if err := checkIndexShape("Scatter", arr.shape, indices.shape, axis); err != nil {
+		panic(err)
+	}
+	Must(CheckScatter(a.shape, indices.shape, axis, arr.shape[axis]))
+	a = a.AsType(arr.dtype)
+	cp := elemCopier(arr, a)
+	n := arr.shape[axis]
+	walkLanes(axis, func(starts []int, steps [][]int) {
+		for i, step := range steps[0] {
+			idx := checkIndex(int(indices.at(starts[0]+step)), n)
+			cp(starts[2]+steps[2][idx], starts[1]+steps[1][i])
+		}
+	}, indices.layout(), a.layout(), arr.layout())
+	return arr
+}
+
+func (a NDArray) ScatterAdd(indices NDArray, axis int, size int) NDArray {
+	arr := ZerosOf(a.dtype, Must(CheckScatter(a.shape, indices.shape, axis, size))...)
+	return a.ScatterAddInto(indices, axis, arr)
+}
+
+// Selecting whole slices along an axis by a 1-d integer array of indices
+
+// The shape of selecting from shape by 1-d indices of iShape along axis
+func CheckIndexSelect(shape []int, iShape []int, axis int) ([]int, error) {
+	out, err := checkIndexLanes("IndexSelect", shape, iShape, axis, 0)
+	if err == nil {
+		out[axis] = iShape[0]
+	}
+	return out, err
+}
+
+// The shape of adding values of shape at 1-d indices of iShape along axis, into an output with the given
+// size on axis
+func CheckIndexAdd(shape []int, iShape []int, axis int, size int) ([]int, error) {
+	out, err := checkIndexLanes("IndexAdd", shape, iShape, axis, size)
+	if err == nil && shape[axis] != iShape[0] {
+		err = &ShapeError{Op: "IndexAdd", Axis: axis, Expected: iShape, Actual: shape, Reason: "values don't match the indices"}
+	}
+	return out, err
+}
+
+func checkIndexLanes(op string, shape []int, iShape []int, axis int, size int) ([]int, error) {
+	if err := CheckAxes(op, shape, axis); err != nil {
+		return nil, err
+	}
+	if len(iShape) != 1 {
+		return nil, &ShapeError{Op: op, Axis: -1, Actual: iShape, Reason: "indices must be 1-d"}
+	}
+	out := append([]int{}, shape...)
+	out[axis] = size
+	return out, nil
+}
+
+func (a NDArray) IndexSelect(indices NDArray, axis int) NDArray {
+	arr := ZerosOf(a.dtype, Must(CheckIndexSelect(a.shape, indices.shape, axis))...)
+	return a.IndexSelectInto(indices, axis, arr)
+}
+
+// arr[..., i, ...] = a[..., indices[i], ...] along axis
+func (a NDArray) IndexSelectInto(indices NDArray, axis int, arr NDArray) NDArray {
+	requirePacked(arr)
+	Must(CheckIndexSelect(a.shape, indices.shape, axis))
+	a = a.AsType(arr.dtype)
+	cp := elemCopier(arr, a)
+	idx := laneIndices(indices, a.shape[axis])
+	walkLanes(axis, func(starts []int, steps [][]int) {
+		for j, step := range steps[0] {
+			cp(starts[0]+step, starts[1]+steps[1][idx[j]])
+		}
+	}, arr.layout(), a.layout())
+	return arr
+}
+
+// the checked values of 1-d indices into an axis of size n
+func laneIndices(indices NDArray, n int) []int {
+	idx := make([]int, indices.shape[0])
+	for j := range idx {
+		idx[j] = checkIndex(int(indices.Get([]int{j})), n)
+	}
+	return idx
+}
+
+func (a NDArray) IndexAdd(indices NDArray, axis int, size int) NDArray {
+	arr := ZerosOf(a.dtype, Must(CheckIndexAdd(a.shape, indices.shape, axis, size))...)
+	return a.IndexAddInto(indices, axis, arr)
+}
+
+// Adds a[..., i, ...] into arr[..., indices[i], ...] along axis. This is the gradient of IndexSelect.
+func (a NDArray) IndexAddInto(indices NDArray, axis int, arr NDArray) NDArray {
+	requirePacked(arr)
+	if err := CheckAxes("IndexAdd", arr.shape, axis); err != nil {
+		panic(err)
+	}
+	Must(CheckIndexAdd(a.shape, indices.shape, axis, arr.shape[axis]))
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr64 NDArray) NDArray {
+			// via64Into starts from zeros
+			arr.AsTypeInto(arr64)
+			return a.IndexAddInto(indices, axis, arr64)
+		})
+	}
+	idx := laneIndices(indices, arr.shape[axis])
+	// repeated indices add into the same element of a lane, and each lane is walked by one worker
+	walkLanes(axis, func(starts []int, steps [][]int) {
+		for j, step := range steps[0] {
+			arr.data[starts[1]+steps[1][idx[j]]] += a.data[starts[0]+step]
+		}
+	}, a.layout(), arr.layout())
+	return arr
+}
@@ -0,0 +1,91 @@
+package calc_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// random indices below n with the given shape, which repeat when there are more than n of them along axis
+func randomIndices(rng *calc.RNG, n int, shape ...int) calc.NDArray {
+	indices := calc.ZerosOf(calc.Int64, shape...)
+	rng.Uniform(0, float64(n), shape...).ForEach(func(_ int, index []int, v float64) {
+		indices.Set(index, v)
+	})
+	return indices
+}
+
+// index with its entry on axis replaced by i
+func along(index []int, axis int, i int) []int {
+	index = append([]int{}, index...)
+	index[axis] = i
+	return index
+}
+
+func TestGatherScatter(t *testing.T) {
+	rng := calc.NewRNG(34)
+	a := rng.Normal(0, 1, 3, 4, 5)
+	for axis := 0; axis < 3; axis++ {
+		shape := append([]int{}, a.Shape()...)
+		shape[axis] = 6
+		indices := randomIndices(rng, a.Shape()[axis], shape...)
+		values := rng.Normal(0, 1, shape...)
+
+		gathered, scatterAdded := calc.Zeros(shape...), calc.Zeros(a.Shape()...)
+		indices.ForEach(func(_ int, index []int, i float64) {
+			gathered.Set(index, a.Get(along(index, axis, int(i))))
+			to := along(index, axis, int(i))
+			scatterAdded.Set(to, scatterAdded.Get(to)+values.Get(index))
+		})
+		checkSame(t, "Gather", a.Gather(indices, axis), gathered)
+		checkSame(t, "Gather of a transposed view", a.Permute(2, 1, 0).Gather(indices.Permute(2, 1, 0), 2-axis), gathered.Permute(2, 1, 0))
+		checkClose(t, "ScatterAdd", values.ScatterAdd(indices, axis, a.Shape()[axis]), scatterAdded)
+
+		// unindexed elements keep their value, and the last of repeated indices wins
+		scattered := a.Contiguous().MulConstant(1)
+		want := a.Contiguous().MulConstant(1)
+		indices.ForEach(func(_ int, index []int, i float64) {
+			want.Set(along(index, axis, int(i)), values.Get(index))
+		})
+		checkSame(t, "ScatterInto", values.ScatterInto(indices, axis, scattered), want)
+	}
+}
+
+func TestIndexSelect(t *testing.T) {
+	rng := calc.NewRNG(35)
+	a := rng.Normal(0, 1, 3, 4, 5)
+	for axis := 0; axis < 3; axis++ {
+		indices := randomIndices(rng, a.Shape()[axis], 7)
+		selected := a.IndexSelect(indices, axis)
+		added := selected.IndexAdd(indices, axis, a.Shape()[axis])
+
+		wantAdded := calc.Zeros(a.Shape()...)
+		selected.ForEach(func(_ int, index []int, v float64) {
+			i := int(indices.Get([]int{index[axis]}))
+			if w := a.Get(along(index, axis, i)); v != w {
+				t.Errorf("IndexSelect along %d %v = %v, want %v", axis, index, v, w)
+			}
+			to := along(index, axis, i)
+			wantAdded.Set(to, wantAdded.Get(to)+v)
+		})
+		checkClose(t, "IndexAdd", added, wantAdded)
+	}
+
+	// an embedding lookup of rows, with the indices as floats
+	table := rng.Normal(0, 1, 5, 3)
+	checkSame(t, "IndexSelect float indices", table.IndexSelect(calc.FromRaw([]int{2}, []float64{4, 4}), 0), table.Slice(0, 4, 5).Concat(table.Slice(0, 4, 5), 0))
+
+	for name, f := range map[string]func(){
+		"Gather":      func() { a.Gather(calc.Constant(3, 1, 4, 5).AsType(calc.Int64), 0) },
+		"IndexSelect": func() { a.IndexSelect(calc.FromRawInt64([]int{1}, []int64{-1}), 1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s with an out of range index didn't panic", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
@@ -0,0 +1,133 @@
+package calc
+
+import (
+	"sync/atomic"
+
+	"gonum.org/v1/gonum/blas"
+)
+
+// The blas convolutions either call gemm once per kernel tap and output row, or with im2col copy the
+// input patch of every output position into a (positions, taps * filters) matrix and convolve each image
+// with a single large gemm. im2col uses more memory but is usually faster for small filter counts.
+var im2col atomic.Bool
+
+// Switches the blas convolutions to the im2col path. Defaults to off.
+func SetConvIm2Col(on bool) {
+	im2col.Store(on)
+}
+
+func ConvIm2Col() bool {
+	return im2col.Load()
+}
+
+// an input position copied into the column matrix, both in units of filters
+type patch struct {
+	col int
+	in  int
+}
+
+// Every (output position, kernel tap) pair that lands inside the input. The column index is
+// position*kSize + tap, and the input index is the flattened spatial position of the input.
+func convPatches(dims []convDim) []patch {
+	_, kSize, _ := convSizes(dims)
+	var patches []patch
+	pos := make([]int, len(dims))
+	p := 0
+
+	var taps func(d int, kPos int, inPos int)
+	taps = func(d int, kPos int, inPos int) {
+		if d == len(dims) {
+			patches = append(patches, patch{col: p*kSize + kPos, in: inPos})
+			return
+		}
+		dim := dims[d]
+		for kk := 0; kk < dim.k; kk++ {
+			ip := dim.pos(pos[d], kk)
+			if ip < 0 || ip >= dim.in {
+				continue
+			}
+			taps(d+1, kPos*dim.k+kk, inPos*dim.in+ip)
+		}
+	}
+	var outs func(d int)
+	outs = func(d int) {
+		if d == len(dims) {
+			taps(0, 0, 0)
+			p++
+			return
+		}
+		for o := 0; o < dims[d].out; o++ {
+			pos[d] = o
+			outs(d + 1)
+		}
+	}
+	outs(0)
+	return patches
+}
+
+// copies the inf filters of group g of a single image into col, which must already be zeroed
+func im2colImage[T float](in []T, col []T, patches []patch, inf int, groups int, g int) {
+	for _, p := range patches {
+		i := p.in*inf*groups + g*inf
+		copy(col[p.col*inf:(p.col+1)*inf], in[i:i+inf])
+	}
+}
+
+// adds col back into the inf filters of group g of a single image
+func col2imImage[T float](col []T, out []T, patches []patch, inf int, groups int, g int) {
+	for _, p := range patches {
+		o := out[p.in*inf*groups+g*inf:]
+		for f, v := range col[p.col*inf : (p.col+1)*inf] {
+			o[f] += v
+		}
+	}
+}
+
+func zero[T float](s []T) {
+	for i := range s {
+		s[i] = 0
+	}
+}
+
+// the column matrix of a group is (outSize, kSize * inf), and the kernel of a group is the
+// (kSize * inf, kf / groups) matrix starting at column g * kf / groups
+
+func im2colConvImage[T float](in []T, k []T, out []T, col []T, patches []patch, outSize int, inf int, kf int, groups int) {
+	gkf, rowLen := kf/groups, len(col)/outSize
+	for g := 0; g < groups; g++ {
+		zero(col)
+		im2colImage(in, col, patches, inf, groups, g)
+		gemm(blas.NoTrans, blas.NoTrans,
+			general[T]{Rows: outSize, Cols: rowLen, Data: col, Stride: rowLen},
+			general[T]{Rows: rowLen, Cols: gkf, Data: k[g*gkf:], Stride: kf},
+			general[T]{Rows: outSize, Cols: gkf, Data: out[g*gkf:], Stride: kf},
+		)
+	}
+}
+
+func im2colInverseConvImage[T float](in []T, g []T, k []T, col []T, patches []patch, outSize int, inf int, kf int, groups int) {
+	gkf, rowLen := kf/groups, len(col)/outSize
+	for gr := 0; gr < groups; gr++ {
+		zero(col)
+		im2colImage(in, col, patches, inf, groups, gr)
+		gemm(blas.Trans, blas.NoTrans,
+			general[T]{Rows: outSize, Cols: rowLen, Data: col, Stride: rowLen},
+			general[T]{Rows: outSize, Cols: gkf, Data: g[gr*gkf:], Stride: kf},
+			general[T]{Rows: rowLen, Cols: gkf, Data: k[gr*gkf:], Stride: kf},
+		)
+	}
+}
+
+// in is the (outSize, kf) output of the forward convolution and out its (inSize, outf * groups) input
+func im2colConvTransposeImage[T float](in []T, k []T, out []T, col []T, patches []patch, outSize int, outf int, kf int, groups int) {
+	gkf, rowLen := kf/groups, len(col)/outSize
+	for g := 0; g < groups; g++ {
+		zero(col)
+		gemm(blas.NoTrans, blas.Trans,
+			general[T]{Rows: outSize, Cols: gkf, Data: in[g*gkf:], Stride: kf},
+			general[T]{Rows: rowLen, Cols: gkf, Data: k[g*gkf:], Stride: kf},
+			general[T]{Rows: outSize, Cols: rowLen, Data: col, Stride: rowLen},
+		)
+		col2imImage(col, out, patches, outf, groups, g)
+	}
+}
@@ -0,0 +1,358 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/blas"
+	"gonum.org/v1/gonum/blas/blas64"
+	"gonum.org/v1/gonum/lapack"
+	"gonum.org/v1/gonum/lapack/lapack64"
+)
+
+// Linear algebra works on the trailing two axes, treating every index of the leading axes as a separate
+// matrix. Matrices are computed in float64 with lapack, in parallel over the batch.
+
+// Checks that shape is a batch of matrices, with at least two axes
+func CheckMatrices(op string, shape []int) error {
+	if len(shape) < 2 {
+		return &ShapeError{Op: op, Axis: -1, Actual: shape, Reason: "expected a batch of matrices"}
+	}
+	return nil
+}
+
+// Checks that shape is a batch of square matrices
+func CheckSquareMatrices(op string, shape []int) error {
+	if err := CheckMatrices(op, shape); err != nil {
+		return err
+	}
+	if rows, cols := shape[len(shape)-2], shape[len(shape)-1]; rows != cols {
+		return &ShapeError{Op: op, Axis: len(shape) - 1, Actual: shape, Reason: fmt.Sprintf("expected square matrices, got %d x %d", rows, cols)}
+	}
+	return nil
+}
+
+// Checks that a x = b can be solved, where a is a batch of square matrices and b has the same leading axes
+// and as many rows
+func CheckSolve(aShape []int, bShape []int) error {
+	if err := CheckSquareMatrices("Solve", aShape); err != nil {
+		return err
+	}
+	if err := CheckMatrices("Solve", bShape); err != nil {
+		return err
+	}
+	n := len(aShape)
+	if len(bShape) != n || !ShapeEqual(aShape[:n-1], bShape[:n-1]) {
+		return &ShapeError{Op: "Solve", Axis: -1, Expected: aShape, Actual: bShape, Reason: "can't solve against b"}
+	}
+	return nil
+}
+
+// the number of matrices in shape and the size of each
+func matrixDims(op string, shape []int) (batch int, rows int, cols int) {
+	if err := CheckMatrices(op, shape); err != nil {
+		panic(err)
+	}
+	rows, cols = shape[len(shape)-2], shape[len(shape)-1]
+	return shapeSize(shape[:len(shape)-2]), rows, cols
+}
+
+func squareDims(op string, shape []int) (batch int, n int) {
+	if err := CheckSquareMatrices(op, shape); err != nil {
+		panic(err)
+	}
+	return shapeSize(shape[:len(shape)-2]), shape[len(shape)-1]
+}
+
+// matrix i of a packed float64 array, sharing its storage
+func matrixAt(data []float64, i int, rows int, cols int) blas64.General {
+	return blas64.General{Rows: rows, Cols: cols, Data: data[i*rows*cols : (i+1)*rows*cols], Stride: cols}
+}
+
+// runs f for every matrix in the batch across the pool
+func eachMatrix(batch int, f func(i int)) {
+	splitRange(batch, 1, func(start int, end int) {
+		for i := start; i < end; i++ {
+			f(i)
+		}
+	})
+}
+
+// Shape of a with its trailing two axes replaced by dims
+func matrixShape(shape []int, dims ...int) []int {
+	return append(append([]int{}, shape[:len(shape)-2]...), dims...)
+}
+
+func (a NDArray) Inverse() NDArray {
+	return a.InverseInto(ZerosOf(a.dtype, a.shape...))
+}
+
+// Panics if any matrix is singular
+func (a NDArray) InverseInto(arr NDArray) NDArray {
+	a = a.Contiguous()
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.InverseInto(arr) })
+	}
+	batch, n := squareDims("Inverse", a.shape)
+	copy(arr.data, a.data)
+	eachMatrix(batch, func(i int) {
+		m := matrixAt(arr.data, i, n, n)
+		ipiv := make([]int, n)
+		if !lapack64.Getrf(m, ipiv) {
+			panic("matrix is singular")
+		}
+		work := []float64{0}
+		lapack64.Getri(m, ipiv, work, -1)
+		work = make([]float64, int(work[0]))
+		lapack64.Getri(m, ipiv, work, len(work))
+	})
+	return arr
+}
+
+// Solves a x = b for x, where b has the same leading axes as a
+func (a NDArray) Solve(b NDArray) NDArray {
+	return a.SolveInto(b, ZerosOf(PromoteTypes(a.dtype, b.dtype), b.shape...))
+}
+
+// Panics if any matrix is singular
+func (a NDArray) SolveInto(b NDArray, arr NDArray) NDArray {
+	a, b = a.Contiguous(), b.Contiguous()
+	requirePacked(arr)
+	if !both64(a, b) || arr.dtype != Float64 {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.SolveInto(b.AsType(Float64), arr) })
+	}
+	if err := CheckSolve(a.shape, b.shape); err != nil {
+		panic(err)
+	}
+	batch, n := squareDims("Solve", a.shape)
+	_, rows, cols := matrixDims("Solve", b.shape)
+	lu := append([]float64{}, a.data...)
+	copy(arr.data, b.data)
+	eachMatrix(batch, func(i int) {
+		m := matrixAt(lu, i, n, n)
+		ipiv := make([]int, n)
+		if !lapack64.Getrf(m, ipiv) {
+			panic("matrix is singular")
+		}
+		lapack64.Getrs(blas.NoTrans, m, matrixAt(arr.data, i, rows, cols), ipiv)
+	})
+	return arr
+}
+
+// Determinants, with the trailing two axes kept at size 1
+func (a NDArray) Det() NDArray {
+	squareDims("Det", a.shape)
+	return a.DetInto(ZerosOf(a.dtype, matrixShape(a.shape, 1, 1)...))
+}
+
+func (a NDArray) DetInto(arr NDArray) NDArray {
+	return a.luDiagInto("Det", arr, func(diag []float64, swaps int) float64 {
+		det := 1.
+		for _, d := range diag {
+			det *= d
+		}
+		if swaps%2 == 1 {
+			det = -det
+		}
+		return det
+	})
+}
+
+// Logs of the absolute determinants, with the trailing two axes kept at size 1. Singular matrices are
+// -Inf.
+func (a NDArray) LogDet() NDArray {
+	squareDims("LogDet", a.shape)
+	return a.LogDetInto(ZerosOf(a.dtype, matrixShape(a.shape, 1, 1)...))
+}
+
+func (a NDArray) LogDetInto(arr NDArray) NDArray {
+	return a.luDiagInto("LogDet", arr, func(diag []float64, swaps int) float64 {
+		logDet := 0.
+		for _, d := range diag {
+			logDet += math.Log(math.Abs(d))
+		}
+		return logDet
+	})
+}
+
+// LU factors every matrix and sets arr to f of the diagonal of U and the number of row swaps
+func (a NDArray) luDiagInto(op string, arr NDArray, f func(diag []float64, swaps int) float64) NDArray {
+	a = a.Contiguous()
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.luDiagInto(op, arr, f) })
+	}
+	batch, n := squareDims(op, a.shape)
+	lu := append([]float64{}, a.data...)
+	eachMatrix(batch, func(i int) {
+		m := matrixAt(lu, i, n, n)
+		ipiv := make([]int, n)
+		// singular matrices still factor, with a zero on the diagonal
+		lapack64.Getrf(m, ipiv)
+		diag := make([]float64, n)
+		swaps := 0
+		for j := range diag {
+			diag[j] = m.Data[j*n+j]
+			if ipiv[j] != j {
+				swaps++
+			}
+		}
+		arr.data[i] = f(diag, swaps)
+	})
+	return arr
+}
+
+// The lower triangular L with L L^T = a. Only the lower triangle of a is read.
+func (a NDArray) Cholesky() NDArray {
+	return a.CholeskyInto(ZerosOf(a.dtype, a.shape...))
+}
+
+// Panics if any matrix isn't positive definite
+func (a NDArray) CholeskyInto(arr NDArray) NDArray {
+	a = a.Contiguous()
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.CholeskyInto(arr) })
+	}
+	batch, n := squareDims("Cholesky", a.shape)
+	copy(arr.data, a.data)
+	eachMatrix(batch, func(i int) {
+		m := matrixAt(arr.data, i, n, n)
+		if _, ok := lapack64.Potrf(blas64.Symmetric{Uplo: blas.Lower, N: n, Data: m.Data, Stride: n}); !ok {
+			panic("matrix is not positive definite")
+		}
+		for r := 0; r < n; r++ {
+			for c := r + 1; c < n; c++ {
+				m.Data[r*n+c] = 0
+			}
+		}
+	})
+	return arr
+}
+
+// The reduced QR decomposition a = q r. For m x n matrices with k = min(m, n), q is m x k with orthonormal
+// columns and r is k x n upper triangular.
+func (a NDArray) QR() (q NDArray, r NDArray) {
+	_, rows, cols := matrixDims("QR", a.shape)
+	k := min(rows, cols)
+	return a.QRInto(ZerosOf(a.dtype, matrixShape(a.shape, rows, k)...), ZerosOf(a.dtype, matrixShape(a.shape, k, cols)...))
+}
+
+func (a NDArray) QRInto(q NDArray, r NDArray) (NDArray, NDArray) {
+	a = a.Contiguous()
+	requirePacked(q)
+	requirePacked(r)
+	if !both64(a, q) || r.dtype != Float64 {
+		q64, r64 := a.AsType(Float64).QRInto(Zeros(q.shape...), Zeros(r.shape...))
+		return q64.AsTypeInto(q), r64.AsTypeInto(r)
+	}
+	batch, rows, cols := matrixDims("QR", a.shape)
+	k := min(rows, cols)
+	qr := append([]float64{}, a.data...)
+	eachMatrix(batch, func(i int) {
+		m := matrixAt(qr, i, rows, cols)
+		tau := make([]float64, k)
+		work := []float64{0}
+		lapack64.Geqrf(m, tau, work, -1)
+		work = make([]float64, int(work[0]))
+		lapack64.Geqrf(m, tau, work, len(work))
+
+		rm := matrixAt(r.data, i, k, cols)
+		for y := 0; y < k; y++ {
+			for x := y; x < cols; x++ {
+				rm.Data[y*cols+x] = m.Data[y*cols+x]
+			}
+		}
+
+		// the reflectors are in the first k columns
+		m.Cols = k
+		work = []float64{0}
+		lapack64.Orgqr(m, tau, work, -1)
+		work = make([]float64, int(work[0]))
+		lapack64.Orgqr(m, tau, work, len(work))
+		qm := matrixAt(q.data, i, rows, k)
+		for y := 0; y < rows; y++ {
+			copy(qm.Data[y*k:(y+1)*k], m.Data[y*cols:y*cols+k])
+		}
+	})
+	return q, r
+}
+
+// The reduced singular value decomposition a = u diag(s) v^T. For m x n matrices with k = min(m, n), u is
+// m x k, s has the k singular values in descending order along the last axis, and v is n x k.
+func (a NDArray) SVD() (u NDArray, s NDArray, v NDArray) {
+	_, rows, cols := matrixDims("SVD", a.shape)
+	k := min(rows, cols)
+	return a.SVDInto(
+		ZerosOf(a.dtype, matrixShape(a.shape, rows, k)...),
+		ZerosOf(a.dtype, matrixShape(a.shape, k)...),
+		ZerosOf(a.dtype, matrixShape(a.shape, cols, k)...),
+	)
+}
+
+// Panics if the decomposition doesn't converge
+func (a NDArray) SVDInto(u NDArray, s NDArray, v NDArray) (NDArray, NDArray, NDArray) {
+	a = a.Contiguous()
+	requirePacked(u)
+	requirePacked(s)
+	requirePacked(v)
+	if !both64(a, u) || !both64(s, v) {
+		u64, s64, v64 := a.AsType(Float64).SVDInto(Zeros(u.shape...), Zeros(s.shape...), Zeros(v.shape...))
+		return u64.AsTypeInto(u), s64.AsTypeInto(s), v64.AsTypeInto(v)
+	}
+	batch, rows, cols := matrixDims("SVD", a.shape)
+	k := min(rows, cols)
+	work64 := append([]float64{}, a.data...)
+	eachMatrix(batch, func(i int) {
+		m := matrixAt(work64, i, rows, cols)
+		um := matrixAt(u.data, i, rows, k)
+		vt := blas64.General{Rows: k, Cols: cols, Data: make([]float64, k*cols), Stride: cols}
+		sv := s.data[i*k : (i+1)*k]
+		work := []float64{0}
+		lapack64.Gesvd(lapack.SVDStore, lapack.SVDStore, m, um, vt, sv, work, -1)
+		work = make([]float64, int(work[0]))
+		if !lapack64.Gesvd(lapack.SVDStore, lapack.SVDStore, m, um, vt, sv, work, len(work)) {
+			panic("SVD did not converge")
+		}
+		vm := matrixAt(v.data, i, cols, k)
+		for y := 0; y < k; y++ {
+			for x := 0; x < cols; x++ {
+				vm.Data[x*k+y] = vt.Data[y*cols+x]
+			}
+		}
+	})
+	return u, s, v
+}
+
+// The eigendecomposition a = v diag(w) v^T of symmetric matrices. w has the eigenvalues in ascending order
+// along the last axis, and the columns of v are the matching eigenvectors. Only the lower triangle of a
+// is read.
+func (a NDArray) Eigh() (w NDArray, v NDArray) {
+	_, n := squareDims("Eigh", a.shape)
+	return a.EighInto(ZerosOf(a.dtype, matrixShape(a.shape, n)...), ZerosOf(a.dtype, a.shape...))
+}
+
+// Panics if the decomposition doesn't converge
+func (a NDArray) EighInto(w NDArray, v NDArray) (NDArray, NDArray) {
+	a = a.Contiguous()
+	requirePacked(w)
+	requirePacked(v)
+	if !both64(a, w) || v.dtype != Float64 {
+		w64, v64 := a.AsType(Float64).EighInto(Zeros(w.shape...), Zeros(v.shape...))
+		return w64.AsTypeInto(w), v64.AsTypeInto(v)
+	}
+	batch, n := squareDims("Eigh", a.shape)
+	copy(v.data, a.data)
+	eachMatrix(batch, func(i int) {
+		sym := blas64.Symmetric{Uplo: blas.Lower, N: n, Data: matrixAt(v.data, i, n, n).Data, Stride: n}
+		wv := w.data[i*n : (i+1)*n]
+		work := []float64{0}
+		lapack64.Syev(lapack.EVCompute, sym, wv, work, -1)
+		work = make([]float64, int(work[0]))
+		if !lapack64.Syev(lapack.EVCompute, sym, wv, work, len(work)) {
+			panic("eigendecomposition did not converge")
+		}
+	})
+	return w, v
+}
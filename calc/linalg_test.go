@@ -0,0 +1,85 @@
+package calc_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// a batch of n x n identity matrices
+func eye(batch int, n int) calc.NDArray {
+	arr := calc.Zeros(batch, n, n)
+	for b := 0; b < batch; b++ {
+		for i := 0; i < n; i++ {
+			arr.Set([]int{b, i, i}, 1)
+		}
+	}
+	return arr
+}
+
+func matMul(a calc.NDArray, b calc.NDArray) calc.NDArray {
+	return a.MatMul(b, 1, 2)
+}
+
+// Each decomposition multiplies back to its input
+func TestLinalgDecompositions(t *testing.T) {
+	rng := calc.NewRNG(5)
+	for _, shape := range [][]int{{2, 4, 3}, {2, 3, 4}, {2, 3, 3}} {
+		a := rng.Normal(0, 1, shape...)
+		k := min(shape[1], shape[2])
+
+		q, r := a.QR()
+		checkClose(t, "QR", matMul(q, r), a)
+		checkClose(t, "QR q^T q", matMul(q.Transpose(1, 2), q), eye(2, k))
+		r.ForEach(func(_ int, index []int, value float64) {
+			if index[1] > index[2] && value != 0 {
+				t.Errorf("QR r %v = %v below the diagonal", index, value)
+			}
+		})
+
+		u, s, v := a.SVD()
+		checkClose(t, "SVD", matMul(u.Mul(s.Reshape(2, 1, k)), v.Transpose(1, 2)), a)
+		checkClose(t, "SVD u^T u", matMul(u.Transpose(1, 2), u), eye(2, k))
+		checkClose(t, "SVD v^T v", matMul(v.Transpose(1, 2), v), eye(2, k))
+		for b := 0; b < 2; b++ {
+			for i := 1; i < k; i++ {
+				if s.Get([]int{b, i}) > s.Get([]int{b, i - 1}) {
+					t.Errorf("SVD s[%d] isn't descending: %v", b, s)
+				}
+			}
+		}
+	}
+
+	x := rng.Normal(0, 1, 2, 3, 3)
+	sym := matMul(x, x.Transpose(1, 2)).Add(eye(2, 3))
+
+	l := sym.Cholesky()
+	checkClose(t, "Cholesky", matMul(l, l.Transpose(1, 2)), sym)
+
+	w, v := sym.Eigh()
+	checkClose(t, "Eigh", matMul(v.Mul(w.Reshape(2, 1, 3)), v.Transpose(1, 2)), sym)
+	for b := 0; b < 2; b++ {
+		if w.Get([]int{b, 0}) > w.Get([]int{b, 1}) || w.Get([]int{b, 1}) > w.Get([]int{b, 2}) {
+			t.Errorf("Eigh w[%d] isn't ascending: %v", b, w)
+		}
+	}
+}
+
+func TestLinalgSolve(t *testing.T) {
+	rng := calc.NewRNG(6)
+	a := rng.Normal(0, 1, 2, 3, 3).Add(eye(2, 3).MulConstant(3))
+	b := rng.Normal(0, 1, 2, 3, 2)
+
+	checkClose(t, "Inverse", matMul(a, a.Inverse()), eye(2, 3))
+	checkClose(t, "Solve", matMul(a, a.Solve(b)), b)
+	inv32 := a.AsType(calc.Float32).Inverse()
+	if inv32.DType() != calc.Float32 {
+		t.Errorf("Inverse of float32 is %s", inv32.DType())
+	}
+	// scaled so checkClose allows float32 rounding
+	checkClose(t, "Inverse float32", inv32.AsType(calc.Float64).Add(a.Inverse().MulConstant(-1)).MulConstant(1e-4), calc.Zeros(2, 3, 3))
+
+	m := calc.FromRaw([]int{2, 2, 2}, []float64{3, 1, 4, 2, 0, 1, 1, 0})
+	checkClose(t, "Det", m.Det(), calc.FromRaw([]int{2, 1, 1}, []float64{2, -1}))
+	checkClose(t, "LogDet", m.LogDet(), calc.FromRaw([]int{2, 1, 1}, []float64{0.6931471805599453, 0}))
+}
@@ -0,0 +1,140 @@
+package calc
+
+import "math"
+
+// arr[i] = f(a[i]), computed in float64 for other dtypes
+func (a NDArray) mapElemsInto(arr NDArray, f func(v float64) float64) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.mapElemsInto(arr, f) })
+	}
+	mapInto(a, arr.data, f)
+	return arr
+}
+
+func (a NDArray) Sqrt() NDArray {
+	return a.SqrtInto(ZerosOf(a.dtype, a.shape...))
+}
+
+func (a NDArray) SqrtInto(arr NDArray) NDArray {
+	return a.mapElemsInto(arr, math.Sqrt)
+}
+
+func (a NDArray) Tanh() NDArray {
+	return a.TanhInto(ZerosOf(a.dtype, a.shape...))
+}
+
+func (a NDArray) TanhInto(arr NDArray) NDArray {
+	return a.mapElemsInto(arr, math.Tanh)
+}
+
+func (a NDArray) Sin() NDArray {
+	return a.SinInto(ZerosOf(a.dtype, a.shape...))
+}
+
+func (a NDArray) SinInto(arr NDArray) NDArray {
+	return a.mapElemsInto(arr, math.Sin)
+}
+
+func (a NDArray) Cos() NDArray {
+	return a.CosInto(ZerosOf(a.dtype, a.shape...))
+}
+
+func (a NDArray) CosInto(arr NDArray) NDArray {
+	return a.mapElemsInto(arr, math.Cos)
+}
+
+func (a NDArray) Erf() NDArray {
+	return a.ErfInto(ZerosOf(a.dtype, a.shape...))
+}
+
+func (a NDArray) ErfInto(arr NDArray) NDArray {
+	return a.mapElemsInto(arr, math.Erf)
+}
+
+// log(1 + a), accurate for a near 0
+func (a NDArray) Log1p() NDArray {
+	return a.Log1pInto(ZerosOf(a.dtype, a.shape...))
+}
+
+func (a NDArray) Log1pInto(arr NDArray) NDArray {
+	return a.mapElemsInto(arr, math.Log1p)
+}
+
+// e^a - 1, accurate for a near 0
+func (a NDArray) Expm1() NDArray {
+	return a.Expm1Into(ZerosOf(a.dtype, a.shape...))
+}
+
+func (a NDArray) Expm1Into(arr NDArray) NDArray {
+	return a.mapElemsInto(arr, math.Expm1)
+}
+
+// Element-wise minimum of a and b, broadcasting
+func (a NDArray) Minimum(b NDArray) NDArray {
+	c := ZerosOf(PromoteTypes(a.dtype, b.dtype), BroadcastShape(a.shape, b.shape)...)
+	return a.MinimumInto(b, c)
+}
+
+func (a NDArray) MinimumInto(b NDArray, c NDArray) NDArray {
+	return a.pairInto(b, c, math.Min)
+}
+
+// Element-wise maximum of a and b, broadcasting
+func (a NDArray) Maximum(b NDArray) NDArray {
+	c := ZerosOf(PromoteTypes(a.dtype, b.dtype), BroadcastShape(a.shape, b.shape)...)
+	return a.MaximumInto(b, c)
+}
+
+func (a NDArray) MaximumInto(b NDArray, c NDArray) NDArray {
+	return a.pairInto(b, c, math.Max)
+}
+
+// c = f(a, b) with broadcasting, computed in float64 for other dtypes
+func (a NDArray) pairInto(b NDArray, c NDArray, f func(x float64, y float64) float64) NDArray {
+	requirePacked(c)
+	if !both64(a, b) || c.dtype != Float64 {
+		return a.via64Into(c, func(a NDArray, c NDArray) NDArray { return a.pairInto(b.AsType(Float64), c, f) })
+	}
+
+	walkBroadcast(a.layout(), b.layout(), c.shape, func(aIndex int, bIndex int, outIndex int) {
+		c.data[outIndex] = f(a.data[aIndex], b.data[bIndex])
+	})
+	return c
+}
+
+// Picks elements of x where a is nonzero and of y elsewhere, broadcasting all three
+func (a NDArray) Where(x NDArray, y NDArray) NDArray {
+	shape := BroadcastShape(a.shape, BroadcastShape(x.shape, y.shape))
+	return a.WhereInto(x, y, ZerosOf(PromoteTypes(x.dtype, y.dtype), shape...))
+}
+
+// x and y are converted to the dtype of arr
+func (a NDArray) WhereInto(x NDArray, y NDArray, arr NDArray) NDArray {
+	x, y = x.AsType(arr.dtype), y.AsType(arr.dtype)
+	requirePacked(arr)
+	copyX, copyY := elemCopier(arr, x), elemCopier(arr, y)
+
+	if a.packed() && x.packed() && y.packed() &&
+		ShapeEqual(a.shape, arr.shape) && ShapeEqual(x.shape, arr.shape) && ShapeEqual(y.shape, arr.shape) {
+		parallelFor(arr.size(), func(start int, end int) {
+			for i := start; i < end; i++ {
+				if a.at(i) != 0 {
+					copyX(i, i)
+				} else {
+					copyY(i, i)
+				}
+			}
+		})
+		return arr
+	}
+
+	arr.ForEach(func(dataIndex int, index []int, value float64) {
+		if a.at(a.dataIndexBroadcast(index)) != 0 {
+			copyX(dataIndex, x.dataIndexBroadcast(index))
+		} else {
+			copyY(dataIndex, y.dataIndexBroadcast(index))
+		}
+	})
+	return arr
+}
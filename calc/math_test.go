@@ -0,0 +1,71 @@
+package calc_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+func TestElementwiseMath(t *testing.T) {
+	rng := calc.NewRNG(37)
+	a := rng.Normal(0, 2, 3, 4)
+	pos := a.Mul(a)
+	for _, c := range []struct {
+		name string
+		op   func(a calc.NDArray) calc.NDArray
+		f    func(v float64) float64
+		in   calc.NDArray
+	}{
+		{"Sqrt", calc.NDArray.Sqrt, math.Sqrt, pos},
+		{"Tanh", calc.NDArray.Tanh, math.Tanh, a},
+		{"Sin", calc.NDArray.Sin, math.Sin, a},
+		{"Cos", calc.NDArray.Cos, math.Cos, a},
+		{"Erf", calc.NDArray.Erf, math.Erf, a},
+		{"Log1p", calc.NDArray.Log1p, math.Log1p, pos},
+		{"Expm1", calc.NDArray.Expm1, math.Expm1, a},
+	} {
+		want := calc.Zeros(c.in.Shape()...)
+		c.in.ForEach(func(_ int, index []int, v float64) {
+			want.Set(index, c.f(v))
+		})
+		checkSame(t, c.name, c.op(c.in), want)
+		checkSame(t, c.name+" of a transposed view", c.op(c.in.Transpose(0, 1)), want.Transpose(0, 1))
+		checkClose32(t, c.name+" float32", c.op(c.in.AsType(calc.Float32)), want)
+	}
+
+	// accurate where the naive forms round to 0
+	tiny := calc.FromRaw([]int{1}, []float64{1e-20})
+	checkSame(t, "Log1p of tiny", tiny.Log1p(), tiny)
+	checkSame(t, "Expm1 of tiny", tiny.Expm1(), tiny)
+
+	checkSame(t, "Clip", calc.FromRaw([]int{4}, []float64{-2, -0.5, 0.5, 3}).Clip(-1, 1), calc.FromRaw([]int{4}, []float64{-1, -0.5, 0.5, 1}))
+}
+
+func TestMinimumMaximumWhere(t *testing.T) {
+	rng := calc.NewRNG(38)
+	a, b := rng.Normal(0, 1, 3, 4), rng.Normal(0, 1, 1, 4)
+	minimum, maximum, where := calc.Zeros(3, 4), calc.Zeros(3, 4), calc.Zeros(3, 4)
+	cond := a.Greater(b)
+	a.ForEach(func(_ int, index []int, x float64) {
+		y := b.Get([]int{0, index[1]})
+		minimum.Set(index, math.Min(x, y))
+		maximum.Set(index, math.Max(x, y))
+		if x > y {
+			where.Set(index, 2*x)
+		} else {
+			where.Set(index, y)
+		}
+	})
+	checkSame(t, "Minimum", a.Minimum(b), minimum)
+	checkSame(t, "Maximum", b.Maximum(a), maximum)
+	checkSame(t, "Where", cond.Where(a.MulConstant(2), b), where)
+	checkSame(t, "Where with float cond", cond.AsType(calc.Float64).Where(a.MulConstant(2), b), where)
+
+	ints := calc.FromRawInt64([]int{3}, []int64{-3, 5, 2})
+	checkSame(t, "int64 Minimum", ints.Minimum(calc.FromRawInt64([]int{1}, []int64{1})), calc.FromRawInt64([]int{3}, []int64{-3, 1, 1}))
+	if got := ints.Maximum(a.Slice(0, 0, 1).Slice(1, 0, 1).Reshape(1)); got.DType() != calc.Float64 {
+		t.Errorf("Maximum of int64 and float64 is %s", got.DType())
+	}
+	checkSame(t, "Minimum NaN", calc.FromRaw([]int{2}, []float64{math.NaN(), 1}).Minimum(calc.FromRaw([]int{2}, []float64{0, math.NaN()})), calc.FromRaw([]int{2}, []float64{math.NaN(), math.NaN()}))
+}
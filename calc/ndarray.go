@@ -32,6 +32,7 @@ func Ones(shape ...int) NDArray {
 	return Constant(1.0, shape...)
 }
 
+// Drawn from the global math/rand source, use an RNG for reproducible arrays
 func RandomUniform(min float64, max float64, shape ...int) NDArray {
 	arr := Zeros(shape...)
 	for i := range arr.data {
@@ -40,6 +41,7 @@ func RandomUniform(min float64, max float64, shape ...int) NDArray {
 	return arr
 }
 
+// Drawn from the global math/rand source, use an RNG for reproducible arrays
 func RandomNormal(mean float64, stddev float64, shape ...int) NDArray {
 	arr := Zeros(shape...)
 	for i := range arr.data {
@@ -48,22 +50,13 @@ func RandomNormal(mean float64, stddev float64, shape ...int) NDArray {
 	return arr
 }
 
+// The shape that a and b broadcast to, or nil if they can't. Like NumPy, shapes are aligned at their last
+// axis, missing leading axes count as size 1, and axes of size 1 stretch to match the other shape.
 func BroadcastShape(aShape []int, bShape []int) []int {
 	if len(aShape) > len(bShape) {
 		return BroadcastShape(bShape, aShape)
-	} else if len(aShape) < len(bShape) {
-		// TODO: we don't actually handle adding dims in dataIndexBroadcast
-		naShape := make([]int, len(bShape))
-		for i := range naShape {
-			j := i - len(bShape) + len(aShape)
-			if j < 0 {
-				naShape[i] = 1
-			} else {
-				naShape[i] = aShape[j]
-			}
-		}
-		aShape = naShape
 	}
+	aShape = padShape(aShape, len(bShape))
 	outShape := make([]int, len(aShape))
 	for i := range aShape {
 		if aShape[i] == bShape[i] {
@@ -79,6 +72,19 @@ func BroadcastShape(aShape []int, bShape []int) []int {
 	return outShape
 }
 
+// shape with size 1 axes added in front up to rank
+func padShape(shape []int, rank int) []int {
+	if len(shape) >= rank {
+		return shape
+	}
+	padded := make([]int, rank)
+	for i := range padded {
+		padded[i] = 1
+	}
+	copy(padded[rank-len(shape):], shape)
+	return padded
+}
+
 func AggrShape(aShape []int, axes []int) []int {
 	outShape := make([]int, len(aShape))
 	copy(outShape, aShape)
@@ -88,21 +94,9 @@ func AggrShape(aShape []int, axes []int) []int {
 	return outShape
 }
 
+// Returns nil if the shapes can't be multiplied, see CheckMatMul for why
 func MatMulShape(aShape []int, bShape []int, a1 int, a2 int) []int {
-	if len(aShape) != len(bShape) {
-		return nil
-	}
-	if aShape[a2] != bShape[a1] {
-		return nil
-	}
-	tAShape := append([]int{}, aShape...)
-	tBShape := append([]int{}, bShape...)
-	tAShape[a1], tAShape[a2], tBShape[a1], tBShape[a2] = 1, 1, 1, 1
-
-	outShape := BroadcastShape(tAShape, tBShape)
-	outShape[a1] = aShape[a1]
-	outShape[a2] = bShape[a2]
-
+	outShape, _ := CheckMatMul(aShape, bShape, a1, a2)
 	return outShape
 }
 
@@ -112,26 +106,9 @@ func TransposeShape(shape []int, a1 int, a2 int) []int {
 	return outShape
 }
 
-func Conv2DShape(shape []int, hAxis int, wAxis int, fAxis int, kernelH int, kernelW int, kernelF int) []int {
-	outShape := append([]int{}, shape...)
-	outShape[hAxis] -= kernelH - 1
-	outShape[wAxis] -= kernelW - 1
-	outShape[fAxis] = kernelF
-	return outShape
-}
-
-func InverseConv2DShape(shape []int, kShape []int, hAxis int, wAxis int, fAxis int) []int {
-	kernelH := shape[hAxis] - kShape[hAxis] + 1
-	kernelW := shape[wAxis] - kShape[wAxis] + 1
-	return []int{kernelH, kernelW, shape[fAxis], kShape[fAxis]}
-}
-
+// Panics if data doesn't have the size of shape, see NewArray
 func FromRaw(shape []int, data []float64) NDArray {
-	// TODO: validate len(data) = prod(shape)
-	return NDArray{
-		shape: shape,
-		data:  data,
-	}
+	return Must(NewArray(shape, data))
 }
 
 func ShapeEqual(s1 []int, s2 []int) bool {
@@ -148,13 +125,34 @@ func ShapeEqual(s1 []int, s2 []int) bool {
 
 type NDArray struct {
 	shape []int
-	data  []float64
+	dtype DType
+
+	// only the one matching dtype is set
+	data     []float64
+	data32   []float32
+	dataI64  []int64
+	dataBool []bool
+
+	// nil for packed arrays, see view.go
+	strides []int
+	offset  int
+	rows    []int
 
 	// cached offsets for dataIndex*
 	broadcastSizes []int
 }
 
 func (a NDArray) dataIndex(index []int) int {
+	if !a.packed() {
+		dataIndex := a.offset
+		for i := range index {
+			dataIndex += a.strides[i] * index[i]
+		}
+		if a.rows != nil {
+			dataIndex += a.rows[index[0]]
+		}
+		return dataIndex
+	}
 	dataIndex := 0
 	innerSize := 1
 
@@ -166,22 +164,26 @@ func (a NDArray) dataIndex(index []int) int {
 	return dataIndex
 }
 
+// index may have more leading axes than a, which a is broadcast along
 func (a *NDArray) dataIndexBroadcast(index []int) int {
+	index = index[len(index)-len(a.shape):]
 	if len(a.broadcastSizes) == 0 {
 		a.broadcastSizes = make([]int, len(a.shape))
-		innerSize := 1
+		strides := a.stridesOf()
 		for i := len(index) - 1; i >= 0; i-- {
 			if a.shape[i] != 1 {
-				a.broadcastSizes[i] = innerSize
+				a.broadcastSizes[i] = strides[i]
 			}
-			innerSize *= a.shape[i]
 		}
 	}
-	dataIndex := 0
+	dataIndex := a.offset
 
 	for i := len(index) - 1; i >= 0; i-- {
 		dataIndex += a.broadcastSizes[i] * index[i]
 	}
+	if a.rows != nil {
+		dataIndex += a.rows[index[0]]
+	}
 
 	return dataIndex
 }
@@ -201,23 +203,42 @@ func (a NDArray) index(dataIndex int, reuse ...[]int) []int {
 }
 
 func (a NDArray) Get(index []int) float64 {
-	return a.data[a.dataIndex(index)]
+	return a.at(a.dataIndex(index))
 }
 
 func (a NDArray) Set(index []int, value float64) {
-	a.data[a.dataIndex(index)] = value
+	a.setAt(a.dataIndex(index), value)
 }
 
 func (a NDArray) Fill(value float64) {
-	for i := range a.data {
-		a.data[i] = value
+	if !a.packed() {
+		a.ForEach(func(dataIndex int, index []int, _ float64) {
+			a.setAt(dataIndex, value)
+		})
+		return
+	}
+	switch a.dtype {
+	case Float32:
+		for i := range a.data32 {
+			a.data32[i] = float32(value)
+		}
+	case Int64:
+		for i := range a.dataI64 {
+			a.dataI64[i] = int64(value)
+		}
+	case Bool:
+		for i := range a.dataBool {
+			a.dataBool[i] = value != 0
+		}
+	default:
+		for i := range a.data {
+			a.data[i] = value
+		}
 	}
 }
 
 func (a NDArray) SetSlice(b NDArray, axis int, offset int) {
-	walkSlice(a.shape, b.shape, axis, offset, func(inIndex int, outIndex int) {
-		a.data[inIndex] = b.data[outIndex]
-	})
+	b.AsType(a.dtype).copyInto(a.Slice(axis, offset, offset+b.shape[axis]))
 }
 
 func (a NDArray) Shape() []int {
@@ -225,6 +246,7 @@ func (a NDArray) Shape() []int {
 }
 
 func (a NDArray) String() string {
+	a = a.Contiguous()
 	mods := make([]int, len(a.shape))
 	mods[len(a.shape)-1] = a.shape[len(a.shape)-1]
 	for i := len(a.shape) - 2; i >= 0; i-- {
@@ -233,7 +255,7 @@ func (a NDArray) String() string {
 
 	out := []byte{}
 
-	for i := range a.data {
+	for i := 0; i < a.size(); i++ {
 		opened := false
 		for _, m := range mods {
 			if i%m == 0 {
@@ -247,7 +269,16 @@ func (a NDArray) String() string {
 		if !opened {
 			out = append(out, ' ')
 		}
-		out = strconv.AppendFloat(out, a.data[i], 'g', -1, 64)
+		switch a.dtype {
+		case Float32:
+			out = strconv.AppendFloat(out, a.at(i), 'g', -1, 32)
+		case Int64:
+			out = strconv.AppendInt(out, a.dataI64[i], 10)
+		case Bool:
+			out = strconv.AppendBool(out, a.dataBool[i])
+		default:
+			out = strconv.AppendFloat(out, a.data[i], 'g', -1, 64)
+		}
 		for _, m := range mods {
 			if (i+1)%m == 0 {
 				out = append(out, ']')
@@ -257,17 +288,31 @@ func (a NDArray) String() string {
 	return string(out)
 }
 
+// dataIndex is the position in storage, which for views isn't the position in index order
 func (a NDArray) ForEach(f func(dataIndex int, index []int, value float64)) {
 	index := make([]int, len(a.shape))
 	passedIndex := make([]int, len(a.shape))
-	for i := range a.data {
-		f(i, passedIndex, a.data[i])
+	if !a.packed() {
+		for i := shapeSize(a.shape); i > 0; i-- {
+			dataIndex := a.dataIndex(index)
+			f(dataIndex, passedIndex, a.at(dataIndex))
+			a.nextIndex(index)
+			copy(passedIndex, index)
+		}
+		return
+	}
+	for i := 0; i < a.size(); i++ {
+		f(i, passedIndex, a.at(i))
 		a.nextIndex(index)
 		copy(passedIndex, index)
 	}
 }
 
 func (a NDArray) nextIndex(index []int) {
+	// rank 0 arrays have a single element
+	if len(index) == 0 {
+		return
+	}
 	index[len(index)-1]++
 	for i := len(index) - 1; i >= 0; i-- {
 		if index[i] == a.shape[i] {
@@ -282,108 +327,180 @@ func (a NDArray) nextIndex(index []int) {
 }
 
 func (a NDArray) Add(b NDArray) NDArray {
-	// TODO: assert size valid
-	newShape := BroadcastShape(a.shape, b.shape)
-	c := Zeros(newShape...)
+	newShape := Must(CheckBroadcast("Add", a.shape, b.shape))
+	c := ZerosOf(PromoteTypes(a.dtype, b.dtype), newShape...)
 	return a.AddInto(b, c)
 }
 
+// computed in the dtype of c
 func (a NDArray) AddInto(b NDArray, c NDArray) NDArray {
-	// TODO: assert size valid
-	if ShapeEqual(a.shape, b.shape) {
-		for i := range c.data {
-			c.data[i] = a.data[i] + b.data[i]
-		}
+	requirePacked(c)
+	checkBroadcastTo("Add", a.shape, c.shape)
+	checkBroadcastTo("Add", b.shape, c.shape)
+	if c.dtype == Bool {
+		return a.via64Into(c, func(a NDArray, c NDArray) NDArray { return a.AddInto(b.AsType(Float64), c) })
+	}
+	a, b = a.AsType(c.dtype), b.AsType(c.dtype)
+	switch c.dtype {
+	case Float32:
+		addInto(a.data32, b.data32, c.data32, a.layout(), b.layout(), c.shape)
+	case Int64:
+		addInto(a.dataI64, b.dataI64, c.dataI64, a.layout(), b.layout(), c.shape)
+	default:
+		addInto(a.data, b.data, c.data, a.layout(), b.layout(), c.shape)
+	}
+	return c
+}
+
+func addInto[T number](a []T, b []T, c []T, aLayout layout, bLayout layout, cShape []int) {
+	if aLayout.packed() && bLayout.packed() && ShapeEqual(aLayout.shape, bLayout.shape) {
+		parallelFor(len(c), func(start int, end int) {
+			for i := start; i < end; i++ {
+				c[i] = a[i] + b[i]
+			}
+		})
 	} else {
-		walkBroadcast(a.shape, b.shape, c.shape, func(aIndex int, bIndex int, outIndex int) {
-			c.data[outIndex] = a.data[aIndex] + b.data[bIndex]
+		walkBroadcast(aLayout, bLayout, cShape, func(aIndex int, bIndex int, outIndex int) {
+			c[outIndex] = a[aIndex] + b[bIndex]
 		})
 	}
-	return c
 }
 
 func (a NDArray) MulConstant(b float64) NDArray {
-	c := Zeros(a.shape...)
-	copy(c.data, a.data)
-	for i := range c.data {
-		c.data[i] *= b
+	arr := ZerosOf(a.dtype, a.shape...)
+	return a.MulConstantInto(b, arr)
+}
+
+func (a NDArray) MulConstantInto(b float64, arr NDArray) NDArray {
+	requirePacked(arr)
+	if a.dtype != arr.dtype || !arr.dtype.IsFloat() {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.MulConstantInto(b, arr) })
 	}
-	return c
+	if arr.dtype == Float32 && !a.packed() {
+		walkElems(a.layout(), func(aIndex int, i int) {
+			arr.data32[i] = a.data32[aIndex] * float32(b)
+		})
+		return arr
+	}
+	if arr.dtype == Float32 {
+		parallelFor(len(arr.data32), func(start int, end int) {
+			for i := start; i < end; i++ {
+				arr.data32[i] = a.data32[i] * float32(b)
+			}
+		})
+		return arr
+	}
+	mapInto(a, arr.data, func(v float64) float64 { return v * b })
+	return arr
 }
 
 func (a NDArray) Mul(b NDArray) NDArray {
-	// TODO: assert size valid
-	newShape := BroadcastShape(a.shape, b.shape)
-	c := Zeros(newShape...)
+	newShape := Must(CheckBroadcast("Mul", a.shape, b.shape))
+	c := ZerosOf(PromoteTypes(a.dtype, b.dtype), newShape...)
 	return a.MulInto(b, c)
 }
 
+// computed in the dtype of c
 func (a NDArray) MulInto(b NDArray, c NDArray) NDArray {
-	// TODO: assert size valid
-	if ShapeEqual(a.Shape(), b.Shape()) {
-		for i := range c.data {
-			c.data[i] = a.data[i] * b.data[i]
-		}
+	requirePacked(c)
+	checkBroadcastTo("Mul", a.shape, c.shape)
+	checkBroadcastTo("Mul", b.shape, c.shape)
+	if c.dtype == Bool {
+		return a.via64Into(c, func(a NDArray, c NDArray) NDArray { return a.MulInto(b.AsType(Float64), c) })
+	}
+	a, b = a.AsType(c.dtype), b.AsType(c.dtype)
+	switch c.dtype {
+	case Float32:
+		mulInto(a.data32, b.data32, c.data32, a.layout(), b.layout(), c.shape)
+	case Int64:
+		mulInto(a.dataI64, b.dataI64, c.dataI64, a.layout(), b.layout(), c.shape)
+	default:
+		mulInto(a.data, b.data, c.data, a.layout(), b.layout(), c.shape)
+	}
+	return c
+}
+
+func mulInto[T number](a []T, b []T, c []T, aLayout layout, bLayout layout, cShape []int) {
+	if aLayout.packed() && bLayout.packed() && ShapeEqual(aLayout.shape, bLayout.shape) {
+		parallelFor(len(c), func(start int, end int) {
+			for i := start; i < end; i++ {
+				c[i] = a[i] * b[i]
+			}
+		})
 	} else {
-		walkBroadcast(a.shape, b.shape, c.shape, func(aIndex int, bIndex int, outIndex int) {
-			c.data[outIndex] = a.data[aIndex] * b.data[bIndex]
+		walkBroadcast(aLayout, bLayout, cShape, func(aIndex int, bIndex int, outIndex int) {
+			c[outIndex] = a[aIndex] * b[bIndex]
 		})
 	}
-	return c
+}
+
+// whether a and b both have float64 storage, otherwise ops without native kernels convert them
+func both64(a NDArray, b NDArray) bool {
+	return a.dtype == Float64 && b.dtype == Float64
 }
 
 func (a NDArray) Div(b NDArray) NDArray {
-	// TODO: assert size valid
-	newShape := BroadcastShape(a.shape, b.shape)
+	c := ZerosOf(PromoteTypes(a.dtype, b.dtype), Must(CheckBroadcast("Div", a.shape, b.shape))...)
+	return a.DivInto(b, c)
+}
+
+func (a NDArray) DivInto(b NDArray, c NDArray) NDArray {
+	requirePacked(c)
+	checkBroadcastTo("Div", a.shape, c.shape)
+	checkBroadcastTo("Div", b.shape, c.shape)
+	if !both64(a, b) || c.dtype != Float64 {
+		return a.via64Into(c, func(a NDArray, c NDArray) NDArray { return a.DivInto(b.AsType(Float64), c) })
+	}
 
-	c := Zeros(newShape...)
-	walkBroadcast(a.shape, b.shape, c.shape, func(aIndex int, bIndex int, outIndex int) {
+	walkBroadcast(a.layout(), b.layout(), c.shape, func(aIndex int, bIndex int, outIndex int) {
 		c.data[outIndex] = a.data[aIndex] / b.data[bIndex]
 	})
 	return c
 }
 
 func (a NDArray) Concat(b NDArray, axis int) NDArray {
-	// TODO: assert all sizes other than axis are equal
-	shape := make([]int, len(a.shape))
-	for i := range shape {
-		if i == axis {
-			shape[i] = a.shape[i] + b.shape[i]
-		} else {
-			shape[i] = a.shape[i]
-		}
-	}
+	shape := Must(CheckConcat(axis, a.shape, b.shape))
+	c := ZerosOf(PromoteTypes(a.dtype, b.dtype), shape...)
+	return a.ConcatInto(b, axis, c)
+}
 
-	c := Zeros(shape...)
+func (a NDArray) ConcatInto(b NDArray, axis int, c NDArray) NDArray {
 	c.ForEach(func(dataIndex int, index []int, value float64) {
 		if index[axis] >= a.shape[axis] {
 			index[axis] -= a.shape[axis]
-			c.data[dataIndex] = b.Get(index)
+			c.setAt(dataIndex, b.Get(index))
 		} else {
-			c.data[dataIndex] = a.Get(index)
+			c.setAt(dataIndex, a.Get(index))
 		}
 	})
 	return c
 }
 
+// Returns a view
 func (a NDArray) Slice(axis int, start int, end int) NDArray {
+	if err := CheckSlice(a.shape, axis, start, end); err != nil {
+		panic(err)
+	}
 	outShape := append([]int{}, a.shape...)
 	outShape[axis] = end - start
 
-	arr := Zeros(outShape...)
-	return a.SliceInto(axis, start, end, arr)
+	strides := append([]int{}, a.stridesOf()...)
+	if a.rows != nil {
+		if axis == 0 {
+			return a.rowsView(outShape, a.rows[start:end], strides, a.offset)
+		}
+		return a.rowsView(outShape, a.rows, strides, a.offset+start*strides[axis])
+	}
+	return a.view(outShape, strides, a.offset+start*strides[axis])
 }
 
 func (a NDArray) SliceInto(axis int, start int, end int, arr NDArray) NDArray {
-	walkSlice(a.shape, arr.shape, axis, start, func(inIndex int, outIndex int) {
-		arr.data[outIndex] = a.data[inIndex]
-	})
-	return arr
+	return a.Slice(axis, start, end).AsTypeInto(arr)
 }
 
 func (a NDArray) Split(axis int, batch int) []NDArray {
-	if a.shape[axis]%batch != 0 {
-		panic(fmt.Sprintf("Cannot split %v on axis %d into batches of %d", a.shape, axis, batch))
+	if err := CheckSplit(a.shape, axis, batch); err != nil {
+		panic(err)
 	}
 	batchCount := a.shape[axis] / batch
 	batchShape := append([]int{}, a.shape...)
@@ -391,7 +508,7 @@ func (a NDArray) Split(axis int, batch int) []NDArray {
 
 	arrs := make([]NDArray, batchCount)
 	for i := range arrs {
-		arrs[i] = Zeros(batchShape...)
+		arrs[i] = ZerosOf(a.dtype, batchShape...)
 	}
 
 	a.ForEach(func(dataIndex int, index []int, value float64) {
@@ -403,57 +520,149 @@ func (a NDArray) Split(axis int, batch int) []NDArray {
 	return arrs
 }
 
+// Returns a view if the axes being merged or split are evenly strided, and a reshaped copy otherwise
 func (a NDArray) Reshape(shape ...int) NDArray {
-	// TODO: validate prod(a.shape) == prod(shape)
-	return NDArray{
-		shape: shape,
-		data:  a.data,
+	if err := CheckReshape(a.shape, shape); err != nil {
+		panic(err)
 	}
+	if !a.packed() && a.rows == nil {
+		if strides, ok := reshapeStrides(a.shape, a.strides, shape); ok {
+			return a.view(shape, strides, a.offset)
+		}
+	}
+	a = a.Contiguous()
+	arr := a.storage()
+	arr.shape = shape
+	return arr
+}
+
+// The strides viewing an array of shape and strides as newShape, if every run of axes that's merged or
+// split is evenly strided. Size 1 axes can go anywhere.
+func reshapeStrides(shape []int, strides []int, newShape []int) ([]int, bool) {
+	if shapeSize(shape) == 0 {
+		return nil, false
+	}
+	var oldShape, oldStrides []int
+	for i, sz := range shape {
+		if sz != 1 {
+			oldShape = append(oldShape, sz)
+			oldStrides = append(oldStrides, strides[i])
+		}
+	}
+	newStrides := make([]int, len(newShape))
+	for i := range newStrides {
+		newStrides[i] = 1
+	}
+	// match up runs old[oi:oj] and new[ni:nj] with the same size
+	for oi, ni := 0, 0; oi < len(oldShape); {
+		oj, nj := oi+1, ni+1
+		oSize, nSize := oldShape[oi], newShape[ni]
+		for oSize != nSize {
+			if nSize < oSize {
+				nSize *= newShape[nj]
+				nj++
+			} else {
+				oSize *= oldShape[oj]
+				oj++
+			}
+		}
+		for k := oi; k < oj-1; k++ {
+			if oldStrides[k] != oldShape[k+1]*oldStrides[k+1] {
+				return nil, false
+			}
+		}
+		newStrides[nj-1] = oldStrides[oj-1]
+		for k := nj - 1; k > ni; k-- {
+			newStrides[k-1] = newStrides[k] * newShape[k]
+		}
+		oi, ni = oj, nj
+	}
+	return newStrides, true
 }
 
 func (a NDArray) Sign() NDArray {
-	arr := Zeros(a.Shape()...)
-	for i, v := range a.data {
+	arr := ZerosOf(a.dtype, a.shape...)
+	return a.SignInto(arr)
+}
+
+func (a NDArray) SignInto(arr NDArray) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.SignInto(arr) })
+	}
+	mapInto(a, arr.data, func(v float64) float64 {
 		if v < 0 {
-			arr.data[i] = -1.
+			return -1.
 		} else if v > 0 {
-			arr.data[i] = 1.
+			return 1.
 		}
-	}
+		return 0.
+	})
 	return arr
 }
 
 func (a NDArray) PowConstant(e float64) NDArray {
+	arr := ZerosOf(a.dtype, a.shape...)
+	return a.PowConstantInto(e, arr)
+}
+
+func (a NDArray) PowConstantInto(e float64, arr NDArray) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.PowConstantInto(e, arr) })
+	}
 	f := func(v float64) float64 { return math.Pow(v, e) }
 	if e == 2.0 {
 		f = func(v float64) float64 { return v * v }
 	} else if e == 0.5 {
 		f = math.Sqrt
 	}
-	arr := Zeros(a.Shape()...)
-	for i := range arr.data {
-		arr.data[i] = f(a.data[i])
-	}
+	mapInto(a, arr.data, f)
 	return arr
 }
 
+// Bool arrays are summed as Int64
 func (a NDArray) Sum(axes ...int) NDArray {
-	arr := Zeros(AggrShape(a.shape, axes)...)
-	walkAggr(a.shape, arr.shape, func(inIndex int, outIndex int) {
-		arr.data[outIndex] += a.data[inIndex]
-	})
+	dtype := a.dtype
+	if dtype == Bool {
+		dtype = Int64
+	}
+	arr := ZerosOf(dtype, AggrShape(a.shape, axes)...)
+	return a.SumInto(arr, axes...)
+}
+
+// arr must have the AggrShape of the axes, and is overwritten
+func (a NDArray) SumInto(arr NDArray, axes ...int) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.SumInto(arr, axes...) })
+	}
+	arr.Fill(0.)
+	reduceInto(a.layout(), arr.shape, arr.data, func(o []float64, inIndex int, outIndex int) {
+		o[outIndex] += a.data[inIndex]
+	}, sumMerge)
 	return arr
 }
 
 func (a NDArray) Mean(axes ...int) NDArray {
-	arr := Zeros(AggrShape(a.shape, axes)...)
+	arr := ZerosOf(a.dtype, AggrShape(a.shape, axes)...)
+	return a.MeanInto(arr, axes...)
+}
+
+// arr must have the AggrShape of the axes, and is overwritten
+func (a NDArray) MeanInto(arr NDArray, axes ...int) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.MeanInto(arr, axes...) })
+	}
 	div := 1
 	for _, ax := range axes {
 		div *= a.shape[ax]
 	}
-	walkAggr(a.shape, arr.shape, func(inIndex int, outIndex int) {
-		arr.data[outIndex] += a.data[inIndex]
-	})
+	arr.Fill(0.)
+	reduceInto(a.layout(), arr.shape, arr.data, func(o []float64, inIndex int, outIndex int) {
+		o[outIndex] += a.data[inIndex]
+	}, sumMerge)
 	for i := range arr.data {
 		arr.data[i] /= float64(div)
 	}
@@ -462,48 +671,89 @@ func (a NDArray) Mean(axes ...int) NDArray {
 }
 
 func (a NDArray) Max(axes ...int) NDArray {
-	arr := Constant(math.Inf(-1), AggrShape(a.shape, axes)...)
-	walkAggr(a.shape, arr.shape, func(inIndex int, outIndex int) {
-		if a.data[inIndex] > arr.data[outIndex] {
-			arr.data[outIndex] = a.data[inIndex]
+	arr := ZerosOf(a.dtype, AggrShape(a.shape, axes)...)
+	return a.MaxInto(arr, axes...)
+}
+
+// arr must have the AggrShape of the axes, and is overwritten
+func (a NDArray) MaxInto(arr NDArray, axes ...int) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.MaxInto(arr, axes...) })
+	}
+	arr.Fill(math.Inf(-1))
+	reduceInto(a.layout(), arr.shape, arr.data, func(o []float64, inIndex int, outIndex int) {
+		if a.data[inIndex] > o[outIndex] {
+			o[outIndex] = a.data[inIndex]
 		}
-	})
+	}, math.Max)
 
 	return arr
 }
 
+// Returns a Bool mask. Int64 and Bool arrays are compared exactly.
 func (a NDArray) Greater(b NDArray) NDArray {
-	arr := Zeros(BroadcastShape(a.shape, b.shape)...)
+	arr := ZerosOf(Bool, BroadcastShape(a.shape, b.shape)...)
+	return a.GreaterInto(b, arr)
+}
+
+// arr must be a Bool array
+func (a NDArray) GreaterInto(b NDArray, arr NDArray) NDArray {
+	if !PromoteTypes(a.dtype, b.dtype).IsFloat() {
+		a, b = a.AsType(Int64), b.AsType(Int64)
+		arr.ForEach(func(dataIndex int, index []int, value float64) {
+			arr.dataBool[dataIndex] = a.dataI64[a.dataIndexBroadcast(index)] > b.dataI64[b.dataIndexBroadcast(index)]
+		})
+		return arr
+	}
 
 	arr.ForEach(func(dataIndex int, index []int, value float64) {
-		av := a.data[a.dataIndexBroadcast(index)]
-		bv := b.data[b.dataIndexBroadcast(index)]
-		if av > bv {
-			arr.data[dataIndex] = 1.
-		}
+		arr.dataBool[dataIndex] = a.at(a.dataIndexBroadcast(index)) > b.at(b.dataIndexBroadcast(index))
 	})
 
 	return arr
 }
 
+// Returns a Bool mask. Floats are equal within Epsilon, Int64 and Bool arrays are compared exactly.
 func (a NDArray) Equal(b NDArray) NDArray {
-	arr := Zeros(BroadcastShape(a.shape, b.shape)...)
+	arr := ZerosOf(Bool, BroadcastShape(a.shape, b.shape)...)
+	return a.EqualInto(b, arr)
+}
+
+// arr must be a Bool array
+func (a NDArray) EqualInto(b NDArray, arr NDArray) NDArray {
+	if !PromoteTypes(a.dtype, b.dtype).IsFloat() {
+		a, b = a.AsType(Int64), b.AsType(Int64)
+		arr.ForEach(func(dataIndex int, index []int, value float64) {
+			arr.dataBool[dataIndex] = a.dataI64[a.dataIndexBroadcast(index)] == b.dataI64[b.dataIndexBroadcast(index)]
+		})
+		return arr
+	}
 
 	arr.ForEach(func(dataIndex int, index []int, value float64) {
-		av := a.data[a.dataIndexBroadcast(index)]
-		bv := b.data[b.dataIndexBroadcast(index)]
-		if math.Abs(av-bv) < Epsilon {
-			arr.data[dataIndex] = 1.
-		}
+		av := a.at(a.dataIndexBroadcast(index))
+		bv := b.at(b.dataIndexBroadcast(index))
+		arr.dataBool[dataIndex] = math.Abs(av-bv) < Epsilon
 	})
 
 	return arr
 }
 
 func (a NDArray) EqualMask(e1 NDArray, e2 NDArray) NDArray {
-	arr := Zeros(a.shape...)
+	arr := ZerosOf(a.dtype, a.shape...)
+	return a.EqualMaskInto(e1, e2, arr)
+}
+
+func (a NDArray) EqualMaskInto(e1 NDArray, e2 NDArray, arr NDArray) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) || !both64(e1, e2) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray {
+			return a.EqualMaskInto(e1.AsType(Float64), e2.AsType(Float64), arr)
+		})
+	}
+	arr.Fill(0.)
 
-	if ShapeEqual(a.shape, e1.shape) && ShapeEqual(a.shape, e2.shape) {
+	if a.packed() && e1.packed() && e2.packed() && ShapeEqual(a.shape, e1.shape) && ShapeEqual(a.shape, e2.shape) {
 		for i, v := range a.data {
 			if math.Abs(e1.data[i]-e2.data[i]) < Epsilon {
 				arr.data[i] = v
@@ -514,7 +764,7 @@ func (a NDArray) EqualMask(e1 NDArray, e2 NDArray) NDArray {
 			v1 := e1.data[e1.dataIndexBroadcast(index)]
 			v2 := e2.data[e2.dataIndexBroadcast(index)]
 			if math.Abs(v1-v2) < Epsilon {
-				arr.data[dataIndex] = a.data[dataIndex]
+				arr.data[dataIndex] = a.data[a.dataIndexBroadcast(index)]
 			}
 		})
 	}
@@ -523,22 +773,29 @@ func (a NDArray) EqualMask(e1 NDArray, e2 NDArray) NDArray {
 }
 
 func (a NDArray) MatMul(b NDArray, a1 int, a2 int) NDArray {
-	arr := Zeros(MatMulShape(a.shape, b.shape, a1, a2)...)
+	arr := ZerosOf(PromoteTypes(a.dtype, b.dtype), Must(CheckMatMul(a.shape, b.shape, a1, a2))...)
 	return a.MatMulInto(b, a1, a2, arr)
 }
 
+// computed in the dtype of arr
 func (a NDArray) MatMulInto(b NDArray, a1 int, a2 int, arr NDArray) NDArray {
+	if blasMatMul(a, b, a1, a2, arr) {
+		return arr
+	}
+	requirePacked(arr)
+	if !both64(a, b) || arr.dtype != Float64 {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray {
+			return a.MatMulInto(b.AsType(Float64), a1, a2, arr)
+		})
+	}
+
 	// We rely on the data being zeros
 	arr.Fill(0.)
 
-	aOff := 1
-	bOff := 1
-	for i := a2 + 1; i < len(a.shape); i++ {
-		aOff *= a.shape[i]
-	}
-	for i := a1 + 1; i < len(b.shape); i++ {
-		bOff *= b.shape[i]
-	}
+	// a is stepped along a2 and b along a1, which a ReindexRoot leading axis has no stride for
+	a, b = a.strided(), b.strided()
+	aOff := a.stridesOf()[a2]
+	bOff := b.stridesOf()[a1]
 
 	arr.ForEach(func(dataIndex int, index []int, value float64) {
 		ia2 := index[a2]
@@ -558,283 +815,220 @@ func (a NDArray) MatMulInto(b NDArray, a1 int, a2 int, arr NDArray) NDArray {
 	return arr
 }
 
+// Returns a view
 func (a NDArray) Transpose(a1 int, a2 int) NDArray {
-	arr := Zeros(TransposeShape(a.shape, a1, a2)...)
-
-	arr.ForEach(func(dataIndex int, index []int, value float64) {
-		index[a1], index[a2] = index[a2], index[a1]
-		arr.data[dataIndex] = a.Get(index)
-	})
-
-	return arr
+	a = a.strided()
+	strides := append([]int{}, a.stridesOf()...)
+	strides[a1], strides[a2] = strides[a2], strides[a1]
+	return a.view(TransposeShape(a.shape, a1, a2), strides, a.offset)
 }
 
 func (a NDArray) Log() NDArray {
-	arr := Zeros(a.shape...)
+	arr := ZerosOf(a.dtype, a.shape...)
+	return a.LogInto(arr)
+}
 
-	for i, v := range a.data {
-		arr.data[i] = math.Log(v)
+func (a NDArray) LogInto(arr NDArray) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.LogInto(arr) })
 	}
-
+	mapInto(a, arr.data, math.Log)
 	return arr
 }
 
 func (a NDArray) Exp() NDArray {
-	arr := Zeros(a.shape...)
+	arr := ZerosOf(a.dtype, a.shape...)
+	return a.ExpInto(arr)
+}
 
-	for i, v := range a.data {
-		arr.data[i] = math.Exp(v)
+func (a NDArray) ExpInto(arr NDArray) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.ExpInto(arr) })
 	}
-
+	mapInto(a, arr.data, math.Exp)
 	return arr
 }
 
 func (a NDArray) Clip(min float64, max float64) NDArray {
-	arr := Zeros(a.shape...)
+	arr := ZerosOf(a.dtype, a.shape...)
+	return a.ClipInto(min, max, arr)
+}
 
-	for i, v := range a.data {
+func (a NDArray) ClipInto(min float64, max float64, arr NDArray) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.ClipInto(min, max, arr) })
+	}
+	mapInto(a, arr.data, func(v float64) float64 {
 		if v < min {
-			v = min
+			return min
 		} else if v > max {
-			v = max
+			return max
 		}
-		arr.data[i] = v
-	}
+		return v
+	})
 	return arr
 }
 
+// Returns a view
 func (a NDArray) Reverse(axes ...int) NDArray {
-	arr := Zeros(a.shape...)
-	a.ForEach(func(dataIndex int, index []int, value float64) {
-		for _, ax := range axes {
-			index[ax] = a.Shape()[ax] - index[ax] - 1
+	strides := append([]int{}, a.stridesOf()...)
+	offset := a.offset
+	rows := a.rows
+	for _, ax := range axes {
+		offset += (a.shape[ax] - 1) * strides[ax]
+		strides[ax] = -strides[ax]
+		if ax == 0 && rows != nil {
+			reversed := make([]int, len(rows))
+			for i, r := range rows {
+				reversed[len(rows)-1-i] = r
+			}
+			rows = reversed
 		}
-		arr.Set(index, value)
-	})
-	return arr
+	}
+	if rows != nil {
+		return a.rowsView(append([]int{}, a.shape...), rows, strides, offset)
+	}
+	return a.view(append([]int{}, a.shape...), strides, offset)
 }
 
-func (a NDArray) Conv2D(k NDArray, hAxis int, wAxis int, fAxis int) NDArray {
-	kShape := k.Shape()
-	kh, kw, kf := kShape[0], kShape[1], kShape[3]
-	arr := Zeros(Conv2DShape(a.Shape(), hAxis, wAxis, fAxis, kh, kw, kf)...)
-	return a.Conv2DInto(k, hAxis, wAxis, fAxis, arr)
+func (a NDArray) ReLU() NDArray {
+	arr := ZerosOf(a.dtype, a.shape...)
+	return a.ReLUInto(arr)
 }
 
-func (a NDArray) Conv2DInto(k NDArray, hAxis int, wAxis int, fAxis int, arr NDArray) NDArray {
-	kShape := k.Shape()
-	kh, kw, inf, kf := kShape[0], kShape[1], kShape[2], kShape[3]
-
-	arr.Fill(0.)
-
-	adim := len(a.shape)
-	if fAxis == adim-1 && wAxis == adim-2 && hAxis == adim-3 {
-		blasConv2D(a, k, arr)
-		return arr
+func (a NDArray) ReLUInto(arr NDArray) NDArray {
+	requirePacked(arr)
+	if arr.dtype == Bool {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.ReLUInto(arr) })
 	}
-
-	var iHOff, iWOff, iFOff int
-	iSize := 1
-	for i := len(a.shape) - 1; i >= 0; i-- {
-		if i == fAxis {
-			iFOff = iSize
-		} else if i == wAxis {
-			iWOff = iSize
-		} else if i == hAxis {
-			iHOff = iSize
-		}
-		iSize *= a.shape[i]
+	a = a.AsType(arr.dtype)
+	switch arr.dtype {
+	case Float32:
+		reluInto(a.layout(), a.data32, arr.data32)
+	case Int64:
+		reluInto(a.layout(), a.dataI64, arr.dataI64)
+	default:
+		reluInto(a.layout(), a.data, arr.data)
 	}
 
-	kFOff := 1
-	kIFOff := kFOff * kf
-	kWOff := kIFOff * inf
-	kHOff := kWOff * kw
-
-	arr.ForEach(func(dataIndex int, index []int, value float64) {
-		fIndex := index[fAxis]
-		index[fAxis] = 0
-		iDataIndex := a.dataIndex(index)
-
-		ai1 := iDataIndex
-		ki1 := fIndex * kFOff
-		for h := 0; h < kh; h++ {
-			ai2 := ai1
-			ki2 := ki1
-			for w := 0; w < kw; w++ {
-				ai := ai2
-				ki := ki2
-				for f := 0; f < inf; f++ {
-					arr.data[dataIndex] += a.data[ai] * k.data[ki]
-					ai += iFOff
-					ki += kIFOff
-				}
-				ai2 += iWOff
-				ki2 += kWOff
-			}
-			ai1 += iHOff
-			ki1 += kHOff
-		}
-	})
 	return arr
 }
 
-func (a NDArray) InverseConv2D(g NDArray, hAxis int, wAxis int, fAxis int) NDArray {
-	kShape := InverseConv2DShape(a.shape, g.shape, hAxis, wAxis, fAxis)
-	arr := Zeros(kShape...)
-	return a.InverseConv2DInto(g, hAxis, wAxis, fAxis, arr)
-}
-
-func (a NDArray) InverseConv2DInto(g NDArray, hAxis int, wAxis int, fAxis int, arr NDArray) NDArray {
-	kShape := arr.Shape()
-	kh, kw, inf, kf := kShape[0], kShape[1], kShape[2], kShape[3]
-	arr.Fill(0.)
-
-	adim := len(a.shape)
-	if fAxis == adim-1 && wAxis == adim-2 && hAxis == adim-3 {
-		blasInverseConv2D(a, g, arr)
-		return arr
+func reluInto[T number](aLayout layout, a []T, arr []T) {
+	if !aLayout.packed() {
+		walkElems(aLayout, func(aIndex int, i int) {
+			if a[aIndex] > 0. {
+				arr[i] = a[aIndex]
+			} else {
+				arr[i] = 0.
+			}
+		})
+		return
 	}
-
-	var iHOff, iWOff, iFOff int
-	iSize := 1
-	for i := len(a.shape) - 1; i >= 0; i-- {
-		if i == fAxis {
-			iFOff = iSize
-		} else if i == wAxis {
-			iWOff = iSize
-		} else if i == hAxis {
-			iHOff = iSize
-		}
-		iSize *= a.shape[i]
-	}
-
-	kFOff := 1
-	kIFOff := kFOff * kf
-	kWOff := kIFOff * inf
-	kHOff := kWOff * kw
-
-	g.ForEach(func(dataIndex int, index []int, value float64) {
-		fIndex := index[fAxis]
-		index[fAxis] = 0
-		iDataIndex := a.dataIndex(index)
-
-		ai1 := iDataIndex
-		ki1 := fIndex * kFOff
-		for h := 0; h < kh; h++ {
-			ai2 := ai1
-			ki2 := ki1
-			for w := 0; w < kw; w++ {
-				ai := ai2
-				ki := ki2
-				for f := 0; f < inf; f++ {
-					arr.data[ki] += a.data[ai] * value
-					ai += iFOff
-					ki += kIFOff
-				}
-				ai2 += iWOff
-				ki2 += kWOff
+	parallelFor(len(arr), func(start int, end int) {
+		for i := start; i < end; i++ {
+			if a[i] > 0. {
+				arr[i] = a[i]
+			} else {
+				arr[i] = 0.
 			}
-			ai1 += h * iHOff
-			ki1 += h * kHOff
 		}
 	})
-
-	return arr
 }
 
-func (a NDArray) ReLU() NDArray {
-	arr := Zeros(a.shape...)
-	return a.ReLUInto(arr)
+func (a NDArray) ReLUMask(m NDArray) NDArray {
+	arr := ZerosOf(a.dtype, BroadcastShape(a.shape, m.shape)...)
+	return a.ReLUMaskInto(m, arr)
 }
 
-func (a NDArray) ReLUInto(arr NDArray) NDArray {
-	for i, v := range a.data {
-		if v > 0. {
-			arr.data[i] = v
-		} else {
-			arr.data[i] = 0.
-		}
+// computed in the dtype of arr
+func (a NDArray) ReLUMaskInto(m NDArray, arr NDArray) NDArray {
+	requirePacked(arr)
+	if arr.dtype == Bool {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.ReLUMaskInto(m.AsType(Float64), arr) })
+	}
+	a, m = a.AsType(arr.dtype), m.AsType(arr.dtype)
+	switch arr.dtype {
+	case Float32:
+		reluMaskInto(a, m, arr, a.data32, m.data32, arr.data32)
+	case Int64:
+		reluMaskInto(a, m, arr, a.dataI64, m.dataI64, arr.dataI64)
+	default:
+		reluMaskInto(a, m, arr, a.data, m.data, arr.data)
 	}
 
 	return arr
 }
 
-func (a NDArray) ReLUMask(m NDArray) NDArray {
-	arr := Zeros(BroadcastShape(a.shape, m.shape)...)
-	return a.ReLUMaskInto(m, arr)
-}
-
-func (a NDArray) ReLUMaskInto(m NDArray, arr NDArray) NDArray {
-	if ShapeEqual(a.shape, m.shape) {
-		for i := range arr.data {
-			if m.data[i] > 0 {
-				arr.data[i] = a.data[i]
-			} else {
-				arr.data[i] = 0.
+func reluMaskInto[T number](a NDArray, m NDArray, arr NDArray, aData []T, mData []T, arrData []T) {
+	if a.packed() && m.packed() && ShapeEqual(a.shape, m.shape) {
+		parallelFor(len(arrData), func(start int, end int) {
+			for i := start; i < end; i++ {
+				if mData[i] > 0 {
+					arrData[i] = aData[i]
+				} else {
+					arrData[i] = 0.
+				}
 			}
-		}
+		})
 	} else {
 		arr.ForEach(func(dataIndex int, index []int, value float64) {
 			aIndex := a.dataIndexBroadcast(index)
 			mIndex := m.dataIndexBroadcast(index)
 
-			if m.data[mIndex] > 0 {
-				arr.data[dataIndex] = a.data[aIndex]
+			if mData[mIndex] > 0 {
+				arrData[dataIndex] = aData[aIndex]
 			} else {
-				arr.data[dataIndex] = 0.
+				arrData[dataIndex] = 0.
 			}
 		})
 	}
-
-	return arr
 }
 
+// Returns a view with row i of a moved to row indices[i], where indices is a permutation of the rows
 func (a NDArray) ReindexRoot(indices []int) NDArray {
-	if len(indices) != a.shape[0] {
-		// TODO: PANIC
+	if len(a.shape) == 0 || len(indices) != a.shape[0] {
+		panic(&ShapeError{Op: "ReindexRoot", Axis: 0, Actual: a.shape, Reason: fmt.Sprintf("need one index per row, not %d", len(indices))})
 	}
-
-	arr := Zeros(a.shape...)
-
-	topSz := 1
-	for _, sz := range a.shape[1:] {
-		topSz *= sz
-	}
-
+	strides := a.stridesOf()
+	rows := make([]int, len(indices))
+	seen := make([]bool, len(indices))
 	for i, idx := range indices {
-		inIndex := i * topSz
-		outIndex := idx * topSz
-		for j, v := range a.data[inIndex : inIndex+topSz] {
-			arr.data[outIndex+j] = v
+		if idx < 0 || idx >= len(indices) || seen[idx] {
+			panic(&ShapeError{Op: "ReindexRoot", Axis: 0, Actual: a.shape, Reason: fmt.Sprintf("indices %v aren't a permutation of the rows", indices)})
+		}
+		seen[idx] = true
+		if a.rows != nil {
+			rows[idx] = a.rows[i]
+		} else {
+			rows[idx] = i * strides[0]
 		}
 	}
+	return a.rowsView(a.shape, rows, strides, a.offset)
+}
 
-	return arr
+func (a NDArray) ReindexRootInto(indices []int, arr NDArray) NDArray {
+	return a.ReindexRoot(indices).AsTypeInto(arr)
 }
 
+// Returns a view
 func (a NDArray) SliceRoot(start int, length int) NDArray {
-	topSz := 1
-	for _, sz := range a.shape[1:] {
-		topSz *= sz
-	}
-
-	newShape := make([]int, len(a.shape))
-	copy(newShape, a.shape)
-	newShape[0] = length
-
-	return NDArray{
-		data:  a.data[start*topSz : start*topSz+length*topSz],
-		shape: newShape,
-	}
+	return a.Slice(0, start, start+length)
 }
 
 func (a NDArray) Normalize(axis int) NDArray {
-	arr := Zeros(a.shape...)
+	arr := ZerosOf(a.dtype, a.shape...)
 	return a.NormalizeInto(axis, arr)
 }
 
 func (a NDArray) NormalizeInto(axis int, arr NDArray) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.NormalizeInto(axis, arr) })
+	}
 	aggrShape := make([]int, len(a.shape))
 	for i := range aggrShape {
 		if i == axis {
@@ -853,32 +1047,38 @@ func (a NDArray) NormalizeInto(axis int, arr NDArray) NDArray {
 	}
 
 	mean := make([]float64, size)
-	walkAggr(a.shape, aggrShape, func(inIndex int, outIndex int) {
-		mean[outIndex] += a.data[inIndex] / div
-	})
+	reduceInto(a.layout(), aggrShape, mean, func(o []float64, inIndex int, outIndex int) {
+		o[outIndex] += a.data[inIndex] / div
+	}, sumMerge)
 
 	stddev := make([]float64, size)
-	walkAggr(a.shape, aggrShape, func(inIndex int, outIndex int) {
+	reduceInto(a.layout(), aggrShape, stddev, func(o []float64, inIndex int, outIndex int) {
 		v := a.data[inIndex] - mean[outIndex]
-		stddev[outIndex] += v * v
-	})
+		o[outIndex] += v * v
+	}, sumMerge)
 	for i := range stddev {
 		stddev[i] = math.Sqrt(stddev[i] / div)
 	}
 
-	walkAggr(a.shape, aggrShape, func(inIndex int, outIndex int) {
-		arr.data[inIndex] = (a.data[inIndex] - mean[outIndex]) / stddev[outIndex]
+	walkBroadcast(a.layout(), packedLayout(aggrShape), a.shape, func(inIndex int, aggrIndex int, outIndex int) {
+		arr.data[outIndex] = (a.data[inIndex] - mean[aggrIndex]) / stddev[aggrIndex]
 	})
 
 	return arr
 }
 
 func (a NDArray) InverseNormalize(g NDArray, axis int) NDArray {
-	arr := Zeros(a.shape...)
+	arr := ZerosOf(a.dtype, a.shape...)
 	return a.InverseNormalizeInto(g, axis, arr)
 }
 
 func (a NDArray) InverseNormalizeInto(g NDArray, axis int, arr NDArray) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) || g.dtype != Float64 {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray {
+			return a.InverseNormalizeInto(g.AsType(Float64), axis, arr)
+		})
+	}
 	aggrShape := make([]int, len(a.shape))
 	for i := range aggrShape {
 		if i == axis {
@@ -897,25 +1097,29 @@ func (a NDArray) InverseNormalizeInto(g NDArray, axis int, arr NDArray) NDArray
 	}
 
 	mean := make([]float64, size)
-	walkAggr(a.shape, aggrShape, func(inIndex int, outIndex int) {
-		mean[outIndex] += a.data[inIndex]
-	})
+	reduceInto(a.layout(), aggrShape, mean, func(o []float64, inIndex int, outIndex int) {
+		o[outIndex] += a.data[inIndex]
+	}, sumMerge)
 	for i := range mean {
 		mean[i] /= div
 	}
 
-	walkAggr(a.shape, aggrShape, func(inIndex int, outIndex int) {
-		arr.data[inIndex] = a.data[inIndex] - mean[outIndex]
+	walkBroadcast(a.layout(), packedLayout(aggrShape), a.shape, func(inIndex int, aggrIndex int, outIndex int) {
+		arr.data[outIndex] = a.data[inIndex] - mean[aggrIndex]
 	})
 
+	// the rest works on the centered values in arr, alongside g which blas reads linearly
+	g = g.Contiguous()
+	packed := packedLayout(a.shape)
+
 	stddev := make([]float64, size)
 	if axis == len(a.shape)-1 {
 		blasStddev(arr.data, stddev)
 	} else {
-		walkAggr(a.shape, aggrShape, func(inIndex int, outIndex int) {
+		reduceInto(packed, aggrShape, stddev, func(o []float64, inIndex int, outIndex int) {
 			v := arr.data[inIndex]
-			stddev[outIndex] += v * v
-		})
+			o[outIndex] += v * v
+		}, sumMerge)
 		for i := range stddev {
 			stddev[i] = math.Sqrt(stddev[i] / div)
 		}
@@ -925,18 +1129,18 @@ func (a NDArray) InverseNormalizeInto(g NDArray, axis int, arr NDArray) NDArray
 	if axis == len(a.shape)-1 {
 		blasDVariance(arr.data, g.data, stddev, dVariance)
 	} else {
-		walkAggr(a.shape, aggrShape, func(inIndex int, outIndex int) {
+		reduceInto(packed, aggrShape, dVariance, func(o []float64, inIndex int, outIndex int) {
 			s := stddev[outIndex]
-			dVariance[outIndex] += g.data[inIndex] * arr.data[inIndex] * -0.5 / (s * s * s)
-		})
+			o[outIndex] += g.data[inIndex] * arr.data[inIndex] * -0.5 / (s * s * s)
+		}, sumMerge)
 	}
 
 	dMean := make([]float64, size)
-	walkAggr(a.shape, aggrShape, func(inIndex int, outIndex int) {
-		dMean[outIndex] += g.data[inIndex]*-1.0/stddev[outIndex] + dVariance[outIndex]*-2*arr.data[inIndex]/div
-	})
+	reduceInto(packed, aggrShape, dMean, func(o []float64, inIndex int, outIndex int) {
+		o[outIndex] += g.data[inIndex]*-1.0/stddev[outIndex] + dVariance[outIndex]*-2*arr.data[inIndex]/div
+	}, sumMerge)
 
-	walkAggr(a.shape, aggrShape, func(inIndex int, outIndex int) {
+	walkBroadcast(packed, packedLayout(aggrShape), a.shape, func(inIndex int, outIndex int, _ int) {
 		arr.data[inIndex] = g.data[inIndex]/stddev[outIndex] + dVariance[outIndex]*2*arr.data[inIndex]/div + dMean[outIndex]/div
 	})
 
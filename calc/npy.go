@@ -0,0 +1,347 @@
+package calc
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// NumPy .npy files are a magic string, a version, a Python dict literal header describing the dtype, order
+// and shape, then the raw elements. .npz files are zip archives of .npy files named after their arrays.
+
+var npyMagic = []byte("\x93NUMPY")
+
+var (
+	npyDescr   = regexp.MustCompile(`'descr'\s*:\s*'([^']*)'`)
+	npyFortran = regexp.MustCompile(`'fortran_order'\s*:\s*(True|False)`)
+	npyShape   = regexp.MustCompile(`'shape'\s*:\s*\(([^)]*)\)`)
+)
+
+// Reads a .npy array. Floats read as Float64 or Float32, bools as Bool, and signed or unsigned integers
+// of any width as Int64. Fortran ordered arrays are transposed back to C order.
+func ReadNpy(r io.Reader) (NDArray, error) {
+	header, err := readNpyHeader(r)
+	if err != nil {
+		return NDArray{}, err
+	}
+
+	descr := npyDescr.FindStringSubmatch(header)
+	fortran := npyFortran.FindStringSubmatch(header)
+	shapeStr := npyShape.FindStringSubmatch(header)
+	if descr == nil || fortran == nil || shapeStr == nil {
+		return NDArray{}, fmt.Errorf("npy: malformed header %q", header)
+	}
+
+	shape := []int{}
+	for _, s := range strings.Split(shapeStr[1], ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		sz, err := strconv.Atoi(s)
+		if err != nil || sz < 0 {
+			return NDArray{}, fmt.Errorf("npy: bad shape (%s)", shapeStr[1])
+		}
+		shape = append(shape, sz)
+	}
+	// no element is wider than 8 bytes, so this bounds the data of every dtype
+	if _, _, ok := checkedShapeSize(shape, 8); !ok {
+		return NDArray{}, fmt.Errorf("npy: bad shape (%s)", shapeStr[1])
+	}
+
+	readShape := shape
+	if fortran[1] == "True" {
+		readShape = make([]int, len(shape))
+		for i := range shape {
+			readShape[i] = shape[len(shape)-1-i]
+		}
+	}
+	arr, err := readNpyData(r, descr[1], readShape)
+	if err != nil {
+		return NDArray{}, err
+	}
+	if fortran[1] == "True" {
+		axes := make([]int, len(shape))
+		for i := range axes {
+			axes[i] = len(shape) - 1 - i
+		}
+		arr = arr.permute(axes).Contiguous()
+	}
+	return arr, nil
+}
+
+func readNpyHeader(r io.Reader) (string, error) {
+	prefix := make([]byte, len(npyMagic)+2)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return "", fmt.Errorf("npy: reading magic: %w", err)
+	}
+	if !bytes.Equal(prefix[:len(npyMagic)], npyMagic) {
+		return "", errors.New("npy: not a .npy file")
+	}
+
+	var headerLen int
+	switch major := prefix[len(npyMagic)]; major {
+	case 1:
+		var n uint16
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return "", fmt.Errorf("npy: reading header length: %w", err)
+		}
+		headerLen = int(n)
+	case 2, 3:
+		var n uint32
+		if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+			return "", fmt.Errorf("npy: reading header length: %w", err)
+		}
+		headerLen = int(n)
+	default:
+		return "", fmt.Errorf("npy: unsupported version %d", major)
+	}
+
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", fmt.Errorf("npy: reading header: %w", err)
+	}
+	return string(header), nil
+}
+
+func readNpyData(r io.Reader, descr string, shape []int) (NDArray, error) {
+	if len(descr) < 3 {
+		return NDArray{}, fmt.Errorf("npy: unsupported dtype %q", descr)
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	switch descr[0] {
+	case '>':
+		order = binary.BigEndian
+	case '<', '|', '=':
+	default:
+		return NDArray{}, fmt.Errorf("npy: unsupported dtype %q", descr)
+	}
+
+	n := shapeSize(shape)
+	switch descr[1:] {
+	case "f8":
+		data, err := readNpyElems[float64](r, order, n)
+		if err != nil {
+			return NDArray{}, err
+		}
+		return FromRaw(shape, data), nil
+	case "f4":
+		data, err := readNpyElems[float32](r, order, n)
+		if err != nil {
+			return NDArray{}, err
+		}
+		return FromRaw32(shape, data), nil
+	case "b1":
+		data, err := readNpyElems[bool](r, order, n)
+		if err != nil {
+			return NDArray{}, err
+		}
+		return FromRawBool(shape, data), nil
+	case "i8":
+		data, err := readNpyElems[int64](r, order, n)
+		if err != nil {
+			return NDArray{}, err
+		}
+		return FromRawInt64(shape, data), nil
+	case "i4":
+		return readNpyInts[int32](r, order, shape)
+	case "i2":
+		return readNpyInts[int16](r, order, shape)
+	case "i1":
+		return readNpyInts[int8](r, order, shape)
+	case "u8":
+		return readNpyInts[uint64](r, order, shape)
+	case "u4":
+		return readNpyInts[uint32](r, order, shape)
+	case "u2":
+		return readNpyInts[uint16](r, order, shape)
+	case "u1":
+		return readNpyInts[uint8](r, order, shape)
+	default:
+		return NDArray{}, fmt.Errorf("npy: unsupported dtype %q", descr)
+	}
+}
+
+// elements read per chunk, so a header claiming more data than the file holds fails at the end of the
+// file rather than allocating all of it up front
+const npyChunk = 1 << 16
+
+func readNpyElems[T float64 | float32 | bool | int64 | int8 | int16 | int32 | uint8 | uint16 | uint32 | uint64](r io.Reader, order binary.ByteOrder, n int) ([]T, error) {
+	data := make([]T, 0, min(n, npyChunk))
+	for len(data) < n {
+		m := min(n-len(data), npyChunk)
+		data = slices.Grow(data, m)[:len(data)+m]
+		if err := binary.Read(r, order, data[len(data)-m:]); err != nil {
+			return nil, fmt.Errorf("npy: reading data: %w", err)
+		}
+	}
+	return data, nil
+}
+
+// reads narrower integers and widens them to Int64
+func readNpyInts[T int8 | int16 | int32 | uint8 | uint16 | uint32 | uint64](r io.Reader, order binary.ByteOrder, shape []int) (NDArray, error) {
+	raw, err := readNpyElems[T](r, order, shapeSize(shape))
+	if err != nil {
+		return NDArray{}, err
+	}
+	data := make([]int64, len(raw))
+	for i, v := range raw {
+		data[i] = int64(v)
+	}
+	return FromRawInt64(shape, data), nil
+}
+
+// Writes a as a little endian, C ordered .npy array of its dtype
+func WriteNpy(w io.Writer, a NDArray) error {
+	a = a.Contiguous()
+	var descr string
+	var data any
+	n := shapeSize(a.shape)
+	switch a.dtype {
+	case Float32:
+		descr, data = "<f4", a.data32[:n]
+	case Int64:
+		descr, data = "<i8", a.dataI64[:n]
+	case Bool:
+		descr, data = "|b1", a.dataBool[:n]
+	default:
+		descr, data = "<f8", a.data[:n]
+	}
+
+	shape := make([]string, len(a.shape))
+	for i, sz := range a.shape {
+		shape[i] = strconv.Itoa(sz)
+	}
+	shapeStr := strings.Join(shape, ", ")
+	if len(shape) == 1 {
+		shapeStr += ","
+	}
+	header := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%s), }", descr, shapeStr)
+
+	// version 1 stores the header length in 2 bytes, so longer padded headers need version 2
+	version, padded := byte(1), npyPad(header, 2)
+	if len(padded) > 1<<16-1 {
+		version, padded = 2, npyPad(header, 4)
+	}
+	header = padded
+
+	bw := bufio.NewWriter(w)
+	bw.Write(npyMagic)
+	bw.Write([]byte{version, 0})
+	if version == 1 {
+		binary.Write(bw, binary.LittleEndian, uint16(len(header)))
+	} else {
+		binary.Write(bw, binary.LittleEndian, uint32(len(header)))
+	}
+	bw.WriteString(header)
+	if err := binary.Write(bw, binary.LittleEndian, data); err != nil {
+		return fmt.Errorf("npy: writing data: %w", err)
+	}
+	return bw.Flush()
+}
+
+// pads header with spaces and a newline so the data after it starts 64 byte aligned, given the size of
+// the header length field
+func npyPad(header string, lenSize int) string {
+	prefixLen := len(npyMagic) + 2 + lenSize
+	return header + strings.Repeat(" ", 63-(prefixLen+len(header))%64) + "\n"
+}
+
+func LoadNpy(path string) (NDArray, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return NDArray{}, err
+	}
+	defer f.Close()
+	return ReadNpy(f)
+}
+
+func SaveNpy(path string, a NDArray) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteNpy(f, a); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Reads every array in a .npz archive of size bytes, keyed by name without the .npy extension.
+// Compressed archives (numpy.savez_compressed) are read too.
+func ReadNpz(r io.ReaderAt, size int64) (map[string]NDArray, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("npz: %w", err)
+	}
+	arrays := map[string]NDArray{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("npz: %s: %w", f.Name, err)
+		}
+		arr, err := ReadNpy(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("npz: %s: %w", f.Name, err)
+		}
+		arrays[strings.TrimSuffix(f.Name, ".npy")] = arr
+	}
+	return arrays, nil
+}
+
+// Writes arrays as an uncompressed .npz archive, like numpy.savez, with entries in name order
+func WriteNpz(w io.Writer, arrays map[string]NDArray) error {
+	names := make([]string, 0, len(arrays))
+	for name := range arrays {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	zw := zip.NewWriter(w)
+	for _, name := range names {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: name + ".npy", Method: zip.Store})
+		if err != nil {
+			return fmt.Errorf("npz: %s: %w", name, err)
+		}
+		if err := WriteNpy(fw, arrays[name]); err != nil {
+			return fmt.Errorf("npz: %s: %w", name, err)
+		}
+	}
+	return zw.Close()
+}
+
+func LoadNpz(path string) (map[string]NDArray, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return ReadNpz(f, info.Size())
+}
+
+func SaveNpz(path string, arrays map[string]NDArray) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteNpz(f, arrays); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,231 @@
+package calc_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// a .npy file with the given header dict, padded the way numpy.save does so the data is 64 byte aligned
+func npyFile(header string, data any) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("\x93NUMPY\x01\x00")
+	header += strings.Repeat(" ", 63-(10+len(header))%64) + "\n"
+	binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	binary.Write(&buf, binary.LittleEndian, data)
+	return buf.Bytes()
+}
+
+func checkSame(t *testing.T, name string, got calc.NDArray, want calc.NDArray) {
+	t.Helper()
+	if got.DType() != want.DType() {
+		t.Errorf("%s: dtype %s, want %s", name, got.DType(), want.DType())
+	}
+	if !calc.ShapeEqual(got.Shape(), want.Shape()) || len(got.Shape()) != len(want.Shape()) {
+		t.Errorf("%s: shape %v, want %v", name, got.Shape(), want.Shape())
+		return
+	}
+	want.ForEach(func(_ int, index []int, w float64) {
+		if g := got.Get(index); g != w && !(math.IsNaN(g) && math.IsNaN(w)) {
+			t.Errorf("%s: %v = %v, want %v", name, index, g, w)
+		}
+	})
+}
+
+func TestNpyRoundTrip(t *testing.T) {
+	rng := calc.NewRNG(9)
+	f8 := rng.Normal(0, 1, 2, 3, 4)
+	f8.Set([]int{0, 0, 0}, math.Inf(-1))
+	f8.Set([]int{0, 0, 1}, math.NaN())
+	arrays := map[string]calc.NDArray{
+		"f8":         f8,
+		"f4":         rng.Normal(0, 1, 5, 2).AsType(calc.Float32),
+		"i8":         calc.FromRawInt64([]int{4}, []int64{-1 << 62, -1, 0, 1 << 40}),
+		"bool":       calc.FromRawBool([]int{2, 2}, []bool{true, false, false, true}),
+		"scalar":     calc.FromRaw([]int{}, []float64{2.5}),
+		"empty":      calc.Zeros(0, 3),
+		"transposed": f8.Transpose(0, 2),
+	}
+	for name, a := range arrays {
+		var buf bytes.Buffer
+		if err := calc.WriteNpy(&buf, a); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		raw := buf.Bytes()
+		headerLen := int(binary.LittleEndian.Uint16(raw[8:10]))
+		if (10+headerLen)%64 != 0 || raw[10+headerLen-1] != '\n' {
+			t.Errorf("%s: data starts at %d, want 64 byte aligned after a newline", name, 10+headerLen)
+		}
+		got, err := calc.ReadNpy(&buf)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		checkSame(t, name, got, a)
+	}
+
+	var buf bytes.Buffer
+	if err := calc.WriteNpz(&buf, arrays); err != nil {
+		t.Fatal(err)
+	}
+	read, err := calc.ReadNpz(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(read) != len(arrays) {
+		t.Errorf("npz has %d arrays, want %d", len(read), len(arrays))
+	}
+	for name, a := range arrays {
+		checkSame(t, "npz "+name, read[name], a)
+	}
+}
+
+// Headers that only fit in version 1 before padding still switch to version 2 when padding overflows it
+func TestNpyLongHeaders(t *testing.T) {
+	// each size 1 axis adds 3 bytes to the shape, so these ranks cross 65535 bytes of padded header
+	for rank := 21800; rank < 21830; rank++ {
+		shape := make([]int, rank)
+		for i := range shape {
+			shape[i] = 1
+		}
+		a := calc.FromRaw(shape, []float64{float64(rank)})
+		var buf bytes.Buffer
+		if err := calc.WriteNpy(&buf, a); err != nil {
+			t.Fatal(err)
+		}
+		raw := buf.Bytes()
+		var dataStart int
+		switch raw[6] {
+		case 1:
+			dataStart = 10 + int(binary.LittleEndian.Uint16(raw[8:10]))
+		case 2:
+			dataStart = 12 + int(binary.LittleEndian.Uint32(raw[8:12]))
+			if dataStart-12 <= 1<<16-1 {
+				t.Errorf("rank %d: version 2 for a header of %d bytes", rank, dataStart-12)
+			}
+		default:
+			t.Fatalf("rank %d: version %d", rank, raw[6])
+		}
+		if dataStart%64 != 0 || raw[dataStart-1] != '\n' || len(raw) != dataStart+8 {
+			t.Errorf("rank %d: data starts at %d of %d bytes", rank, dataStart, len(raw))
+		}
+		got, err := calc.ReadNpy(&buf)
+		if err != nil {
+			t.Fatalf("rank %d: %v", rank, err)
+		}
+		checkSame(t, "long header", got, a)
+	}
+}
+
+// Headers that don't match the data are errors, without allocating what the header claims
+func TestNpyBadFiles(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		header string
+		data   any
+		want   string
+	}{
+		{"oversized shape", "{'descr': '<f8', 'fortran_order': False, 'shape': (100000000000000,), }", []float64{1, 2}, "reading data"},
+		{"overflowing shape", "{'descr': '<f8', 'fortran_order': False, 'shape': (4611686018427387904, 4), }", []float64{1, 2}, "bad shape"},
+		{"overflowing bytes", "{'descr': '<f4', 'fortran_order': False, 'shape': (1152921504606846976, 2), }", []float32{1, 2}, "bad shape"},
+		{"overflowing zero size", "{'descr': '<f8', 'fortran_order': False, 'shape': (0, 4611686018427387904, 4), }", []float64{}, ""},
+		{"truncated", "{'descr': '<f8', 'fortran_order': False, 'shape': (2, 3), }", []float64{1, 2, 3, 4, 5}, "reading data"},
+		{"truncated widened", "{'descr': '<i2', 'fortran_order': True, 'shape': (3, 2), }", []int16{1, 2, 3}, "reading data"},
+		{"truncated bool", "{'descr': '|b1', 'fortran_order': False, 'shape': (70000,), }", make([]bool, 65537), "reading data"},
+	} {
+		_, err := calc.ReadNpy(bytes.NewReader(npyFile(c.header, c.data)))
+		if c.want == "" {
+			if err != nil {
+				t.Errorf("%s: %v", c.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: got %v, want an error containing %q", c.name, err, c.want)
+		}
+	}
+}
+
+// Files laid out the way NumPy writes them, including dtypes WriteNpy doesn't produce
+func TestNpyNumPyFiles(t *testing.T) {
+	cases := []struct {
+		name string
+		file []byte
+		want calc.NDArray
+	}{
+		{
+			"fortran order",
+			// numpy.save of numpy.asfortranarray(numpy.arange(6.).reshape(2, 3))
+			npyFile("{'descr': '<f8', 'fortran_order': True, 'shape': (2, 3), }", []float64{0, 3, 1, 4, 2, 5}),
+			calc.FromRaw([]int{2, 3}, []float64{0, 1, 2, 3, 4, 5}),
+		},
+		{
+			"fortran order rank 3",
+			npyFile("{'descr': '<i8', 'fortran_order': True, 'shape': (2, 2, 2), }", []int64{0, 4, 2, 6, 1, 5, 3, 7}),
+			calc.FromRawInt64([]int{2, 2, 2}, []int64{0, 1, 2, 3, 4, 5, 6, 7}),
+		},
+		{
+			"u1",
+			npyFile("{'descr': '|u1', 'fortran_order': False, 'shape': (4,), }", []uint8{0, 1, 128, 255}),
+			calc.FromRawInt64([]int{4}, []int64{0, 1, 128, 255}),
+		},
+		{
+			"i4",
+			npyFile("{'descr': '<i4', 'fortran_order': False, 'shape': (3,), }", []int32{-7, 0, 1 << 30}),
+			calc.FromRawInt64([]int{3}, []int64{-7, 0, 1 << 30}),
+		},
+		{
+			"b1",
+			npyFile("{'descr': '|b1', 'fortran_order': False, 'shape': (1, 3), }", []bool{true, false, true}),
+			calc.FromRawBool([]int{1, 3}, []bool{true, false, true}),
+		},
+		{
+			"f4",
+			npyFile("{'descr': '<f4', 'fortran_order': False, 'shape': (2,), }", []float32{1.5, -0.25}),
+			calc.FromRaw32([]int{2}, []float32{1.5, -0.25}),
+		},
+		{
+			"scalar",
+			npyFile("{'descr': '<f8', 'fortran_order': False, 'shape': (), }", []float64{3}),
+			calc.FromRaw([]int{}, []float64{3}),
+		},
+		{
+			// numpy 2 leaves room after the shape for it to grow
+			"growth padding",
+			npyFile("{'descr': '<f8', 'fortran_order': False, 'shape': (2,), }"+strings.Repeat(" ", 20), []float64{1, 2}),
+			calc.FromRaw([]int{2}, []float64{1, 2}),
+		},
+	}
+	for _, c := range cases {
+		got, err := calc.ReadNpy(bytes.NewReader(c.file))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		checkSame(t, c.name, got, c.want)
+	}
+
+	// big endian data, which numpy writes for arrays with a big endian dtype
+	var buf bytes.Buffer
+	header := "{'descr': '>f8', 'fortran_order': False, 'shape': (2,), }"
+	header += strings.Repeat(" ", 63-(10+len(header))%64) + "\n"
+	buf.WriteString("\x93NUMPY\x01\x00")
+	binary.Write(&buf, binary.LittleEndian, uint16(len(header)))
+	buf.WriteString(header)
+	binary.Write(&buf, binary.BigEndian, []float64{1.25, -3})
+	got, err := calc.ReadNpy(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSame(t, "big endian", got, calc.FromRaw([]int{2}, []float64{1.25, -3}))
+
+	for _, bad := range []string{"not a npy file", "\x93NUMPY\x09\x00"} {
+		if _, err := calc.ReadNpy(strings.NewReader(bad)); err == nil {
+			t.Errorf("reading %q didn't fail", bad)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package calc
+
+import "fmt"
+
+type PadMode int
+
+const (
+	// fills with a value
+	PadConstant PadMode = iota
+	// mirrors without repeating the edge, so 1 2 3 pads to 3 2 1 2 3 2 1
+	PadReflect
+	// mirrors repeating the edge, so 1 2 3 pads to 2 1 1 2 3 3 2
+	PadSymmetric
+	// repeats the edge, so 1 2 3 pads to 1 1 1 2 3 3 3
+	PadEdge
+)
+
+func (m PadMode) String() string {
+	switch m {
+	case PadConstant:
+		return "constant"
+	case PadReflect:
+		return "reflect"
+	case PadSymmetric:
+		return "symmetric"
+	case PadEdge:
+		return "edge"
+	default:
+		return fmt.Sprintf("PadMode(%d)", int(m))
+	}
+}
+
+// The shape of an array padded by widths, which has a {before, after} pair for each axis
+func CheckPad(shape []int, widths [][2]int, mode PadMode) ([]int, error) {
+	if len(widths) != len(shape) {
+		return nil, &ShapeError{Op: "Pad", Axis: -1, Actual: shape, Reason: fmt.Sprintf("%d pad widths for rank %d", len(widths), len(shape))}
+	}
+	if mode < PadConstant || mode > PadEdge {
+		return nil, &ShapeError{Op: "Pad", Axis: -1, Reason: fmt.Sprintf("unknown mode %s", mode)}
+	}
+	out := make([]int, len(shape))
+	for i, w := range widths {
+		if w[0] < 0 || w[1] < 0 {
+			return nil, &ShapeError{Op: "Pad", Axis: i, Reason: fmt.Sprintf("negative pad width %v", w)}
+		}
+		if mode != PadConstant && shape[i] == 0 && w[0]+w[1] > 0 {
+			return nil, &ShapeError{Op: "Pad", Axis: i, Actual: shape, Reason: fmt.Sprintf("can't %s pad an empty axis", mode)}
+		}
+		out[i] = shape[i] + w[0] + w[1]
+	}
+	return out, nil
+}
+
+// For each index of an axis of size n padded by w, the index of the input it reads, or -1 for the
+// constant
+func padSource(n int, w [2]int, mode PadMode) []int {
+	src := make([]int, n+w[0]+w[1])
+	for o := range src {
+		i := o - w[0]
+		switch {
+		case i >= 0 && i < n:
+			src[o] = i
+		case mode == PadConstant:
+			src[o] = -1
+		case mode == PadEdge:
+			src[o] = min(max(i, 0), n-1)
+		case mode == PadReflect && n == 1:
+			src[o] = 0
+		case mode == PadReflect:
+			// mirror images repeat every 2(n-1)
+			period := 2 * (n - 1)
+			j := (i%period + period) % period
+			if j >= n {
+				j = period - j
+			}
+			src[o] = j
+		default:
+			period := 2 * n
+			j := (i%period + period) % period
+			if j >= n {
+				j = period - 1 - j
+			}
+			src[o] = j
+		}
+	}
+	return src
+}
+
+// For every element of out, the padded array, in order, calls f with its storage index and the storage
+// index of the element of in it's copied from, or -1 where the constant goes
+func walkPad(in layout, out layout, widths [][2]int, mode PadMode, f func(outIndex int, inIndex int)) {
+	inSteps, outSteps := in.steps(in.shape), out.steps(out.shape)
+	sources := make([][]int, len(in.shape))
+	for ax := range in.shape {
+		sources[ax] = padSource(in.shape[ax], widths[ax], mode)
+	}
+
+	outShape := out.shape
+	size := shapeSize(outShape)
+	index := make([]int, len(outShape))
+	for n := 0; n < size; n++ {
+		o, i, constant := out.offset, in.offset, false
+		for ax, idx := range index {
+			o += outSteps[ax][idx]
+			if src := sources[ax][idx]; src < 0 {
+				constant = true
+			} else {
+				i += inSteps[ax][src]
+			}
+		}
+		if constant {
+			i = -1
+		}
+		f(o, i)
+
+		for ax := len(index) - 1; ax >= 0; ax-- {
+			index[ax]++
+			if index[ax] < outShape[ax] {
+				break
+			}
+			index[ax] = 0
+		}
+	}
+}
+
+// Pads each axis by its {before, after} pair of widths, filling with value in PadConstant mode
+func (a NDArray) Pad(widths [][2]int, mode PadMode, value float64) NDArray {
+	arr := ZerosOf(a.dtype, Must(CheckPad(a.shape, widths, mode))...)
+	return a.PadInto(widths, mode, value, arr)
+}
+
+func (a NDArray) PadInto(widths [][2]int, mode PadMode, value float64, arr NDArray) NDArray {
+	requirePacked(arr)
+	outShape := Must(CheckPad(a.shape, widths, mode))
+	if !ShapeEqual(outShape, arr.shape) {
+		panic(&ShapeError{Op: "Pad", Axis: -1, Expected: outShape, Actual: arr.shape, Reason: "wrong output shape"})
+	}
+	src := a.AsType(arr.dtype)
+	cp := elemCopier(arr, src)
+	walkPad(src.layout(), arr.layout(), widths, mode, func(outIndex int, inIndex int) {
+		if inIndex < 0 {
+			arr.setAt(outIndex, value)
+		} else {
+			cp(outIndex, inIndex)
+		}
+	})
+	return arr
+}
+
+// The adjoint of Pad, summing each element of a padded array into the element of shape it was copied
+// from. Constant padding is dropped.
+func (a NDArray) Unpad(shape []int, widths [][2]int, mode PadMode) NDArray {
+	arr := ZerosOf(a.dtype, shape...)
+	return a.UnpadInto(widths, mode, arr)
+}
+
+func (a NDArray) UnpadInto(widths [][2]int, mode PadMode, arr NDArray) NDArray {
+	requirePacked(arr)
+	padded := Must(CheckPad(arr.shape, widths, mode))
+	if !ShapeEqual(padded, a.shape) {
+		panic(&ShapeError{Op: "Unpad", Axis: -1, Expected: padded, Actual: a.shape, Reason: "input isn't the padded shape of the output"})
+	}
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.UnpadInto(widths, mode, arr) })
+	}
+	arr.Fill(0)
+	walkPad(arr.layout(), a.layout(), widths, mode, func(outIndex int, inIndex int) {
+		if inIndex >= 0 {
+			arr.data[inIndex] += a.data[outIndex]
+		}
+	})
+	return arr
+}
@@ -0,0 +1,62 @@
+package calc_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+func TestPadModes(t *testing.T) {
+	a := calc.FromRaw([]int{3}, []float64{1, 2, 3})
+	for _, c := range []struct {
+		mode   calc.PadMode
+		widths [2]int
+		want   []float64
+	}{
+		{calc.PadConstant, [2]int{2, 1}, []float64{9, 9, 1, 2, 3, 9}},
+		{calc.PadReflect, [2]int{2, 2}, []float64{3, 2, 1, 2, 3, 2, 1}},
+		{calc.PadSymmetric, [2]int{2, 2}, []float64{2, 1, 1, 2, 3, 3, 2}},
+		{calc.PadEdge, [2]int{2, 2}, []float64{1, 1, 1, 2, 3, 3, 3}},
+		// wider than the axis, repeating the mirror images
+		{calc.PadReflect, [2]int{5, 4}, []float64{2, 1, 2, 3, 2, 1, 2, 3, 2, 1, 2, 3}},
+		{calc.PadSymmetric, [2]int{5, 4}, []float64{2, 3, 3, 2, 1, 1, 2, 3, 3, 2, 1, 1}},
+		{calc.PadEdge, [2]int{5, 4}, []float64{1, 1, 1, 1, 1, 1, 2, 3, 3, 3, 3, 3}},
+		{calc.PadReflect, [2]int{0, 0}, []float64{1, 2, 3}},
+	} {
+		got := a.Pad([][2]int{c.widths}, c.mode, 9)
+		checkSame(t, c.mode.String(), got, calc.FromRaw([]int{len(c.want)}, c.want))
+	}
+	checkSame(t, "reflect of one element", calc.FromRaw([]int{1}, []float64{7}).Pad([][2]int{{2, 1}}, calc.PadReflect, 0), calc.FromRaw([]int{4}, []float64{7, 7, 7, 7}))
+	checkSame(t, "int64 constant", calc.FromRawInt64([]int{2}, []int64{1<<53 + 1, 2}).Pad([][2]int{{1, 0}}, calc.PadConstant, -1), calc.FromRawInt64([]int{3}, []int64{-1, 1<<53 + 1, 2}))
+}
+
+func TestPad(t *testing.T) {
+	rng := calc.NewRNG(44)
+	a := rng.Normal(0, 1, 3, 4, 2)
+	widths := [][2]int{{1, 2}, {0, 3}, {2, 2}}
+	for _, mode := range []calc.PadMode{calc.PadConstant, calc.PadReflect, calc.PadSymmetric, calc.PadEdge} {
+		// padding every axis at once is padding them one at a time
+		want := a
+		for ax := range widths {
+			axisWidths := make([][2]int, len(widths))
+			axisWidths[ax] = widths[ax]
+			want = want.Pad(axisWidths, mode, 0.5)
+		}
+		padded := a.Pad(widths, mode, 0.5)
+		checkSame(t, mode.String(), padded, want)
+		checkSame(t, mode.String()+" of a transposed view", a.Permute(2, 1, 0).Pad([][2]int{widths[2], widths[1], widths[0]}, mode, 0.5), want.Permute(2, 1, 0))
+
+		// Unpad is the adjoint: <Pad(a), g> = <a, Unpad(g)>, leaving out the constant
+		g := rng.Normal(0, 1, padded.Shape()...)
+		lhs := a.Pad(widths, mode, 0).Mul(g).Sum(0, 1, 2).Get([]int{0, 0, 0})
+		rhs := a.Mul(g.Unpad(a.Shape(), widths, mode)).Sum(0, 1, 2).Get([]int{0, 0, 0})
+		if math.Abs(lhs-rhs) > 1e-9 {
+			t.Errorf("%s: <Pad(a), g> = %v, <a, Unpad(g)> = %v", mode, lhs, rhs)
+		}
+	}
+
+	if _, err := calc.CheckPad([]int{0}, [][2]int{{1, 0}}, calc.PadConstant); err != nil {
+		t.Errorf("CheckPad of an empty axis in constant mode: %v", err)
+	}
+}
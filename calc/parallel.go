@@ -0,0 +1,214 @@
+package calc
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Element-wise, broadcast, slice and reduction kernels are split across a pool of worker goroutines.
+// Tasks that don't find an idle worker run on the calling goroutine, so kernels can nest without
+// deadlocking and a busy pool degrades to running serially.
+
+// Kernels smaller than this many elements aren't split
+const minGrain = 4096
+
+// The most chunks a deterministic reduction over the leading axis is split into
+const reduceBlocks = 16
+
+type workerPool struct {
+	size  int
+	tasks chan func()
+}
+
+func newWorkerPool(size int) *workerPool {
+	p := &workerPool{
+		size:  size,
+		tasks: make(chan func()),
+	}
+	// the calling goroutine does a share of the work too
+	for i := 1; i < size; i++ {
+		go func() {
+			for task := range p.tasks {
+				task()
+			}
+		}()
+	}
+	return p
+}
+
+var pool atomic.Pointer[workerPool]
+
+var deterministic atomic.Bool
+
+func init() {
+	pool.Store(newWorkerPool(runtime.GOMAXPROCS(0)))
+}
+
+// Sets the number of goroutines kernels are split across, including the caller. Defaults to GOMAXPROCS.
+// Must not be called while kernels are running.
+func SetWorkers(n int) {
+	if n < 1 {
+		n = 1
+	}
+	old := pool.Swap(newWorkerPool(n))
+	close(old.tasks)
+}
+
+func Workers() int {
+	return pool.Load().size
+}
+
+// When on, reductions over the leading axis are split into a fixed number of chunks that depend only on
+// the shape of the input, so results are bit-for-bit reproducible regardless of the number of workers.
+// When off (the default) they're split into one chunk per worker, which rounds differently as the worker
+// count changes.
+func SetDeterministicReductions(on bool) {
+	deterministic.Store(on)
+}
+
+// Runs f(0) ... f(n-1) across the pool and waits for them all
+func parallelTasks(n int, f func(i int)) {
+	if n == 1 {
+		f(0)
+		return
+	}
+	p := pool.Load()
+	var wg sync.WaitGroup
+	wg.Add(n - 1)
+	for i := 1; i < n; i++ {
+		i := i
+		task := func() {
+			defer wg.Done()
+			f(i)
+		}
+		select {
+		case p.tasks <- task:
+		default:
+			task()
+		}
+	}
+	f(0)
+	wg.Wait()
+}
+
+// splits [0, n) into at most one range per worker, with at least grain items in each
+func splitRange(n int, grain int, f func(start int, end int)) {
+	parts := Workers()
+	if grain < 1 {
+		grain = 1
+	}
+	if maxParts := n / grain; maxParts < parts {
+		parts = maxParts
+	}
+	if parts <= 1 {
+		f(0, n)
+		return
+	}
+	parallelTasks(parts, func(i int) {
+		f(i*n/parts, (i+1)*n/parts)
+	})
+}
+
+// Runs f over ranges of [0, n) across the pool
+func parallelFor(n int, f func(start int, end int)) {
+	splitRange(n, minGrain, f)
+}
+
+// Runs f over ranges of the leading axis of shape across the pool
+func parallelRows(shape []int, f func(start int, end int)) {
+	if len(shape) == 0 {
+		f(0, 1)
+		return
+	}
+	splitRange(shape[0], rowGrain(shape), f)
+}
+
+// the number of leading axis rows that make up minGrain elements
+func rowGrain(shape []int) int {
+	rowSize := shapeSize(shape[1:])
+	if rowSize == 0 {
+		return shape[0] + 1
+	}
+	return (minGrain + rowSize - 1) / rowSize
+}
+
+// Reduces an array laid out as in into out, which has the AggrShape outShape and is already filled with the
+// identity of the reduction. acc folds input inIndex into o[outIndex] and merge combines two partial
+// results, and inIndex is a storage index. If the leading axis isn't reduced, rows are reduced into disjoint outputs in parallel.
+// Otherwise rows are split into chunks that each reduce into their own copy of out, and the copies are
+// merged in order.
+func reduceInto(in layout, outShape []int, out []float64, acc func(o []float64, inIndex int, outIndex int), merge func(x float64, y float64) float64) {
+	inShape := in.shape
+	if len(inShape) == 0 {
+		acc(out, in.offset, 0)
+		return
+	}
+	if outShape[0] == inShape[0] {
+		parallelRows(inShape, func(start int, end int) {
+			walkAggrRows(in, outShape, start, end, func(inIndex int, outIndex int) {
+				acc(out, inIndex, outIndex)
+			})
+		})
+		return
+	}
+
+	n := inShape[0]
+	chunks := reduceChunks(n, rowGrain(inShape))
+	if chunks <= 1 {
+		walkAggrRows(in, outShape, 0, n, func(inIndex int, outIndex int) {
+			acc(out, inIndex, outIndex)
+		})
+		return
+	}
+
+	partials := make([][]float64, chunks)
+	parallelTasks(chunks, func(i int) {
+		p := append([]float64{}, out...)
+		walkAggrRows(in, outShape, i*n/chunks, (i+1)*n/chunks, func(inIndex int, outIndex int) {
+			acc(p, inIndex, outIndex)
+		})
+		partials[i] = p
+	})
+	for _, p := range partials {
+		for i, v := range p {
+			out[i] = merge(out[i], v)
+		}
+	}
+}
+
+// The number of chunks to split a reduction over n items into, each with at least grain items unless
+// there's only one. With deterministic reductions this is at most reduceBlocks and only depends on n and
+// grain, so the chunks and the order they're merged in don't change with the worker count.
+func reduceChunks(n int, grain int) int {
+	if grain < 1 {
+		grain = 1
+	}
+	chunks := Workers()
+	if deterministic.Load() {
+		chunks = reduceBlocks
+	}
+	if maxChunks := n / grain; maxChunks < chunks {
+		chunks = maxChunks
+	}
+	return chunks
+}
+
+func sumMerge(x float64, y float64) float64 {
+	return x + y
+}
+
+// arr[i] = f(a[i]) across the pool, for a Float64 array a in index order
+func mapInto(a NDArray, arr []float64, f func(v float64) float64) {
+	if !a.packed() {
+		walkElems(a.layout(), func(aIndex int, i int) {
+			arr[i] = f(a.data[aIndex])
+		})
+		return
+	}
+	parallelFor(len(arr), func(start int, end int) {
+		for i := start; i < end; i++ {
+			arr[i] = f(a.data[i])
+		}
+	})
+}
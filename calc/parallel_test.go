@@ -0,0 +1,31 @@
+package calc_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// With deterministic reductions, reducing over the leading axis gives the same bits for any worker count
+func TestDeterministicReductions(t *testing.T) {
+	calc.SetDeterministicReductions(true)
+	defer calc.SetDeterministicReductions(false)
+	defer calc.SetWorkers(calc.Workers())
+
+	a := calc.NewRNG(1).Uniform(-1, 1, 20000, 3, 2)
+	reduce := func() []calc.NDArray {
+		return []calc.NDArray{a.Sum(0), a.Sum(0, 2), a.Sum(), a.Mean(0), a.Var(0), a.LogSumExp(0)}
+	}
+	calc.SetWorkers(1)
+	want := reduce()
+	for _, workers := range []int{2, 3, 8} {
+		calc.SetWorkers(workers)
+		for i, got := range reduce() {
+			want[i].ForEach(func(_ int, index []int, value float64) {
+				if g := got.Get(index); g != value {
+					t.Errorf("%d workers, reduction %d: %v = %v, want %v", workers, i, index, g, value)
+				}
+			})
+		}
+	}
+}
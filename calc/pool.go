@@ -0,0 +1,99 @@
+package calc
+
+import (
+	"math/bits"
+	"sync"
+)
+
+// A free list of array storage for reusing temporaries, bucketed by dtype and power of two capacity.
+// Safe for concurrent use.
+type Pool struct {
+	mu   sync.Mutex
+	free map[poolKey][]NDArray
+}
+
+type poolKey struct {
+	dtype DType
+	// storage in the bucket holds at least 1<<class elements
+	class int
+}
+
+func NewPool() *Pool {
+	return &Pool{
+		free: map[poolKey][]NDArray{},
+	}
+}
+
+var defaultPool = NewPool()
+
+// A zeroed array from the default pool. Release it once it's no longer needed so its storage is reused.
+func Alloc(dtype DType, shape ...int) NDArray {
+	return defaultPool.Zeros(dtype, shape...)
+}
+
+// Returns the storage of a to the default pool. Neither a nor any view of it may be used afterwards.
+// Releasing a view releases the storage of the array it views, from where the view starts.
+func (a NDArray) Release() {
+	defaultPool.Release(a)
+}
+
+// the smallest class holding n elements
+func poolClass(n int) int {
+	if n <= 1 {
+		return 0
+	}
+	return bits.Len(uint(n - 1))
+}
+
+// A zeroed array, reusing released storage if there is any large enough
+func (p *Pool) Zeros(dtype DType, shape ...int) NDArray {
+	size := shapeSize(shape)
+	key := poolKey{dtype, poolClass(size)}
+
+	p.mu.Lock()
+	free := p.free[key]
+	if len(free) == 0 {
+		p.mu.Unlock()
+		arr := ZerosOf(dtype, 1<<key.class).storageRange(0, size)
+		arr.shape = shape
+		return arr
+	}
+	arr := free[len(free)-1]
+	p.free[key] = free[:len(free)-1]
+	p.mu.Unlock()
+
+	arr = arr.storageRange(0, size)
+	arr.shape = shape
+	arr.Fill(0.)
+	return arr
+}
+
+// Returns the storage of a to the pool. Neither a nor any view of it may be used afterwards.
+func (p *Pool) Release(a NDArray) {
+	arr := a.storage().fullCapacity()
+	n := arr.size()
+	if n == 0 {
+		return
+	}
+	// round down so everything in a bucket is large enough for it
+	key := poolKey{a.dtype, bits.Len(uint(n)) - 1}
+
+	p.mu.Lock()
+	p.free[key] = append(p.free[key], arr)
+	p.mu.Unlock()
+}
+
+// a's storage extended to its capacity
+func (a NDArray) fullCapacity() NDArray {
+	switch a.dtype {
+	case Float32:
+		a.data32 = a.data32[:cap(a.data32)]
+	case Int64:
+		a.dataI64 = a.dataI64[:cap(a.dataI64)]
+	case Bool:
+		a.dataBool = a.dataBool[:cap(a.dataBool)]
+	default:
+		a.data = a.data[:cap(a.data)]
+	}
+	return a
+}
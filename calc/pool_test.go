@@ -0,0 +1,104 @@
+package calc_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+func TestPool(t *testing.T) {
+	p := calc.NewPool()
+	a := p.Zeros(calc.Float64, 3, 5)
+	a.Fill(7)
+	p.Release(a)
+
+	// anything up to the 16 elements a's storage holds reuses it, zeroed
+	b := p.Zeros(calc.Float64, 2, 6)
+	checkSame(t, "reused", b, calc.Zeros(2, 6))
+	b.Set([]int{0, 0}, 3)
+	if a.Get([]int{0, 0}) != 3 {
+		t.Error("Zeros after Release didn't reuse the released storage")
+	}
+
+	// other dtypes and larger sizes get new storage
+	p.Release(b)
+	c := p.Zeros(calc.Float32, 2, 6)
+	d := p.Zeros(calc.Float64, 17)
+	c.Set([]int{0, 0}, 5)
+	d.Set([]int{0}, 5)
+	if a.Get([]int{0, 0}) != 3 {
+		t.Error("Zeros of another dtype or a larger size reused the released storage")
+	}
+	checkSame(t, "float32", c, calc.FromRaw32([]int{2, 6}, []float32{5, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}))
+
+	// releasing a view returns the storage past its end too
+	p.Release(d.Slice(0, 0, 4))
+	e := p.Zeros(calc.Float64, 20)
+	e.Set([]int{0}, 9)
+	if d.Get([]int{0}) != 9 {
+		t.Error("Zeros after releasing a view didn't reuse the viewed array")
+	}
+}
+
+// Each Into variant must overwrite every element of arr, so it gives the same result into a dirty buffer
+func TestIntoOverwrites(t *testing.T) {
+	rng := calc.NewRNG(27)
+	a, b := rng.Normal(0, 1, 3, 4), rng.Normal(0, 1, 3, 4)
+	pos := a.Mul(a).Add(calc.Ones(1))
+	k := rng.Normal(0, 1, 2, 2, 4, 2)
+	img, imgFirst := rng.Normal(0, 1, 1, 3, 3, 4), rng.Normal(0, 1, 1, 4, 3, 3)
+	indices := calc.FromRawInt64([]int{3, 2}, []int64{0, 3, 1, 1, 2, 0})
+	cases := []struct {
+		name string
+		op   func() calc.NDArray
+		into func(arr calc.NDArray) calc.NDArray
+	}{
+		{"Add", func() calc.NDArray { return a.Add(b) }, func(arr calc.NDArray) calc.NDArray { return a.AddInto(b, arr) }},
+		{"Add broadcast", func() calc.NDArray { return a.Add(b.Slice(0, 0, 1)) }, func(arr calc.NDArray) calc.NDArray { return a.AddInto(b.Slice(0, 0, 1), arr) }},
+		{"Mul", func() calc.NDArray { return a.Mul(b) }, func(arr calc.NDArray) calc.NDArray { return a.MulInto(b, arr) }},
+		{"MulConstant", func() calc.NDArray { return a.MulConstant(3) }, func(arr calc.NDArray) calc.NDArray { return a.MulConstantInto(3, arr) }},
+		{"Sum", func() calc.NDArray { return a.Sum(1) }, func(arr calc.NDArray) calc.NDArray { return a.SumInto(arr, 1) }},
+		{"Mean", func() calc.NDArray { return a.Mean(0) }, func(arr calc.NDArray) calc.NDArray { return a.MeanInto(arr, 0) }},
+		{"Max", func() calc.NDArray { return a.Max(1) }, func(arr calc.NDArray) calc.NDArray { return a.MaxInto(arr, 1) }},
+		{"Var", func() calc.NDArray { return a.Var(0) }, func(arr calc.NDArray) calc.NDArray { return a.VarInto(arr, 0) }},
+		{"MatMul", func() calc.NDArray { return a.MatMul(b.Transpose(0, 1), 0, 1) }, func(arr calc.NDArray) calc.NDArray { return a.MatMulInto(b.Transpose(0, 1), 0, 1, arr) }},
+		{"Exp", func() calc.NDArray { return a.Exp() }, func(arr calc.NDArray) calc.NDArray { return a.ExpInto(arr) }},
+		{"Log", func() calc.NDArray { return pos.Log() }, func(arr calc.NDArray) calc.NDArray { return pos.LogInto(arr) }},
+		{"Sqrt", func() calc.NDArray { return pos.Sqrt() }, func(arr calc.NDArray) calc.NDArray { return pos.SqrtInto(arr) }},
+		{"ReLU", func() calc.NDArray { return a.ReLU() }, func(arr calc.NDArray) calc.NDArray { return a.ReLUInto(arr) }},
+		{"Clip", func() calc.NDArray { return a.Clip(-0.5, 0.5) }, func(arr calc.NDArray) calc.NDArray { return a.ClipInto(-0.5, 0.5, arr) }},
+		{"Slice", func() calc.NDArray { return a.Slice(1, 1, 3) }, func(arr calc.NDArray) calc.NDArray { return a.SliceInto(1, 1, 3, arr) }},
+		{"CumSum", func() calc.NDArray { return a.CumSum(1) }, func(arr calc.NDArray) calc.NDArray { return a.CumSumInto(1, arr) }},
+		{"Tile", func() calc.NDArray { return a.Tile(2, 1) }, func(arr calc.NDArray) calc.NDArray { return a.TileInto([]int{2, 1}, arr) }},
+		{"Gather", func() calc.NDArray { return a.Gather(indices, 1) }, func(arr calc.NDArray) calc.NDArray { return a.GatherInto(indices, 1, arr) }},
+		{"Conv2D", func() calc.NDArray { return img.Conv2D(k, 1, 2, 3, calc.ConvOpts{Padding: calc.PaddingSame}) }, func(arr calc.NDArray) calc.NDArray {
+			return img.Conv2DInto(k, 1, 2, 3, calc.ConvOpts{Padding: calc.PaddingSame}, arr)
+		}},
+		{"Conv2D filters first", func() calc.NDArray { return imgFirst.Conv2D(k, 2, 3, 1, calc.ConvOpts{}) }, func(arr calc.NDArray) calc.NDArray {
+			return imgFirst.Conv2DInto(k, 2, 3, 1, calc.ConvOpts{}, arr)
+		}},
+	}
+	for _, c := range cases {
+		want := c.op()
+		dirty := calc.Zeros(want.Shape()...)
+		dirty.Fill(math.NaN())
+		checkSame(t, c.name, c.into(dirty), want)
+	}
+
+	// in place, with the output being one of the inputs
+	inPlace := a.Contiguous().MulConstant(1)
+	inPlace.AddInto(b, inPlace)
+	checkSame(t, "AddInto in place", inPlace, a.Add(b))
+	inPlace.MulInto(inPlace, inPlace)
+	checkSame(t, "MulInto in place", inPlace, a.Add(b).Mul(a.Add(b)))
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("AddInto a transposed view didn't panic")
+			}
+		}()
+		a.AddInto(b, calc.Zeros(4, 3).Transpose(0, 1))
+	}()
+}
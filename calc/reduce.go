@@ -0,0 +1,152 @@
+package calc
+
+import "math"
+
+func (a NDArray) Min(axes ...int) NDArray {
+	arr := ZerosOf(a.dtype, AggrShape(a.shape, axes)...)
+	return a.MinInto(arr, axes...)
+}
+
+// arr must have the AggrShape of the axes, and is overwritten
+func (a NDArray) MinInto(arr NDArray, axes ...int) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.MinInto(arr, axes...) })
+	}
+	arr.Fill(math.Inf(1))
+	reduceInto(a.layout(), arr.shape, arr.data, func(o []float64, inIndex int, outIndex int) {
+		if a.data[inIndex] < o[outIndex] {
+			o[outIndex] = a.data[inIndex]
+		}
+	}, math.Min)
+
+	return arr
+}
+
+func (a NDArray) Prod(axes ...int) NDArray {
+	arr := ZerosOf(a.dtype, AggrShape(a.shape, axes)...)
+	return a.ProdInto(arr, axes...)
+}
+
+// arr must have the AggrShape of the axes, and is overwritten
+func (a NDArray) ProdInto(arr NDArray, axes ...int) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.ProdInto(arr, axes...) })
+	}
+	arr.Fill(1.)
+	reduceInto(a.layout(), arr.shape, arr.data, func(o []float64, inIndex int, outIndex int) {
+		o[outIndex] *= a.data[inIndex]
+	}, func(x float64, y float64) float64 { return x * y })
+
+	return arr
+}
+
+func (a NDArray) LogSumExp(axes ...int) NDArray {
+	arr := ZerosOf(a.dtype, AggrShape(a.shape, axes)...)
+	return a.LogSumExpInto(arr, axes...)
+}
+
+// log(sum(exp(a))) over the axes, shifted by the max so large values don't overflow
+func (a NDArray) LogSumExpInto(arr NDArray, axes ...int) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.LogSumExpInto(arr, axes...) })
+	}
+	shift := a.Max(axes...)
+	for i, m := range shift.data {
+		// all -Inf sums to 0 and any +Inf to +Inf without shifting
+		if math.IsInf(m, 0) {
+			shift.data[i] = 0.
+		}
+	}
+	arr.Fill(0.)
+	reduceInto(a.layout(), arr.shape, arr.data, func(o []float64, inIndex int, outIndex int) {
+		o[outIndex] += math.Exp(a.data[inIndex] - shift.data[outIndex])
+	}, sumMerge)
+	for i, v := range arr.data {
+		arr.data[i] = shift.data[i] + math.Log(v)
+	}
+
+	return arr
+}
+
+func (a NDArray) Var(axes ...int) NDArray {
+	arr := ZerosOf(a.dtype, AggrShape(a.shape, axes)...)
+	return a.VarInto(arr, axes...)
+}
+
+// The population variance over the axes
+func (a NDArray) VarInto(arr NDArray, axes ...int) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.VarInto(arr, axes...) })
+	}
+	div := 1
+	for _, ax := range axes {
+		div *= a.shape[ax]
+	}
+	mean := a.Mean(axes...)
+	arr.Fill(0.)
+	reduceInto(a.layout(), arr.shape, arr.data, func(o []float64, inIndex int, outIndex int) {
+		v := a.data[inIndex] - mean.data[outIndex]
+		o[outIndex] += v * v
+	}, sumMerge)
+	for i := range arr.data {
+		arr.data[i] /= float64(div)
+	}
+
+	return arr
+}
+
+func (a NDArray) Std(axes ...int) NDArray {
+	arr := ZerosOf(a.dtype, AggrShape(a.shape, axes)...)
+	return a.StdInto(arr, axes...)
+}
+
+// The population standard deviation over the axes
+func (a NDArray) StdInto(arr NDArray, axes ...int) NDArray {
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.StdInto(arr, axes...) })
+	}
+	a.VarInto(arr, axes...)
+	for i, v := range arr.data {
+		arr.data[i] = math.Sqrt(v)
+	}
+	return arr
+}
+
+func (a NDArray) CumSum(axis int) NDArray {
+	arr := ZerosOf(a.dtype, a.shape...)
+	return a.CumSumInto(axis, arr)
+}
+
+// Running sums along axis
+func (a NDArray) CumSumInto(axis int, arr NDArray) NDArray {
+	return a.scanInto(axis, arr, 0., func(acc float64, v float64) float64 { return acc + v })
+}
+
+func (a NDArray) CumProd(axis int) NDArray {
+	arr := ZerosOf(a.dtype, a.shape...)
+	return a.CumProdInto(axis, arr)
+}
+
+// Running products along axis
+func (a NDArray) CumProdInto(axis int, arr NDArray) NDArray {
+	return a.scanInto(axis, arr, 1., func(acc float64, v float64) float64 { return acc * v })
+}
+
+func (a NDArray) scanInto(axis int, arr NDArray, identity float64, f func(acc float64, v float64) float64) NDArray {
+	requirePacked(arr)
+	if !both64(a, arr) {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.scanInto(axis, arr, identity, f) })
+	}
+	walkLanes(axis, func(starts []int, steps [][]int) {
+		acc := identity
+		for i := 0; i < a.shape[axis]; i++ {
+			acc = f(acc, a.data[starts[0]+steps[0][i]])
+			arr.data[starts[1]+steps[1][i]] = acc
+		}
+	}, a.layout(), arr.layout())
+	return arr
+}
@@ -0,0 +1,84 @@
+package calc_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// f folded over the axes of a, starting from init, with the axes kept at size 1
+func naiveReduce(a calc.NDArray, axes []int, init float64, f func(acc float64, v float64) float64) calc.NDArray {
+	arr := calc.Zeros(calc.AggrShape(a.Shape(), axes)...)
+	arr.Fill(init)
+	a.ForEach(func(_ int, index []int, v float64) {
+		out := append([]int{}, index...)
+		for _, ax := range axes {
+			out[ax] = 0
+		}
+		arr.Set(out, f(arr.Get(out), v))
+	})
+	return arr
+}
+
+// f run along axis of a, starting from init
+func naiveScan(a calc.NDArray, axis int, init float64, f func(acc float64, v float64) float64) calc.NDArray {
+	arr := calc.Zeros(a.Shape()...)
+	a.ForEach(func(_ int, index []int, v float64) {
+		acc := init
+		prev := append([]int{}, index...)
+		for i := 0; i <= index[axis]; i++ {
+			prev[axis] = i
+			acc = f(acc, a.Get(prev))
+		}
+		arr.Set(index, acc)
+	})
+	return arr
+}
+
+func TestReductions(t *testing.T) {
+	rng := calc.NewRNG(32)
+	a := rng.Normal(0, 1, 3, 4, 5)
+	add := func(acc float64, v float64) float64 { return acc + v }
+	mul := func(acc float64, v float64) float64 { return acc * v }
+	for _, v := range []calc.NDArray{a, a.Permute(2, 0, 1)} {
+		for _, axes := range [][]int{{0}, {2}, {0, 2}, {0, 1, 2}} {
+			n := 1.
+			for _, ax := range axes {
+				n *= float64(v.Shape()[ax])
+			}
+			mean := naiveReduce(v, axes, 0, add).MulConstant(1 / n)
+			sq := naiveReduce(v.Add(v.Mean(axes...).MulConstant(-1)), axes, 0, func(acc float64, x float64) float64 { return acc + x*x }).MulConstant(1 / n)
+
+			checkSame(t, "Min", v.Min(axes...), naiveReduce(v, axes, math.Inf(1), math.Min))
+			checkClose(t, "Prod", v.Prod(axes...), naiveReduce(v, axes, 1, mul))
+			checkClose(t, "LogSumExp", v.LogSumExp(axes...), naiveReduce(v.Exp(), axes, 0, add).Log())
+			checkClose(t, "Var", v.Var(axes...), sq)
+			checkClose(t, "Std", v.Std(axes...), sq.Sqrt())
+			checkClose(t, "Mean", v.Mean(axes...), mean)
+		}
+		for axis := 0; axis < 3; axis++ {
+			checkClose(t, "CumSum", v.CumSum(axis), naiveScan(v, axis, 0, add))
+			checkClose(t, "CumProd", v.CumProd(axis), naiveScan(v, axis, 1, mul))
+		}
+	}
+
+	checkClose32(t, "float32 Var", a.AsType(calc.Float32).Var(1), a.Var(1))
+	checkClose32(t, "float32 CumSum", a.AsType(calc.Float32).CumSum(2), a.CumSum(2))
+	checkSame(t, "int64 Min", calc.FromRawInt64([]int{2, 2}, []int64{3, -4, 2, 7}).Min(1), calc.FromRawInt64([]int{2, 1}, []int64{-4, 2}))
+	checkSame(t, "int64 Prod", calc.FromRawInt64([]int{2, 2}, []int64{3, -4, 2, 7}).Prod(0), calc.FromRawInt64([]int{1, 2}, []int64{6, -28}))
+}
+
+func TestLogSumExpLimits(t *testing.T) {
+	inf := math.Inf(1)
+	a := calc.FromRaw([]int{4, 2}, []float64{
+		1000, 1000,
+		-1000, -1001,
+		-inf, -inf,
+		inf, 3,
+	})
+	got := a.LogSumExp(1)
+	checkClose(t, "LogSumExp", got.Slice(0, 0, 2), calc.FromRaw([]int{2, 1}, []float64{1000 + math.Ln2, -1000 + math.Log1p(math.Exp(-1))}))
+	// exact, since checkClose lets NaN through
+	checkSame(t, "LogSumExp of infinities", got.Slice(0, 2, 4), calc.FromRaw([]int{2, 1}, []float64{-inf, inf}))
+}
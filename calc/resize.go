@@ -0,0 +1,196 @@
+package calc
+
+import "fmt"
+
+// Resizing is separable, so each spatial axis is resampled in turn. Output pixel o of an axis resized from
+// in to out samples the input at (o + 0.5) * in / out - 0.5, so pixel centers line up like TensorFlow's
+// half_pixel_centers and PyTorch's align_corners=False.
+
+type ResizeMethod int
+
+const (
+	// copies the input pixel whose center is nearest, which repeats each pixel when upsampling by an
+	// integer factor
+	ResizeNearest ResizeMethod = iota
+	// interpolates linearly between the two nearest input pixels along each axis, clamping at the edges
+	ResizeBilinear
+)
+
+func (m ResizeMethod) String() string {
+	switch m {
+	case ResizeNearest:
+		return "nearest"
+	case ResizeBilinear:
+		return "bilinear"
+	default:
+		return fmt.Sprintf("ResizeMethod(%d)", int(m))
+	}
+}
+
+// The shape of an array resized to outH x outW along hAxis and wAxis
+func CheckResize2D(shape []int, hAxis int, wAxis int, outH int, outW int) ([]int, error) {
+	return checkResize("Resize2D", shape, []int{hAxis, wAxis}, []int{outH, outW})
+}
+
+func checkResize(op string, shape []int, axes []int, sizes []int) ([]int, error) {
+	if err := CheckAxes(op, shape, axes...); err != nil {
+		return nil, err
+	}
+	out := append([]int{}, shape...)
+	for i, ax := range axes {
+		if sizes[i] < 0 {
+			return nil, &ShapeError{Op: op, Axis: ax, Actual: shape, Reason: fmt.Sprintf("negative size %d", sizes[i])}
+		}
+		if shape[ax] == 0 && sizes[i] > 0 {
+			return nil, &ShapeError{Op: op, Axis: ax, Actual: shape, Reason: "can't resize an empty axis"}
+		}
+		out[ax] = sizes[i]
+	}
+	return out, nil
+}
+
+// output pixel o of a resized axis is (1 - w) * input[i0] + w * input[i1]
+type resizeTap struct {
+	i0, i1 int
+	w      float64
+}
+
+func resizeTaps(in int, out int, method ResizeMethod) []resizeTap {
+	taps := make([]resizeTap, out)
+	for o := range taps {
+		switch method {
+		case ResizeNearest:
+			// floor((o + 0.5) * in / out) in integers, so integer factors are exact
+			i := min((2*o+1)*in/(2*out), in-1)
+			taps[o] = resizeTap{i, i, 0}
+		case ResizeBilinear:
+			src := max((float64(o)+0.5)*float64(in)/float64(out)-0.5, 0)
+			i := min(int(src), in-1)
+			taps[o] = resizeTap{i, min(i+1, in-1), src - float64(i)}
+		default:
+			panic(fmt.Sprintf("unknown resize method %s", method))
+		}
+	}
+	return taps
+}
+
+// Resamples a to outH x outW along hAxis and wAxis
+func (a NDArray) Resize2D(hAxis int, wAxis int, outH int, outW int, method ResizeMethod) NDArray {
+	dtype := a.dtype
+	if !dtype.IsFloat() {
+		dtype = Float64
+	}
+	arr := ZerosOf(dtype, Must(CheckResize2D(a.shape, hAxis, wAxis, outH, outW))...)
+	return a.Resize2DInto(hAxis, wAxis, method, arr)
+}
+
+// output size is taken from arr
+func (a NDArray) Resize2DInto(hAxis int, wAxis int, method ResizeMethod, arr NDArray) NDArray {
+	return a.resizeInto([]int{hAxis, wAxis}, method, false, arr)
+}
+
+// The adjoint of Resize2D, the gradient of its input: each pixel of a, which has the resized shape, is
+// spread over the input pixels it was sampled from by the same weights. The result is inH x inW.
+func (a NDArray) Resize2DTranspose(hAxis int, wAxis int, inH int, inW int, method ResizeMethod) NDArray {
+	dtype := a.dtype
+	if !dtype.IsFloat() {
+		dtype = Float64
+	}
+	arr := ZerosOf(dtype, Must(checkResize("Resize2DTranspose", a.shape, []int{hAxis, wAxis}, []int{inH, inW}))...)
+	return a.Resize2DTransposeInto(hAxis, wAxis, method, arr)
+}
+
+// input size is taken from arr
+func (a NDArray) Resize2DTransposeInto(hAxis int, wAxis int, method ResizeMethod, arr NDArray) NDArray {
+	return a.resizeInto([]int{hAxis, wAxis}, method, true, arr)
+}
+
+// Resamples a along each axis to the size of arr, or with transpose, accumulates a resized array back into
+// an arr of the original size
+func (a NDArray) resizeInto(axes []int, method ResizeMethod, transpose bool, arr NDArray) NDArray {
+	requirePacked(arr)
+	op := "Resize"
+	if transpose {
+		op = "ResizeTranspose"
+	}
+	sizes := make([]int, len(axes))
+	for i, ax := range axes {
+		if ax >= 0 && ax < len(arr.shape) {
+			sizes[i] = arr.shape[ax]
+		}
+	}
+	outShape := Must(checkResize(op, a.shape, axes, sizes))
+	if !ShapeEqual(outShape, arr.shape) {
+		panic(&ShapeError{Op: op, Axis: -1, Expected: outShape, Actual: arr.shape, Reason: "only the resized axes may differ"})
+	}
+	if !arr.dtype.IsFloat() {
+		return a.via64Into(arr, func(a NDArray, arr NDArray) NDArray { return a.resizeInto(axes, method, transpose, arr) })
+	}
+
+	cur := a.AsType(arr.dtype)
+	for i, ax := range axes {
+		if cur.shape[ax] == arr.shape[ax] && i < len(axes)-1 {
+			// equal sizes sample every pixel at its own center
+			continue
+		}
+		next := arr
+		if i < len(axes)-1 {
+			next = ZerosOf(arr.dtype, append(append(append([]int{}, cur.shape[:ax]...), arr.shape[ax]), cur.shape[ax+1:]...)...)
+		}
+		l := cur.layout()
+		src := resampleSource{l.offset, l.offsets(0, ax), l.offsets(ax, ax+1), l.offsets(ax+1, len(l.shape))}
+		in, out := cur.shape[ax], next.shape[ax]
+		var taps []resizeTap
+		if transpose {
+			taps = resizeTaps(out, in, method)
+		} else {
+			taps = resizeTaps(in, out, method)
+		}
+		if arr.dtype == Float32 {
+			resample(cur.data32, next.data32, src, out, taps, transpose)
+		} else {
+			resample(cur.data, next.data, src, out, taps, transpose)
+		}
+		cur = next
+	}
+	return arr
+}
+
+// Where the pixels of an outer x in x inner view being resampled along its middle axis are in storage: the
+// pixel (o, i, k) is at offset+outer[o]+axis[i]+inner[k]
+type resampleSource struct {
+	offset             int
+	outer, axis, inner []int
+}
+
+// Resamples the middle axis of src into dst, a packed outer x out x inner array. Transposed, taps index
+// dst and each src pixel is added to the dst pixels it would be sampled from.
+func resample[T float](src []T, dst []T, s resampleSource, out int, taps []resizeTap, transpose bool) {
+	in, inner := len(s.axis), len(s.inner)
+	grain := minGrain/(max(in, out, 1)*max(inner, 1)) + 1
+	splitRange(len(s.outer), grain, func(start int, end int) {
+		for o := start; o < end; o++ {
+			base, d := s.offset+s.outer[o], dst[o*out*inner:(o+1)*out*inner]
+			if transpose {
+				clear(d)
+				for j, t := range taps {
+					w0, w1 := T(1-t.w), T(t.w)
+					row, d0, d1 := base+s.axis[j], d[t.i0*inner:(t.i0+1)*inner], d[t.i1*inner:(t.i1+1)*inner]
+					for k, off := range s.inner {
+						v := src[row+off]
+						d0[k] += w0 * v
+						d1[k] += w1 * v
+					}
+				}
+				continue
+			}
+			for j, t := range taps {
+				w0, w1 := T(1-t.w), T(t.w)
+				row, s0, s1 := d[j*inner:(j+1)*inner], base+s.axis[t.i0], base+s.axis[t.i1]
+				for k, off := range s.inner {
+					row[k] = w0*src[s0+off] + w1*src[s1+off]
+				}
+			}
+		}
+	})
+}
@@ -0,0 +1,74 @@
+package calc_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// the weights of the input pixels output pixel o samples on an axis resized from in to out
+func naiveResizeWeights(in int, out int, o int, method calc.ResizeMethod) map[int]float64 {
+	src := (float64(o)+0.5)*float64(in)/float64(out) - 0.5
+	if method == calc.ResizeNearest {
+		return map[int]float64{min(int(math.Floor(src+0.5)), in-1): 1}
+	}
+	src = math.Min(math.Max(src, 0), float64(in-1))
+	i := int(math.Floor(src))
+	w := map[int]float64{i: 1 - (src - float64(i))}
+	if i+1 < in {
+		w[i+1] += src - float64(i)
+	}
+	return w
+}
+
+func naiveResize2D(a calc.NDArray, hAxis int, wAxis int, outH int, outW int, method calc.ResizeMethod) calc.NDArray {
+	shape := append([]int{}, a.Shape()...)
+	inH, inW := shape[hAxis], shape[wAxis]
+	shape[hAxis], shape[wAxis] = outH, outW
+	arr := calc.Zeros(shape...)
+	arr.ForEach(func(_ int, index []int, _ float64) {
+		in := append([]int{}, index...)
+		sum := 0.
+		for h, wh := range naiveResizeWeights(inH, outH, index[hAxis], method) {
+			for w, ww := range naiveResizeWeights(inW, outW, index[wAxis], method) {
+				in[hAxis], in[wAxis] = h, w
+				sum += wh * ww * a.Get(in)
+			}
+		}
+		arr.Set(index, sum)
+	})
+	return arr
+}
+
+func TestResize2D(t *testing.T) {
+	rng := calc.NewRNG(46)
+	a := rng.Normal(0, 1, 2, 4, 5, 3)
+	for _, method := range []calc.ResizeMethod{calc.ResizeNearest, calc.ResizeBilinear} {
+		for _, size := range [][2]int{{8, 10}, {2, 3}, {7, 2}, {4, 5}, {1, 1}} {
+			want := naiveResize2D(a, 1, 2, size[0], size[1], method)
+			checkClose(t, method.String(), a.Resize2D(1, 2, size[0], size[1], method), want)
+			// channels first
+			first := a.Permute(0, 3, 1, 2)
+			checkClose(t, method.String()+" channels first", first.Resize2D(2, 3, size[0], size[1], method), want.Permute(0, 3, 1, 2))
+
+			// the transpose is the adjoint: <Resize(a), g> = <a, Resize2DTranspose(g)>
+			g := rng.Normal(0, 1, want.Shape()...)
+			lhs := want.Mul(g).Sum(0, 1, 2, 3).Get([]int{0, 0, 0, 0})
+			rhs := a.Mul(g.Resize2DTranspose(1, 2, 4, 5, method)).Sum(0, 1, 2, 3).Get([]int{0, 0, 0, 0})
+			if math.Abs(lhs-rhs) > 1e-9 {
+				t.Errorf("%s %v: <Resize(a), g> = %v, <a, Resize2DTranspose(g)> = %v", method, size, lhs, rhs)
+			}
+		}
+		checkSame(t, method.String()+" to the same size", a.Resize2D(1, 2, 4, 5, method), a)
+	}
+
+	// half pixel centers
+	edge := calc.FromRaw([]int{1, 2}, []float64{0, 1})
+	checkClose(t, "bilinear 2 to 4", edge.Resize2D(0, 1, 1, 4, calc.ResizeBilinear), calc.FromRaw([]int{1, 4}, []float64{0, 0.25, 0.75, 1}))
+	checkSame(t, "nearest 2 to 4", edge.Resize2D(0, 1, 1, 4, calc.ResizeNearest), calc.FromRaw([]int{1, 4}, []float64{0, 0, 1, 1}))
+
+	ints := calc.FromRawInt64([]int{1, 2}, []int64{0, 4})
+	checkClose(t, "bilinear of int64", ints.Resize2D(0, 1, 1, 4, calc.ResizeBilinear), calc.FromRaw([]int{1, 4}, []float64{0, 1, 3, 4}))
+	checkClose32(t, "bilinear of float32", a.AsType(calc.Float32).Resize2D(1, 2, 7, 3, calc.ResizeBilinear), naiveResize2D(a, 1, 2, 7, 3, calc.ResizeBilinear))
+}
@@ -0,0 +1,51 @@
+package calc
+
+import "math/rand/v2"
+
+// A seeded random source, so arrays drawn in the same order from the same seed are identical. An RNG isn't
+// safe for concurrent use; give each goroutine its own with Split.
+type RNG struct {
+	r *rand.Rand
+}
+
+func NewRNG(seed uint64) *RNG {
+	return &RNG{r: rand.New(rand.NewPCG(seed, 0))}
+}
+
+// A new RNG seeded from r's stream, independent of r from then on
+func (r *RNG) Split() *RNG {
+	return &RNG{r: rand.New(rand.NewPCG(r.r.Uint64(), r.r.Uint64()))}
+}
+
+func (r *RNG) Uniform(min float64, max float64, shape ...int) NDArray {
+	arr := Zeros(shape...)
+	for i := range arr.data {
+		arr.data[i] = min + r.r.Float64()*(max-min)
+	}
+	return arr
+}
+
+func (r *RNG) Normal(mean float64, stddev float64, shape ...int) NDArray {
+	arr := Zeros(shape...)
+	for i := range arr.data {
+		arr.data[i] = mean + r.r.NormFloat64()*stddev
+	}
+	return arr
+}
+
+func (r *RNG) Float64() float64 {
+	return r.r.Float64()
+}
+
+func (r *RNG) IntN(n int) int {
+	return r.r.IntN(n)
+}
+
+// A random permutation of [0, n)
+func (r *RNG) Perm(n int) []int {
+	return r.r.Perm(n)
+}
+
+func (r *RNG) Shuffle(n int, swap func(i int, j int)) {
+	r.r.Shuffle(n, swap)
+}
@@ -0,0 +1,83 @@
+package calc_test
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+func TestRNGReproducible(t *testing.T) {
+	draw := func(r *calc.RNG) []calc.NDArray {
+		return []calc.NDArray{r.Uniform(-1, 1, 3, 4), r.Normal(2, 3, 5), calc.FromRaw([]int{1}, []float64{float64(r.IntN(1000))})}
+	}
+	a, b := draw(calc.NewRNG(40)), draw(calc.NewRNG(40))
+	for i := range a {
+		checkSame(t, "same seed", a[i], b[i])
+	}
+	if c := calc.NewRNG(41).Uniform(-1, 1, 3, 4); c.Get([]int{0, 0}) == a[0].Get([]int{0, 0}) {
+		t.Error("different seeds gave the same first value")
+	}
+
+	// splits are reproducible, differ from each other and from the parent, and can be used concurrently
+	parent := calc.NewRNG(42)
+	splits := []*calc.RNG{parent.Split(), parent.Split(), parent.Split()}
+	want := make([]calc.NDArray, len(splits))
+	for i := range want {
+		r := calc.NewRNG(42)
+		for j := 0; j < i; j++ {
+			r.Split()
+		}
+		want[i] = r.Split().Normal(0, 1, 100)
+	}
+	got := make([]calc.NDArray, len(splits))
+	var wg sync.WaitGroup
+	for i, r := range splits {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got[i] = r.Normal(0, 1, 100)
+		}()
+	}
+	wg.Wait()
+	rest := parent.Normal(0, 1, 100)
+	for i := range got {
+		checkSame(t, "split", got[i], want[i])
+		for j := i + 1; j < len(got); j++ {
+			if got[i].Get([]int{0}) == got[j].Get([]int{0}) {
+				t.Errorf("splits %d and %d start the same", i, j)
+			}
+		}
+		if got[i].Get([]int{0}) == rest.Get([]int{0}) {
+			t.Errorf("split %d starts the same as its parent", i)
+		}
+	}
+}
+
+func TestRNGDistributions(t *testing.T) {
+	rng := calc.NewRNG(43)
+	const n = 20000
+	u := rng.Uniform(2, 5, n)
+	u.ForEach(func(_ int, index []int, v float64) {
+		if v < 2 || v >= 5 {
+			t.Errorf("Uniform(2, 5) drew %v", v)
+		}
+	})
+	normal := rng.Normal(1, 2, n)
+	mean, std := normal.Mean(0).Get([]int{0}), normal.Std(0).Get([]int{0})
+	// several standard errors of slack
+	if math.Abs(u.Mean(0).Get([]int{0})-3.5) > 0.05 || math.Abs(mean-1) > 0.1 || math.Abs(std-2) > 0.1 {
+		t.Errorf("Uniform(2, 5) mean %v, Normal(1, 2) mean %v std %v", u.Mean(0).Get([]int{0}), mean, std)
+	}
+
+	perm := rng.Perm(50)
+	sorted := append([]int{}, perm...)
+	sort.Ints(sorted)
+	for i, v := range sorted {
+		if v != i {
+			t.Fatalf("Perm(50) = %v isn't a permutation", perm)
+		}
+	}
+}
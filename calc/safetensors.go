@@ -0,0 +1,326 @@
+package calc
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// safetensors files are an 8 byte little endian header length, a JSON header mapping each name to its
+// dtype, shape and [begin, end) byte offsets into the data that follows, then the little endian data.
+
+// How WriteSafetensors stores floating point arrays
+type FloatEncoding string
+
+const (
+	// each array's own dtype
+	NativeFloats FloatEncoding = ""
+	F32          FloatEncoding = "F32"
+	F16          FloatEncoding = "F16"
+	BF16         FloatEncoding = "BF16"
+)
+
+type safetensorsEntry struct {
+	DType       string `json:"dtype"`
+	Shape       []int  `json:"shape"`
+	DataOffsets [2]int `json:"data_offsets"`
+}
+
+// bytes per element of each dtype safetensors files can hold
+var safetensorsSizes = map[string]int{
+	"F64": 8, "F32": 4, "F16": 2, "BF16": 2,
+	"I64": 8, "I32": 4, "I16": 2, "I8": 1,
+	"U64": 8, "U32": 4, "U16": 2, "U8": 1,
+	"BOOL": 1,
+}
+
+// Reads every array in a safetensors file by name. F64 reads as Float64, F32, F16 and BF16 as Float32,
+// integers as Int64 and BOOL as Bool.
+func ReadSafetensors(r io.Reader) (map[string]NDArray, error) {
+	var headerLen uint64
+	if err := binary.Read(r, binary.LittleEndian, &headerLen); err != nil {
+		return nil, fmt.Errorf("safetensors: reading header length: %w", err)
+	}
+	if headerLen > 100<<20 {
+		return nil, fmt.Errorf("safetensors: header length %d is too large", headerLen)
+	}
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("safetensors: reading header: %w", err)
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(header, &raw); err != nil {
+		return nil, fmt.Errorf("safetensors: parsing header: %w", err)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("safetensors: reading data: %w", err)
+	}
+
+	arrays := map[string]NDArray{}
+	for name, msg := range raw {
+		if name == "__metadata__" {
+			continue
+		}
+		var e safetensorsEntry
+		if err := json.Unmarshal(msg, &e); err != nil {
+			return nil, fmt.Errorf("safetensors: %s: %w", name, err)
+		}
+		arr, err := e.decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("safetensors: %s: %w", name, err)
+		}
+		arrays[name] = arr
+	}
+	return arrays, nil
+}
+
+func (e safetensorsEntry) decode(data []byte) (NDArray, error) {
+	elemSize, ok := safetensorsSizes[e.DType]
+	if !ok {
+		return NDArray{}, fmt.Errorf("unsupported dtype %q", e.DType)
+	}
+	shape := e.Shape
+	if shape == nil {
+		shape = []int{}
+	}
+	n, size, ok := checkedShapeSize(shape, elemSize)
+	if !ok {
+		return NDArray{}, fmt.Errorf("bad shape %v", e.Shape)
+	}
+	begin, end := e.DataOffsets[0], e.DataOffsets[1]
+	if begin < 0 || end < begin || end > len(data) || end-begin != size {
+		return NDArray{}, fmt.Errorf("data offsets %v don't fit %d %s elements", e.DataOffsets, n, e.DType)
+	}
+	b := data[begin:end]
+
+	switch e.DType {
+	case "F64":
+		out := make([]float64, n)
+		for i := range out {
+			out[i] = math.Float64frombits(binary.LittleEndian.Uint64(b[8*i:]))
+		}
+		return FromRaw(shape, out), nil
+	case "F32", "F16", "BF16":
+		out := make([]float32, n)
+		for i := range out {
+			switch e.DType {
+			case "F32":
+				out[i] = math.Float32frombits(binary.LittleEndian.Uint32(b[4*i:]))
+			case "F16":
+				out[i] = halfToFloat32(binary.LittleEndian.Uint16(b[2*i:]))
+			default:
+				out[i] = math.Float32frombits(uint32(binary.LittleEndian.Uint16(b[2*i:])) << 16)
+			}
+		}
+		return FromRaw32(shape, out), nil
+	case "BOOL":
+		out := make([]bool, n)
+		for i := range out {
+			out[i] = b[i] != 0
+		}
+		return FromRawBool(shape, out), nil
+	default:
+		out := make([]int64, n)
+		for i := range out {
+			elem := b[elemSize*i:]
+			switch e.DType {
+			case "I64", "U64":
+				out[i] = int64(binary.LittleEndian.Uint64(elem))
+			case "I32":
+				out[i] = int64(int32(binary.LittleEndian.Uint32(elem)))
+			case "U32":
+				out[i] = int64(binary.LittleEndian.Uint32(elem))
+			case "I16":
+				out[i] = int64(int16(binary.LittleEndian.Uint16(elem)))
+			case "U16":
+				out[i] = int64(binary.LittleEndian.Uint16(elem))
+			case "I8":
+				out[i] = int64(int8(elem[0]))
+			default:
+				out[i] = int64(elem[0])
+			}
+		}
+		return FromRawInt64(shape, out), nil
+	}
+}
+
+// Writes arrays as a safetensors file in name order. Float64 and Float32 arrays are stored in the floats
+// encoding, Int64 arrays as I64 and Bool arrays as BOOL.
+func WriteSafetensors(w io.Writer, arrays map[string]NDArray, floats FloatEncoding) error {
+	names := make([]string, 0, len(arrays))
+	for name := range arrays {
+		if name == "__metadata__" {
+			return fmt.Errorf("safetensors: %q is reserved", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	header := map[string]safetensorsEntry{}
+	dtypes := make([]string, len(names))
+	offset := 0
+	for i, name := range names {
+		a := arrays[name]
+		switch {
+		case a.dtype == Int64:
+			dtypes[i] = "I64"
+		case a.dtype == Bool:
+			dtypes[i] = "BOOL"
+		case floats != NativeFloats:
+			dtypes[i] = string(floats)
+		case a.dtype == Float32:
+			dtypes[i] = "F32"
+		default:
+			dtypes[i] = "F64"
+		}
+		elemSize, ok := safetensorsSizes[dtypes[i]]
+		if !ok {
+			return fmt.Errorf("safetensors: unsupported float encoding %q", floats)
+		}
+		size := shapeSize(a.shape) * elemSize
+		header[name] = safetensorsEntry{DType: dtypes[i], Shape: append([]int{}, a.shape...), DataOffsets: [2]int{offset, offset + size}}
+		offset += size
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return fmt.Errorf("safetensors: %w", err)
+	}
+	// pad with spaces so the data starts 8 byte aligned
+	headerJSON = append(headerJSON, strings.Repeat(" ", (8-len(headerJSON)%8)%8)...)
+
+	bw := bufio.NewWriter(w)
+	binary.Write(bw, binary.LittleEndian, uint64(len(headerJSON)))
+	bw.Write(headerJSON)
+	var buf [8]byte
+	for i, name := range names {
+		a := arrays[name]
+		switch dtypes[i] {
+		case "F64":
+			a = a.AsType(Float64)
+		case "F32", "F16", "BF16":
+			a = a.AsType(Float32)
+		}
+		a = a.Contiguous()
+		for j := 0; j < shapeSize(a.shape); j++ {
+			switch dtypes[i] {
+			case "F64":
+				binary.LittleEndian.PutUint64(buf[:], math.Float64bits(a.data[j]))
+				bw.Write(buf[:8])
+			case "F32":
+				binary.LittleEndian.PutUint32(buf[:], math.Float32bits(a.data32[j]))
+				bw.Write(buf[:4])
+			case "F16":
+				binary.LittleEndian.PutUint16(buf[:], float32ToHalf(a.data32[j]))
+				bw.Write(buf[:2])
+			case "BF16":
+				binary.LittleEndian.PutUint16(buf[:], float32ToBFloat16(a.data32[j]))
+				bw.Write(buf[:2])
+			case "I64":
+				binary.LittleEndian.PutUint64(buf[:], uint64(a.dataI64[j]))
+				bw.Write(buf[:8])
+			case "BOOL":
+				if a.dataBool[j] {
+					bw.WriteByte(1)
+				} else {
+					bw.WriteByte(0)
+				}
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func LoadSafetensors(path string) (map[string]NDArray, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadSafetensors(bufio.NewReader(f))
+}
+
+func SaveSafetensors(path string, arrays map[string]NDArray, floats FloatEncoding) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteSafetensors(f, arrays, floats); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// IEEE half precision, rounding to nearest even
+func float32ToHalf(f float32) uint16 {
+	bits := math.Float32bits(f)
+	sign := uint16(bits>>16) & 0x8000
+	exp := int(bits>>23) & 0xff
+	mant := bits & 0x7fffff
+
+	if exp == 0xff {
+		if mant != 0 {
+			return sign | 0x7e00
+		}
+		return sign | 0x7c00
+	}
+	e := exp - 127 + 15
+	if e >= 0x1f {
+		return sign | 0x7c00
+	}
+	if e <= 0 {
+		// subnormal, or zero once the implicit bit is shifted out entirely
+		if e < -10 {
+			return sign
+		}
+		mant |= 0x800000
+		shift := uint(14 - e)
+		half := mant >> shift
+		rem := mant & (1<<shift - 1)
+		if halfway := uint32(1) << (shift - 1); rem > halfway || rem == halfway && half&1 == 1 {
+			half++
+		}
+		return sign | uint16(half)
+	}
+	// a carry out of the mantissa rounds up into the exponent, and to infinity from the largest value
+	half := uint32(e)<<10 | mant>>13
+	if rem := mant & 0x1fff; rem > 0x1000 || rem == 0x1000 && half&1 == 1 {
+		half++
+	}
+	return sign | uint16(half)
+}
+
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h&0x8000) << 16
+	exp := uint32(h>>10) & 0x1f
+	mant := uint32(h & 0x3ff)
+	switch exp {
+	case 0:
+		// subnormal, mant * 2^-24
+		f := float32(mant) / (1 << 24)
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	case 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mant<<13)
+	}
+	return math.Float32frombits(sign | (exp+127-15)<<23 | mant<<13)
+}
+
+// the top half of a float32, rounding to nearest even
+func float32ToBFloat16(f float32) uint16 {
+	bits := math.Float32bits(f)
+	if f != f {
+		return uint16(bits>>16) | 0x40
+	}
+	return uint16((bits + 0x7fff + (bits>>16)&1) >> 16)
+}
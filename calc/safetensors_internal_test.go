@@ -0,0 +1,122 @@
+package calc
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFloat32ToHalf(t *testing.T) {
+	cases := []struct {
+		name string
+		f    float32
+		want uint16
+	}{
+		{"one", 1, 0x3c00},
+		{"negative", -2, 0xc000},
+		{"zero", 0, 0x0000},
+		{"negative zero", float32(math.Copysign(0, -1)), 0x8000},
+		{"largest", 65504, 0x7bff},
+		{"below the overflow tie", 65519, 0x7bff},
+		{"overflow tie", 65520, 0x7c00},
+		{"overflow", 1e6, 0x7c00},
+		{"inf", float32(math.Inf(1)), 0x7c00},
+		{"negative inf", float32(math.Inf(-1)), 0xfc00},
+		{"nan", float32(math.NaN()), 0x7e00},
+		{"tie to even down", 1 + 1.0/(1<<11), 0x3c00},
+		{"tie to even up", 1 + 3.0/(1<<11), 0x3c02},
+		{"above a tie", 1 + 1.0/(1<<11) + 1.0/(1<<20), 0x3c01},
+		{"carry into the exponent", 2 - 1.0/(1<<12), 0x4000},
+		{"smallest normal", 1.0 / (1 << 14), 0x0400},
+		{"smallest subnormal", 1.0 / (1 << 24), 0x0001},
+		{"largest subnormal", 1023.0 / (1 << 24), 0x03ff},
+		{"subnormal tie to zero", 1.0 / (1 << 25), 0x0000},
+		{"subnormal tie to even", 3.0 / (1 << 25), 0x0002},
+		{"subnormal rounding up", 3.0 / (1 << 26), 0x0001},
+		{"subnormal carry into normal", 1023.5 / (1 << 24), 0x0400},
+		{"underflow", 1.0 / (1 << 26), 0x0000},
+		{"negative underflow", -1.0 / (1 << 26), 0x8000},
+	}
+	for _, c := range cases {
+		if got := float32ToHalf(c.f); got != c.want {
+			t.Errorf("%s: float32ToHalf(%v) = %#04x, want %#04x", c.name, c.f, got, c.want)
+		}
+	}
+}
+
+func TestHalfToFloat32(t *testing.T) {
+	cases := []struct {
+		h    uint16
+		want float32
+	}{
+		{0x3c00, 1},
+		{0xc000, -2},
+		{0x7bff, 65504},
+		{0x0400, 1.0 / (1 << 14)},
+		{0x0001, 1.0 / (1 << 24)},
+		{0x8001, -1.0 / (1 << 24)},
+		{0x03ff, 1023.0 / (1 << 24)},
+		{0x0000, 0},
+		{0x8000, float32(math.Copysign(0, -1))},
+		{0x7c00, float32(math.Inf(1))},
+		{0xfc00, float32(math.Inf(-1))},
+	}
+	for _, c := range cases {
+		if got := halfToFloat32(c.h); math.Float32bits(got) != math.Float32bits(c.want) {
+			t.Errorf("halfToFloat32(%#04x) = %v, want %v", c.h, got, c.want)
+		}
+	}
+	for _, h := range []uint16{0x7e00, 0x7c01, 0xfe00} {
+		if got := halfToFloat32(h); got == got {
+			t.Errorf("halfToFloat32(%#04x) = %v, want NaN", h, got)
+		}
+	}
+
+	// every half value is exactly a float32, so converting back gives the same bits
+	for h := 0; h < 1<<16; h++ {
+		f := halfToFloat32(uint16(h))
+		if f != f {
+			continue
+		}
+		if back := float32ToHalf(f); back != uint16(h) {
+			t.Errorf("%#04x converts back to %#04x", h, back)
+		}
+	}
+}
+
+func TestFloat32ToBFloat16(t *testing.T) {
+	cases := []struct {
+		name string
+		f    float32
+		want uint16
+	}{
+		{"one", 1, 0x3f80},
+		{"negative", -2, 0xc000},
+		{"negative zero", float32(math.Copysign(0, -1)), 0x8000},
+		{"tie to even down", 1 + 1.0/(1<<8), 0x3f80},
+		{"tie to even up", 1 + 3.0/(1<<8), 0x3f82},
+		{"above a tie", 1 + 1.0/(1<<8) + 1.0/(1<<20), 0x3f81},
+		{"carry into the exponent", 2 - 1.0/(1<<9), 0x4000},
+		{"overflow", math.MaxFloat32, 0x7f80},
+		{"inf", float32(math.Inf(1)), 0x7f80},
+		{"negative inf", float32(math.Inf(-1)), 0xff80},
+		{"smallest subnormal", math.Float32frombits(0x00010000), 0x0001},
+		{"subnormal tie to zero", math.Float32frombits(0x00008000), 0x0000},
+		{"subnormal tie to even", math.Float32frombits(0x00018000), 0x0002},
+	}
+	for _, c := range cases {
+		if got := float32ToBFloat16(c.f); got != c.want {
+			t.Errorf("%s: float32ToBFloat16(%v) = %#04x, want %#04x", c.name, c.f, got, c.want)
+		}
+	}
+
+	// NaNs stay NaN even when their payload is only in the bits that are dropped
+	for _, bits := range []uint32{0x7fc00000, 0x7f800001, 0xff800001} {
+		got := float32ToBFloat16(math.Float32frombits(bits))
+		if f := math.Float32frombits(uint32(got) << 16); f == f {
+			t.Errorf("float32ToBFloat16 of NaN %#08x = %#04x, which isn't NaN", bits, got)
+		}
+		if got&0x8000 != uint16(bits>>16)&0x8000 {
+			t.Errorf("float32ToBFloat16 of NaN %#08x = %#04x, which lost the sign", bits, got)
+		}
+	}
+}
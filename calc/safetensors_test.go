@@ -0,0 +1,51 @@
+package calc_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// a safetensors file with the given JSON header and data
+func safetensorsFile(header string, data []byte) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, uint64(len(header)))
+	buf.WriteString(header)
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+// Headers that don't match the data are errors
+func TestSafetensorsBadHeaders(t *testing.T) {
+	data := make([]byte, 16)
+	for _, c := range []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"overflowing shape", `{"a": {"dtype": "F32", "shape": [4611686018427387904, 4], "data_offsets": [0, 0]}}`, "bad shape"},
+		{"overflowing bytes", `{"a": {"dtype": "F64", "shape": [2305843009213693952], "data_offsets": [0, 0]}}`, "bad shape"},
+		{"negative shape", `{"a": {"dtype": "F32", "shape": [-1, 4], "data_offsets": [0, 16]}}`, "bad shape"},
+		{"offsets past the data", `{"a": {"dtype": "F32", "shape": [8], "data_offsets": [0, 32]}}`, "don't fit"},
+		{"offsets of the wrong size", `{"a": {"dtype": "F32", "shape": [3], "data_offsets": [0, 16]}}`, "don't fit"},
+		{"reversed offsets", `{"a": {"dtype": "U8", "shape": [0], "data_offsets": [8, 4]}}`, "don't fit"},
+		{"dtype", `{"a": {"dtype": "C64", "shape": [2], "data_offsets": [0, 16]}}`, "unsupported dtype"},
+	} {
+		_, err := calc.ReadSafetensors(bytes.NewReader(safetensorsFile(c.header, data)))
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: got %v, want an error containing %q", c.name, err, c.want)
+		}
+	}
+
+	arrays, err := calc.ReadSafetensors(bytes.NewReader(safetensorsFile(
+		`{"__metadata__": {"format": "pt"}, "a": {"dtype": "I32", "shape": [2, 2], "data_offsets": [0, 16]}}`,
+		[]byte{1, 0, 0, 0, 0xff, 0xff, 0xff, 0xff, 3, 0, 0, 0, 4, 0, 0, 0},
+	)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSame(t, "I32", arrays["a"], calc.FromRawInt64([]int{2, 2}, []int64{1, -1, 3, 4}))
+}
@@ -0,0 +1,246 @@
+package calc
+
+import "fmt"
+
+// The shape of an array permuted so axis i of the result is axis axes[i] of it
+func CheckPermute(shape []int, axes []int) ([]int, error) {
+	if len(axes) != len(shape) {
+		return nil, &ShapeError{Op: "Permute", Axis: -1, Actual: shape, Reason: fmt.Sprintf("permutation %v doesn't have an axis per dimension", axes)}
+	}
+	if err := CheckAxes("Permute", shape, axes...); err != nil {
+		return nil, err
+	}
+	seen := make([]bool, len(shape))
+	out := make([]int, len(axes))
+	for i, ax := range axes {
+		if seen[ax] {
+			return nil, &ShapeError{Op: "Permute", Axis: ax, Actual: shape, Reason: fmt.Sprintf("repeated in permutation %v", axes)}
+		}
+		seen[ax] = true
+		out[i] = shape[ax]
+	}
+	return out, nil
+}
+
+// Returns a view with axis i of the result being axis axes[i] of a
+func (a NDArray) Permute(axes ...int) NDArray {
+	Must(CheckPermute(a.shape, axes))
+	return a.permute(axes)
+}
+
+// The permutation undoing axes
+func InversePermutation(axes []int) []int {
+	inv := make([]int, len(axes))
+	for i, ax := range axes {
+		inv[ax] = i
+	}
+	return inv
+}
+
+// The shape with a size 1 axis inserted before axis, which may be the rank to append one
+func CheckExpandDims(shape []int, axis int) ([]int, error) {
+	if axis < 0 || axis > len(shape) {
+		return nil, &ShapeError{Op: "ExpandDims", Axis: axis, Actual: shape, Reason: fmt.Sprintf("out of range for rank %d", len(shape))}
+	}
+	out := append(append(append([]int{}, shape[:axis]...), 1), shape[axis:]...)
+	return out, nil
+}
+
+// Returns a view with a new size 1 axis at axis
+func (a NDArray) ExpandDims(axis int) NDArray {
+	shape := Must(CheckExpandDims(a.shape, axis))
+	a = a.strided()
+	strides := a.stridesOf()
+	strides = append(append(append([]int{}, strides[:axis]...), 0), strides[axis:]...)
+	return a.view(shape, strides, a.offset)
+}
+
+// The shape without the given size 1 axes, or without every size 1 axis if none are given
+func CheckSqueeze(shape []int, axes ...int) ([]int, error) {
+	if err := CheckAxes("Squeeze", shape, axes...); err != nil {
+		return nil, err
+	}
+	for _, ax := range axes {
+		if shape[ax] != 1 {
+			return nil, &ShapeError{Op: "Squeeze", Axis: ax, Actual: shape, Reason: fmt.Sprintf("can't squeeze an axis of size %d", shape[ax])}
+		}
+	}
+	out := []int{}
+	for i, drop := range squeezed(shape, axes) {
+		if !drop {
+			out = append(out, shape[i])
+		}
+	}
+	return out, nil
+}
+
+// which axes of shape squeezing axes drops
+func squeezed(shape []int, axes []int) []bool {
+	drop := make([]bool, len(shape))
+	for _, ax := range axes {
+		drop[ax] = true
+	}
+	if len(axes) == 0 {
+		for i, sz := range shape {
+			drop[i] = sz == 1
+		}
+	}
+	return drop
+}
+
+// Returns a view without the given size 1 axes, or without every size 1 axis if none are given
+func (a NDArray) Squeeze(axes ...int) NDArray {
+	shape := Must(CheckSqueeze(a.shape, axes...))
+	drop := squeezed(a.shape, axes)
+	a = a.strided()
+	strides := []int{}
+	for i, st := range a.stridesOf() {
+		if !drop[i] {
+			strides = append(strides, st)
+		}
+	}
+	return a.view(shape, strides, a.offset)
+}
+
+// The shape of arrays with shapes stacked along a new axis
+func CheckStack(axis int, shapes ...[]int) ([]int, error) {
+	if len(shapes) == 0 {
+		return nil, &ShapeError{Op: "Stack", Axis: axis, Reason: "nothing to stack"}
+	}
+	for _, shape := range shapes[1:] {
+		if !ShapeEqual(shape, shapes[0]) {
+			return nil, &ShapeError{Op: "Stack", Axis: -1, Expected: shapes[0], Actual: shape, Reason: "shapes differ"}
+		}
+	}
+	if axis < 0 || axis > len(shapes[0]) {
+		return nil, &ShapeError{Op: "Stack", Axis: axis, Actual: shapes[0], Reason: fmt.Sprintf("out of range for rank %d", len(shapes[0])+1)}
+	}
+	out := append(append(append([]int{}, shapes[0][:axis]...), len(shapes)), shapes[0][axis:]...)
+	return out, nil
+}
+
+// Joins arrays of the same shape along a new axis
+func Stack(axis int, as ...NDArray) NDArray {
+	shapes := make([][]int, len(as))
+	dtype := Bool
+	for i, a := range as {
+		shapes[i] = a.shape
+		dtype = PromoteTypes(dtype, a.dtype)
+	}
+	arr := ZerosOf(dtype, Must(CheckStack(axis, shapes...))...)
+	return StackInto(axis, as, arr)
+}
+
+func StackInto(axis int, as []NDArray, arr NDArray) NDArray {
+	requirePacked(arr)
+	shapes := make([][]int, len(as))
+	for i, a := range as {
+		shapes[i] = a.shape
+	}
+	if shape := Must(CheckStack(axis, shapes...)); !ShapeEqual(shape, arr.shape) {
+		panic(&ShapeError{Op: "Stack", Axis: -1, Expected: shape, Actual: arr.shape, Reason: "wrong output shape"})
+	}
+	for i, a := range as {
+		arr.SetSlice(a.ExpandDims(axis), axis, i)
+	}
+	return arr
+}
+
+// Checks that axis splits evenly into pieces of size batch
+func CheckSplit(shape []int, axis int, batch int) error {
+	if err := CheckAxes("Split", shape, axis); err != nil {
+		return err
+	}
+	if batch <= 0 || shape[axis]%batch != 0 {
+		return &ShapeError{Op: "Split", Axis: axis, Actual: shape, Reason: fmt.Sprintf("can't split into batches of %d", batch)}
+	}
+	return nil
+}
+
+// The shape of an array tiled reps[i] times along each axis i. Like numpy.tile, when reps is longer than
+// the shape the array gets leading axes of size 1, and when it's shorter the leading axes aren't repeated.
+func CheckTile(shape []int, reps []int) ([]int, error) {
+	shape, reps = tileAxes(shape, reps)
+	out := make([]int, len(shape))
+	for i, r := range reps {
+		if r < 0 {
+			return nil, &ShapeError{Op: "Tile", Axis: i, Actual: shape, Reason: fmt.Sprintf("negative repetitions %d", r)}
+		}
+		out[i] = shape[i] * r
+	}
+	return out, nil
+}
+
+// shape and reps padded with leading 1s to the same length
+func tileAxes(shape []int, reps []int) ([]int, []int) {
+	n := max(len(shape), len(reps))
+	return padOnes(shape, n), padOnes(reps, n)
+}
+
+func padOnes(s []int, n int) []int {
+	out := make([]int, n)
+	for i := range out {
+		out[i] = 1
+	}
+	copy(out[n-len(s):], s)
+	return out
+}
+
+// Repeats the whole of a reps[i] times along each axis i, like numpy.tile
+func (a NDArray) Tile(reps ...int) NDArray {
+	arr := ZerosOf(a.dtype, Must(CheckTile(a.shape, reps))...)
+	return a.TileInto(reps, arr)
+}
+
+func (a NDArray) TileInto(reps []int, arr NDArray) NDArray {
+	requirePacked(arr)
+	if shape := Must(CheckTile(a.shape, reps)); !ShapeEqual(shape, arr.shape) {
+		panic(&ShapeError{Op: "Tile", Axis: -1, Expected: shape, Actual: arr.shape, Reason: "wrong output shape"})
+	}
+	// a (reps[0], shape[0], reps[1], shape[1], ...) view that doesn't move along the repetition axes
+	a = a.strided()
+	inShape, reps := tileAxes(a.shape, reps)
+	strides := make([]int, len(inShape))
+	copy(strides[len(inShape)-len(a.shape):], a.stridesOf())
+	shape := make([]int, 0, 2*len(reps))
+	tiled := make([]int, 0, 2*len(reps))
+	for i, r := range reps {
+		shape = append(shape, r, inShape[i])
+		tiled = append(tiled, 0, strides[i])
+	}
+	a.view(shape, tiled, a.offset).AsTypeInto(arr.Reshape(shape...))
+	return arr
+}
+
+// The shape of an array with each element repeated repeats times along axis
+func CheckRepeat(shape []int, axis int, repeats int) ([]int, error) {
+	if err := CheckAxes("Repeat", shape, axis); err != nil {
+		return nil, err
+	}
+	if repeats < 0 {
+		return nil, &ShapeError{Op: "Repeat", Axis: axis, Actual: shape, Reason: fmt.Sprintf("negative repeats %d", repeats)}
+	}
+	out := append([]int{}, shape...)
+	out[axis] *= repeats
+	return out, nil
+}
+
+// Repeats each element of a repeats times along axis, like numpy.repeat
+func (a NDArray) Repeat(axis int, repeats int) NDArray {
+	arr := ZerosOf(a.dtype, Must(CheckRepeat(a.shape, axis, repeats))...)
+	return a.RepeatInto(axis, repeats, arr)
+}
+
+func (a NDArray) RepeatInto(axis int, repeats int, arr NDArray) NDArray {
+	requirePacked(arr)
+	if shape := Must(CheckRepeat(a.shape, axis, repeats)); !ShapeEqual(shape, arr.shape) {
+		panic(&ShapeError{Op: "Repeat", Axis: -1, Expected: shape, Actual: arr.shape, Reason: "wrong output shape"})
+	}
+	// a view with a repetition axis after axis that doesn't move
+	a = a.strided()
+	shape := append(append(append([]int{}, a.shape[:axis+1]...), repeats), a.shape[axis+1:]...)
+	strides := a.stridesOf()
+	strides = append(append(append([]int{}, strides[:axis+1]...), 0), strides[axis+1:]...)
+	a.view(shape, strides, a.offset).AsTypeInto(arr.Reshape(shape...))
+	return arr
+}
@@ -0,0 +1,122 @@
+package calc_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// numpy.tile by looking up each element of the result, with a and reps padded with leading 1s like numpy
+func naiveTile(a calc.NDArray, reps []int) calc.NDArray {
+	n := max(len(a.Shape()), len(reps))
+	lead := n - len(a.Shape())
+	inShape, padded := make([]int, n), make([]int, n)
+	for i := range inShape {
+		inShape[i], padded[i] = 1, 1
+	}
+	copy(inShape[lead:], a.Shape())
+	copy(padded[n-len(reps):], reps)
+	shape := make([]int, n)
+	for i := range shape {
+		shape[i] = inShape[i] * padded[i]
+	}
+	out := calc.ZerosOf(a.DType(), shape...)
+	out.ForEach(func(_ int, index []int, _ float64) {
+		src := make([]int, len(a.Shape()))
+		for i := range src {
+			src[i] = index[lead+i] % inShape[lead+i]
+		}
+		out.Set(index, a.Get(src))
+	})
+	return out
+}
+
+func TestTile(t *testing.T) {
+	rng := calc.NewRNG(15)
+	a := rng.Normal(0, 1, 2, 3)
+	for _, reps := range [][]int{
+		{1, 1},
+		{2, 3},
+		{3, 1},
+		{0, 2},
+		// longer than the rank adds leading axes
+		{2, 1, 2},
+		{2, 2, 1, 1},
+		// shorter than the rank repeats the trailing axes
+		{2},
+		{},
+	} {
+		want := naiveTile(a, reps)
+		checkSame(t, "Tile", a.Tile(reps...), want)
+		checkSame(t, "Tile of a transposed view", a.Transpose(0, 1).Tile(reps...), naiveTile(a.Transpose(0, 1).Contiguous(), reps))
+		shape, err := calc.CheckTile(a.Shape(), reps)
+		if err != nil || !calc.ShapeEqual(shape, want.Shape()) || len(shape) != len(want.Shape()) {
+			t.Errorf("CheckTile(%v, %v) = %v, %v, want %v", a.Shape(), reps, shape, err, want.Shape())
+		}
+	}
+	checkSame(t, "Tile of a scalar", calc.FromRaw([]int{}, []float64{4}).Tile(2, 3), calc.Ones(2, 3).MulConstant(4))
+	checkSame(t, "Tile int64", calc.FromRawInt64([]int{2}, []int64{1, 2}).Tile(2, 2), calc.FromRawInt64([]int{2, 4}, []int64{1, 2, 1, 2, 1, 2, 1, 2}))
+	if _, err := calc.CheckTile([]int{2, 3}, []int{1, -1}); err == nil {
+		t.Error("CheckTile with negative repetitions didn't fail")
+	}
+}
+
+func TestRepeat(t *testing.T) {
+	a := calc.FromRaw([]int{2, 2}, []float64{1, 2, 3, 4})
+	checkSame(t, "Repeat 0", a.Repeat(0, 2), calc.FromRaw([]int{4, 2}, []float64{1, 2, 1, 2, 3, 4, 3, 4}))
+	checkSame(t, "Repeat 1", a.Repeat(1, 3), calc.FromRaw([]int{2, 6}, []float64{1, 1, 1, 2, 2, 2, 3, 3, 3, 4, 4, 4}))
+	checkSame(t, "Repeat of a transposed view", a.Transpose(0, 1).Repeat(1, 2), calc.FromRaw([]int{2, 4}, []float64{1, 1, 3, 3, 2, 2, 4, 4}))
+	checkSame(t, "Repeat 0 times", a.Repeat(1, 0), calc.Zeros(2, 0))
+}
+
+func TestShapeViews(t *testing.T) {
+	rng := calc.NewRNG(16)
+	a := rng.Normal(0, 1, 2, 3, 4)
+
+	p := a.Permute(2, 0, 1)
+	if !calc.ShapeEqual(p.Shape(), []int{4, 2, 3}) {
+		t.Errorf("Permute has shape %v, want [4 2 3]", p.Shape())
+	}
+	p.ForEach(func(_ int, index []int, v float64) {
+		if w := a.Get([]int{index[1], index[2], index[0]}); v != w {
+			t.Errorf("Permute %v = %v, want %v", index, v, w)
+		}
+	})
+	checkSame(t, "InversePermutation", p.Permute(calc.InversePermutation([]int{2, 0, 1})...), a)
+
+	e := a.ExpandDims(1)
+	checkSame(t, "ExpandDims", e, a.Reshape(2, 1, 3, 4))
+	checkSame(t, "ExpandDims at the end", a.ExpandDims(3), a.Reshape(2, 3, 4, 1))
+	checkSame(t, "Squeeze", e.Squeeze(1), a)
+	checkSame(t, "Squeeze all", a.Reshape(1, 2, 1, 12, 1).Squeeze(), a.Reshape(2, 12))
+	checkSame(t, "Squeeze a transposed view", a.Transpose(0, 2).ExpandDims(0).Squeeze(0), a.Transpose(0, 2).Contiguous())
+
+	parts := []calc.NDArray{rng.Normal(0, 1, 2, 3), rng.Normal(0, 1, 2, 3), rng.Normal(0, 1, 2, 3)}
+	for axis := 0; axis <= 2; axis++ {
+		s := calc.Stack(axis, parts...)
+		for i, part := range parts {
+			checkSame(t, "Stack", s.Slice(axis, i, i+1).Squeeze(axis).Contiguous(), part)
+		}
+	}
+	if s := calc.Stack(0, calc.FromRawInt64([]int{1}, []int64{1}), calc.FromRaw32([]int{1}, []float32{2})); s.DType() != calc.Float32 {
+		t.Errorf("Stack of int64 and float32 is %s", s.DType())
+	}
+
+	for _, c := range []struct {
+		name string
+		err  error
+	}{
+		{"Permute repeated", func() error { _, err := calc.CheckPermute([]int{2, 3}, []int{0, 0}); return err }()},
+		{"Permute short", func() error { _, err := calc.CheckPermute([]int{2, 3}, []int{0}); return err }()},
+		{"ExpandDims out of range", func() error { _, err := calc.CheckExpandDims([]int{2, 3}, 3); return err }()},
+		{"Squeeze size 2", func() error { _, err := calc.CheckSqueeze([]int{2, 1}, 0); return err }()},
+		{"Stack different shapes", func() error { _, err := calc.CheckStack(0, []int{2}, []int{3}); return err }()},
+		{"Stack nothing", func() error { _, err := calc.CheckStack(0); return err }()},
+		{"Split uneven", calc.CheckSplit([]int{5, 2}, 0, 2)},
+		{"Repeat negative", func() error { _, err := calc.CheckRepeat([]int{2}, 0, -1); return err }()},
+	} {
+		if _, ok := c.err.(*calc.ShapeError); !ok {
+			t.Errorf("%s: got %v, want a ShapeError", c.name, c.err)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package calc
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// The sizes before, along and after axis of shape. A packed array of shape is made of before*after 1-d
+// lanes along axis, each with stride after.
+func laneSizes(shape []int, axis int) (before int, n int, after int) {
+	return shapeSize(shape[:axis]), shape[axis], shapeSize(shape[axis+1:])
+}
+
+// Calls f for every lane along axis of arrays laid out as ls, which only differ in the size of axis. Element
+// i of the lane of ls[j] is at storage index starts[j]+steps[j][i]. Lanes are split across the pool.
+func walkLanes(axis int, f func(starts []int, steps [][]int), ls ...layout) {
+	shape := ls[0].shape
+	before, n, after := laneSizes(shape, axis)
+	lanes := before * after
+	axisSteps := make([][][]int, len(ls))
+	steps := make([][]int, len(ls))
+	for j, l := range ls {
+		axisSteps[j] = l.steps(l.shape)
+		steps[j] = axisSteps[j][axis]
+	}
+	splitRange(lanes, rowGrain([]int{lanes, n}), func(start int, end int) {
+		starts := make([]int, len(ls))
+		index := make([]int, len(shape))
+		for l := start; l < end; l++ {
+			// the index of the lane along every other axis
+			for ax, rest := len(shape)-1, l; ax >= 0; ax-- {
+				if ax != axis {
+					index[ax], rest = rest%shape[ax], rest/shape[ax]
+				}
+			}
+			for j := range ls {
+				starts[j] = ls[j].offset
+				for ax, i := range index {
+					if ax != axis {
+						starts[j] += axisSteps[j][ax][i]
+					}
+				}
+			}
+			f(starts, steps)
+		}
+	})
+}
+
+func (a NDArray) ArgMax(axis int) NDArray {
+	arr := ZerosOf(Int64, AggrShape(a.shape, []int{axis})...)
+	return a.ArgMaxInto(axis, arr)
+}
+
+// Indices of the first largest elements along axis, as an Int64 array with axis kept at size 1
+func (a NDArray) ArgMaxInto(axis int, arr NDArray) NDArray {
+	return a.argBestInto(axis, arr, func(v float64, best float64) bool { return v > best })
+}
+
+func (a NDArray) ArgMin(axis int) NDArray {
+	arr := ZerosOf(Int64, AggrShape(a.shape, []int{axis})...)
+	return a.ArgMinInto(axis, arr)
+}
+
+// Indices of the first smallest elements along axis, as an Int64 array with axis kept at size 1
+func (a NDArray) ArgMinInto(axis int, arr NDArray) NDArray {
+	return a.argBestInto(axis, arr, func(v float64, best float64) bool { return v < best })
+}
+
+func (a NDArray) argBestInto(axis int, arr NDArray, better func(v float64, best float64) bool) NDArray {
+	requirePacked(arr)
+	if arr.dtype != Int64 {
+		panic(fmt.Sprintf("indices must be int64, not %s", arr.dtype))
+	}
+	walkLanes(axis, func(starts []int, steps [][]int) {
+		best, bestV := 0, math.NaN()
+		for i, step := range steps[0] {
+			v := a.at(starts[0] + step)
+			// NaNs are skipped, leaving the first index if they're all there is
+			if !math.IsNaN(v) && (math.IsNaN(bestV) || better(v, bestV)) {
+				best, bestV = i, v
+			}
+		}
+		arr.dataI64[starts[1]] = int64(best)
+	}, a.layout(), arr.layout())
+	return arr
+}
+
+func (a NDArray) ArgSort(axis int) NDArray {
+	arr := ZerosOf(Int64, a.shape...)
+	return a.ArgSortInto(axis, arr)
+}
+
+// Indices that stably sort a in ascending order along axis, as an Int64 array
+func (a NDArray) ArgSortInto(axis int, arr NDArray) NDArray {
+	return a.sortedIndicesInto(axis, arr, false)
+}
+
+func (a NDArray) Sort(axis int) NDArray {
+	arr := ZerosOf(a.dtype, a.shape...)
+	return a.SortInto(axis, arr)
+}
+
+// a sorted in ascending order along axis
+func (a NDArray) SortInto(axis int, arr NDArray) NDArray {
+	indices := a.ArgSort(axis)
+	return a.GatherInto(indices, axis, arr)
+}
+
+// The shape of the k largest elements of shape along axis
+func CheckTopK(shape []int, k int, axis int) ([]int, error) {
+	if err := CheckAxes("TopK", shape, axis); err != nil {
+		return nil, err
+	}
+	if k < 0 || k > shape[axis] {
+		return nil, &ShapeError{Op: "TopK", Axis: axis, Actual: shape, Reason: fmt.Sprintf("can't take %d of %d elements", k, shape[axis])}
+	}
+	out := append([]int{}, shape...)
+	out[axis] = k
+	return out, nil
+}
+
+func (a NDArray) TopK(k int, axis int) (values NDArray, indices NDArray) {
+	shape := Must(CheckTopK(a.shape, k, axis))
+	values, indices = ZerosOf(a.dtype, shape...), ZerosOf(Int64, shape...)
+	return a.TopKInto(axis, values, indices)
+}
+
+// The k largest elements along axis in descending order, and their indices as an Int64 array. Equal
+// elements keep their order. k is taken from the size of axis in values and indices.
+func (a NDArray) TopKInto(axis int, values NDArray, indices NDArray) (NDArray, NDArray) {
+	a.sortedIndicesInto(axis, indices, true)
+	return a.GatherInto(indices, axis, values), indices
+}
+
+// stably sorts the lanes of a along axis, keeping the first arr.shape[axis] indices of each
+func (a NDArray) sortedIndicesInto(axis int, arr NDArray, descending bool) NDArray {
+	requirePacked(arr)
+	if arr.dtype != Int64 {
+		panic(fmt.Sprintf("indices must be int64, not %s", arr.dtype))
+	}
+	if err := CheckAxes("TopK", arr.shape, axis); err != nil {
+		panic(err)
+	}
+	Must(CheckTopK(a.shape, arr.shape[axis], axis))
+	n, k := a.shape[axis], arr.shape[axis]
+	walkLanes(axis, func(starts []int, steps [][]int) {
+		idx := make([]int, n)
+		vals := make([]float64, n)
+		for i := range idx {
+			idx[i] = i
+			vals[i] = a.at(starts[0] + steps[0][i])
+		}
+		sort.SliceStable(idx, func(i int, j int) bool {
+			if descending {
+				return vals[idx[i]] > vals[idx[j]]
+			}
+			return vals[idx[i]] < vals[idx[j]]
+		})
+		for i := 0; i < k; i++ {
+			arr.dataI64[starts[1]+steps[1][i]] = int64(idx[i])
+		}
+	}, a.layout(), arr.layout())
+	return arr
+}
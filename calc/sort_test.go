@@ -0,0 +1,97 @@
+package calc_test
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// The indices that stably sort every lane of a along axis, as an Int64 array
+func naiveArgSort(a calc.NDArray, axis int, descending bool) calc.NDArray {
+	shape := a.Shape()
+	size := 1
+	for _, n := range shape {
+		size *= n
+	}
+	indices := make([]int64, size)
+	a.Slice(axis, 0, 1).ForEach(func(_ int, index []int, _ float64) {
+		lane := append([]int{}, index...)
+		at := func(i int) float64 {
+			lane[axis] = i
+			return a.Get(lane)
+		}
+		idx := make([]int, shape[axis])
+		for i := range idx {
+			idx[i] = i
+		}
+		sort.SliceStable(idx, func(i int, j int) bool {
+			if descending {
+				return at(idx[i]) > at(idx[j])
+			}
+			return at(idx[i]) < at(idx[j])
+		})
+		for i, v := range idx {
+			lane[axis] = i
+			// row-major position of lane
+			pos := 0
+			for ax, n := range shape {
+				pos = pos*n + lane[ax]
+			}
+			indices[pos] = int64(v)
+		}
+	})
+	return calc.FromRawInt64(shape, indices)
+}
+
+// rounded so lanes have ties
+func tiedNormal(rng *calc.RNG, shape ...int) calc.NDArray {
+	a := rng.Normal(0, 2, shape...)
+	a.ForEach(func(_ int, index []int, v float64) {
+		a.Set(index, math.Round(v))
+	})
+	return a
+}
+
+func TestSort(t *testing.T) {
+	rng := calc.NewRNG(29)
+	a := tiedNormal(rng, 3, 5, 4)
+	for axis := 0; axis < 3; axis++ {
+		for _, v := range []calc.NDArray{a, a.Permute(2, 0, 1).Transpose(0, 1)} {
+			asc, desc := naiveArgSort(v, axis, false), naiveArgSort(v, axis, true)
+			checkSame(t, "ArgSort", v.ArgSort(axis), asc)
+			checkSame(t, "Sort", v.Sort(axis), v.Gather(asc, axis))
+
+			k := v.Shape()[axis] - 1
+			values, indices := v.TopK(k, axis)
+			checkSame(t, "TopK indices", indices, desc.Slice(axis, 0, k))
+			checkSame(t, "TopK values", values, v.Gather(desc.Slice(axis, 0, k), axis))
+		}
+	}
+
+	if values, _ := a.TopK(0, 1); values.Shape()[1] != 0 {
+		t.Errorf("TopK(0) has shape %v", values.Shape())
+	}
+	var shapeErr *calc.ShapeError
+	if _, err := calc.CheckTopK([]int{3, 5}, 6, 1); !errors.As(err, &shapeErr) {
+		t.Errorf("CheckTopK of 6 of 5 elements: %v", err)
+	}
+}
+
+func TestArgMaxArgMin(t *testing.T) {
+	rng := calc.NewRNG(30)
+	a := tiedNormal(rng, 4, 6)
+	for axis := 0; axis < 2; axis++ {
+		// the first of ties, which a stable sort puts first
+		checkSame(t, "ArgMax", a.ArgMax(axis), naiveArgSort(a, axis, true).Slice(axis, 0, 1))
+		checkSame(t, "ArgMin", a.ArgMin(axis), naiveArgSort(a, axis, false).Slice(axis, 0, 1))
+	}
+
+	nan := math.NaN()
+	b := calc.FromRaw([]int{3, 3}, []float64{nan, 1, 2, 3, nan, 1, nan, nan, nan})
+	checkSame(t, "ArgMax skips NaN", b.ArgMax(1), calc.FromRawInt64([]int{3, 1}, []int64{2, 0, 0}))
+	checkSame(t, "ArgMin skips NaN", b.ArgMin(1), calc.FromRawInt64([]int{3, 1}, []int64{1, 2, 0}))
+	checkSame(t, "ArgMax of int64", calc.FromRawInt64([]int{3}, []int64{-1, 5, 5}).ArgMax(0), calc.FromRawInt64([]int{1}, []int64{1}))
+}
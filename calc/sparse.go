@@ -0,0 +1,252 @@
+package calc
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Sparse matrices only store their nonzero elements, as float64 values. COO lists each element's row and
+// column, which is easy to build. CSR groups them by row, which is what SpMM needs.
+
+// A sparse matrix in coordinate form
+type COO struct {
+	rows, cols int
+	rowIdx     []int
+	colIdx     []int
+	values     []float64
+}
+
+// A COO matrix with values[i] at (rowIdx[i], colIdx[i]). Duplicate positions are summed when converted.
+func NewCOO(rows int, cols int, rowIdx []int, colIdx []int, values []float64) (COO, error) {
+	if rows < 0 || cols < 0 {
+		return COO{}, &ShapeError{Op: "NewCOO", Axis: -1, Actual: []int{rows, cols}, Reason: "negative size"}
+	}
+	if len(rowIdx) != len(values) || len(colIdx) != len(values) {
+		return COO{}, &ShapeError{
+			Op:       "NewCOO",
+			Axis:     -1,
+			Expected: []int{len(values), len(values)},
+			Actual:   []int{len(rowIdx), len(colIdx)},
+			Reason:   "need a row and column index per value",
+		}
+	}
+	for i := range values {
+		if rowIdx[i] < 0 || rowIdx[i] >= rows || colIdx[i] < 0 || colIdx[i] >= cols {
+			return COO{}, &ShapeError{
+				Op:     "NewCOO",
+				Axis:   -1,
+				Actual: []int{rows, cols},
+				Reason: fmt.Sprintf("element (%d, %d) out of range", rowIdx[i], colIdx[i]),
+			}
+		}
+	}
+	return COO{rows: rows, cols: cols, rowIdx: rowIdx, colIdx: colIdx, values: values}, nil
+}
+
+func (s COO) Shape() []int {
+	return []int{s.rows, s.cols}
+}
+
+func (s COO) NNZ() int {
+	return len(s.values)
+}
+
+// Sorts the elements by row then column, summing duplicates
+func (s COO) CSR() CSR {
+	order := make([]int, len(s.values))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i int, j int) bool {
+		a, b := order[i], order[j]
+		if s.rowIdx[a] != s.rowIdx[b] {
+			return s.rowIdx[a] < s.rowIdx[b]
+		}
+		return s.colIdx[a] < s.colIdx[b]
+	})
+
+	out := CSR{rows: s.rows, cols: s.cols, rowPtr: make([]int, s.rows+1)}
+	for k, i := range order {
+		if k > 0 && s.rowIdx[i] == s.rowIdx[order[k-1]] && s.colIdx[i] == s.colIdx[order[k-1]] {
+			out.values[len(out.values)-1] += s.values[i]
+			continue
+		}
+		out.colIdx = append(out.colIdx, s.colIdx[i])
+		out.values = append(out.values, s.values[i])
+		out.rowPtr[s.rowIdx[i]+1]++
+	}
+	for r := 0; r < s.rows; r++ {
+		out.rowPtr[r+1] += out.rowPtr[r]
+	}
+	return out
+}
+
+func (s COO) Dense() NDArray {
+	arr := Zeros(s.rows, s.cols)
+	for i, v := range s.values {
+		arr.data[s.rowIdx[i]*s.cols+s.colIdx[i]] += v
+	}
+	return arr
+}
+
+// A sparse matrix in compressed sparse row form. The columns and values of row r are at
+// [rowPtr[r], rowPtr[r+1]) of colIdx and values.
+type CSR struct {
+	rows, cols int
+	rowPtr     []int
+	colIdx     []int
+	values     []float64
+}
+
+func NewCSR(rows int, cols int, rowPtr []int, colIdx []int, values []float64) (CSR, error) {
+	if rows < 0 || cols < 0 {
+		return CSR{}, &ShapeError{Op: "NewCSR", Axis: -1, Actual: []int{rows, cols}, Reason: "negative size"}
+	}
+	if len(rowPtr) != rows+1 {
+		return CSR{}, &ShapeError{Op: "NewCSR", Axis: 0, Expected: []int{rows + 1}, Actual: []int{len(rowPtr)}, Reason: "need rows+1 row pointers"}
+	}
+	if len(colIdx) != len(values) {
+		return CSR{}, &ShapeError{Op: "NewCSR", Axis: -1, Expected: []int{len(values)}, Actual: []int{len(colIdx)}, Reason: "need a column index per value"}
+	}
+	if rowPtr[0] != 0 || rowPtr[rows] != len(values) {
+		return CSR{}, &ShapeError{Op: "NewCSR", Axis: 0, Reason: fmt.Sprintf("row pointers must run from 0 to %d", len(values))}
+	}
+	for r := 0; r < rows; r++ {
+		if rowPtr[r+1] < rowPtr[r] {
+			return CSR{}, &ShapeError{Op: "NewCSR", Axis: 0, Reason: fmt.Sprintf("row pointer %d decreases", r+1)}
+		}
+	}
+	for _, c := range colIdx {
+		if c < 0 || c >= cols {
+			return CSR{}, &ShapeError{Op: "NewCSR", Axis: 1, Actual: []int{rows, cols}, Reason: fmt.Sprintf("column %d out of range", c)}
+		}
+	}
+	return CSR{rows: rows, cols: cols, rowPtr: rowPtr, colIdx: colIdx, values: values}, nil
+}
+
+// The nonzero elements of a 2D array
+func (a NDArray) CSR() CSR {
+	if len(a.shape) != 2 {
+		panic(&ShapeError{Op: "CSR", Axis: -1, Actual: a.shape, Reason: "need a 2D array"})
+	}
+	out := CSR{rows: a.shape[0], cols: a.shape[1], rowPtr: make([]int, a.shape[0]+1)}
+	a.ForEach(func(dataIndex int, index []int, value float64) {
+		if value != 0 {
+			out.colIdx = append(out.colIdx, index[1])
+			out.values = append(out.values, value)
+			out.rowPtr[index[0]+1]++
+		}
+	})
+	for r := 0; r < out.rows; r++ {
+		out.rowPtr[r+1] += out.rowPtr[r]
+	}
+	return out
+}
+
+func (s CSR) Shape() []int {
+	return []int{s.rows, s.cols}
+}
+
+func (s CSR) NNZ() int {
+	return len(s.values)
+}
+
+func (s CSR) Dense() NDArray {
+	arr := Zeros(s.rows, s.cols)
+	for r := 0; r < s.rows; r++ {
+		for i := s.rowPtr[r]; i < s.rowPtr[r+1]; i++ {
+			arr.data[r*s.cols+s.colIdx[i]] += s.values[i]
+		}
+	}
+	return arr
+}
+
+func (s CSR) COO() COO {
+	out := COO{rows: s.rows, cols: s.cols, rowIdx: make([]int, len(s.values)), colIdx: s.colIdx, values: s.values}
+	for r := 0; r < s.rows; r++ {
+		for i := s.rowPtr[r]; i < s.rowPtr[r+1]; i++ {
+			out.rowIdx[i] = r
+		}
+	}
+	return out
+}
+
+func (s CSR) Transpose() CSR {
+	out := CSR{
+		rows:   s.cols,
+		cols:   s.rows,
+		rowPtr: make([]int, s.cols+1),
+		colIdx: make([]int, len(s.values)),
+		values: make([]float64, len(s.values)),
+	}
+	for _, c := range s.colIdx {
+		out.rowPtr[c+1]++
+	}
+	for c := 0; c < s.cols; c++ {
+		out.rowPtr[c+1] += out.rowPtr[c]
+	}
+	next := append([]int{}, out.rowPtr[:s.cols]...)
+	for r := 0; r < s.rows; r++ {
+		for i := s.rowPtr[r]; i < s.rowPtr[r+1]; i++ {
+			c := s.colIdx[i]
+			out.colIdx[next[c]] = r
+			out.values[next[c]] = s.values[i]
+			next[c]++
+		}
+	}
+	return out
+}
+
+// s b for a 2D dense b, in the dtype of b
+func (s CSR) MatMul(b NDArray) NDArray {
+	s.checkMatMul(b)
+	dtype := b.dtype
+	if !dtype.IsFloat() {
+		dtype = Float64
+	}
+	return s.MatMulInto(b, ZerosOf(dtype, s.rows, b.shape[1]))
+}
+
+// computed in the dtype of arr, across the pool
+func (s CSR) MatMulInto(b NDArray, arr NDArray) NDArray {
+	requirePacked(arr)
+	s.checkMatMul(b)
+	if len(arr.shape) != 2 || arr.shape[0] != s.rows || arr.shape[1] != b.shape[1] {
+		panic(&ShapeError{Op: "SparseMatMul", Axis: -1, Expected: []int{s.rows, b.shape[1]}, Actual: arr.shape, Reason: "wrong output shape"})
+	}
+	if !arr.dtype.IsFloat() {
+		return b.via64Into(arr, func(b NDArray, arr NDArray) NDArray { return s.MatMulInto(b, arr) })
+	}
+	b = b.AsType(arr.dtype).Contiguous()
+	if arr.dtype == Float32 {
+		spmm(s, b.data32, arr.data32, b.shape[1])
+	} else {
+		spmm(s, b.data, arr.data, b.shape[1])
+	}
+	return arr
+}
+
+func (s CSR) checkMatMul(b NDArray) {
+	if len(b.shape) != 2 || b.shape[0] != s.cols {
+		panic(&ShapeError{Op: "SparseMatMul", Axis: 0, Actual: b.shape, Reason: fmt.Sprintf("b must be 2D with %d rows", s.cols)})
+	}
+}
+
+func spmm[T float](s CSR, b []T, c []T, n int) {
+	grain := 1
+	if s.rows > 0 {
+		grain = minGrain/(max(n, 1)*(len(s.values)/s.rows+1)) + 1
+	}
+	splitRange(s.rows, grain, func(start int, end int) {
+		for r := start; r < end; r++ {
+			row := c[r*n : (r+1)*n]
+			clear(row)
+			for i := s.rowPtr[r]; i < s.rowPtr[r+1]; i++ {
+				v, bRow := T(s.values[i]), b[s.colIdx[i]*n:(s.colIdx[i]+1)*n]
+				for j := range row {
+					row[j] += v * bRow[j]
+				}
+			}
+		}
+	})
+}
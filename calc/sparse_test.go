@@ -0,0 +1,121 @@
+package calc_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// a dense matrix with about half its elements zero
+func sparseDense(rng *calc.RNG, rows int, cols int) calc.NDArray {
+	arr := rng.Normal(0, 1, rows, cols)
+	mask := rng.Normal(0, 1, rows, cols)
+	mask.ForEach(func(_ int, index []int, m float64) {
+		if m < 0 {
+			arr.Set(index, 0)
+		}
+	})
+	return arr
+}
+
+func TestCOOToCSR(t *testing.T) {
+	// out of order, with (1, 2) given three times and (0, 0) twice
+	coo, err := calc.NewCOO(3, 4,
+		[]int{1, 0, 2, 1, 0, 1},
+		[]int{2, 3, 0, 2, 0, 2},
+		[]float64{1, 2, 3, 4, 5, -6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := calc.FromRaw([]int{3, 4}, []float64{
+		5, 0, 0, 2,
+		0, 0, -1, 0,
+		3, 0, 0, 0,
+	})
+	checkSame(t, "COO", coo.Dense(), want)
+	csr := coo.CSR()
+	if csr.NNZ() != 4 {
+		t.Errorf("CSR has %d elements, want the 4 distinct positions", csr.NNZ())
+	}
+	checkSame(t, "CSR", csr.Dense(), want)
+	checkSame(t, "CSR COO", csr.COO().Dense(), want)
+
+	for _, bad := range []struct {
+		rows, cols     int
+		rowIdx, colIdx []int
+	}{
+		{2, 2, []int{0, 2}, []int{0, 0}},
+		{2, 2, []int{0, 0}, []int{0, -1}},
+		{2, 2, []int{0}, []int{0, 1}},
+		{-1, 2, []int{0, 0}, []int{0, 1}},
+	} {
+		if _, err := calc.NewCOO(bad.rows, bad.cols, bad.rowIdx, bad.colIdx, []float64{1, 2}); err == nil {
+			t.Errorf("NewCOO(%d, %d, %v, %v) didn't fail", bad.rows, bad.cols, bad.rowIdx, bad.colIdx)
+		}
+	}
+}
+
+func TestCSRDenseRoundTrip(t *testing.T) {
+	rng := calc.NewRNG(10)
+	for _, shape := range [][]int{{5, 7}, {1, 1}, {4, 0}, {0, 3}} {
+		dense := sparseDense(rng, shape[0], shape[1])
+		csr := dense.CSR()
+		nonzero := 0
+		dense.ForEach(func(_ int, _ []int, v float64) {
+			if v != 0 {
+				nonzero++
+			}
+		})
+		if csr.NNZ() != nonzero {
+			t.Errorf("%v: CSR has %d elements, want %d", shape, csr.NNZ(), nonzero)
+		}
+		checkSame(t, "CSR", csr.Dense(), dense)
+		checkSame(t, "CSR of a transposed view", dense.Transpose(0, 1).CSR().Dense(), dense.Transpose(0, 1).Contiguous())
+		checkSame(t, "Transpose", csr.Transpose().Dense(), dense.Transpose(0, 1).Contiguous())
+		checkSame(t, "Transpose twice", csr.Transpose().Transpose().Dense(), dense)
+	}
+
+	csr, err := calc.NewCSR(2, 3, []int{0, 2, 3}, []int{0, 2, 1}, []float64{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSame(t, "NewCSR", csr.Dense(), calc.FromRaw([]int{2, 3}, []float64{1, 0, 2, 0, 3, 0}))
+	for _, rowPtr := range [][]int{{0, 2}, {1, 2, 3}, {0, 2, 2}, {0, 3, 2}} {
+		if _, err := calc.NewCSR(2, 3, rowPtr, []int{0, 2, 1}, []float64{1, 2, 3}); err == nil {
+			t.Errorf("NewCSR with row pointers %v didn't fail", rowPtr)
+		}
+	}
+	if _, err := calc.NewCSR(2, 3, []int{0, 2, 3}, []int{0, 3, 1}, []float64{1, 2, 3}); err == nil {
+		t.Error("NewCSR with a column out of range didn't fail")
+	}
+}
+
+func TestSparseMatMul(t *testing.T) {
+	rng := calc.NewRNG(11)
+	a := sparseDense(rng, 6, 5)
+	b := rng.Normal(0, 1, 5, 3)
+	csr := a.CSR()
+
+	checkClose(t, "MatMul", csr.MatMul(b), a.MatMul(b, 0, 1))
+	checkClose(t, "MatMul transposed view", csr.MatMul(b.Transpose(0, 1).Contiguous().Transpose(0, 1)), a.MatMul(b, 0, 1))
+	checkClose(t, "Transpose MatMul", csr.Transpose().MatMul(a.MatMul(b, 0, 1)), a.Transpose(0, 1).MatMul(a.MatMul(b, 0, 1), 0, 1))
+
+	got32 := csr.MatMul(b.AsType(calc.Float32))
+	if got32.DType() != calc.Float32 {
+		t.Errorf("MatMul of float32 is %s", got32.DType())
+	}
+	// scaled so checkClose allows float32 rounding
+	checkClose(t, "MatMul float32", got32.AsType(calc.Float64).MulConstant(1e-4), a.MatMul(b, 0, 1).MulConstant(1e-4))
+
+	ints := calc.FromRawInt64([]int{5, 2}, []int64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10})
+	checkClose(t, "MatMul int64", csr.MatMul(ints), a.MatMul(ints.AsType(calc.Float64), 0, 1))
+
+	func() {
+		defer func() {
+			if _, ok := recover().(*calc.ShapeError); !ok {
+				t.Error("MatMul with the wrong inner size didn't panic with a ShapeError")
+			}
+		}()
+		csr.MatMul(rng.Normal(0, 1, 6, 3))
+	}()
+}
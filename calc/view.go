@@ -0,0 +1,179 @@
+package calc
+
+import "math"
+
+// Transpose, Permute, Slice, Reverse, Reshape, ExpandDims, Squeeze, SliceRoot and ReindexRoot return views
+// sharing storage with the original array. A view has per-axis strides (which may be negative for reversed
+// axes, or zero for repeated ones) and an offset of its first element. ReindexRoot views instead have the
+// storage offset of each row of their leading axis, since reordered rows aren't evenly spaced.
+// Kernels walk views in place through their layout (see walk.go). Only the ones handing storage to blas or
+// gonum call Contiguous() first, which is free for arrays that are already packed.
+
+// whether a is laid out row-major from the start of its storage
+func (a NDArray) packed() bool {
+	return a.strides == nil
+}
+
+func packedStrides(shape []int) []int {
+	strides := make([]int, len(shape))
+	innerSize := 1
+	for i := len(shape) - 1; i >= 0; i-- {
+		strides[i] = innerSize
+		innerSize *= shape[i]
+	}
+	return strides
+}
+
+func (a NDArray) stridesOf() []int {
+	if a.packed() {
+		return packedStrides(a.shape)
+	}
+	return a.strides
+}
+
+// a view of the same storage with the given layout. If the layout turns out to be row-major, the
+// storage is resliced instead so the result is packed.
+func (a NDArray) view(shape []int, strides []int, offset int) NDArray {
+	if stridesPacked(shape, strides) {
+		arr := a.storageRange(offset, offset+shapeSize(shape))
+		arr.shape = shape
+		return arr
+	}
+	arr := a.storage()
+	arr.shape = shape
+	arr.strides = strides
+	arr.offset = offset
+	return arr
+}
+
+// a view whose leading axis rows start at offset+rows[i] in storage, which is an ordinary strided view if
+// the rows are evenly spaced
+func (a NDArray) rowsView(shape []int, rows []int, strides []int, offset int) NDArray {
+	strides = append([]int{}, strides...)
+	strides[0] = 0
+	if evenlySpaced(rows) {
+		if len(rows) > 1 {
+			strides[0] = rows[1] - rows[0]
+		}
+		if len(rows) > 0 {
+			offset += rows[0]
+		}
+		return a.view(shape, strides, offset)
+	}
+	arr := a.storage()
+	arr.shape = shape
+	arr.strides = strides
+	arr.offset = offset
+	arr.rows = rows
+	return arr
+}
+
+func evenlySpaced(rows []int) bool {
+	for i := 2; i < len(rows); i++ {
+		if rows[i]-rows[i-1] != rows[1]-rows[0] {
+			return false
+		}
+	}
+	return true
+}
+
+// ReindexRoot views can only be sliced and reversed in place, so other views are taken of a packed copy
+func (a NDArray) strided() NDArray {
+	if a.rows != nil {
+		return a.Contiguous()
+	}
+	return a
+}
+
+func stridesPacked(shape []int, strides []int) bool {
+	packed := packedStrides(shape)
+	for i := range shape {
+		// strides of unit axes don't matter
+		if shape[i] != 1 && strides[i] != packed[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// a packed array over elements [start, end) of a's storage, with no shape
+func (a NDArray) storageRange(start int, end int) NDArray {
+	arr := NDArray{dtype: a.dtype}
+	switch a.dtype {
+	case Float32:
+		arr.data32 = a.data32[start:end]
+	case Int64:
+		arr.dataI64 = a.dataI64[start:end]
+	case Bool:
+		arr.dataBool = a.dataBool[start:end]
+	default:
+		arr.data = a.data[start:end]
+	}
+	return arr
+}
+
+func shapeSize(shape []int) int {
+	size := 1
+	for _, s := range shape {
+		size *= s
+	}
+	return size
+}
+
+// The number of elements of shape, and their size in bytes at elemSize bytes each, for shapes read from
+// files. ok is false if a dimension is negative or either count overflows an int.
+func checkedShapeSize(shape []int, elemSize int) (n int, bytes int, ok bool) {
+	n = 1
+	for _, s := range shape {
+		if s < 0 || s > 0 && n > math.MaxInt/s {
+			return 0, 0, false
+		}
+		n *= s
+	}
+	if n > math.MaxInt/elemSize {
+		return 0, 0, false
+	}
+	return n, n * elemSize, true
+}
+
+// Returns a packed copy of a view, or a itself if it's already packed
+func (a NDArray) Contiguous() NDArray {
+	if a.packed() {
+		return a
+	}
+	arr := ZerosOf(a.dtype, a.shape...)
+	return a.copyInto(arr)
+}
+
+// copies the elements of a into arr, which has the same shape and dtype but may be a view
+func (a NDArray) copyInto(arr NDArray) NDArray {
+	cp := elemCopier(arr, a)
+	// arr is walked as an operand, which is safe as long as none of its elements overlap
+	walkBroadcast(arr.layout(), a.layout(), a.shape, func(arrIndex int, aIndex int, _ int) {
+		cp(arrIndex, aIndex)
+	})
+	return arr
+}
+
+// panics if arr can't be written to linearly, which every *Into kernel needs of its output
+func requirePacked(arr NDArray) {
+	if !arr.packed() {
+		panic("output of an *Into op must be packed, not a view")
+	}
+}
+
+// Whether a is the Transpose of the last two axes of a packed array, which blas can read directly
+func (a NDArray) transposedLast2() bool {
+	n := len(a.shape)
+	if a.packed() || a.rows != nil || n < 2 || a.offset != 0 {
+		return false
+	}
+	strides := packedStrides(TransposeShape(a.shape, n-2, n-1))
+	strides[n-2], strides[n-1] = strides[n-1], strides[n-2]
+	for i := range strides {
+		if strides[i] != a.strides[i] && a.shape[i] != 1 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,146 @@
+package calc_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// Views of a 4x5x6 array
+var viewCases = []struct {
+	name string
+	view func(x calc.NDArray) calc.NDArray
+}{
+	{"Transpose", func(x calc.NDArray) calc.NDArray { return x.Transpose(0, 2) }},
+	{"Slice", func(x calc.NDArray) calc.NDArray { return x.Slice(1, 1, 4) }},
+	{"Reverse", func(x calc.NDArray) calc.NDArray { return x.Reverse(0, 2) }},
+	{"Permute", func(x calc.NDArray) calc.NDArray { return x.Permute(2, 0, 1) }},
+	{"ReindexRoot", func(x calc.NDArray) calc.NDArray { return x.ReindexRoot([]int{2, 0, 3, 1}) }},
+	{"ReindexRoot Slice", func(x calc.NDArray) calc.NDArray {
+		return x.ReindexRoot([]int{3, 0, 2, 1}).Slice(0, 1, 4).Slice(2, 2, 5)
+	}},
+	{"ReindexRoot Reverse", func(x calc.NDArray) calc.NDArray { return x.ReindexRoot([]int{1, 3, 0, 2}).Reverse(0, 1) }},
+	{"Reshape Slice", func(x calc.NDArray) calc.NDArray { return x.Slice(2, 1, 5).Reshape(2, 10, 4) }},
+}
+
+// Kernels taking any rank 3 or higher array
+var viewKernels = []struct {
+	name string
+	op   func(v calc.NDArray) calc.NDArray
+}{
+	{"Exp", calc.NDArray.Exp},
+	{"Tanh", calc.NDArray.Tanh},
+	{"ReLU", calc.NDArray.ReLU},
+	{"MulConstant", func(v calc.NDArray) calc.NDArray { return v.MulConstant(3) }},
+	{"Float32", func(v calc.NDArray) calc.NDArray { return v.AsType(calc.Float32).MulConstant(2) }},
+	{"Add", func(v calc.NDArray) calc.NDArray { return v.Add(v.Sum(0)) }},
+	{"Mul", func(v calc.NDArray) calc.NDArray { return v.Mul(v) }},
+	{"Div", func(v calc.NDArray) calc.NDArray { return v.Div(v.Exp()) }},
+	{"Maximum", func(v calc.NDArray) calc.NDArray { return v.Maximum(calc.Zeros(1)) }},
+	{"Where", func(v calc.NDArray) calc.NDArray { return v.Where(v, v.MulConstant(-1)) }},
+	{"Sum", func(v calc.NDArray) calc.NDArray { return v.Sum(0) }},
+	{"Sum inner", func(v calc.NDArray) calc.NDArray { return v.Sum(1, 2) }},
+	{"Max", func(v calc.NDArray) calc.NDArray { return v.Max(2) }},
+	{"Var", func(v calc.NDArray) calc.NDArray { return v.Var(0) }},
+	{"LogSumExp", func(v calc.NDArray) calc.NDArray { return v.LogSumExp(1) }},
+	{"Normalize", func(v calc.NDArray) calc.NDArray { return v.Normalize(1) }},
+	{"InverseNormalize", func(v calc.NDArray) calc.NDArray { return v.InverseNormalize(v.Exp(), 0) }},
+	{"CumSum", func(v calc.NDArray) calc.NDArray { return v.CumSum(1) }},
+	{"ArgMax", func(v calc.NDArray) calc.NDArray { return v.ArgMax(0) }},
+	{"Sort", func(v calc.NDArray) calc.NDArray { return v.Sort(2) }},
+	{"Gather", func(v calc.NDArray) calc.NDArray { return v.Gather(v.ArgSort(1), 1) }},
+	{"IndexSelect", func(v calc.NDArray) calc.NDArray {
+		return v.IndexSelect(calc.FromRawInt64([]int{3}, []int64{1, 0, 1}), 0)
+	}},
+	{"Pad", func(v calc.NDArray) calc.NDArray {
+		return v.Pad([][2]int{{1, 0}, {0, 2}, {1, 1}}, calc.PadReflect, 0)
+	}},
+	{"Resize2D", func(v calc.NDArray) calc.NDArray { return v.Resize2D(1, 2, 5, 3, calc.ResizeBilinear) }},
+	{"Tile", func(v calc.NDArray) calc.NDArray { return v.Tile(1, 2, 1) }},
+	{"MatMul", func(v calc.NDArray) calc.NDArray { return v.MatMul(v.Transpose(1, 2), 1, 2) }},
+}
+
+func checkClose(t *testing.T, name string, got calc.NDArray, want calc.NDArray) {
+	t.Helper()
+	if !calc.ShapeEqual(got.Shape(), want.Shape()) {
+		t.Errorf("%s: shape %v, want %v", name, got.Shape(), want.Shape())
+		return
+	}
+	want.ForEach(func(_ int, index []int, w float64) {
+		if g := got.Get(index); math.Abs(g-w) > 1e-9*math.Max(1, math.Abs(w)) {
+			t.Errorf("%s: %v = %v, want %v", name, index, g, w)
+		}
+	})
+}
+
+// Kernels walk views in place, and give the same result as on a packed copy
+func TestViewKernels(t *testing.T) {
+	x := calc.NewRNG(7).Normal(0, 1, 4, 5, 6)
+	for _, c := range viewCases {
+		v := c.view(x)
+		packed := v.Contiguous()
+		for _, k := range viewKernels {
+			checkClose(t, fmt.Sprintf("%s of %s", k.name, c.name), k.op(v), k.op(packed))
+		}
+	}
+}
+
+func TestViewsShareStorage(t *testing.T) {
+	for _, c := range viewCases {
+		x := calc.NewRNG(7).Normal(0, 1, 4, 5, 6)
+		v := c.view(x)
+		want := v.Contiguous().MulConstant(2)
+		x.MulConstantInto(2, x)
+		checkClose(t, c.name, v, want)
+	}
+}
+
+func TestReindexRoot(t *testing.T) {
+	x := calc.NewRNG(7).Normal(0, 1, 4, 3)
+	indices := []int{2, 0, 3, 1}
+	v := x.ReindexRoot(indices)
+	for i, idx := range indices {
+		for j := 0; j < 3; j++ {
+			if got, want := v.Get([]int{idx, j}), x.Get([]int{i, j}); got != want {
+				t.Errorf("row %d moved to %d: [%d] = %v, want %v", i, idx, j, got, want)
+			}
+		}
+	}
+	// reindexing a reindexed view composes the permutations
+	back := make([]int, len(indices))
+	for i, idx := range indices {
+		back[idx] = i
+	}
+	checkClose(t, "ReindexRoot undone", v.ReindexRoot(back), x)
+
+	into := calc.Zeros(4, 3)
+	x.ReindexRootInto(indices, into)
+	checkClose(t, "ReindexRootInto", into, v)
+
+	for _, bad := range [][]int{{0, 1, 2}, {0, 1, 1, 2}, {0, 1, 2, 4}} {
+		func() {
+			defer func() {
+				if _, ok := recover().(*calc.ShapeError); !ok {
+					t.Errorf("ReindexRoot(%v) didn't panic with a ShapeError", bad)
+				}
+			}()
+			x.ReindexRoot(bad)
+		}()
+	}
+}
+
+// Reshaping a view copies it when the merged axes aren't evenly strided
+func TestReshapeViews(t *testing.T) {
+	x := calc.NewRNG(7).Normal(0, 1, 4, 5, 6)
+	views := []calc.NDArray{x.Transpose(1, 2), x.Reverse(2), x.Slice(1, 1, 5), x.ReindexRoot([]int{1, 3, 0, 2})}
+	for _, v := range views {
+		shape := v.Shape()
+		size := shape[0] * shape[1] * shape[2]
+		for _, newShape := range [][]int{{size}, {2, size / 2}, {2, 2, size / 4}, {shape[0], shape[1] * shape[2]}} {
+			name := fmt.Sprintf("Reshape %v to %v", shape, newShape)
+			checkClose(t, name, v.Reshape(newShape...), v.Contiguous().Reshape(newShape...))
+		}
+	}
+}
@@ -1,93 +1,136 @@
 package calc
 
-func walkBroadcast(aShape []int, bShape []int, outShape []int, f func(aIndex int, bIndex int, outIndex int)) {
-	asz, bsz := 1, 1
-	for i := range aShape {
-		asz *= aShape[i]
-		bsz *= bShape[i]
-	}
-	if canFastBroadcast(aShape, bShape) {
-		fastWalkAggr(asz, bsz, func(inIndex int, outIndex int) {
-			f(inIndex, outIndex, inIndex)
-		})
-		return
-	} else if canFastBroadcast(bShape, aShape) {
-		fastWalkAggr(bsz, asz, func(inIndex int, outIndex int) {
-			f(outIndex, inIndex, inIndex)
-		})
-		return
-	}
+// How an operand of a walk is laid out in storage: its shape, the stride of each axis and the storage index
+// of its first element. ReindexRoot views have no stride for their leading axis, and instead give the
+// storage offset of each row in rows.
+type layout struct {
+	shape   []int
+	strides []int
+	offset  int
+	rows    []int
+}
+
+func (a NDArray) layout() layout {
+	return layout{shape: a.shape, strides: a.stridesOf(), offset: a.offset, rows: a.rows}
+}
 
-	aSize := make([]int, len(aShape))
-	bSize := make([]int, len(aShape))
-	outSize := make([]int, len(aShape))
-
-	for i := len(aShape) - 1; i >= 0; i-- {
-		if i == len(aShape)-1 {
-			aSize[i] = 1
-			bSize[i] = 1
-			outSize[i] = 1
-		} else {
-			aSize[i] = aSize[i+1] * aShape[i+1]
-			bSize[i] = bSize[i+1] * bShape[i+1]
-			outSize[i] = outSize[i+1] * outShape[i+1]
+func packedLayout(shape []int) layout {
+	return layout{shape: shape, strides: packedStrides(shape)}
+}
+
+// whether l is row-major from the start of storage, so it can be indexed linearly
+func (l layout) packed() bool {
+	return l.rows == nil && l.offset == 0 && stridesPacked(l.shape, l.strides)
+}
+
+// The storage offset of each index along each axis of outShape, which l is broadcast to. Axes l doesn't
+// have or has at size 1 don't move.
+func (l layout) steps(outShape []int) [][]int {
+	lead := len(outShape) - len(l.shape)
+	steps := make([][]int, len(outShape))
+	for ax := range steps {
+		steps[ax] = make([]int, outShape[ax])
+		if ax < lead || l.shape[ax-lead] == 1 {
+			continue
+		}
+		for i := range steps[ax] {
+			if ax == lead && l.rows != nil {
+				steps[ax][i] = l.rows[i]
+			} else {
+				steps[ax][i] = i * l.strides[ax-lead]
+			}
 		}
 	}
+	return steps
+}
 
-	for i := range aShape {
-		if aShape[i] == 1 {
-			aSize[i] = 0
-		}
-		if bShape[i] == 1 {
-			bSize[i] = 0
+// the storage offset from l.offset of each index of axes [from, to) of l, in index order
+func (l layout) offsets(from int, to int) []int {
+	steps := l.steps(l.shape)
+	offsets := []int{0}
+	for ax := from; ax < to; ax++ {
+		next := make([]int, 0, len(offsets)*l.shape[ax])
+		for _, o := range offsets {
+			for _, step := range steps[ax] {
+				next = append(next, o+step)
+			}
+		}
+		offsets = next
+	}
+	return offsets
+}
+
+// f is called in parallel for different rows of outShape, so it must only write to outIndex. a and b may
+// have fewer axes than outShape, and aIndex and bIndex are storage indices.
+func walkBroadcast(a layout, b layout, outShape []int, f func(aIndex int, bIndex int, outIndex int)) {
+	if len(outShape) == 0 {
+		f(a.offset, b.offset, 0)
+		return
+	}
+	if a.packed() && b.packed() {
+		aShape, bShape := padShape(a.shape, len(outShape)), padShape(b.shape, len(outShape))
+		rowSize := shapeSize(outShape[1:])
+		if canFastBroadcast(aShape, bShape) {
+			parallelRows(outShape, func(start int, end int) {
+				fastWalkAggr(start*rowSize, end*rowSize, shapeSize(bShape), func(inIndex int, outIndex int) {
+					f(inIndex, outIndex, inIndex)
+				})
+			})
+			return
+		} else if canFastBroadcast(bShape, aShape) {
+			parallelRows(outShape, func(start int, end int) {
+				fastWalkAggr(start*rowSize, end*rowSize, shapeSize(aShape), func(inIndex int, outIndex int) {
+					f(outIndex, inIndex, inIndex)
+				})
+			})
+			return
 		}
 	}
 
+	aSteps, bSteps := a.steps(outShape), b.steps(outShape)
+	outSize := packedStrides(outShape)
+
 	var walk func(int, int, int, int)
 	walk = func(aIndex int, bIndex int, outIndex int, axis int) {
-		if axis == len(aShape) {
+		if axis == len(outShape) {
 			f(aIndex, bIndex, outIndex)
 			return
 		}
 
-		outInc := outSize[axis]
-		aInc := aSize[axis]
-		bInc := bSize[axis]
-
+		aStep, bStep, outInc := aSteps[axis], bSteps[axis], outSize[axis]
 		for i := 0; i < outShape[axis]; i++ {
-			walk(aIndex, bIndex, outIndex, axis+1)
-
+			walk(aIndex+aStep[i], bIndex+bStep[i], outIndex, axis+1)
 			outIndex += outInc
-			aIndex += aInc
-			bIndex += bInc
 		}
 	}
 
-	walk(0, 0, 0, 0)
+	parallelRows(outShape, func(start int, end int) {
+		for i := start; i < end; i++ {
+			walk(a.offset+aSteps[0][i], b.offset+bSteps[0][i], i*outSize[0], 1)
+		}
+	})
 }
 
-func walkAggr(inShape []int, outShape []int, f func(inIndex int, outIndex int)) {
-	if canFastAggr(outShape) {
-		inSz := 1
-		for i := range inShape {
-			inSz *= inShape[i]
-		}
-		fastWalkAggr(inSz, outShape[len(outShape)-1], f)
+// calls f(inIndex, outIndex) for each element of a in index order, with inIndex its storage index and
+// outIndex its position, in parallel for different rows
+func walkElems(a layout, f func(inIndex int, outIndex int)) {
+	walkBroadcast(a, a, a.shape, func(aIndex int, _ int, outIndex int) {
+		f(aIndex, outIndex)
+	})
+}
+
+// walks rows [start, end) of the leading axis of in, with inIndex a storage index
+func walkAggrRows(in layout, outShape []int, start int, end int, f func(inIndex int, outIndex int)) {
+	if start >= end {
 		return
 	}
-	inSize := make([]int, len(inShape))
-	outSize := make([]int, len(inShape))
-
-	for i := len(inShape) - 1; i >= 0; i-- {
-		if i == len(inShape)-1 {
-			inSize[i] = 1
-			outSize[i] = 1
-		} else {
-			inSize[i] = inSize[i+1] * inShape[i+1]
-			outSize[i] = outSize[i+1] * outShape[i+1]
-		}
+	if in.packed() && canFastAggr(outShape) {
+		rowSize := shapeSize(in.shape[1:])
+		fastWalkAggr(start*rowSize, end*rowSize, outShape[len(outShape)-1], f)
+		return
 	}
-
+	inSteps := in.steps(in.shape)
+	outSize := packedStrides(outShape)
 	for i := range outShape {
 		if outShape[i] == 1 {
 			outSize[i] = 0
@@ -96,56 +139,21 @@ func walkAggr(inShape []int, outShape []int, f func(inIndex int, outIndex int))
 
 	var walk func(int, int, int)
 	walk = func(inIndex int, outIndex int, axis int) {
-		if axis == len(inShape) {
+		if axis == len(in.shape) {
 			f(inIndex, outIndex)
 			return
 		}
 
-		for i := 0; i < inShape[axis]; i++ {
-			walk(inIndex, outIndex, axis+1)
-
+		inStep := inSteps[axis]
+		for i := 0; i < in.shape[axis]; i++ {
+			walk(inIndex+inStep[i], outIndex, axis+1)
 			outIndex += outSize[axis]
-			inIndex += inSize[axis]
 		}
 	}
 
-	walk(0, 0, 0)
-}
-
-func walkSlice(inShape []int, outShape []int, sliceAxis int, offset int, f func(inIndex int, outIndex int)) {
-	inSize := make([]int, len(inShape))
-	outSize := make([]int, len(inShape))
-
-	for i := len(inShape) - 1; i >= 0; i-- {
-		if i == len(inShape)-1 {
-			inSize[i] = 1
-			outSize[i] = 1
-		} else {
-			inSize[i] = inSize[i+1] * inShape[i+1]
-			outSize[i] = outSize[i+1] * outShape[i+1]
-		}
+	for i := start; i < end; i++ {
+		walk(in.offset+inSteps[0][i], i*outSize[0], 1)
 	}
-
-	var walk func(int, int, int)
-	walk = func(inIndex int, outIndex int, axis int) {
-		if axis == len(inShape) {
-			f(inIndex, outIndex)
-			return
-		}
-
-		if axis == sliceAxis {
-			inIndex += offset * inSize[axis]
-		}
-
-		for i := 0; i < outShape[axis]; i++ {
-			walk(inIndex, outIndex, axis+1)
-
-			outIndex += outSize[axis]
-			inIndex += inSize[axis]
-		}
-	}
-
-	walk(0, 0, 0)
 }
 
 func canFastAggr(outShape []int) bool {
@@ -157,10 +165,17 @@ func canFastAggr(outShape []int) bool {
 	return true
 }
 
-func fastWalkAggr(inSize int, outSize int, f func(inIndex int, outIndex int)) {
-	for inIndex := 0; inIndex < inSize; inIndex += outSize {
-		for outIndex := 0; outIndex < outSize; outIndex++ {
-			f(inIndex+outIndex, outIndex)
+// walks inputs [start, end), whose outputs repeat every outSize
+func fastWalkAggr(start int, end int, outSize int, f func(inIndex int, outIndex int)) {
+	if start >= end {
+		return
+	}
+	outIndex := start % outSize
+	for inIndex := start; inIndex < end; inIndex++ {
+		f(inIndex, outIndex)
+		outIndex++
+		if outIndex == outSize {
+			outIndex = 0
 		}
 	}
 }
@@ -61,17 +61,15 @@ func bwFloat(shape []int, data []byte) calc.NDArray {
 }
 
 func categorical10(shape []int, data []byte) calc.NDArray {
-	shape = []int{shape[0], 10}
-	fData := make([]float64, len(data)*10)
-	labels := make([][]float64, 10)
-	for i := range labels {
-		labels[i] = make([]float64, 10)
-		labels[i][i] = 1.0
-	}
+	return sparse(shape, data).OneHot(10)
+}
+
+func sparse(shape []int, data []byte) calc.NDArray {
+	labels := make([]int64, len(data))
 	for i, v := range data {
-		copy(fData[i*10:], labels[v])
+		labels[i] = int64(v)
 	}
-	return calc.FromRaw(shape, fData)
+	return calc.FromRawInt64(shape, labels)
 }
 
 func LoadMNIST() (XTrain, YTrain, XTest, YTest calc.NDArray) {
@@ -80,3 +78,11 @@ func LoadMNIST() (XTrain, YTrain, XTest, YTest calc.NDArray) {
 		bwFloat(loadIDX("dataset/t10k-images-idx3-ubyte.gz")),
 		categorical10(loadIDX("dataset/t10k-labels-idx1-ubyte.gz"))
 }
+
+// Like LoadMNIST, but the labels are Int64 class indices instead of one-hot
+func LoadMNISTSparse() (XTrain, YTrain, XTest, YTest calc.NDArray) {
+	return bwFloat(loadIDX("dataset/train-images-idx3-ubyte.gz")),
+		sparse(loadIDX("dataset/train-labels-idx1-ubyte.gz")),
+		bwFloat(loadIDX("dataset/t10k-images-idx3-ubyte.gz")),
+		sparse(loadIDX("dataset/t10k-labels-idx1-ubyte.gz"))
+}
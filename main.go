@@ -2,7 +2,6 @@ package main
 
 import (
 	"fmt"
-	"math/rand"
 	"reflect"
 	"strings"
 	"time"
@@ -14,7 +13,9 @@ import (
 )
 
 func main() {
-	rand.Seed(time.Now().UnixNano())
+	seed := uint64(time.Now().UnixNano())
+	fmt.Printf("seed %d\n", seed)
+	rng := calc.NewRNG(seed)
 
 	batchSize := 100
 
@@ -34,7 +35,7 @@ func main() {
 	x := tensor.Input(batchSize, 28, 28, 1)
 	y := tensor.Input(batchSize, 10)
 
-	m := model.NewModel()
+	m := model.NewModel(rng.Split())
 
 	var t tensor.Tensor = tensor.Reshape(x, batchSize, 28, 28, 1)
 
@@ -62,7 +63,7 @@ func main() {
 	const lr = 1e-2
 	opt := model.SGDMomentumOptimizer{LR: lr, Momentum: 0.1, Nesterov: true}
 
-	m.Compile(&opt, x, y, pred, loss, tensor.CategoricalAccuracy(y, pred))
+	m.MustCompile(&opt, x, y, pred, loss, tensor.CategoricalAccuracy(y, pred))
 
 	const epochs = 10
 
@@ -72,7 +73,7 @@ func main() {
 	}
 
 	for epoch := 0; epoch < epochs; epoch++ {
-		rand.Shuffle(len(index), func(i, j int) { index[i], index[j] = index[j], index[i] })
+		rng.Shuffle(len(index), func(i, j int) { index[i], index[j] = index[j], index[i] })
 		X = X.ReindexRoot(index)
 		Y = Y.ReindexRoot(index)
 
@@ -48,7 +48,7 @@ func BenchmarkModel(b *testing.B) {
 	x := tensor.Input(batchSize, 28, 28, 1)
 	y := tensor.Input(batchSize, 10)
 
-	m := model.NewModel()
+	m := model.NewModel(calc.NewRNG(1))
 
 	var t tensor.Tensor = tensor.Reshape(x, batchSize, 28, 28, 1)
 
@@ -75,7 +75,7 @@ func BenchmarkModel(b *testing.B) {
 
 	opt := model.SGDOptimizer{LR: 0.001}
 
-	m.Compile(&opt, x, y, pred, loss, tensor.CategoricalAccuracy(y, pred))
+	m.MustCompile(&opt, x, y, pred, loss, tensor.CategoricalAccuracy(y, pred))
 	b.ResetTimer()
 	b.ReportAllocs()
 
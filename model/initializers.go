@@ -6,32 +6,64 @@ import (
 	"github.com/tsholmes/go-dl/calc"
 )
 
-type Initializer func(shape ...int) calc.NDArray
+// Initial weight values of shape, with any randomness drawn from rng
+type Initializer func(rng *calc.RNG, shape ...int) calc.NDArray
 
-func GlorotUniform(shape ...int) calc.NDArray {
+func GlorotUniform(rng *calc.RNG, shape ...int) calc.NDArray {
 	fan_in := shape[len(shape)-2]
 	fan_out := shape[len(shape)-1]
 	init := math.Sqrt(6.0 / float64(fan_in+fan_out))
-	return calc.RandomUniform(-init, init, shape...)
+	return rng.Uniform(-init, init, shape...)
 }
 
-func GlorotNormal(shape ...int) calc.NDArray {
+func GlorotNormal(rng *calc.RNG, shape ...int) calc.NDArray {
 	fan_in := shape[len(shape)-2]
 	fan_out := shape[len(shape)-1]
 	init := math.Sqrt(2.0 / float64(fan_in+fan_out))
-	return calc.RandomNormal(0, init, shape...)
+	return rng.Normal(0, init, shape...)
 }
 
-func LecunNormal(shape ...int) calc.NDArray {
+func LecunNormal(rng *calc.RNG, shape ...int) calc.NDArray {
 	fan_in := shape[len(shape)-2]
 	init := math.Sqrt(1.0 / float64(fan_in))
-	return calc.RandomNormal(0, init, shape...)
+	return rng.Normal(0, init, shape...)
 }
 
-func Zeros(shape ...int) calc.NDArray {
+func Uniform(min float64, max float64) Initializer {
+	return func(rng *calc.RNG, shape ...int) calc.NDArray {
+		return rng.Uniform(min, max, shape...)
+	}
+}
+
+func Zeros(rng *calc.RNG, shape ...int) calc.NDArray {
 	return calc.Zeros(shape...)
 }
 
-func Ones(shape ...int) calc.NDArray {
+func Ones(rng *calc.RNG, shape ...int) calc.NDArray {
 	return calc.Ones(shape...)
 }
+
+// Random matrices over the last two axes with orthonormal rows or columns, whichever there are fewer of,
+// scaled by gain
+func Orthogonal(gain float64) Initializer {
+	return func(rng *calc.RNG, shape ...int) calc.NDArray {
+		n := len(shape)
+		rows, cols := shape[n-2], shape[n-1]
+		tall := append(append([]int{}, shape[:n-2]...), max(rows, cols), min(rows, cols))
+		q, r := rng.Normal(0, 1, tall...).QR()
+
+		// flip columns to make the diagonal of r positive, so q is uniformly distributed
+		signs := calc.Zeros(append(append([]int{}, shape[:n-2]...), 1, min(rows, cols))...)
+		signs.ForEach(func(dataIndex int, index []int, value float64) {
+			diag := append([]int{}, index...)
+			diag[n-2] = index[n-1]
+			signs.Set(index, math.Copysign(gain, r.Get(diag)))
+		})
+		q = q.Mul(signs)
+
+		if rows < cols {
+			return q.Transpose(n-2, n-1).Contiguous()
+		}
+		return q
+	}
+}
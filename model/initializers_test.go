@@ -0,0 +1,88 @@
+package model_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/model"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+func TestInitializers(t *testing.T) {
+	rng := calc.NewRNG(6)
+	limit := math.Sqrt(6. / 7)
+	model.GlorotUniform(rng, 2, 3, 4).ForEach(func(_ int, index []int, v float64) {
+		if math.Abs(v) > limit {
+			t.Errorf("GlorotUniform %v = %v, beyond %v", index, v, limit)
+		}
+	})
+	for name, init := range map[string]model.Initializer{"GlorotUniform": model.GlorotUniform, "GlorotNormal": model.GlorotNormal, "LecunNormal": model.LecunNormal, "Orthogonal": model.Orthogonal(1)} {
+		a, b := init(calc.NewRNG(7), 3, 5), init(calc.NewRNG(7), 3, 5)
+		a.ForEach(func(_ int, index []int, v float64) {
+			if b.Get(index) != v {
+				t.Errorf("%s from the same seed differs at %v", name, index)
+			}
+		})
+	}
+
+	// orthonormal rows when there are fewer rows, and columns otherwise, scaled by the gain
+	for _, shape := range [][]int{{3, 5}, {5, 3}, {2, 4, 4}} {
+		q := model.Orthogonal(2)(rng, shape...)
+		n := len(shape)
+		small := q
+		if shape[n-2] < shape[n-1] {
+			small = q.Transpose(n-2, n-1)
+		}
+		gram := small.Transpose(n-2, n-1).MatMul(small, n-2, n-1)
+		gram.ForEach(func(_ int, index []int, v float64) {
+			want := 0.
+			if index[n-2] == index[n-1] {
+				want = 4
+			}
+			if math.Abs(v-want) > 1e-9 {
+				t.Errorf("Orthogonal %v: gram %v = %v, want %v", shape, index, v, want)
+			}
+		})
+	}
+}
+
+// a small model trained from seed, giving its losses and final weights
+func trainSeeded(seed uint64) ([]float64, []calc.NDArray) {
+	m := model.NewModel(calc.NewRNG(seed))
+	x, y := tensor.Input(8, 3), tensor.Input(8, 2)
+	pred := tensor.Softmax(model.Dense(m, tensor.Tanh(model.Dense(m, x, 4, true)), 2, true))
+	m.MustCompile(&model.SGDOptimizer{LR: 0.1}, x, y, pred, tensor.CategoricalCrossEntropy(y, pred))
+
+	data := calc.NewRNG(seed + 1)
+	X, Y := data.Normal(0, 1, 8, 3), calc.Zeros(8, 2)
+	for i := 0; i < 8; i++ {
+		Y.Set([]int{i, data.IntN(2)}, 1)
+	}
+	var losses []float64
+	for i := 0; i < 5; i++ {
+		loss, _ := m.Train(X, Y)
+		losses = append(losses, loss)
+	}
+	return losses, m.Weights()
+}
+
+func TestSeededTrainingReproducible(t *testing.T) {
+	lossesA, weightsA := trainSeeded(8)
+	lossesB, weightsB := trainSeeded(8)
+	for i := range lossesA {
+		if lossesA[i] != lossesB[i] {
+			t.Errorf("loss %d = %v, then %v", i, lossesA[i], lossesB[i])
+		}
+	}
+	for i, w := range weightsA {
+		w.ForEach(func(_ int, index []int, v float64) {
+			if weightsB[i].Get(index) != v {
+				t.Errorf("weight %d %v = %v, then %v", i, index, v, weightsB[i].Get(index))
+			}
+		})
+	}
+	if lossesC, _ := trainSeeded(9); lossesC[0] == lossesA[0] {
+		t.Error("another seed gave the same loss")
+	}
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"github.com/tsholmes/go-dl/calc"
 	"github.com/tsholmes/go-dl/tensor"
 )
 
@@ -22,7 +23,11 @@ func Dense(m *Model, x tensor.Tensor, size int, useBias bool) tensor.Tensor {
 
 	weight := m.AddWeight(wShape...)
 
-	x = tensor.MatMul(x, weight, axis-1, axis)
+	if tensor.IsSparse(x) {
+		x = tensor.SparseMatMul(x, weight)
+	} else {
+		x = tensor.MatMul(x, weight, axis-1, axis)
+	}
 
 	if useBias {
 		bShape := onesLike(x)
@@ -35,7 +40,33 @@ func Dense(m *Model, x tensor.Tensor, size int, useBias bool) tensor.Tensor {
 	return x
 }
 
+func Conv1D(m *Model, x tensor.Tensor, kernelW int, filters int) tensor.Tensor {
+	return Conv1DWith(m, x, kernelW, filters, calc.ConvOpts{})
+}
+
+func Conv1DWith(m *Model, x tensor.Tensor, kernelW int, filters int, opts calc.ConvOpts) tensor.Tensor {
+	slen := len(x.Shape())
+	fAxis := slen - 1
+	wAxis := slen - 2
+	inFilters := x.Shape()[fAxis]
+
+	biasShape := onesLike(x)
+	biasShape[fAxis] = filters
+
+	weight := m.AddWeight(kernelW, inFilters, filters)
+	bias := m.AddBias(biasShape...)
+
+	x = tensor.Conv1D(x, weight, wAxis, fAxis, opts)
+	x = tensor.Add(x, bias)
+
+	return x
+}
+
 func Conv2D(m *Model, x tensor.Tensor, kernelH int, kernelW int, filters int) tensor.Tensor {
+	return Conv2DWith(m, x, kernelH, kernelW, filters, calc.ConvOpts{})
+}
+
+func Conv2DWith(m *Model, x tensor.Tensor, kernelH int, kernelW int, filters int, opts calc.ConvOpts) tensor.Tensor {
 	slen := len(x.Shape())
 	fAxis := slen - 1
 	wAxis := slen - 2
@@ -48,7 +79,105 @@ func Conv2D(m *Model, x tensor.Tensor, kernelH int, kernelW int, filters int) te
 	weight := m.AddWeight(kernelH, kernelW, inFilters, filters)
 	bias := m.AddBias(biasShape...)
 
-	x = tensor.Conv2D(x, weight, hAxis, wAxis, fAxis)
+	x = tensor.Conv2D(x, weight, hAxis, wAxis, fAxis, opts)
+	x = tensor.Add(x, bias)
+
+	return x
+}
+
+// Convolves each input filter separately with depthMultiplier kernels of its own
+func DepthwiseConv2D(m *Model, x tensor.Tensor, kernelH int, kernelW int, depthMultiplier int) tensor.Tensor {
+	return DepthwiseConv2DWith(m, x, kernelH, kernelW, depthMultiplier, calc.ConvOpts{})
+}
+
+func DepthwiseConv2DWith(m *Model, x tensor.Tensor, kernelH int, kernelW int, depthMultiplier int, opts calc.ConvOpts) tensor.Tensor {
+	slen := len(x.Shape())
+	fAxis := slen - 1
+	wAxis := slen - 2
+	hAxis := slen - 3
+	inFilters := x.Shape()[fAxis]
+	filters := inFilters * depthMultiplier
+
+	biasShape := onesLike(x)
+	biasShape[fAxis] = filters
+
+	weight := m.AddWeight(kernelH, kernelW, 1, filters)
+	bias := m.AddBias(biasShape...)
+
+	opts.Groups = inFilters
+	x = tensor.Conv2D(x, weight, hAxis, wAxis, fAxis, opts)
+	x = tensor.Add(x, bias)
+
+	return x
+}
+
+// A depthwise convolution followed by a 1x1 convolution mixing the filters
+func SeparableConv2D(m *Model, x tensor.Tensor, kernelH int, kernelW int, filters int) tensor.Tensor {
+	return SeparableConv2DWith(m, x, kernelH, kernelW, filters, calc.ConvOpts{})
+}
+
+// opts only apply to the depthwise convolution
+func SeparableConv2DWith(m *Model, x tensor.Tensor, kernelH int, kernelW int, filters int, opts calc.ConvOpts) tensor.Tensor {
+	slen := len(x.Shape())
+	fAxis := slen - 1
+	wAxis := slen - 2
+	hAxis := slen - 3
+	inFilters := x.Shape()[fAxis]
+
+	biasShape := onesLike(x)
+	biasShape[fAxis] = filters
+
+	depthwise := m.AddWeight(kernelH, kernelW, 1, inFilters)
+	pointwise := m.AddWeight(1, 1, inFilters, filters)
+	bias := m.AddBias(biasShape...)
+
+	opts.Groups = inFilters
+	x = tensor.Conv2D(x, depthwise, hAxis, wAxis, fAxis, opts)
+	x = tensor.Conv2D(x, pointwise, hAxis, wAxis, fAxis, calc.ConvOpts{})
+	x = tensor.Add(x, bias)
+
+	return x
+}
+
+// Learned upsampling, mapping x back through a convolution with the given opts (usually strided)
+func Conv2DTranspose(m *Model, x tensor.Tensor, kernelH int, kernelW int, filters int, opts calc.ConvOpts) tensor.Tensor {
+	slen := len(x.Shape())
+	fAxis := slen - 1
+	wAxis := slen - 2
+	hAxis := slen - 3
+	inFilters := x.Shape()[fAxis]
+
+	biasShape := onesLike(x)
+	biasShape[fAxis] = filters
+
+	weight := m.AddWeight(kernelH, kernelW, filters, inFilters)
+	bias := m.AddBias(biasShape...)
+
+	x = tensor.Conv2DTranspose(x, weight, hAxis, wAxis, fAxis, opts)
+	x = tensor.Add(x, bias)
+
+	return x
+}
+
+func Conv3D(m *Model, x tensor.Tensor, kernelD int, kernelH int, kernelW int, filters int) tensor.Tensor {
+	return Conv3DWith(m, x, kernelD, kernelH, kernelW, filters, calc.ConvOpts{})
+}
+
+func Conv3DWith(m *Model, x tensor.Tensor, kernelD int, kernelH int, kernelW int, filters int, opts calc.ConvOpts) tensor.Tensor {
+	slen := len(x.Shape())
+	fAxis := slen - 1
+	wAxis := slen - 2
+	hAxis := slen - 3
+	dAxis := slen - 4
+	inFilters := x.Shape()[fAxis]
+
+	biasShape := onesLike(x)
+	biasShape[fAxis] = filters
+
+	weight := m.AddWeight(kernelD, kernelH, kernelW, inFilters, filters)
+	bias := m.AddBias(biasShape...)
+
+	x = tensor.Conv3D(x, weight, dAxis, hAxis, wAxis, fAxis, opts)
 	x = tensor.Add(x, bias)
 
 	return x
@@ -155,6 +284,15 @@ func MaxPooling2D(m *Model, x tensor.Tensor, poolH int, poolW int) tensor.Tensor
 	return x
 }
 
+// Scales the height and width of x up by sizeH and sizeW
+func UpSampling2D(m *Model, x tensor.Tensor, sizeH int, sizeW int, method calc.ResizeMethod) tensor.Tensor {
+	slen := len(x.Shape())
+	wAxis := slen - 2
+	hAxis := slen - 3
+
+	return tensor.UpSample2D(x, hAxis, wAxis, sizeH, sizeW, method)
+}
+
 func BatchNormalization(m *Model, x tensor.Tensor) tensor.Tensor {
 	lastAxis := len(x.Shape()) - 1
 	norm := tensor.Normalize(x, lastAxis)
@@ -167,3 +305,16 @@ func BatchNormalization(m *Model, x tensor.Tensor) tensor.Tensor {
 
 	return tensor.Add(tensor.Mul(norm, gamma), beta)
 }
+
+// Looks up a trainable vector of size dim for each of the integer indices in x, which must be below vocab
+func Embedding(m *Model, x tensor.Tensor, vocab int, dim int) tensor.Tensor {
+	table := m.AddWeightWith(Uniform(-0.05, 0.05), vocab, dim)
+
+	size := 1
+	for _, s := range x.Shape() {
+		size *= s
+	}
+	rows := tensor.IndexSelect(table, tensor.Reshape(x, size), 0)
+
+	return tensor.Reshape(rows, append(append([]int{}, x.Shape()...), dim)...)
+}
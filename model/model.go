@@ -1,20 +1,36 @@
 package model
 
 import (
+	"errors"
+	"fmt"
+	"io"
+
 	"github.com/tsholmes/go-dl/calc"
 	"github.com/tsholmes/go-dl/tensor"
 )
 
-func NewModel() *Model {
+// Weights are initialized from rng, so models built the same way from the same seed are identical
+func NewModel(rng *calc.RNG) *Model {
+	return NewModelOf(calc.Float64, rng)
+}
+
+// Weights are stored and trained as dtype
+func NewModelOf(dtype calc.DType, rng *calc.RNG) *Model {
 	return &Model{
+		dtype:             dtype,
+		rng:               rng,
 		weightInitializer: GlorotUniform,
 		biasInitializer:   Zeros,
 	}
 }
 
 type Model struct {
-	weights    []tensor.Tensor
-	weightVals []calc.NDArray
+	dtype calc.DType
+	rng   *calc.RNG
+
+	weights     []tensor.Tensor
+	weightVals  []calc.NDArray
+	weightNames []string
 
 	weightInitializer Initializer
 	biasInitializer   Initializer
@@ -45,29 +61,65 @@ func (m *Model) AddBias(shape ...int) tensor.Tensor {
 }
 
 func (m *Model) AddWeightWith(init Initializer, shape ...int) tensor.Tensor {
-	t := tensor.Input(shape...)
-	v := init(shape...)
+	t := tensor.InputOf(m.dtype, shape...)
+	v := init(m.rng, shape...).AsType(m.dtype)
 
 	m.weights = append(m.weights, t)
 	m.weightVals = append(m.weightVals, v)
+	m.weightNames = append(m.weightNames, fmt.Sprintf("weight_%d", len(m.weights)-1))
 
 	return t
 }
 
-func (m *Model) Compile(opt Optimizer, input tensor.Tensor, yTrue tensor.Tensor, yPred tensor.Tensor, loss tensor.Tensor, metrics ...tensor.Tensor) {
+// Builds the training, test and prediction graphs. Errors if the graph is incomplete or an op it adds
+// can't work with the shapes it's given.
+func (m *Model) Compile(opt Optimizer, input tensor.Tensor, yTrue tensor.Tensor, yPred tensor.Tensor, loss tensor.Tensor, metrics ...tensor.Tensor) error {
+	for _, t := range []struct {
+		name string
+		t    tensor.Tensor
+	}{{"input", input}, {"yTrue", yTrue}, {"yPred", yPred}, {"loss", loss}} {
+		if t.t == nil {
+			return fmt.Errorf("compile: %s is nil", t.name)
+		}
+	}
+	if opt == nil {
+		return errors.New("compile: optimizer is nil")
+	}
+	if !dependsOn(yPred, input) {
+		return errors.New("compile: yPred doesn't depend on input")
+	}
+	if !dependsOn(loss, yTrue) || !dependsOn(loss, yPred) {
+		return errors.New("compile: loss doesn't depend on both yTrue and yPred")
+	}
+
+	metrics = append([]tensor.Tensor{}, metrics...)
+	weightGradients, err := tensor.Build(func() []tensor.Tensor {
+		for i, mt := range metrics {
+			metrics[i] = tensor.Mean(tensor.Flatten(mt, 0), 0)
+		}
+
+		gradients := tensor.Gradients(loss)
+		var weightGradients []tensor.Tensor
+		for _, w := range m.weights {
+			weightGradients = append(weightGradients, gradients[w.ID()])
+		}
+		return weightGradients
+	})
+	if err != nil {
+		return fmt.Errorf("compile: %w", err)
+	}
+	for i, g := range weightGradients {
+		if g == nil {
+			return fmt.Errorf("compile: loss doesn't depend on weight %d with shape %v", i, m.weights[i].Shape())
+		}
+	}
+
 	m.input = input
 	m.yTrue = yTrue
 	m.yPred = yPred
 	m.loss = loss
 	m.metrics = metrics
-	for i, mt := range m.metrics {
-		m.metrics[i] = tensor.Mean(tensor.Flatten(mt, 0), 0)
-	}
-
-	gradients := tensor.Gradients(loss)
-	for _, w := range m.weights {
-		m.weightGradients = append(m.weightGradients, gradients[w.ID()])
-	}
+	m.weightGradients = weightGradients
 
 	trainTs := []tensor.Tensor{loss}
 	trainTs = append(trainTs, metrics...)
@@ -78,6 +130,24 @@ func (m *Model) Compile(opt Optimizer, input tensor.Tensor, yTrue tensor.Tensor,
 	m.predictEval = tensor.MakeEvaluation(yPred)
 
 	m.opt = opt
+	return nil
+}
+
+// Compile, panicking on error
+func (m *Model) MustCompile(opt Optimizer, input tensor.Tensor, yTrue tensor.Tensor, yPred tensor.Tensor, loss tensor.Tensor, metrics ...tensor.Tensor) {
+	if err := m.Compile(opt, input, yTrue, yPred, loss, metrics...); err != nil {
+		panic(err)
+	}
+}
+
+// whether t is computed from input
+func dependsOn(t tensor.Tensor, input tensor.Tensor) bool {
+	for _, d := range tensor.CollectForward([]tensor.Tensor{t}) {
+		if d.ID() == input.ID() {
+			return true
+		}
+	}
+	return false
 }
 
 func (m *Model) WeightProvisions() []tensor.ProvidedInput {
@@ -89,8 +159,17 @@ func (m *Model) WeightProvisions() []tensor.ProvidedInput {
 }
 
 func (m *Model) Train(X calc.NDArray, Y calc.NDArray) (float64, []float64) {
+	return m.train(tensor.Provide(m.input, X), Y)
+}
+
+// Train for a model whose input is a tensor.SparseInput
+func (m *Model) TrainSparse(X calc.CSR, Y calc.NDArray) (float64, []float64) {
+	return m.train(tensor.ProvideSparse(m.input, X), Y)
+}
+
+func (m *Model) train(X tensor.ProvidedInput, Y calc.NDArray) (float64, []float64) {
 	provisions := append([]tensor.ProvidedInput{
-		tensor.Provide(m.input, X),
+		X,
 		tensor.Provide(m.yTrue, Y),
 	}, m.WeightProvisions()...)
 
@@ -120,8 +199,17 @@ func (m *Model) DebugTrain() {
 }
 
 func (m *Model) Test(X calc.NDArray, Y calc.NDArray) (float64, []float64) {
+	return m.test(tensor.Provide(m.input, X), Y)
+}
+
+// Test for a model whose input is a tensor.SparseInput
+func (m *Model) TestSparse(X calc.CSR, Y calc.NDArray) (float64, []float64) {
+	return m.test(tensor.ProvideSparse(m.input, X), Y)
+}
+
+func (m *Model) test(X tensor.ProvidedInput, Y calc.NDArray) (float64, []float64) {
 	provisions := append([]tensor.ProvidedInput{
-		tensor.Provide(m.input, X),
+		X,
 		tensor.Provide(m.yTrue, Y),
 	}, m.WeightProvisions()...)
 	eval := m.testEval.Evaluate(provisions...)
@@ -143,13 +231,28 @@ func (m *Model) Test(X calc.NDArray, Y calc.NDArray) (float64, []float64) {
 	return loss.Mean(allAxes...).Get(make([]int, len(loss.Shape()))), mvals
 }
 
+// The result is only valid until the next call to Predict
 func (m *Model) Predict(X calc.NDArray) calc.NDArray {
-	provisions := append([]tensor.ProvidedInput{
-		tensor.Provide(m.input, X),
-	}, m.WeightProvisions()...)
+	return m.predict(tensor.Provide(m.input, X))
+}
+
+// Predict for a model whose input is a tensor.SparseInput
+func (m *Model) PredictSparse(X calc.CSR) calc.NDArray {
+	return m.predict(tensor.ProvideSparse(m.input, X))
+}
+
+func (m *Model) predict(X tensor.ProvidedInput) calc.NDArray {
+	provisions := append([]tensor.ProvidedInput{X}, m.WeightProvisions()...)
 	return m.predictEval.Evaluate(provisions...)[0]
 }
 
+// The index of the largest prediction along the last axis, as an Int64 array with that axis removed.
+func (m *Model) PredictClasses(X calc.NDArray) calc.NDArray {
+	pred := m.Predict(X)
+	shape := pred.Shape()
+	return pred.ArgMax(len(shape) - 1).Reshape(shape[:len(shape)-1]...)
+}
+
 func (m *Model) WeightMags() []float64 {
 	var res []float64
 	for _, w := range m.weightVals {
@@ -158,10 +261,101 @@ func (m *Model) WeightMags() []float64 {
 	return res
 }
 
+func (m *Model) DType() calc.DType {
+	return m.dtype
+}
+
+// The source weights are initialized from, for layers that need more randomness
+func (m *Model) RNG() *calc.RNG {
+	return m.rng
+}
+
 func (m *Model) Weights() []calc.NDArray {
 	return m.weightVals
 }
 
+// The names weights are saved and loaded by, matching Weights(). They default to weight_<index>.
+func (m *Model) WeightNames() []string {
+	return m.weightNames
+}
+
+// Sets the name of weight t, which must be unique
+func (m *Model) NameWeight(t tensor.Tensor, name string) {
+	i := m.weightIndex(t)
+	for j, n := range m.weightNames {
+		if n == name && j != i {
+			panic(fmt.Sprintf("weight name %q is already used", name))
+		}
+	}
+	m.weightNames[i] = name
+}
+
+func (m *Model) weightIndex(t tensor.Tensor) int {
+	for i, w := range m.weights {
+		if w.ID() == t.ID() {
+			return i
+		}
+	}
+	panic(fmt.Sprintf("tensor %d is not a weight of this model", t.ID()))
+}
+
+// the weights keyed by name
+func (m *Model) weightArrays() map[string]calc.NDArray {
+	arrays := map[string]calc.NDArray{}
+	for i, name := range m.weightNames {
+		arrays[name] = m.weightVals[i]
+	}
+	return arrays
+}
+
+// Writes the weights by name in the safetensors format, with floats stored in the floats encoding
+func (m *Model) WriteWeights(w io.Writer, floats calc.FloatEncoding) error {
+	return calc.WriteSafetensors(w, m.weightArrays(), floats)
+}
+
+// Replaces the weights with those of the same name in a safetensors file, converted to the model's dtype.
+// Every weight must be present with its shape, and arrays with other names are ignored.
+func (m *Model) ReadWeights(r io.Reader) error {
+	arrays, err := calc.ReadSafetensors(r)
+	if err != nil {
+		return err
+	}
+	return m.setWeights(arrays)
+}
+
+func (m *Model) setWeights(arrays map[string]calc.NDArray) error {
+	for i, name := range m.weightNames {
+		v, ok := arrays[name]
+		if !ok {
+			return fmt.Errorf("weights: %s is missing", name)
+		}
+		if !calc.ShapeEqual(v.Shape(), m.weightVals[i].Shape()) {
+			return fmt.Errorf("weights: %s: %w", name, &calc.ShapeError{
+				Op:       "ReadWeights",
+				Axis:     -1,
+				Expected: m.weightVals[i].Shape(),
+				Actual:   v.Shape(),
+			})
+		}
+	}
+	for i, name := range m.weightNames {
+		arrays[name].AsTypeInto(m.weightVals[i])
+	}
+	return nil
+}
+
+func (m *Model) SaveWeights(path string, floats calc.FloatEncoding) error {
+	return calc.SaveSafetensors(path, m.weightArrays(), floats)
+}
+
+func (m *Model) LoadWeights(path string) error {
+	arrays, err := calc.LoadSafetensors(path)
+	if err != nil {
+		return err
+	}
+	return m.setWeights(arrays)
+}
+
 func mag(w calc.NDArray) float64 {
 	ax := make([]int, len(w.Shape()))
 	for i := range ax {
@@ -0,0 +1,208 @@
+package model_test
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"path/filepath"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/model"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+// a model with a named weight and bias, whose values are exact in every float encoding
+func weightModel(dtype calc.DType, seed uint64) *model.Model {
+	m := model.NewModelOf(dtype, calc.NewRNG(seed))
+	m.NameWeight(m.AddWeight(3, 4), "dense.kernel")
+	m.NameWeight(m.AddBias(4), "dense.bias")
+	m.AddWeight(2, 2)
+	for _, w := range m.Weights() {
+		w.ForEach(func(i int, index []int, _ float64) {
+			w.Set(index, float64(i%7-3)/4+float64(seed))
+		})
+	}
+	return m
+}
+
+func TestWeightsRoundTrip(t *testing.T) {
+	for _, floats := range []calc.FloatEncoding{calc.NativeFloats, calc.F32, calc.F16, calc.BF16} {
+		for _, dtype := range []calc.DType{calc.Float64, calc.Float32} {
+			src, dst := weightModel(dtype, 1), weightModel(dtype, 2)
+			var buf bytes.Buffer
+			if err := src.WriteWeights(&buf, floats); err != nil {
+				t.Fatalf("%q %s: %v", floats, dtype, err)
+			}
+			if err := dst.ReadWeights(&buf); err != nil {
+				t.Fatalf("%q %s: %v", floats, dtype, err)
+			}
+			for i, w := range dst.Weights() {
+				if w.DType() != dtype {
+					t.Errorf("%q %s: %s read as %s", floats, dtype, dst.WeightNames()[i], w.DType())
+				}
+				src.Weights()[i].ForEach(func(_ int, index []int, want float64) {
+					if got := w.Get(index); got != want {
+						t.Errorf("%q %s: %s%v = %v, want %v", floats, dtype, dst.WeightNames()[i], index, got, want)
+					}
+				})
+			}
+		}
+	}
+
+	src, dst := weightModel(calc.Float64, 1), weightModel(calc.Float64, 2)
+	path := filepath.Join(t.TempDir(), "weights.safetensors")
+	if err := src.SaveWeights(path, calc.F32); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.LoadWeights(path); err != nil {
+		t.Fatal(err)
+	}
+	for i, w := range dst.Weights() {
+		src.Weights()[i].ForEach(func(_ int, index []int, want float64) {
+			if got := w.Get(index); got != want {
+				t.Errorf("loaded %s%v = %v, want %v", dst.WeightNames()[i], index, got, want)
+			}
+		})
+	}
+}
+
+func TestReadWeightsMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	if err := weightModel(calc.Float64, 1).WriteWeights(&buf, calc.F32); err != nil {
+		t.Fatal(err)
+	}
+	saved := buf.Bytes()
+
+	renamed := weightModel(calc.Float64, 2)
+	renamed.NameWeight(renamed.AddWeight(1, 1), "extra")
+	if err := renamed.ReadWeights(bytes.NewReader(saved)); err == nil {
+		t.Error("reading weights without extra didn't fail")
+	}
+
+	reshaped := model.NewModel(calc.NewRNG(2))
+	reshaped.NameWeight(reshaped.AddWeight(4, 3), "dense.kernel")
+	before := reshaped.Weights()[0].Get([]int{0, 0})
+	var shapeErr *calc.ShapeError
+	if err := reshaped.ReadWeights(bytes.NewReader(saved)); !errors.As(err, &shapeErr) {
+		t.Errorf("reading a 3x4 kernel into a 4x3 one: got %v, want a ShapeError", err)
+	}
+	if reshaped.Weights()[0].Get([]int{0, 0}) != before {
+		t.Error("a failed read changed the weights")
+	}
+}
+
+// a softmax classifier of a linearly separable problem, trained in dtype
+func trainClassifier(t *testing.T, dtype calc.DType) (first float64, last float64) {
+	t.Helper()
+	rng := calc.NewRNG(3)
+	X := rng.Normal(0, 1, 16, 4).AsType(dtype)
+	Y := calc.ZerosOf(dtype, 16, 2)
+	for i := 0; i < 16; i++ {
+		if X.Get([]int{i, 0})+X.Get([]int{i, 1}) > 0 {
+			Y.Set([]int{i, 0}, 1)
+		} else {
+			Y.Set([]int{i, 1}, 1)
+		}
+	}
+
+	m := model.NewModelOf(dtype, calc.NewRNG(4))
+	x, y := tensor.InputOf(dtype, 16, 4), tensor.InputOf(dtype, 16, 2)
+	pred := tensor.Softmax(model.Dense(m, x, 2, true))
+	m.MustCompile(&model.SGDOptimizer{LR: 0.1}, x, y, pred, tensor.CategoricalCrossEntropy(y, pred))
+
+	for i := 0; i < 50; i++ {
+		loss, _ := m.Train(X, Y)
+		if i == 0 {
+			first = loss
+		}
+		last = loss
+	}
+	for i, w := range m.Weights() {
+		if w.DType() != dtype {
+			t.Errorf("%s: weight %d is %s after training", dtype, i, w.DType())
+		}
+	}
+	classes := m.PredictClasses(X)
+	if shape := classes.Shape(); classes.DType() != calc.Int64 || len(shape) != 1 || shape[0] != 16 {
+		t.Fatalf("%s: PredictClasses is %s %v, want int64 [16]", dtype, classes.DType(), shape)
+	}
+	// after PredictClasses, since it reuses the storage of predictions
+	p := m.Predict(X)
+	if p.DType() != dtype {
+		t.Errorf("%s: predictions are %s", dtype, p.DType())
+	}
+	for i := 0; i < 16; i++ {
+		want := 0.
+		if p.Get([]int{i, 1}) > p.Get([]int{i, 0}) {
+			want = 1
+		}
+		if got := classes.Get([]int{i}); got != want {
+			t.Errorf("%s: PredictClasses %d = %v, want %v", dtype, i, got, want)
+		}
+	}
+	return first, last
+}
+
+func TestFloat32Training(t *testing.T) {
+	first64, last64 := trainClassifier(t, calc.Float64)
+	first32, last32 := trainClassifier(t, calc.Float32)
+	if last32 >= first32/2 {
+		t.Errorf("float32 loss went from %v to %v", first32, last32)
+	}
+	// the same model from the same seed, so only float32 rounding separates them
+	if math.Abs(first32-first64) > 1e-4 || math.Abs(last32-last64) > 1e-3 {
+		t.Errorf("float32 losses %v, %v differ from float64 losses %v, %v", first32, last32, first64, last64)
+	}
+}
+
+func TestEmbedding(t *testing.T) {
+	m := model.NewModel(calc.NewRNG(5))
+	x, y := tensor.InputOf(calc.Int64, 2, 3), tensor.Input(2, 3, 1)
+	pred := tensor.Sum(model.Embedding(m, x, 5, 4), 2)
+	diff := tensor.Sub(pred, y)
+	m.MustCompile(&model.SGDOptimizer{LR: 0.1}, x, y, pred, tensor.Mean(tensor.Mul(diff, diff), 0, 1, 2))
+
+	X := calc.FromRawInt64([]int{2, 3}, []int64{0, 2, 2, 1, 0, 2})
+	table := m.Weights()[0].MulConstant(1)
+	p := m.Predict(X)
+	X.ForEach(func(_ int, index []int, row float64) {
+		want := table.Slice(0, int(row), int(row)+1).Sum(0, 1).Get([]int{0, 0})
+		if got := p.Get(append(index, 0)); math.Abs(got-want) > 1e-12 {
+			t.Errorf("prediction %v = %v, want the sum of row %v, %v", index, got, row, want)
+		}
+	})
+
+	m.Train(X, calc.Ones(2, 3, 1))
+	// only the looked up rows are trained
+	for row := 0; row < 5; row++ {
+		changed := false
+		table.Slice(0, row, row+1).ForEach(func(_ int, index []int, v float64) {
+			if m.Weights()[0].Get([]int{row, index[1]}) != v {
+				changed = true
+			}
+		})
+		if want := row <= 2; changed != want {
+			t.Errorf("row %d changed: %v, want %v", row, changed, want)
+		}
+	}
+}
+
+func TestUpSampling2D(t *testing.T) {
+	m := model.NewModel(calc.NewRNG(10))
+	x := tensor.Input(2, 3, 4, 5)
+	y := model.UpSampling2D(m, x, 2, 3, calc.ResizeNearest)
+	if shape := y.Shape(); !calc.ShapeEqual(shape, []int{2, 6, 12, 5}) {
+		t.Fatalf("UpSampling2D has shape %v, want [2 6 12 5]", shape)
+	}
+
+	X := calc.NewRNG(11).Normal(0, 1, 2, 3, 4, 5)
+	eval := tensor.MakeEvaluation(y)
+	res := eval.Evaluate(tensor.Provide(x, X))[0]
+	// nearest neighbor upsampling by integer factors repeats every pixel
+	res.ForEach(func(_ int, index []int, v float64) {
+		if w := X.Get([]int{index[0], index[1] / 2, index[2] / 3, index[3]}); v != w {
+			t.Errorf("UpSampling2D %v = %v, want %v", index, v, w)
+		}
+	})
+}
@@ -29,7 +29,7 @@ func (o *SGDMomentumOptimizer) UpdateWeights(weights []calc.NDArray, grads []cal
 	if len(o.moments) == 0 {
 		o.moments = make([]calc.NDArray, len(weights))
 		for i, w := range weights {
-			o.moments[i] = calc.Zeros(w.Shape()...)
+			o.moments[i] = calc.ZerosOf(w.DType(), w.Shape()...)
 		}
 	}
 	for i := range weights {
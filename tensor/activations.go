@@ -1,13 +1,14 @@
 package tensor
 
 func Sigmoid(t Tensor) Tensor {
-	// 1 / (1 + e^-x)
-	return PowConstant(
+	// 1 / (1 + e^-x) = (1 + tanh(x/2)) / 2, which doesn't overflow for large negative x
+	half := constantLike(t, 0.5, t.Shape()...)
+	return Mul(
 		Add(
-			Ones(t.Shape()...),
-			Exp(Negate(t)),
+			constantLike(t, 1., t.Shape()...),
+			Tanh(Mul(t, half)),
 		),
-		-1,
+		half,
 	)
 }
 
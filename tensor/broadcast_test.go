@@ -0,0 +1,115 @@
+package tensor_test
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+var broadcastCases = []struct {
+	a, b, out []int
+}{
+	{[]int{2, 3}, []int{2, 3}, []int{2, 3}},
+	{[]int{3, 4}, []int{4}, []int{3, 4}},
+	{[]int{4}, []int{3, 4}, []int{3, 4}},
+	{[]int{3, 1}, []int{4}, []int{3, 4}},
+	{[]int{2, 1, 4}, []int{3, 1}, []int{2, 3, 4}},
+	{[]int{1}, []int{2, 3}, []int{2, 3}},
+	{[]int{5}, []int{}, []int{5}},
+	{[]int{}, []int{2, 3}, []int{2, 3}},
+}
+
+var broadcastOps = []struct {
+	name string
+	op   func(a tensor.Tensor, b tensor.Tensor) tensor.Tensor
+}{
+	{"Add", func(a, b tensor.Tensor) tensor.Tensor { return tensor.Add(a, b) }},
+	{"Sub", tensor.Sub},
+	{"Mul", func(a, b tensor.Tensor) tensor.Tensor { return tensor.Mul(a, b) }},
+	{"Div", tensor.Div},
+	{"Minimum", tensor.Minimum},
+	{"Maximum", tensor.Maximum},
+	{"Where", func(a, b tensor.Tensor) tensor.Tensor { return tensor.Where(tensor.Greater(a, b), a, b) }},
+}
+
+// the sum of all elements of the first output of e
+func evalSum(e *tensor.Evaluation, inputs ...tensor.ProvidedInput) float64 {
+	s := 0.
+	e.Evaluate(inputs...)[0].ForEach(func(dataIndex int, index []int, value float64) {
+		s += value
+	})
+	return s
+}
+
+func TestBroadcastShapes(t *testing.T) {
+	for _, c := range broadcastCases {
+		for _, op := range broadcastOps {
+			out := op.op(tensor.Input(c.a...), tensor.Input(c.b...))
+			if len(out.Shape()) != len(c.out) || !calc.ShapeEqual(out.Shape(), c.out) {
+				t.Errorf("%s %v %v: shape %v, want %v", op.name, c.a, c.b, out.Shape(), c.out)
+			}
+		}
+	}
+}
+
+func TestBroadcastIncompatible(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic adding shapes [2 3] and [3 2]")
+		}
+	}()
+	tensor.Add(tensor.Input(2, 3), tensor.Input(3, 2))
+}
+
+// Gradients have the shape of their input and match central differences of sum(op(a, b) * w)
+func TestBroadcastGradients(t *testing.T) {
+	const h = 1e-6
+	for _, c := range broadcastCases {
+		for _, op := range broadcastOps {
+			name := fmt.Sprintf("%s %v %v", op.name, c.a, c.b)
+			a, b := tensor.Input(c.a...), tensor.Input(c.b...)
+			out := op.op(a, b)
+			w := tensor.Constant(calc.RandomNormal(0, 1, out.Shape()...))
+			loss := tensor.Mul(out, w)
+			grads := tensor.Gradients(loss)
+
+			vals := []calc.NDArray{calc.RandomUniform(1, 2, c.a...), calc.RandomUniform(1, 2, c.b...)}
+			if op.name == "Minimum" || op.name == "Maximum" || op.name == "Where" {
+				// keep a and b apart so the finite differences don't cross a tie
+				vals[1] = calc.RandomUniform(3, 4, c.b...)
+			}
+			provide := func() []tensor.ProvidedInput {
+				return []tensor.ProvidedInput{tensor.Provide(a, vals[0]), tensor.Provide(b, vals[1])}
+			}
+
+			gradEval := tensor.MakeEvaluation(grads[a.ID()], grads[b.ID()])
+			res := gradEval.Evaluate(provide()...)
+			// copied out of the evaluation's buffers
+			analytic := []calc.NDArray{res[0].MulConstant(1), res[1].MulConstant(1)}
+			lossEval := tensor.MakeEvaluation(loss)
+
+			for i, v := range vals {
+				if shape := analytic[i].Shape(); len(shape) != len(v.Shape()) || !calc.ShapeEqual(shape, v.Shape()) {
+					t.Errorf("%s: gradient %d has shape %v, want %v", name, i, shape, v.Shape())
+					continue
+				}
+				v.ForEach(func(dataIndex int, index []int, value float64) {
+					index = append([]int{}, index...)
+					v.Set(index, value+h)
+					plus := evalSum(&lossEval, provide()...)
+					v.Set(index, value-h)
+					minus := evalSum(&lossEval, provide()...)
+					v.Set(index, value)
+
+					numeric := (plus - minus) / (2 * h)
+					if got := analytic[i].Get(index); math.Abs(got-numeric) > 1e-5*math.Max(1, math.Abs(numeric)) {
+						t.Errorf("%s: gradient %d at %v = %v, want %v", name, i, index, got, numeric)
+					}
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package tensor_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+func conv2D(opts calc.ConvOpts) func(ins ...tensor.Tensor) tensor.Tensor {
+	return func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Conv2D(ins[0], ins[1], 1, 2, 3, opts) }
+}
+
+func TestConv2DGradients(t *testing.T) {
+	rng := calc.NewRNG(19)
+	checkGradients(t, []gradientCase{
+		{"Conv2D", conv2D(calc.ConvOpts{}), []calc.NDArray{rng.Normal(0, 1, 2, 5, 4, 2), rng.Normal(0, 1, 3, 2, 2, 3)}},
+		{"Conv2D strided", conv2D(calc.ConvOpts{Strides: []int{2, 3}}), []calc.NDArray{rng.Normal(0, 1, 2, 7, 7, 2), rng.Normal(0, 1, 3, 2, 2, 3)}},
+		{"Conv2D dilated", conv2D(calc.ConvOpts{Dilations: []int{2, 3}}), []calc.NDArray{rng.Normal(0, 1, 1, 6, 7, 2), rng.Normal(0, 1, 2, 2, 2, 2)}},
+		{"Conv2D same", conv2D(calc.ConvOpts{Padding: calc.PaddingSame, Strides: []int{2, 1}}), []calc.NDArray{rng.Normal(0, 1, 2, 5, 4, 2), rng.Normal(0, 1, 2, 3, 2, 2)}},
+		{"Conv2D explicit", conv2D(calc.ConvOpts{Padding: calc.PaddingExplicit, Pads: []int{2, 0, 1, 1}}), []calc.NDArray{rng.Normal(0, 1, 1, 4, 4, 2), rng.Normal(0, 1, 3, 3, 2, 2)}},
+		{"Conv2D filters first", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.Conv2D(ins[0], ins[1], 2, 3, 1, calc.ConvOpts{Strides: []int{2, 2}, Padding: calc.PaddingSame})
+		}, []calc.NDArray{rng.Normal(0, 1, 2, 2, 5, 4), rng.Normal(0, 1, 3, 2, 2, 3)}},
+	})
+}
+
+func TestConv1D3DGradients(t *testing.T) {
+	rng := calc.NewRNG(20)
+	checkGradients(t, []gradientCase{
+		{"Conv1D", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.Conv1D(ins[0], ins[1], 1, 2, calc.ConvOpts{Strides: []int{2}, Padding: calc.PaddingSame})
+		}, []calc.NDArray{rng.Normal(0, 1, 2, 7, 2), rng.Normal(0, 1, 3, 2, 3)}},
+		{"Conv1D dilated", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.Conv1D(ins[0], ins[1], 1, 2, calc.ConvOpts{Dilations: []int{2}})
+		}, []calc.NDArray{rng.Normal(0, 1, 2, 7, 2), rng.Normal(0, 1, 3, 2, 3)}},
+		{"Conv1D filters first", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.Conv1D(ins[0], ins[1], 2, 1, calc.ConvOpts{Padding: calc.PaddingExplicit, Pads: []int{1, 2}})
+		}, []calc.NDArray{rng.Normal(0, 1, 2, 2, 5), rng.Normal(0, 1, 3, 2, 2)}},
+		{"Conv3D", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.Conv3D(ins[0], ins[1], 1, 2, 3, 4, calc.ConvOpts{Strides: []int{1, 2, 1}, Padding: calc.PaddingSame})
+		}, []calc.NDArray{rng.Normal(0, 1, 1, 3, 4, 3, 2), rng.Normal(0, 1, 2, 2, 2, 2, 2)}},
+		{"Conv3D filters first", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.Conv3D(ins[0], ins[1], 2, 3, 4, 1, calc.ConvOpts{Dilations: []int{1, 2, 1}})
+		}, []calc.NDArray{rng.Normal(0, 1, 1, 2, 3, 4, 3), rng.Normal(0, 1, 2, 2, 2, 2, 2)}},
+	})
+}
+
+func TestGroupedConvGradients(t *testing.T) {
+	rng := calc.NewRNG(21)
+	checkGradients(t, []gradientCase{
+		{"Conv2D groups", conv2D(calc.ConvOpts{Groups: 2}), []calc.NDArray{rng.Normal(0, 1, 2, 4, 4, 4), rng.Normal(0, 1, 2, 2, 2, 4)}},
+		{"Conv2D depthwise", conv2D(calc.ConvOpts{Groups: 3, Strides: []int{2, 1}, Padding: calc.PaddingSame}), []calc.NDArray{rng.Normal(0, 1, 2, 5, 4, 3), rng.Normal(0, 1, 3, 2, 1, 6)}},
+		{"Conv2D groups filters first", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.Conv2D(ins[0], ins[1], 2, 3, 1, calc.ConvOpts{Groups: 2})
+		}, []calc.NDArray{rng.Normal(0, 1, 1, 4, 4, 4), rng.Normal(0, 1, 2, 2, 2, 4)}},
+		{"Conv1D groups", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.Conv1D(ins[0], ins[1], 1, 2, calc.ConvOpts{Groups: 3})
+		}, []calc.NDArray{rng.Normal(0, 1, 2, 5, 6), rng.Normal(0, 1, 2, 2, 3)}},
+	})
+}
+
+func TestConvTransposeGradients(t *testing.T) {
+	rng := calc.NewRNG(23)
+	transpose2D := func(opts calc.ConvOpts) func(ins ...tensor.Tensor) tensor.Tensor {
+		return func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Conv2DTranspose(ins[0], ins[1], 1, 2, 3, opts) }
+	}
+	checkGradients(t, []gradientCase{
+		{"Conv2DTranspose", transpose2D(calc.ConvOpts{}), []calc.NDArray{rng.Normal(0, 1, 2, 3, 3, 3), rng.Normal(0, 1, 2, 2, 2, 3)}},
+		{"Conv2DTranspose strided", transpose2D(calc.ConvOpts{Strides: []int{2, 2}, OutputPadding: []int{1, 0}}), []calc.NDArray{rng.Normal(0, 1, 1, 3, 3, 2), rng.Normal(0, 1, 3, 3, 2, 2)}},
+		{"Conv2DTranspose same", transpose2D(calc.ConvOpts{Padding: calc.PaddingSame, Strides: []int{2, 1}, Dilations: []int{1, 2}}), []calc.NDArray{rng.Normal(0, 1, 1, 3, 3, 2), rng.Normal(0, 1, 2, 2, 2, 2)}},
+		{"Conv2DTranspose groups", transpose2D(calc.ConvOpts{Groups: 2}), []calc.NDArray{rng.Normal(0, 1, 1, 3, 3, 4), rng.Normal(0, 1, 2, 2, 2, 4)}},
+		{"Conv1DTranspose", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.Conv1DTranspose(ins[0], ins[1], 2, 1, calc.ConvOpts{Strides: []int{3}})
+		}, []calc.NDArray{rng.Normal(0, 1, 2, 2, 3), rng.Normal(0, 1, 2, 3, 2)}},
+		{"Conv3DTranspose", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.Conv3DTranspose(ins[0], ins[1], 1, 2, 3, 4, calc.ConvOpts{Strides: []int{1, 2, 1}})
+		}, []calc.NDArray{rng.Normal(0, 1, 1, 2, 2, 2, 2), rng.Normal(0, 1, 2, 2, 2, 2, 2)}},
+	})
+}
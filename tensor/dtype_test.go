@@ -0,0 +1,51 @@
+package tensor_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+func TestIntegerAndBoolTensors(t *testing.T) {
+	a, b := tensor.InputOf(calc.Int64, 2, 3), tensor.Input(2, 3)
+	greater := tensor.Greater(a, b)
+	count := tensor.Sum(greater, 1)
+	if greater.DType() != calc.Bool || count.DType() != calc.Int64 || tensor.Add(a, b).DType() != calc.Float64 {
+		t.Errorf("dtypes Greater %s, Sum of Bool %s, int64 + float64 %s", greater.DType(), count.DType(), tensor.Add(a, b).DType())
+	}
+	eval := tensor.MakeEvaluation(count, tensor.Equal(a, tensor.Cast(b, calc.Int64)))
+	res := eval.Evaluate(
+		tensor.Provide(a, calc.FromRawInt64([]int{2, 3}, []int64{1, 2, 3, -1, 0, 5})),
+		tensor.Provide(b, calc.FromRaw([]int{2, 3}, []float64{0.5, 2, 3.5, -1.5, 0.9, 4})),
+	)
+	checkEqual(t, "Sum of Greater", res[0], calc.FromRawInt64([]int{2, 1}, []int64{1, 2}))
+	checkEqual(t, "Equal after truncating", res[1], calc.FromRawBool([]int{2, 3}, []bool{false, true, true, true, true, false}))
+}
+
+func TestSparseLabelGradients(t *testing.T) {
+	rng := calc.NewRNG(26)
+	labels := calc.FromRawInt64([]int{4}, []int64{2, 0, 1, 2})
+	checkGradients(t, []gradientCase{
+		{"SparseCategoricalCrossEntropy", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.SparseCategoricalCrossEntropy(ins[1], tensor.Softmax(ins[0]))
+		}, []calc.NDArray{rng.Normal(0, 1, 4, 3), labels}},
+		{"CategoricalCrossEntropy of OneHot", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.CategoricalCrossEntropy(tensor.OneHot(ins[1], 3), tensor.Softmax(ins[0]))
+		}, []calc.NDArray{rng.Normal(0, 1, 4, 3), labels}},
+	})
+}
+
+// exact comparison, including the dtype
+func checkEqual(t *testing.T, name string, got calc.NDArray, want calc.NDArray) {
+	t.Helper()
+	if got.DType() != want.DType() || !calc.ShapeEqual(got.Shape(), want.Shape()) {
+		t.Errorf("%s: %s %v, want %s %v", name, got.DType(), got.Shape(), want.DType(), want.Shape())
+		return
+	}
+	want.ForEach(func(_ int, index []int, w float64) {
+		if g := got.Get(index); g != w {
+			t.Errorf("%s: %v = %v, want %v", name, index, g, w)
+		}
+	})
+}
@@ -0,0 +1,31 @@
+package tensor_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+func TestEinsumGradients(t *testing.T) {
+	rng := calc.NewRNG(4)
+	einsum := func(spec string) func(ins ...tensor.Tensor) tensor.Tensor {
+		return func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Einsum(spec, ins...) }
+	}
+	checkGradients(t, []gradientCase{
+		{"MatMul", einsum("ij,jk->ik"), []calc.NDArray{rng.Normal(0, 1, 3, 4), rng.Normal(0, 1, 4, 2)}},
+		{"Batched", einsum("bqd,bkd->bqk"), []calc.NDArray{rng.Normal(0, 1, 2, 3, 4), rng.Normal(0, 1, 2, 5, 4)}},
+		{"Three operands", einsum("bi,ijk,bk->bj"), []calc.NDArray{rng.Normal(0, 1, 3, 4), rng.Normal(0, 1, 4, 2, 5), rng.Normal(0, 1, 3, 5)}},
+		{"Summed label", einsum("ij,jk->k"), []calc.NDArray{rng.Normal(0, 1, 3, 4), rng.Normal(0, 1, 4, 2)}},
+		{"Implicit output", einsum("ij,jk"), []calc.NDArray{rng.Normal(0, 1, 3, 4), rng.Normal(0, 1, 4, 2)}},
+		{"Outer", einsum("i,j->ij"), []calc.NDArray{rng.Normal(0, 1, 3), rng.Normal(0, 1, 4)}},
+		{"Same operand twice", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.Einsum("ij,ij->i", ins[0], ins[0])
+		}, []calc.NDArray{rng.Normal(0, 1, 3, 4)}},
+		{"Trace", einsum("ii->"), []calc.NDArray{rng.Normal(0, 1, 3, 3)}},
+		{"Diagonal", einsum("ii->i"), []calc.NDArray{rng.Normal(0, 1, 3, 3)}},
+		{"Batched diagonal", einsum("bii->bi"), []calc.NDArray{rng.Normal(0, 1, 2, 3, 3)}},
+		{"Triple diagonal", einsum("iii->i"), []calc.NDArray{rng.Normal(0, 1, 2, 2, 2)}},
+		{"Diagonal product", einsum("ii,ij->j"), []calc.NDArray{rng.Normal(0, 1, 3, 3), rng.Normal(0, 1, 3, 4)}},
+	})
+}
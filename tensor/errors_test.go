@@ -0,0 +1,61 @@
+package tensor_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+// Constructors check their inputs, so Build returns the error instead of evaluation panicking later
+func TestBuildShapeErrors(t *testing.T) {
+	cases := []struct {
+		name  string
+		build func() tensor.Tensor
+	}{
+		{"Conv1D input filters", func() tensor.Tensor {
+			return tensor.Conv1D(tensor.Input(1, 8, 3), tensor.Input(3, 2, 4), 1, 2, calc.ConvOpts{})
+		}},
+		{"Conv2D groups", func() tensor.Tensor {
+			return tensor.Conv2D(tensor.Input(1, 8, 8, 4), tensor.Input(3, 3, 2, 3), 1, 2, 3, calc.ConvOpts{Groups: 2})
+		}},
+		{"Conv2D kernel larger than input", func() tensor.Tensor {
+			return tensor.Conv2D(tensor.Input(1, 3, 3, 1), tensor.Input(5, 5, 1, 1), 1, 2, 3, calc.ConvOpts{})
+		}},
+		{"Conv3D input filters", func() tensor.Tensor {
+			return tensor.Conv3D(tensor.Input(1, 4, 4, 4, 2), tensor.Input(2, 2, 2, 3, 1), 1, 2, 3, 4, calc.ConvOpts{})
+		}},
+		{"Conv2DTranspose filters", func() tensor.Tensor {
+			return tensor.Conv2DTranspose(tensor.Input(1, 4, 4, 3), tensor.Input(3, 3, 2, 4), 1, 2, 3, calc.ConvOpts{})
+		}},
+		{"Einsum label sizes", func() tensor.Tensor {
+			return tensor.Einsum("ij,jk->ik", tensor.Input(2, 3), tensor.Input(4, 5))
+		}},
+		{"TopK too many", func() tensor.Tensor { return tensor.TopK(tensor.Input(3, 4), 5, 1) }},
+		{"TopKIndices too many", func() tensor.Tensor { return tensor.TopKIndices(tensor.Input(3, 4), 5, 1) }},
+		{"Inverse not square", func() tensor.Tensor { return tensor.Inverse(tensor.Input(2, 3)) }},
+		{"Det rank", func() tensor.Tensor { return tensor.Det(tensor.Input(3)) }},
+		{"Solve", func() tensor.Tensor { return tensor.Solve(tensor.Input(3, 3), tensor.Input(2, 1)) }},
+		{"QR rank", func() tensor.Tensor {
+			q, _ := tensor.QR(tensor.Input(3))
+			return q
+		}},
+		{"Gather index rank", func() tensor.Tensor {
+			return tensor.Gather(tensor.Input(3, 4), tensor.InputOf(calc.Int64, 3), 1)
+		}},
+		{"Scatter values", func() tensor.Tensor {
+			return tensor.Scatter(tensor.Input(3, 2), tensor.InputOf(calc.Int64, 3, 3), 1, 5)
+		}},
+		{"IndexSelect index rank", func() tensor.Tensor {
+			return tensor.IndexSelect(tensor.Input(3, 4), tensor.InputOf(calc.Int64, 2, 2), 0)
+		}},
+		{"CumSum axis", func() tensor.Tensor { return tensor.CumSum(tensor.Input(3, 4), 2) }},
+		{"Sort axis", func() tensor.Tensor { return tensor.Sort(tensor.Input(3, 4), -1) }},
+	}
+	for _, c := range cases {
+		out, err := tensor.Build(c.build)
+		if _, ok := err.(*calc.ShapeError); !ok || out != nil {
+			t.Errorf("%s: got %v, %v, want a ShapeError", c.name, out, err)
+		}
+	}
+}
@@ -16,6 +16,7 @@ func MakeEvaluation(outputs ...Tensor) Evaluation {
 		outputs:     outputs,
 		evaluations: evaluations,
 		timings:     timings,
+		pool:        calc.NewPool(),
 	}
 }
 
@@ -26,21 +27,41 @@ type Evaluation struct {
 	evaluations []Tensor
 
 	timings []time.Duration
+
+	// values are allocated from pool, and released when Evaluate is next called
+	pool    *calc.Pool
+	buffers []calc.NDArray
 }
 
 type ProvidedInput struct {
 	t Tensor
 	v calc.NDArray
+	s *calc.CSR
 }
 
-func Provide(t Tensor, v calc.NDArray) ProvidedInput { return ProvidedInput{t, v} }
+func Provide(t Tensor, v calc.NDArray) ProvidedInput { return ProvidedInput{t: t, v: v} }
+
+// Provides the value of a SparseInput
+func ProvideSparse(t Tensor, s calc.CSR) ProvidedInput { return ProvidedInput{t: t, s: &s} }
 
+// The returned arrays are only valid until the next call to Evaluate, which reuses their storage
 func (e *Evaluation) Evaluate(provisions ...ProvidedInput) []calc.NDArray {
+	for _, b := range e.buffers {
+		e.pool.Release(b)
+	}
+	e.buffers = nil
+
 	eval := &evaluationVisitor{
 		values: map[int64]calc.NDArray{},
+		sparse: map[int64]calc.CSR{},
+		pool:   e.pool,
 	}
 	for _, p := range provisions {
-		eval.values[p.t.ID()] = p.v
+		if p.s != nil {
+			eval.sparse[p.t.ID()] = *p.s
+		} else {
+			eval.values[p.t.ID()] = p.v
+		}
 	}
 
 	for i, t := range e.evaluations {
@@ -50,6 +71,8 @@ func (e *Evaluation) Evaluate(provisions ...ProvidedInput) []calc.NDArray {
 		e.timings[i] += end.Sub(start)
 	}
 
+	e.buffers = eval.buffers
+
 	outputs := make([]calc.NDArray, len(e.outputs))
 	for i, output := range e.outputs {
 		outputs[i] = eval.value(output)
@@ -77,11 +100,24 @@ var _ TensorVisitor = &evaluationVisitor{}
 
 type evaluationVisitor struct {
 	values map[int64]calc.NDArray
+	sparse map[int64]calc.CSR
+
+	pool    *calc.Pool
+	buffers []calc.NDArray
+}
+
+// a zeroed array for the value of t
+func (e *evaluationVisitor) alloc(t Tensor) calc.NDArray {
+	v := e.pool.Zeros(t.DType(), t.Shape()...)
+	e.buffers = append(e.buffers, v)
+	return v
 }
 
 func (e *evaluationVisitor) value(t Tensor) calc.NDArray {
 	v, ok := e.values[t.ID()]
-	if !ok {
+	if !ok && IsSparse(t) {
+		panic(fmt.Sprintf("tensor %d is sparse, which only SparseMatMul and SparseTranspose can read", t.ID()))
+	} else if !ok {
 		panic(fmt.Sprintf("missing value for tensor %d", t.ID()))
 	}
 	return v
@@ -0,0 +1,31 @@
+package tensor_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+// Evaluate recycles the buffers of the previous call, so every call must still compute from scratch
+func TestEvaluateRecyclesBuffers(t *testing.T) {
+	rng := calc.NewRNG(28)
+	wv := rng.Normal(0, 1, 4, 3)
+	x, w := tensor.Input(5, 4), tensor.Constant(wv)
+	h := tensor.ReLU(tensor.MatMul(x, w, 0, 1))
+	y := tensor.Add(tensor.Softmax(h), tensor.Exp(h))
+	s := tensor.Sum(h, 1)
+	grads := tensor.Gradients(tensor.Mean(y))
+
+	eval := tensor.MakeEvaluation(y, s, grads[x.ID()])
+	for i := 0; i < 4; i++ {
+		xv := rng.Normal(0, 1, 5, 4)
+		res := eval.Evaluate(tensor.Provide(x, xv))
+		fresh := tensor.MakeEvaluation(y, s, grads[x.ID()])
+		want := fresh.Evaluate(tensor.Provide(x, xv))
+		checkEqual(t, "Sum", res[1], xv.MatMul(wv, 0, 1).ReLU().Sum(1))
+		for j := range res {
+			checkEqual(t, "recycled evaluation", res[j], want[j])
+		}
+	}
+}
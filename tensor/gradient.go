@@ -2,8 +2,6 @@ package tensor
 
 import (
 	"fmt"
-
-	"github.com/tsholmes/go-dl/calc"
 )
 
 // Get a map of original tensor ID -> gradient tensor given an output tensor
@@ -14,7 +12,7 @@ func Gradients(outputs ...Tensor) map[int64]Tensor {
 	}
 
 	for _, t := range outputs {
-		gv.partialGradients[t.ID()] = []Tensor{Constant(calc.Ones(t.Shape()...))}
+		gv.partialGradients[t.ID()] = []Tensor{constantLike(t, 1., t.Shape()...)}
 	}
 
 	for _, t := range CollectBackward(outputs) {
@@ -39,9 +37,19 @@ func (g *gradientVisitor) push(tensor Tensor, gradient Tensor) {
 func (g *gradientVisitor) collect(tensor Tensor) Tensor {
 	partials := g.partialGradients[tensor.ID()]
 	for i, p := range partials {
+		// Sum away leading axes that tensor was broadcast along, or add them if p was
+		if extra := len(p.Shape()) - len(tensor.Shape()); extra > 0 {
+			axes := make([]int, extra)
+			for j := range axes {
+				axes[j] = j
+			}
+			p = Reshape(Sum(p, axes...), p.Shape()[extra:]...)
+		} else if extra < 0 {
+			p = Reshape(p, append(onesShape(-extra), p.Shape()...)...)
+		}
 		// Broadcast up if sizes aren't equal
 		if shapeLt(p.Shape(), tensor.Shape()) {
-			p = Mul(p, Ones(tensor.Shape()...))
+			p = Mul(p, constantLike(p, 1., tensor.Shape()...))
 		}
 		// Sum down if sizes still aren't equal
 		var sumAxes []int
@@ -58,7 +66,7 @@ func (g *gradientVisitor) collect(tensor Tensor) Tensor {
 
 	var gradient Tensor
 	if len(partials) == 0 {
-		gradient = Constant(calc.Zeros(tensor.Shape()...))
+		gradient = constantLike(tensor, 0., tensor.Shape()...)
 	} else if len(partials) == 1 {
 		gradient = partials[0]
 	} else {
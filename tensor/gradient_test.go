@@ -0,0 +1,80 @@
+package tensor_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+// An op applied to inputs with the given values. Values should keep away from points where the op isn't
+// differentiable, like ties for sorts and maximums, by more than the finite difference step.
+type gradientCase struct {
+	name   string
+	op     func(ins ...tensor.Tensor) tensor.Tensor
+	values []calc.NDArray
+}
+
+// Checks that the gradients of every case have the shape of their input and match central differences of
+// sum(op(ins...) * w) for a random w
+func checkGradients(t *testing.T, cases []gradientCase) {
+	t.Helper()
+	const h = 1e-6
+	for _, c := range cases {
+		ins := make([]tensor.Tensor, len(c.values))
+		for i, v := range c.values {
+			ins[i] = tensor.InputOf(v.DType(), v.Shape()...)
+		}
+		out := c.op(ins...)
+		w := tensor.Constant(calc.NewRNG(1).Normal(0, 1, out.Shape()...).AsType(out.DType()))
+		loss := tensor.Mul(out, w)
+		grads := tensor.Gradients(loss)
+
+		provide := func() []tensor.ProvidedInput {
+			provided := make([]tensor.ProvidedInput, len(ins))
+			for i, in := range ins {
+				provided[i] = tensor.Provide(in, c.values[i])
+			}
+			return provided
+		}
+
+		var wrt []int
+		var gradTensors []tensor.Tensor
+		for i, in := range ins {
+			if in.DType().IsFloat() {
+				wrt = append(wrt, i)
+				gradTensors = append(gradTensors, grads[in.ID()])
+			}
+		}
+		gradEval := tensor.MakeEvaluation(gradTensors...)
+		res := gradEval.Evaluate(provide()...)
+		analytic := make([]calc.NDArray, len(res))
+		for j := range res {
+			// copied out of the evaluation's buffers
+			analytic[j] = res[j].AsType(calc.Float64).MulConstant(1)
+		}
+		lossEval := tensor.MakeEvaluation(loss)
+
+		for j, i := range wrt {
+			v := c.values[i]
+			if shape := analytic[j].Shape(); !calc.ShapeEqual(shape, v.Shape()) {
+				t.Errorf("%s: gradient %d has shape %v, want %v", c.name, i, shape, v.Shape())
+				continue
+			}
+			v.ForEach(func(dataIndex int, index []int, value float64) {
+				index = append([]int{}, index...)
+				v.Set(index, value+h)
+				plus := evalSum(&lossEval, provide()...)
+				v.Set(index, value-h)
+				minus := evalSum(&lossEval, provide()...)
+				v.Set(index, value)
+
+				numeric := (plus - minus) / (2 * h)
+				if got := analytic[j].Get(index); math.Abs(got-numeric) > 1e-5*math.Max(1, math.Abs(numeric)) {
+					t.Errorf("%s: gradient %d at %v = %v, want %v", c.name, i, index, got, numeric)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,26 @@
+package tensor_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+func TestIndexGradients(t *testing.T) {
+	rng := calc.NewRNG(36)
+	// repeats indices along both axes
+	indices := tensor.Constant(calc.FromRawInt64([]int{3, 4}, []int64{0, 2, 2, 1, 1, 1, 0, 2, 2, 0, 0, 1}))
+	rows := tensor.Constant(calc.FromRawInt64([]int{4}, []int64{2, 0, 2, 1}))
+	checkGradients(t, []gradientCase{
+		{"Gather", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Gather(ins[0], indices, 1) }, []calc.NDArray{rng.Normal(0, 1, 3, 3)}},
+		{"Gather axis 0", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Gather(ins[0], indices, 0) }, []calc.NDArray{rng.Normal(0, 1, 3, 4)}},
+		{"ScatterAdd", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.ScatterAdd(ins[0], indices, 1, 3) }, []calc.NDArray{rng.Normal(0, 1, 3, 4)}},
+		// overwritten elements of repeated indices don't reach the output
+		{"Scatter", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Scatter(ins[0], indices, 1, 3) }, []calc.NDArray{rng.Normal(0, 1, 3, 4)}},
+		{"Scatter axis 0", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Scatter(ins[0], indices, 0, 4) }, []calc.NDArray{rng.Normal(0, 1, 3, 4)}},
+		{"IndexSelect", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.IndexSelect(ins[0], rows, 0) }, []calc.NDArray{rng.Normal(0, 1, 3, 2)}},
+		{"IndexSelect axis 1", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.IndexSelect(ins[0], rows, 1) }, []calc.NDArray{rng.Normal(0, 1, 2, 3)}},
+		{"IndexAdd", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.IndexAdd(ins[0], rows, 1, 3) }, []calc.NDArray{rng.Normal(0, 1, 2, 4)}},
+	})
+}
@@ -0,0 +1,104 @@
+package tensor_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+// a batch of two well conditioned 3x3 matrices, one with a positive and one with a negative determinant
+func linalgMatrices() calc.NDArray {
+	a := calc.NewRNG(2).Normal(0, 1, 2, 3, 3)
+	for i := 0; i < 3; i++ {
+		a.Set([]int{0, i, i}, a.Get([]int{0, i, i})+3)
+		a.Set([]int{1, i, i}, a.Get([]int{1, i, i})-3)
+	}
+	return a
+}
+
+// x x^T + n, which is symmetric positive definite
+func spd(x tensor.Tensor) tensor.Tensor {
+	shape := x.Shape()
+	n := shape[len(shape)-1]
+	eye := calc.Zeros(1, n, n)
+	for i := 0; i < n; i++ {
+		eye.Set([]int{0, i, i}, float64(n))
+	}
+	return tensor.Add(tensor.MatMul(x, tensor.Transpose(x, 1, 2), 1, 2), tensor.Constant(eye))
+}
+
+func TestLinalgGradients(t *testing.T) {
+	rng := calc.NewRNG(3)
+	tall, wide := rng.Normal(0, 1, 2, 4, 3), rng.Normal(0, 1, 2, 3, 4)
+	checkGradients(t, []gradientCase{
+		{"Inverse", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Inverse(ins[0]) }, []calc.NDArray{linalgMatrices()}},
+		{"Solve", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Solve(ins[0], ins[1]) }, []calc.NDArray{linalgMatrices(), rng.Normal(0, 1, 2, 3, 2)}},
+		{"Det", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Det(ins[0]) }, []calc.NDArray{linalgMatrices()}},
+		{"LogDet", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.LogDet(ins[0]) }, []calc.NDArray{linalgMatrices()}},
+		{"Cholesky", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Cholesky(spd(ins[0])) }, []calc.NDArray{rng.Normal(0, 1, 2, 3, 3)}},
+		{"Eigh w", func(ins ...tensor.Tensor) tensor.Tensor {
+			w, _ := tensor.Eigh(spd(ins[0]))
+			return w
+		}, []calc.NDArray{rng.Normal(0, 1, 2, 3, 3)}},
+		// squared, so the loss doesn't depend on the sign of each eigenvector
+		{"Eigh v", func(ins ...tensor.Tensor) tensor.Tensor {
+			_, v := tensor.Eigh(spd(ins[0]))
+			return tensor.Mul(v, v)
+		}, []calc.NDArray{rng.Normal(0, 1, 2, 3, 3)}},
+		{"QR q", func(ins ...tensor.Tensor) tensor.Tensor {
+			q, _ := tensor.QR(ins[0])
+			return q
+		}, []calc.NDArray{tall}},
+		{"QR r", func(ins ...tensor.Tensor) tensor.Tensor {
+			_, r := tensor.QR(ins[0])
+			return r
+		}, []calc.NDArray{tall}},
+		{"QR square", func(ins ...tensor.Tensor) tensor.Tensor {
+			q, r := tensor.QR(ins[0])
+			return tensor.Add(q, r)
+		}, []calc.NDArray{linalgMatrices()}},
+		{"SVD s tall", func(ins ...tensor.Tensor) tensor.Tensor {
+			_, s, _ := tensor.SVD(ins[0])
+			return s
+		}, []calc.NDArray{tall}},
+		{"SVD s wide", func(ins ...tensor.Tensor) tensor.Tensor {
+			_, s, _ := tensor.SVD(ins[0])
+			return s
+		}, []calc.NDArray{wide}},
+		// products of a column of u with the matching column of v don't depend on their signs
+		{"SVD u v tall", func(ins ...tensor.Tensor) tensor.Tensor {
+			u, _, v := tensor.SVD(ins[0])
+			return tensor.MatMul(tensor.Mul(u, u), tensor.Transpose(v, 1, 2), 1, 2)
+		}, []calc.NDArray{tall}},
+		{"SVD u v wide", func(ins ...tensor.Tensor) tensor.Tensor {
+			u, _, v := tensor.SVD(ins[0])
+			return tensor.MatMul(u, tensor.Transpose(tensor.Mul(v, v), 1, 2), 1, 2)
+		}, []calc.NDArray{wide}},
+		{"SVD u", func(ins ...tensor.Tensor) tensor.Tensor {
+			u, _, _ := tensor.SVD(ins[0])
+			return tensor.Mul(u, u)
+		}, []calc.NDArray{tall}},
+		{"SVD v", func(ins ...tensor.Tensor) tensor.Tensor {
+			_, _, v := tensor.SVD(ins[0])
+			return tensor.Mul(v, v)
+		}, []calc.NDArray{wide}},
+	})
+}
+
+func TestQRGradientNeedsTallMatrices(t *testing.T) {
+	_, err := tensor.Build(func() []tensor.Tensor {
+		q, _ := tensor.QR(tensor.Input(2, 3, 4))
+		return []tensor.Tensor{q}
+	})
+	if err != nil {
+		t.Fatalf("building QR of wide matrices failed: %v", err)
+	}
+	_, err = tensor.Build(func() map[int64]tensor.Tensor {
+		q, _ := tensor.QR(tensor.Input(2, 3, 4))
+		return tensor.Gradients(q)
+	})
+	if _, ok := err.(*calc.ShapeError); !ok {
+		t.Errorf("gradient of QR of wide matrices: got %v, want a ShapeError", err)
+	}
+}
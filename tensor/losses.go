@@ -6,8 +6,8 @@ func BinaryCrossEntropy(yTrue Tensor, yPred Tensor) Tensor {
 		Add(
 			Mul(yTrue, Log(yPred)),
 			Mul(
-				Sub(Ones(yTrue.Shape()...), yTrue),
-				Log(Sub(Ones(yPred.Shape()...), yPred)),
+				Sub(constantLike(yTrue, 1., yTrue.Shape()...), yTrue),
+				Log1p(Negate(yPred)),
 			),
 		),
 		len(yTrue.Shape())-1,
@@ -22,14 +22,37 @@ func CategoricalCrossEntropy(yTrue Tensor, yPred Tensor) Tensor {
 	))
 }
 
+// Like CategoricalCrossEntropy, with yTrue holding class indices instead of one-hot rows
+func SparseCategoricalCrossEntropy(yTrue Tensor, yPred Tensor) Tensor {
+	// -log(yp[y])
+	ax := len(yPred.Shape()) - 1
+	indices := ExpandDims(yTrue, len(yTrue.Shape()))
+	return Negate(Log(Gather(yPred, indices, ax)))
+}
+
+// The fraction of rows where the largest prediction is the true class. Ties go to the first class.
 func CategoricalAccuracy(yTrue Tensor, yPred Tensor) Tensor {
 	ax := len(yPred.Shape()) - 1
-	yPred = Equal(yPred, Max(yPred, ax))
 	return Mean(
-		Flatten(
-			Sum(Mul(yTrue, Equal(yPred, yTrue)), ax),
-			0,
-		),
+		Flatten(Equal(ArgMax(yPred, ax), ArgMax(yTrue, ax)), 0),
+		0,
+	)
+}
+
+// Like CategoricalAccuracy, with yTrue holding class indices instead of one-hot rows
+func SparseCategoricalAccuracy(yTrue Tensor, yPred Tensor) Tensor {
+	ax := len(yPred.Shape()) - 1
+	return Mean(
+		Flatten(Equal(Reshape(ArgMax(yPred, ax), yTrue.Shape()...), yTrue), 0),
+		0,
+	)
+}
+
+// The fraction of rows where the true class is among the k largest predictions
+func TopKCategoricalAccuracy(yTrue Tensor, yPred Tensor, k int) Tensor {
+	ax := len(yPred.Shape()) - 1
+	return Mean(
+		Flatten(Sum(Equal(TopKIndices(yPred, k, ax), ArgMax(yTrue, ax)), ax), 0),
 		0,
 	)
 }
@@ -0,0 +1,52 @@
+package tensor_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+func TestMathGradients(t *testing.T) {
+	rng := calc.NewRNG(39)
+	a := rng.Normal(0, 1, 3, 4)
+	pos := a.Mul(a).Add(calc.Constant(0.1, 1))
+	unary := func(op func(t tensor.Tensor) tensor.Tensor) func(ins ...tensor.Tensor) tensor.Tensor {
+		return func(ins ...tensor.Tensor) tensor.Tensor { return op(ins[0]) }
+	}
+	// kept away from the bounds, where Clip isn't differentiable
+	clipped := calc.FromRaw([]int{2, 3}, []float64{-2, -0.5, 0.3, 0.9, 1.4, -1.2})
+	cond := tensor.Constant(calc.FromRawBool([]int{3, 1}, []bool{true, false, true}))
+	checkGradients(t, []gradientCase{
+		{"Sqrt", unary(tensor.Sqrt), []calc.NDArray{pos}},
+		{"Tanh", unary(tensor.Tanh), []calc.NDArray{a}},
+		{"Sin", unary(tensor.Sin), []calc.NDArray{a}},
+		{"Cos", unary(tensor.Cos), []calc.NDArray{a}},
+		{"Erf", unary(tensor.Erf), []calc.NDArray{a}},
+		{"Log1p", unary(tensor.Log1p), []calc.NDArray{pos}},
+		{"Expm1", unary(tensor.Expm1), []calc.NDArray{a}},
+		{"Sigmoid", unary(tensor.Sigmoid), []calc.NDArray{a.MulConstant(3)}},
+		{"Clip", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Clip(ins[0], -1, 1) }, []calc.NDArray{clipped}},
+		{"Minimum", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Minimum(ins[0], ins[1]) }, []calc.NDArray{a, rng.Normal(0, 1, 3, 4)}},
+		{"Minimum broadcast", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Minimum(ins[0], ins[1]) }, []calc.NDArray{a, rng.Normal(0, 1, 1, 4)}},
+		{"Maximum broadcast", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Maximum(ins[0], ins[1]) }, []calc.NDArray{rng.Normal(0, 1, 3, 1), a}},
+		{"Where", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Where(cond, ins[0], ins[1]) }, []calc.NDArray{a, rng.Normal(0, 1, 1, 4)}},
+	})
+}
+
+func TestSigmoidLimits(t *testing.T) {
+	x := tensor.Input(4)
+	y := tensor.Sigmoid(x)
+	grad := tensor.Gradients(y)[x.ID()]
+	eval := tensor.MakeEvaluation(y, grad)
+	res := eval.Evaluate(tensor.Provide(x, calc.FromRaw([]int{4}, []float64{-1000, -40, 0, 1000})))
+	for i, want := range []float64{0, 1 / (1 + math.Exp(40)), 0.5, 1} {
+		if got := res[0].Get([]int{i}); math.Abs(got-want) > 1e-12 {
+			t.Errorf("Sigmoid %d = %v, want %v", i, got, want)
+		}
+		if g := res[1].Get([]int{i}); math.IsNaN(g) || g < 0 || g > 0.25 {
+			t.Errorf("Sigmoid gradient %d = %v", i, g)
+		}
+	}
+}
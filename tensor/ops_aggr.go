@@ -2,9 +2,14 @@ package tensor
 
 import "github.com/tsholmes/go-dl/calc"
 
+// Bool tensors are summed as Int64
 func Sum(t Tensor, axes ...int) Tensor {
+	dtype := t.DType()
+	if dtype == calc.Bool {
+		dtype = calc.Int64
+	}
 	return &SumTensor{
-		baseTensor: base(aggr(t, axes...), 0, t),
+		baseTensor: baseOf(dtype, aggr("Sum", t, axes...), t),
 		t:          t,
 		axes:       axes,
 	}
@@ -20,7 +25,7 @@ func (t *SumTensor) Visit(v TensorVisitor) { v.VisitSum(t) }
 
 func (e *evaluationVisitor) VisitSum(t *SumTensor) {
 	v := e.value(t.t)
-	e.values[t.ID()] = v.Sum(t.axes...)
+	e.values[t.ID()] = v.SumInto(e.alloc(t), t.axes...)
 }
 
 func (g *gradientVisitor) VisitSum(t *SumTensor) {
@@ -37,12 +42,12 @@ func Mean(t Tensor, axes ...int) Tensor {
 	}
 
 	s := Sum(t, axes...)
-	return Mul(s, Constant(calc.Constant(1./float64(div), s.Shape()...)))
+	return Mul(s, constantLike(s, 1./float64(div), s.Shape()...))
 }
 
 func Max(t Tensor, axes ...int) Tensor {
 	return &MaxTensor{
-		baseTensor: base(aggr(t, axes...), 0, t),
+		baseTensor: base(aggr("Max", t, axes...), t),
 		t:          t,
 		axes:       axes,
 	}
@@ -58,12 +63,233 @@ func (t *MaxTensor) Visit(v TensorVisitor) { v.VisitMax(t) }
 
 func (e *evaluationVisitor) VisitMax(t *MaxTensor) {
 	v := e.value(t.t)
-	e.values[t.ID()] = v.Max(t.axes...)
+	e.values[t.ID()] = v.MaxInto(e.alloc(t), t.axes...)
 }
 
 func (g *gradientVisitor) VisitMax(t *MaxTensor) {
 	delta := g.collect(t)
 
-	// Pass up gradients only for the elements that are equal to the max
-	g.push(t.t, EqualMask(delta, t, t.t))
+	g.push(t.t, extremeGradient(delta, t, t.t))
+}
+
+// Pass up gradients only for the elements that are equal to their aggregate (which all get the full
+// gradient if there are ties)
+func extremeGradient(delta Tensor, aggregate Tensor, t Tensor) Tensor {
+	return Mul(delta, Equal(t, aggregate))
+}
+
+func Min(t Tensor, axes ...int) Tensor {
+	return &MinTensor{
+		baseTensor: base(aggr("Min", t, axes...), t),
+		t:          t,
+		axes:       axes,
+	}
+}
+
+type MinTensor struct {
+	baseTensor
+	t    Tensor
+	axes []int
+}
+
+func (t *MinTensor) Visit(v TensorVisitor) { v.VisitMin(t) }
+
+func (e *evaluationVisitor) VisitMin(t *MinTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.MinInto(e.alloc(t), t.axes...)
+}
+
+func (g *gradientVisitor) VisitMin(t *MinTensor) {
+	delta := g.collect(t)
+
+	g.push(t.t, extremeGradient(delta, t, t.t))
+}
+
+func Prod(t Tensor, axes ...int) Tensor {
+	return &ProdTensor{
+		baseTensor: base(aggr("Prod", t, axes...), t),
+		t:          t,
+		axes:       axes,
+	}
+}
+
+type ProdTensor struct {
+	baseTensor
+	t    Tensor
+	axes []int
+}
+
+func (t *ProdTensor) Visit(v TensorVisitor) { v.VisitProd(t) }
+
+func (e *evaluationVisitor) VisitProd(t *ProdTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.ProdInto(e.alloc(t), t.axes...)
+}
+
+func (g *gradientVisitor) VisitProd(t *ProdTensor) {
+	delta := g.collect(t)
+
+	// the product of the other elements, which isn't defined where the input is zero
+	g.push(t.t, Div(Mul(delta, t), t.t))
+}
+
+// log(sum(exp(t))) over the axes, without overflowing for large values
+func LogSumExp(t Tensor, axes ...int) Tensor {
+	return &LogSumExpTensor{
+		baseTensor: base(aggr("LogSumExp", t, axes...), t),
+		t:          t,
+		axes:       axes,
+	}
+}
+
+type LogSumExpTensor struct {
+	baseTensor
+	t    Tensor
+	axes []int
+}
+
+func (t *LogSumExpTensor) Visit(v TensorVisitor) { v.VisitLogSumExp(t) }
+
+func (e *evaluationVisitor) VisitLogSumExp(t *LogSumExpTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.LogSumExpInto(e.alloc(t), t.axes...)
+}
+
+func (g *gradientVisitor) VisitLogSumExp(t *LogSumExpTensor) {
+	delta := g.collect(t)
+
+	// softmax over the axes
+	g.push(t.t, Mul(delta, Exp(Sub(t.t, t))))
+}
+
+// The population variance over the axes
+func Var(t Tensor, axes ...int) Tensor {
+	return &VarTensor{
+		baseTensor: base(aggr("Var", t, axes...), t),
+		t:          t,
+		axes:       axes,
+	}
+}
+
+type VarTensor struct {
+	baseTensor
+	t    Tensor
+	axes []int
+}
+
+func (t *VarTensor) Visit(v TensorVisitor) { v.VisitVar(t) }
+
+func (e *evaluationVisitor) VisitVar(t *VarTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.VarInto(e.alloc(t), t.axes...)
+}
+
+func (g *gradientVisitor) VisitVar(t *VarTensor) {
+	delta := g.collect(t)
+
+	// 2 (x - mean) / n
+	g.push(t.t, Mul(delta, Sub(t.t, Mean(t.t, t.axes...)), constantLike(t, 2./float64(aggrSize(t.t, t.axes)), t.Shape()...)))
+}
+
+// The population standard deviation over the axes
+func Std(t Tensor, axes ...int) Tensor {
+	return &StdTensor{
+		baseTensor: base(aggr("Std", t, axes...), t),
+		t:          t,
+		axes:       axes,
+	}
+}
+
+type StdTensor struct {
+	baseTensor
+	t    Tensor
+	axes []int
+}
+
+func (t *StdTensor) Visit(v TensorVisitor) { v.VisitStd(t) }
+
+func (e *evaluationVisitor) VisitStd(t *StdTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.StdInto(e.alloc(t), t.axes...)
+}
+
+func (g *gradientVisitor) VisitStd(t *StdTensor) {
+	delta := g.collect(t)
+
+	// (x - mean) / (n std)
+	n := constantLike(t, float64(aggrSize(t.t, t.axes)), t.Shape()...)
+	g.push(t.t, Div(Mul(delta, Sub(t.t, Mean(t.t, t.axes...))), Mul(t, n)))
+}
+
+// Running sums along axis
+func CumSum(t Tensor, axis int) Tensor {
+	checkAxes("CumSum", t, axis)
+	return &CumSumTensor{
+		baseTensor: base(t.Shape(), t),
+		t:          t,
+		axis:       axis,
+	}
+}
+
+type CumSumTensor struct {
+	baseTensor
+	t    Tensor
+	axis int
+}
+
+func (t *CumSumTensor) Visit(v TensorVisitor) { v.VisitCumSum(t) }
+
+func (e *evaluationVisitor) VisitCumSum(t *CumSumTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.CumSumInto(t.axis, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitCumSum(t *CumSumTensor) {
+	delta := g.collect(t)
+
+	// each element contributes to every later sum
+	g.push(t.t, reverseCumSum(delta, t.axis))
+}
+
+func reverseCumSum(t Tensor, axis int) Tensor {
+	return Reverse(CumSum(Reverse(t, axis), axis), axis)
+}
+
+// Running products along axis
+func CumProd(t Tensor, axis int) Tensor {
+	checkAxes("CumProd", t, axis)
+	return &CumProdTensor{
+		baseTensor: base(t.Shape(), t),
+		t:          t,
+		axis:       axis,
+	}
+}
+
+type CumProdTensor struct {
+	baseTensor
+	t    Tensor
+	axis int
+}
+
+func (t *CumProdTensor) Visit(v TensorVisitor) { v.VisitCumProd(t) }
+
+func (e *evaluationVisitor) VisitCumProd(t *CumProdTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.CumProdInto(t.axis, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitCumProd(t *CumProdTensor) {
+	delta := g.collect(t)
+
+	// sum of each later product divided by the element, which isn't defined where the input is zero
+	g.push(t.t, Div(reverseCumSum(Mul(delta, t), t.axis), t.t))
+}
+
+// the number of elements aggregated into each output
+func aggrSize(t Tensor, axes []int) int {
+	size := 1
+	for _, ax := range axes {
+		size *= t.Shape()[ax]
+	}
+	return size
 }
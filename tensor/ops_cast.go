@@ -0,0 +1,60 @@
+package tensor
+
+import "github.com/tsholmes/go-dl/calc"
+
+func Cast(t Tensor, dtype calc.DType) Tensor {
+	return &CastTensor{
+		baseTensor: baseOf(dtype, t.Shape(), t),
+		t:          t,
+	}
+}
+
+type CastTensor struct {
+	baseTensor
+	t Tensor
+}
+
+func (t *CastTensor) Visit(v TensorVisitor) { v.VisitCast(t) }
+
+func (e *evaluationVisitor) VisitCast(t *CastTensor) {
+	v := e.value(t.t)
+	if v.DType() == t.dtype {
+		e.values[t.ID()] = v
+		return
+	}
+	e.values[t.ID()] = v.AsTypeInto(e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitCast(t *CastTensor) {
+	delta := g.collect(t)
+
+	g.push(t.t, Cast(delta, t.t.DType()))
+}
+
+// Expands class indices into a Float64 tensor with a trailing axis of size depth
+func OneHot(t Tensor, depth int) Tensor {
+	shape := append(append([]int{}, t.Shape()...), depth)
+	return &OneHotTensor{
+		baseTensor: baseOf(calc.Float64, shape, t),
+		t:          t,
+		depth:      depth,
+	}
+}
+
+type OneHotTensor struct {
+	baseTensor
+	t     Tensor
+	depth int
+}
+
+func (t *OneHotTensor) Visit(v TensorVisitor) { v.VisitOneHot(t) }
+
+func (e *evaluationVisitor) VisitOneHot(t *OneHotTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.OneHotInto(e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitOneHot(t *OneHotTensor) {
+	// labels are usually the input, so don't panic like the comparisons do. Indices have no gradient.
+	g.collect(t)
+}
@@ -1,8 +1,10 @@
 package tensor
 
+import "github.com/tsholmes/go-dl/calc"
+
 func Abs(t Tensor) Tensor {
 	return &AbsTensor{
-		baseTensor: base(t.Shape(), 0, t),
+		baseTensor: base(t.Shape(), t),
 		t:          t,
 	}
 }
@@ -16,8 +18,8 @@ func (t *AbsTensor) Visit(v TensorVisitor) { v.VisitAbs(t) }
 
 func (e *evaluationVisitor) VisitAbs(t *AbsTensor) {
 	v := e.value(t.t)
-	v = v.Mul(v.Sign())
-	e.values[t.ID()] = v
+	o := v.SignInto(e.alloc(t))
+	e.values[t.ID()] = v.MulInto(o, o)
 }
 
 func (g *gradientVisitor) VisitAbs(t *AbsTensor) {
@@ -28,7 +30,7 @@ func (g *gradientVisitor) VisitAbs(t *AbsTensor) {
 
 func Sign(t Tensor) Tensor {
 	return &SignTensor{
-		baseTensor: base(t.Shape(), 0, t),
+		baseTensor: base(t.Shape(), t),
 		t:          t,
 	}
 }
@@ -42,16 +44,17 @@ func (t *SignTensor) Visit(v TensorVisitor) { v.VisitSign(t) }
 
 func (e *evaluationVisitor) VisitSign(t *SignTensor) {
 	v := e.value(t.t)
-	e.values[t.ID()] = v.Sign()
+	e.values[t.ID()] = v.SignInto(e.alloc(t))
 }
 
 func (g *gradientVisitor) VisitSign(t *SignTensor) {
 	panic("Sign is not differentiable")
 }
 
+// Returns a Bool mask
 func Greater(a Tensor, b Tensor) Tensor {
 	return &GreaterTensor{
-		baseTensor: base(elementWise(a, b), 0, a, b),
+		baseTensor: baseOf(calc.Bool, elementWise("Greater", a, b), a, b),
 		a:          a,
 		b:          b,
 	}
@@ -68,16 +71,18 @@ func (t *GreaterTensor) Visit(v TensorVisitor) { v.VisitGreater(t) }
 func (e *evaluationVisitor) VisitGreater(t *GreaterTensor) {
 	a := e.value(t.a)
 	b := e.value(t.b)
-	e.values[t.ID()] = a.Greater(b)
+	e.values[t.ID()] = a.GreaterInto(b, e.alloc(t))
 }
 
 func (g *gradientVisitor) VisitGreater(t *GreaterTensor) {
-	panic("Greater is not differentiable")
+	// masks have no gradient
+	g.collect(t)
 }
 
+// Returns a Bool mask
 func Equal(a Tensor, b Tensor) Tensor {
 	return &EqualTensor{
-		baseTensor: base(elementWise(a, b), 0, a, b),
+		baseTensor: baseOf(calc.Bool, elementWise("Equal", a, b), a, b),
 		a:          a,
 		b:          b,
 	}
@@ -94,16 +99,17 @@ func (t *EqualTensor) Visit(v TensorVisitor) { v.VisitEqual(t) }
 func (e *evaluationVisitor) VisitEqual(t *EqualTensor) {
 	a := e.value(t.a)
 	b := e.value(t.b)
-	e.values[t.ID()] = a.Equal(b)
+	e.values[t.ID()] = a.EqualInto(b, e.alloc(t))
 }
 
 func (g *gradientVisitor) VisitEqual(t *EqualTensor) {
-	panic("Equal is not differentiable")
+	// masks have no gradient
+	g.collect(t)
 }
 
 func EqualMask(t Tensor, a Tensor, b Tensor) Tensor {
 	return &EqualMaskTensor{
-		baseTensor: base(t.Shape(), 0, t, a, b),
+		baseTensor: base(t.Shape(), t, a, b),
 		t:          t,
 		a:          a,
 		b:          b,
@@ -123,7 +129,7 @@ func (e *evaluationVisitor) VisitEqualMask(t *EqualMaskTensor) {
 	v := e.value(t.t)
 	a := e.value(t.a)
 	b := e.value(t.b)
-	e.values[t.ID()] = v.EqualMask(a, b)
+	e.values[t.ID()] = v.EqualMaskInto(a, b, e.alloc(t))
 }
 
 func (g *gradientVisitor) VisitEqualMask(t *EqualMaskTensor) {
@@ -133,7 +139,7 @@ func (g *gradientVisitor) VisitEqualMask(t *EqualMaskTensor) {
 
 func ReLU(t Tensor) Tensor {
 	return &ReLUTensor{
-		baseTensor: base(t.Shape(), 1, t),
+		baseTensor: base(t.Shape(), t),
 		t:          t,
 	}
 }
@@ -147,7 +153,7 @@ func (t *ReLUTensor) Visit(v TensorVisitor) { v.VisitReLU(t) }
 
 func (e *evaluationVisitor) VisitReLU(t *ReLUTensor) {
 	v := e.value(t.t)
-	o := t.values[0]
+	o := e.alloc(t)
 	e.values[t.ID()] = v.ReLUInto(o)
 }
 
@@ -160,7 +166,7 @@ func (g *gradientVisitor) VisitReLU(t *ReLUTensor) {
 // Zeroes out all values in t where the corresponding value in m is negative
 func ReLUMask(t Tensor, m Tensor) Tensor {
 	return &ReLUMaskTensor{
-		baseTensor: base(t.Shape(), 1, t, m),
+		baseTensor: base(t.Shape(), t, m),
 		t:          t,
 		m:          m,
 	}
@@ -177,7 +183,7 @@ func (t *ReLUMaskTensor) Visit(v TensorVisitor) { v.VisitReLUMask(t) }
 func (e *evaluationVisitor) VisitReLUMask(t *ReLUMaskTensor) {
 	v := e.value(t.t)
 	mv := e.value(t.m)
-	o := t.values[0]
+	o := e.alloc(t)
 	e.values[t.ID()] = v.ReLUMaskInto(mv, o)
 }
 
@@ -185,3 +191,135 @@ func (g *gradientVisitor) VisitReLUMask(t *ReLUMaskTensor) {
 	// i mean the gradient to t.t is trivial, but its not needed
 	panic("ReLUMask is not differentiable")
 }
+
+func Clip(t Tensor, min float64, max float64) Tensor {
+	return &ClipTensor{
+		baseTensor: base(t.Shape(), t),
+		t:          t,
+		min:        min,
+		max:        max,
+	}
+}
+
+type ClipTensor struct {
+	baseTensor
+	t   Tensor
+	min float64
+	max float64
+}
+
+func (t *ClipTensor) Visit(v TensorVisitor) { v.VisitClip(t) }
+
+func (e *evaluationVisitor) VisitClip(t *ClipTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.ClipInto(t.min, t.max, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitClip(t *ClipTensor) {
+	delta := g.collect(t)
+
+	// only values strictly inside the range get a gradient
+	g.push(t.t, Mul(
+		delta,
+		Greater(t.t, constantLike(t.t, t.min, t.Shape()...)),
+		Greater(constantLike(t.t, t.max, t.Shape()...), t.t),
+	))
+}
+
+// Element-wise minimum of a and b. Ties pass the gradient to a.
+func Minimum(a Tensor, b Tensor) Tensor {
+	return &MinimumTensor{
+		baseTensor: base(elementWise("Minimum", a, b), a, b),
+		a:          a,
+		b:          b,
+	}
+}
+
+type MinimumTensor struct {
+	baseTensor
+	a Tensor
+	b Tensor
+}
+
+func (t *MinimumTensor) Visit(v TensorVisitor) { v.VisitMinimum(t) }
+
+func (e *evaluationVisitor) VisitMinimum(t *MinimumTensor) {
+	a := e.value(t.a)
+	b := e.value(t.b)
+	e.values[t.ID()] = a.MinimumInto(b, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitMinimum(t *MinimumTensor) {
+	delta := g.collect(t)
+
+	pickB := Greater(t.a, t.b)
+	zero := constantLike(delta, 0., t.Shape()...)
+	g.push(t.a, Where(pickB, zero, delta))
+	g.push(t.b, Where(pickB, delta, zero))
+}
+
+// Element-wise maximum of a and b. Ties pass the gradient to a.
+func Maximum(a Tensor, b Tensor) Tensor {
+	return &MaximumTensor{
+		baseTensor: base(elementWise("Maximum", a, b), a, b),
+		a:          a,
+		b:          b,
+	}
+}
+
+type MaximumTensor struct {
+	baseTensor
+	a Tensor
+	b Tensor
+}
+
+func (t *MaximumTensor) Visit(v TensorVisitor) { v.VisitMaximum(t) }
+
+func (e *evaluationVisitor) VisitMaximum(t *MaximumTensor) {
+	a := e.value(t.a)
+	b := e.value(t.b)
+	e.values[t.ID()] = a.MaximumInto(b, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitMaximum(t *MaximumTensor) {
+	delta := g.collect(t)
+
+	pickB := Greater(t.b, t.a)
+	zero := constantLike(delta, 0., t.Shape()...)
+	g.push(t.a, Where(pickB, zero, delta))
+	g.push(t.b, Where(pickB, delta, zero))
+}
+
+// Picks elements of a where cond is nonzero and of b elsewhere. cond has no gradient.
+func Where(cond Tensor, a Tensor, b Tensor) Tensor {
+	return &WhereTensor{
+		baseTensor: baseOf(calc.PromoteTypes(a.DType(), b.DType()), elementWise("Where", cond, a, b), cond, a, b),
+		cond:       cond,
+		a:          a,
+		b:          b,
+	}
+}
+
+type WhereTensor struct {
+	baseTensor
+	cond Tensor
+	a    Tensor
+	b    Tensor
+}
+
+func (t *WhereTensor) Visit(v TensorVisitor) { v.VisitWhere(t) }
+
+func (e *evaluationVisitor) VisitWhere(t *WhereTensor) {
+	cond := e.value(t.cond)
+	a := e.value(t.a)
+	b := e.value(t.b)
+	e.values[t.ID()] = cond.WhereInto(a, b, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitWhere(t *WhereTensor) {
+	delta := g.collect(t)
+
+	zero := constantLike(delta, 0., t.Shape()...)
+	g.push(t.a, Where(t.cond, delta, zero))
+	g.push(t.b, Where(t.cond, zero, delta))
+}
@@ -0,0 +1,405 @@
+package tensor
+
+import "github.com/tsholmes/go-dl/calc"
+
+// k must be (w, tFilters / groups, outFilters)
+func Conv1D(t Tensor, k Tensor, wAxis int, fAxis int, opts calc.ConvOpts) Tensor {
+	return &Conv1DTensor{
+		baseTensor: base(conv1d(t, k, wAxis, fAxis, opts), t, k),
+		t:          t,
+		k:          k,
+		wAxis:      wAxis,
+		fAxis:      fAxis,
+		opts:       opts,
+	}
+}
+
+type Conv1DTensor struct {
+	baseTensor
+	t     Tensor
+	k     Tensor
+	wAxis int
+	fAxis int
+	opts  calc.ConvOpts
+}
+
+func (t *Conv1DTensor) Visit(v TensorVisitor) { v.VisitConv1D(t) }
+
+func (e *evaluationVisitor) VisitConv1D(t *Conv1DTensor) {
+	i := e.value(t.t)
+	k := e.value(t.k)
+
+	v := i.Conv1DInto(k, t.wAxis, t.fAxis, t.opts, e.alloc(t))
+
+	e.values[t.ID()] = v
+}
+
+func (g *gradientVisitor) VisitConv1D(t *Conv1DTensor) {
+	delta := g.collect(t)
+
+	kw := t.k.Shape()[0]
+	kGrad := InverseConv1D(t.t, delta, t.wAxis, t.fAxis, kw, t.opts)
+
+	// the input shape of a strided convolution can't be recovered from delta alone
+	tGrad := conv1DTranspose(delta, t.k, t.wAxis, t.fAxis, t.opts, t.t.Shape())
+
+	g.push(t.t, tGrad)
+	g.push(t.k, kGrad)
+}
+
+func InverseConv1D(t Tensor, g Tensor, wAxis int, fAxis int, kernelW int, opts calc.ConvOpts) Tensor {
+	return &InverseConv1DTensor{
+		baseTensor: base(inverseConv1d(t, g, wAxis, fAxis, kernelW, opts), t, g),
+		t:          t,
+		g:          g,
+		wAxis:      wAxis,
+		fAxis:      fAxis,
+		opts:       opts,
+	}
+}
+
+type InverseConv1DTensor struct {
+	baseTensor
+	t     Tensor
+	g     Tensor
+	wAxis int
+	fAxis int
+	opts  calc.ConvOpts
+}
+
+func (t *InverseConv1DTensor) Visit(v TensorVisitor) { v.VisitInverseConv1D(t) }
+
+func (e *evaluationVisitor) VisitInverseConv1D(t *InverseConv1DTensor) {
+	i := e.value(t.t)
+	g := e.value(t.g)
+
+	v := i.InverseConv1DInto(g, t.wAxis, t.fAxis, t.opts, e.alloc(t))
+
+	e.values[t.ID()] = v
+}
+
+func (g *gradientVisitor) VisitInverseConv1D(t *InverseConv1DTensor) {
+	panic("InverseConv1D is not differentiable")
+}
+
+// k must be (w, outFilters / groups, tFilters), the kernel of the convolution being transposed
+func Conv1DTranspose(t Tensor, k Tensor, wAxis int, fAxis int, opts calc.ConvOpts) Tensor {
+	return conv1DTranspose(t, k, wAxis, fAxis, opts, conv1dTranspose(t, k, wAxis, fAxis, opts))
+}
+
+func conv1DTranspose(t Tensor, k Tensor, wAxis int, fAxis int, opts calc.ConvOpts, shape []int) Tensor {
+	return &Conv1DTransposeTensor{
+		baseTensor: base(shape, t, k),
+		t:          t,
+		k:          k,
+		wAxis:      wAxis,
+		fAxis:      fAxis,
+		opts:       opts,
+	}
+}
+
+type Conv1DTransposeTensor struct {
+	baseTensor
+	t     Tensor
+	k     Tensor
+	wAxis int
+	fAxis int
+	opts  calc.ConvOpts
+}
+
+func (t *Conv1DTransposeTensor) Visit(v TensorVisitor) { v.VisitConv1DTranspose(t) }
+
+func (e *evaluationVisitor) VisitConv1DTranspose(t *Conv1DTransposeTensor) {
+	i := e.value(t.t)
+	k := e.value(t.k)
+
+	v := i.Conv1DTransposeInto(k, t.wAxis, t.fAxis, t.opts, e.alloc(t))
+
+	e.values[t.ID()] = v
+}
+
+func (g *gradientVisitor) VisitConv1DTranspose(t *Conv1DTransposeTensor) {
+	delta := g.collect(t)
+
+	// delta is in the place of the input to the convolution being transposed, and t.t its output
+	kw := t.k.Shape()[0]
+	kGrad := InverseConv1D(delta, t.t, t.wAxis, t.fAxis, kw, t.opts)
+	tGrad := Conv1D(delta, t.k, t.wAxis, t.fAxis, t.opts)
+
+	g.push(t.t, tGrad)
+	g.push(t.k, kGrad)
+}
+
+// k must be (h, w, tFilters / groups, outFilters)
+func Conv2D(t Tensor, k Tensor, hAxis int, wAxis int, fAxis int, opts calc.ConvOpts) Tensor {
+	return &Conv2DTensor{
+		baseTensor: base(conv2d(t, k, hAxis, wAxis, fAxis, opts), t, k),
+		t:          t,
+		k:          k,
+		hAxis:      hAxis,
+		wAxis:      wAxis,
+		fAxis:      fAxis,
+		opts:       opts,
+	}
+}
+
+type Conv2DTensor struct {
+	baseTensor
+	t     Tensor
+	k     Tensor
+	hAxis int
+	wAxis int
+	fAxis int
+	opts  calc.ConvOpts
+}
+
+func (t *Conv2DTensor) Visit(v TensorVisitor) { v.VisitConv2D(t) }
+
+func (e *evaluationVisitor) VisitConv2D(t *Conv2DTensor) {
+	i := e.value(t.t)
+	k := e.value(t.k)
+
+	v := i.Conv2DInto(k, t.hAxis, t.wAxis, t.fAxis, t.opts, e.alloc(t))
+
+	e.values[t.ID()] = v
+}
+
+func (g *gradientVisitor) VisitConv2D(t *Conv2DTensor) {
+	delta := g.collect(t)
+
+	kh, kw := t.k.Shape()[0], t.k.Shape()[1]
+	kGrad := InverseConv2D(t.t, delta, t.hAxis, t.wAxis, t.fAxis, kh, kw, t.opts)
+
+	// the input shape of a strided convolution can't be recovered from delta alone
+	tGrad := conv2DTranspose(delta, t.k, t.hAxis, t.wAxis, t.fAxis, t.opts, t.t.Shape())
+
+	g.push(t.t, tGrad)
+	g.push(t.k, kGrad)
+}
+
+func InverseConv2D(t Tensor, g Tensor, hAxis int, wAxis int, fAxis int, kernelH int, kernelW int, opts calc.ConvOpts) Tensor {
+	return &InverseConv2DTensor{
+		baseTensor: base(inverseConv2d(t, g, hAxis, wAxis, fAxis, kernelH, kernelW, opts), t, g),
+		t:          t,
+		g:          g,
+		hAxis:      hAxis,
+		wAxis:      wAxis,
+		fAxis:      fAxis,
+		opts:       opts,
+	}
+}
+
+type InverseConv2DTensor struct {
+	baseTensor
+	t     Tensor
+	g     Tensor
+	hAxis int
+	wAxis int
+	fAxis int
+	opts  calc.ConvOpts
+}
+
+func (t *InverseConv2DTensor) Visit(v TensorVisitor) { v.VisitInverseConv2D(t) }
+
+func (e *evaluationVisitor) VisitInverseConv2D(t *InverseConv2DTensor) {
+	i := e.value(t.t)
+	g := e.value(t.g)
+
+	v := i.InverseConv2DInto(g, t.hAxis, t.wAxis, t.fAxis, t.opts, e.alloc(t))
+
+	e.values[t.ID()] = v
+}
+
+func (g *gradientVisitor) VisitInverseConv2D(t *InverseConv2DTensor) {
+	panic("InverseConv2D is not differentiable")
+}
+
+// k must be (h, w, outFilters / groups, tFilters), the kernel of the convolution being transposed
+func Conv2DTranspose(t Tensor, k Tensor, hAxis int, wAxis int, fAxis int, opts calc.ConvOpts) Tensor {
+	return conv2DTranspose(t, k, hAxis, wAxis, fAxis, opts, conv2dTranspose(t, k, hAxis, wAxis, fAxis, opts))
+}
+
+func conv2DTranspose(t Tensor, k Tensor, hAxis int, wAxis int, fAxis int, opts calc.ConvOpts, shape []int) Tensor {
+	return &Conv2DTransposeTensor{
+		baseTensor: base(shape, t, k),
+		t:          t,
+		k:          k,
+		hAxis:      hAxis,
+		wAxis:      wAxis,
+		fAxis:      fAxis,
+		opts:       opts,
+	}
+}
+
+type Conv2DTransposeTensor struct {
+	baseTensor
+	t     Tensor
+	k     Tensor
+	hAxis int
+	wAxis int
+	fAxis int
+	opts  calc.ConvOpts
+}
+
+func (t *Conv2DTransposeTensor) Visit(v TensorVisitor) { v.VisitConv2DTranspose(t) }
+
+func (e *evaluationVisitor) VisitConv2DTranspose(t *Conv2DTransposeTensor) {
+	i := e.value(t.t)
+	k := e.value(t.k)
+
+	v := i.Conv2DTransposeInto(k, t.hAxis, t.wAxis, t.fAxis, t.opts, e.alloc(t))
+
+	e.values[t.ID()] = v
+}
+
+func (g *gradientVisitor) VisitConv2DTranspose(t *Conv2DTransposeTensor) {
+	delta := g.collect(t)
+
+	// delta is in the place of the input to the convolution being transposed, and t.t its output
+	kh, kw := t.k.Shape()[0], t.k.Shape()[1]
+	kGrad := InverseConv2D(delta, t.t, t.hAxis, t.wAxis, t.fAxis, kh, kw, t.opts)
+	tGrad := Conv2D(delta, t.k, t.hAxis, t.wAxis, t.fAxis, t.opts)
+
+	g.push(t.t, tGrad)
+	g.push(t.k, kGrad)
+}
+
+// k must be (d, h, w, tFilters / groups, outFilters)
+func Conv3D(t Tensor, k Tensor, dAxis int, hAxis int, wAxis int, fAxis int, opts calc.ConvOpts) Tensor {
+	return &Conv3DTensor{
+		baseTensor: base(conv3d(t, k, dAxis, hAxis, wAxis, fAxis, opts), t, k),
+		t:          t,
+		k:          k,
+		dAxis:      dAxis,
+		hAxis:      hAxis,
+		wAxis:      wAxis,
+		fAxis:      fAxis,
+		opts:       opts,
+	}
+}
+
+type Conv3DTensor struct {
+	baseTensor
+	t     Tensor
+	k     Tensor
+	dAxis int
+	hAxis int
+	wAxis int
+	fAxis int
+	opts  calc.ConvOpts
+}
+
+func (t *Conv3DTensor) Visit(v TensorVisitor) { v.VisitConv3D(t) }
+
+func (e *evaluationVisitor) VisitConv3D(t *Conv3DTensor) {
+	i := e.value(t.t)
+	k := e.value(t.k)
+
+	v := i.Conv3DInto(k, t.dAxis, t.hAxis, t.wAxis, t.fAxis, t.opts, e.alloc(t))
+
+	e.values[t.ID()] = v
+}
+
+func (g *gradientVisitor) VisitConv3D(t *Conv3DTensor) {
+	delta := g.collect(t)
+
+	kd, kh, kw := t.k.Shape()[0], t.k.Shape()[1], t.k.Shape()[2]
+	kGrad := InverseConv3D(t.t, delta, t.dAxis, t.hAxis, t.wAxis, t.fAxis, kd, kh, kw, t.opts)
+
+	// the input shape of a strided convolution can't be recovered from delta alone
+	tGrad := conv3DTranspose(delta, t.k, t.dAxis, t.hAxis, t.wAxis, t.fAxis, t.opts, t.t.Shape())
+
+	g.push(t.t, tGrad)
+	g.push(t.k, kGrad)
+}
+
+func InverseConv3D(t Tensor, g Tensor, dAxis int, hAxis int, wAxis int, fAxis int, kernelD int, kernelH int, kernelW int, opts calc.ConvOpts) Tensor {
+	return &InverseConv3DTensor{
+		baseTensor: base(inverseConv3d(t, g, dAxis, hAxis, wAxis, fAxis, kernelD, kernelH, kernelW, opts), t, g),
+		t:          t,
+		g:          g,
+		dAxis:      dAxis,
+		hAxis:      hAxis,
+		wAxis:      wAxis,
+		fAxis:      fAxis,
+		opts:       opts,
+	}
+}
+
+type InverseConv3DTensor struct {
+	baseTensor
+	t     Tensor
+	g     Tensor
+	dAxis int
+	hAxis int
+	wAxis int
+	fAxis int
+	opts  calc.ConvOpts
+}
+
+func (t *InverseConv3DTensor) Visit(v TensorVisitor) { v.VisitInverseConv3D(t) }
+
+func (e *evaluationVisitor) VisitInverseConv3D(t *InverseConv3DTensor) {
+	i := e.value(t.t)
+	g := e.value(t.g)
+
+	v := i.InverseConv3DInto(g, t.dAxis, t.hAxis, t.wAxis, t.fAxis, t.opts, e.alloc(t))
+
+	e.values[t.ID()] = v
+}
+
+func (g *gradientVisitor) VisitInverseConv3D(t *InverseConv3DTensor) {
+	panic("InverseConv3D is not differentiable")
+}
+
+// k must be (d, h, w, outFilters / groups, tFilters), the kernel of the convolution being transposed
+func Conv3DTranspose(t Tensor, k Tensor, dAxis int, hAxis int, wAxis int, fAxis int, opts calc.ConvOpts) Tensor {
+	return conv3DTranspose(t, k, dAxis, hAxis, wAxis, fAxis, opts, conv3dTranspose(t, k, dAxis, hAxis, wAxis, fAxis, opts))
+}
+
+func conv3DTranspose(t Tensor, k Tensor, dAxis int, hAxis int, wAxis int, fAxis int, opts calc.ConvOpts, shape []int) Tensor {
+	return &Conv3DTransposeTensor{
+		baseTensor: base(shape, t, k),
+		t:          t,
+		k:          k,
+		dAxis:      dAxis,
+		hAxis:      hAxis,
+		wAxis:      wAxis,
+		fAxis:      fAxis,
+		opts:       opts,
+	}
+}
+
+type Conv3DTransposeTensor struct {
+	baseTensor
+	t     Tensor
+	k     Tensor
+	dAxis int
+	hAxis int
+	wAxis int
+	fAxis int
+	opts  calc.ConvOpts
+}
+
+func (t *Conv3DTransposeTensor) Visit(v TensorVisitor) { v.VisitConv3DTranspose(t) }
+
+func (e *evaluationVisitor) VisitConv3DTranspose(t *Conv3DTransposeTensor) {
+	i := e.value(t.t)
+	k := e.value(t.k)
+
+	v := i.Conv3DTransposeInto(k, t.dAxis, t.hAxis, t.wAxis, t.fAxis, t.opts, e.alloc(t))
+
+	e.values[t.ID()] = v
+}
+
+func (g *gradientVisitor) VisitConv3DTranspose(t *Conv3DTransposeTensor) {
+	delta := g.collect(t)
+
+	// delta is in the place of the input to the convolution being transposed, and t.t its output
+	kd, kh, kw := t.k.Shape()[0], t.k.Shape()[1], t.k.Shape()[2]
+	kGrad := InverseConv3D(delta, t.t, t.dAxis, t.hAxis, t.wAxis, t.fAxis, kd, kh, kw, t.opts)
+	tGrad := Conv3D(delta, t.k, t.dAxis, t.hAxis, t.wAxis, t.fAxis, t.opts)
+
+	g.push(t.t, tGrad)
+	g.push(t.k, kGrad)
+}
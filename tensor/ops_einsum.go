@@ -0,0 +1,105 @@
+package tensor
+
+import (
+	"strings"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// Evaluates an einsum spec like "bij,bjk->bik" over the operands (see calc.Einsum). The gradient of each
+// operand is itself an einsum, of the output gradient with the other operands.
+func Einsum(spec string, operands ...Tensor) Tensor {
+	shapes := make([][]int, len(operands))
+	for i, o := range operands {
+		shapes[i] = o.Shape()
+	}
+	return &EinsumTensor{
+		baseTensor: base(calc.Must(calc.CheckEinsum(spec, shapes...)), operands...),
+		spec:       spec,
+		operands:   operands,
+	}
+}
+
+type EinsumTensor struct {
+	baseTensor
+	spec     string
+	operands []Tensor
+}
+
+func (t *EinsumTensor) Visit(v TensorVisitor) { v.VisitEinsum(t) }
+
+func (e *evaluationVisitor) VisitEinsum(t *EinsumTensor) {
+	operands := make([]calc.NDArray, len(t.operands))
+	for i, o := range t.operands {
+		operands[i] = e.value(o)
+	}
+	e.values[t.ID()] = calc.EinsumInto(t.spec, e.alloc(t), operands...)
+}
+
+func (g *gradientVisitor) VisitEinsum(t *EinsumTensor) {
+	delta := g.collect(t)
+
+	inputs, output, _ := calc.ParseEinsum(t.spec, len(t.operands))
+	for i, o := range t.operands {
+		labels := inputs[i]
+		gradInputs := []string{output}
+		gradOperands := []Tensor{delta}
+		for j := range t.operands {
+			if j != i {
+				gradInputs = append(gradInputs, inputs[j])
+				gradOperands = append(gradOperands, t.operands[j])
+			}
+		}
+
+		// a label repeated within this operand only has a gradient on the diagonal, so each repeat gets a new
+		// label tied to the first by an identity operand
+		gradLabels := []byte(labels)
+		fresh := unusedLabels(t.spec)
+		for k := range labels {
+			if strings.IndexByte(labels[:k], labels[k]) >= 0 {
+				gradLabels[k] = fresh[0]
+				fresh = fresh[1:]
+				gradInputs = append(gradInputs, string([]byte{labels[k], gradLabels[k]}))
+				gradOperands = append(gradOperands, identityLike(o, o.Shape()[k]))
+			}
+		}
+		others := strings.Join(gradInputs, "")
+
+		// labels only in this operand were summed over, so their gradient is broadcast back
+		var outLabels []byte
+		shape := make([]int, len(labels))
+		for k := range gradLabels {
+			shape[k] = 1
+			if strings.IndexByte(others, gradLabels[k]) >= 0 {
+				outLabels = append(outLabels, gradLabels[k])
+				shape[k] = o.Shape()[k]
+			}
+		}
+
+		spec := strings.Join(gradInputs, ",") + "->" + string(outLabels)
+		g.push(o, Reshape(Einsum(spec, gradOperands...), shape...))
+	}
+}
+
+// the letters that aren't labels in spec
+func unusedLabels(spec string) []byte {
+	var labels []byte
+	for _, r := range "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ" {
+		if !strings.ContainsRune(spec, r) {
+			labels = append(labels, byte(r))
+		}
+	}
+	return labels
+}
+
+// an n x n identity matrix with the dtype of t
+func identityLike(t Tensor, n int) Tensor {
+	m := calc.Zeros(n, n)
+	for i := 0; i < n; i++ {
+		m.Set([]int{i, i}, 1)
+	}
+	if t.DType().IsFloat() {
+		m = m.AsType(t.DType())
+	}
+	return Constant(m)
+}
@@ -0,0 +1,169 @@
+package tensor
+
+import "github.com/tsholmes/go-dl/calc"
+
+// out[..., i, ...] = t[..., indices[..., i, ...], ...] along axis. indices is an integer tensor with the
+// same shape as t on every other axis.
+func Gather(t Tensor, indices Tensor, axis int) Tensor {
+	if err := calc.CheckGather(t.Shape(), indices.Shape(), axis); err != nil {
+		panic(err)
+	}
+	return &GatherTensor{
+		baseTensor: baseOf(t.DType(), indices.Shape(), t, indices),
+		t:          t,
+		indices:    indices,
+		axis:       axis,
+	}
+}
+
+type GatherTensor struct {
+	baseTensor
+	t       Tensor
+	indices Tensor
+	axis    int
+}
+
+func (t *GatherTensor) Visit(v TensorVisitor) { v.VisitGather(t) }
+
+func (e *evaluationVisitor) VisitGather(t *GatherTensor) {
+	v, indices := e.value(t.t), e.value(t.indices)
+	e.values[t.ID()] = v.GatherInto(indices, t.axis, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitGather(t *GatherTensor) {
+	delta := g.collect(t)
+
+	g.push(t.t, ScatterAdd(delta, t.indices, t.axis, t.t.Shape()[t.axis]))
+}
+
+// Adds t into zeros at [..., indices[..., i, ...], ...] along axis, which has the given size in the output.
+// t and indices must have the same shape.
+func ScatterAdd(t Tensor, indices Tensor, axis int, size int) Tensor {
+	return &ScatterAddTensor{
+		baseTensor: baseOf(t.DType(), calc.Must(calc.CheckScatter(t.Shape(), indices.Shape(), axis, size)), t, indices),
+		t:          t,
+		indices:    indices,
+		axis:       axis,
+	}
+}
+
+type ScatterAddTensor struct {
+	baseTensor
+	t       Tensor
+	indices Tensor
+	axis    int
+}
+
+func (t *ScatterAddTensor) Visit(v TensorVisitor) { v.VisitScatterAdd(t) }
+
+func (e *evaluationVisitor) VisitScatterAdd(t *ScatterAddTensor) {
+	v, indices := e.value(t.t), e.value(t.indices)
+	e.values[t.ID()] = v.ScatterAddInto(indices, t.axis, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitScatterAdd(t *ScatterAddTensor) {
+	delta := g.collect(t)
+
+	g.push(t.t, Gather(delta, t.indices, t.axis))
+}
+
+// Sets zeros at [..., indices[..., i, ...], ...] along axis to t, where the axis has the given size in the
+// output. t and indices must have the same shape, and the last of any repeated index wins.
+func Scatter(t Tensor, indices Tensor, axis int, size int) Tensor {
+	return &ScatterTensor{
+		baseTensor: baseOf(t.DType(), calc.Must(calc.CheckScatter(t.Shape(), indices.Shape(), axis, size)), t, indices),
+		t:          t,
+		indices:    indices,
+		axis:       axis,
+	}
+}
+
+type ScatterTensor struct {
+	baseTensor
+	t       Tensor
+	indices Tensor
+	axis    int
+}
+
+func (t *ScatterTensor) Visit(v TensorVisitor) { v.VisitScatter(t) }
+
+func (e *evaluationVisitor) VisitScatter(t *ScatterTensor) {
+	v, indices := e.value(t.t), e.value(t.indices)
+	e.values[t.ID()] = v.ScatterInto(indices, t.axis, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitScatter(t *ScatterTensor) {
+	delta := g.collect(t)
+
+	// only the last element of repeated indices reaches the output, so it's found by scattering each
+	// element's position along axis and checking which position survived
+	shape := t.indices.Shape()
+	positions := calc.ZerosOf(calc.Int64, shape...)
+	positions.ForEach(func(_ int, index []int, _ float64) {
+		positions.Set(index, float64(index[t.axis]))
+	})
+	pos := Constant(positions)
+	kept := Equal(Gather(Scatter(pos, t.indices, t.axis, t.Shape()[t.axis]), t.indices, t.axis), pos)
+	g.push(t.t, Where(kept, Gather(delta, t.indices, t.axis), constantLike(delta, 0., shape...)))
+}
+
+// out[..., i, ...] = t[..., indices[i], ...] along axis, where indices is a 1-d integer tensor. With
+// axis 0 this looks up rows of an embedding table.
+func IndexSelect(t Tensor, indices Tensor, axis int) Tensor {
+	return &IndexSelectTensor{
+		baseTensor: baseOf(t.DType(), calc.Must(calc.CheckIndexSelect(t.Shape(), indices.Shape(), axis)), t, indices),
+		t:          t,
+		indices:    indices,
+		axis:       axis,
+	}
+}
+
+type IndexSelectTensor struct {
+	baseTensor
+	t       Tensor
+	indices Tensor
+	axis    int
+}
+
+func (t *IndexSelectTensor) Visit(v TensorVisitor) { v.VisitIndexSelect(t) }
+
+func (e *evaluationVisitor) VisitIndexSelect(t *IndexSelectTensor) {
+	v, indices := e.value(t.t), e.value(t.indices)
+	e.values[t.ID()] = v.IndexSelectInto(indices, t.axis, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitIndexSelect(t *IndexSelectTensor) {
+	delta := g.collect(t)
+
+	g.push(t.t, IndexAdd(delta, t.indices, t.axis, t.t.Shape()[t.axis]))
+}
+
+// Adds t[..., i, ...] into zeros at [..., indices[i], ...] along axis, which has the given size in the output
+func IndexAdd(t Tensor, indices Tensor, axis int, size int) Tensor {
+	return &IndexAddTensor{
+		baseTensor: baseOf(t.DType(), calc.Must(calc.CheckIndexAdd(t.Shape(), indices.Shape(), axis, size)), t, indices),
+		t:          t,
+		indices:    indices,
+		axis:       axis,
+	}
+}
+
+type IndexAddTensor struct {
+	baseTensor
+	t       Tensor
+	indices Tensor
+	axis    int
+}
+
+func (t *IndexAddTensor) Visit(v TensorVisitor) { v.VisitIndexAdd(t) }
+
+func (e *evaluationVisitor) VisitIndexAdd(t *IndexAddTensor) {
+	v, indices := e.value(t.t), e.value(t.indices)
+	e.values[t.ID()] = v.IndexAddInto(indices, t.axis, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitIndexAdd(t *IndexAddTensor) {
+	delta := g.collect(t)
+
+	g.push(t.t, IndexSelect(delta, t.indices, t.axis))
+}
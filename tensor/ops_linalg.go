@@ -0,0 +1,429 @@
+package tensor
+
+import "github.com/tsholmes/go-dl/calc"
+
+// Linear algebra works on the trailing two axes, treating every index of the leading axes as a separate
+// matrix. Gradients of decompositions of symmetric matrices are symmetric, since only one triangle is read.
+
+// a b over the trailing two axes
+func batchMatMul(a Tensor, b Tensor) Tensor {
+	n := len(a.Shape())
+	return MatMul(a, b, n-2, n-1)
+}
+
+func batchTranspose(t Tensor) Tensor {
+	n := len(t.Shape())
+	return Transpose(t, n-2, n-1)
+}
+
+// (t + t^T) / 2
+func symmetrize(t Tensor) Tensor {
+	return Mul(
+		Add(t, batchTranspose(t)),
+		constantLike(t, 0.5, t.Shape()...),
+	)
+}
+
+// Reshapes the last axis of t into a row, so multiplying by it scales the columns of a matrix
+func asRow(t Tensor) Tensor {
+	shape := t.Shape()
+	return Reshape(t, append(append([]int{}, shape[:len(shape)-1]...), 1, shape[len(shape)-1])...)
+}
+
+// Reshapes the last axis of t into a column, so multiplying by it scales the rows of a matrix
+func asColumn(t Tensor) Tensor {
+	shape := t.Shape()
+	return Reshape(t, append(append([]int{}, shape...), 1)...)
+}
+
+// an n x n constant with f(row, col) in each element and size 1 leading axes to match the rank of t
+func constantMatrix(t Tensor, n int, f func(r int, c int) float64) Tensor {
+	shape := onesShape(len(t.Shape()))
+	shape[len(shape)-2], shape[len(shape)-1] = n, n
+
+	m := calc.Zeros(shape...)
+	for r := 0; r < n; r++ {
+		for c := 0; c < n; c++ {
+			m.Set(append(make([]int, len(shape)-2), r, c), f(r, c))
+		}
+	}
+	if t.DType().IsFloat() {
+		m = m.AsType(t.DType())
+	}
+	return Constant(m)
+}
+
+// panics with a *calc.ShapeError unless t is a batch of matrices
+func checkMatrices(op string, t Tensor) {
+	if err := calc.CheckMatrices(op, t.Shape()); err != nil {
+		panic(err)
+	}
+}
+
+// panics with a *calc.ShapeError unless t is a batch of square matrices
+func checkSquare(op string, t Tensor) {
+	if err := calc.CheckSquareMatrices(op, t.Shape()); err != nil {
+		panic(err)
+	}
+}
+
+// f[i][j] = 1 / (w[j] - w[i]) off the diagonal and 0 on it, for the n values along the last axis of w.
+// t is the batch of matrices w came from.
+func inverseDifferences(t Tensor, w Tensor, n int) Tensor {
+	eye := constantMatrix(t, n, func(r int, c int) float64 {
+		if r == c {
+			return 1
+		}
+		return 0
+	})
+	offDiagonal := Sub(constantLike(t, 1, eye.Shape()...), eye)
+	diff := Sub(asRow(w), asColumn(w))
+	return Mul(PowConstant(Add(diff, eye), -1), offDiagonal)
+}
+
+func linalgShape(t Tensor, dims ...int) []int {
+	shape := t.Shape()
+	return append(append([]int{}, shape[:len(shape)-2]...), dims...)
+}
+
+func Inverse(t Tensor) Tensor {
+	checkSquare("Inverse", t)
+	return &InverseTensor{
+		baseTensor: base(t.Shape(), t),
+		t:          t,
+	}
+}
+
+type InverseTensor struct {
+	baseTensor
+	t Tensor
+}
+
+func (t *InverseTensor) Visit(v TensorVisitor) { v.VisitInverse(t) }
+
+func (e *evaluationVisitor) VisitInverse(t *InverseTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.InverseInto(e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitInverse(t *InverseTensor) {
+	delta := g.collect(t)
+
+	// -a^-T d a^-T
+	inv := batchTranspose(t)
+	g.push(t.t, Negate(batchMatMul(batchMatMul(inv, delta), inv)))
+}
+
+// Solves a x = b for x, where b has the same leading axes as a
+func Solve(a Tensor, b Tensor) Tensor {
+	if err := calc.CheckSolve(a.Shape(), b.Shape()); err != nil {
+		panic(err)
+	}
+	return &SolveTensor{
+		baseTensor: base(b.Shape(), a, b),
+		a:          a,
+		b:          b,
+	}
+}
+
+type SolveTensor struct {
+	baseTensor
+	a Tensor
+	b Tensor
+}
+
+func (t *SolveTensor) Visit(v TensorVisitor) { v.VisitSolve(t) }
+
+func (e *evaluationVisitor) VisitSolve(t *SolveTensor) {
+	a := e.value(t.a)
+	b := e.value(t.b)
+	e.values[t.ID()] = a.SolveInto(b, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitSolve(t *SolveTensor) {
+	delta := g.collect(t)
+
+	// db = a^-T d, da = -db x^T
+	db := Solve(batchTranspose(t.a), delta)
+	g.push(t.b, db)
+	g.push(t.a, Negate(batchMatMul(db, batchTranspose(t))))
+}
+
+// Determinants, with the trailing two axes kept at size 1
+func Det(t Tensor) Tensor {
+	checkSquare("Det", t)
+	return &DetTensor{
+		baseTensor: base(linalgShape(t, 1, 1), t),
+		t:          t,
+	}
+}
+
+type DetTensor struct {
+	baseTensor
+	t Tensor
+}
+
+func (t *DetTensor) Visit(v TensorVisitor) { v.VisitDet(t) }
+
+func (e *evaluationVisitor) VisitDet(t *DetTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.DetInto(e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitDet(t *DetTensor) {
+	delta := g.collect(t)
+
+	// d det(a) a^-T
+	g.push(t.t, Mul(delta, t, batchTranspose(Inverse(t.t))))
+}
+
+// Logs of the absolute determinants, with the trailing two axes kept at size 1
+func LogDet(t Tensor) Tensor {
+	checkSquare("LogDet", t)
+	return &LogDetTensor{
+		baseTensor: base(linalgShape(t, 1, 1), t),
+		t:          t,
+	}
+}
+
+type LogDetTensor struct {
+	baseTensor
+	t Tensor
+}
+
+func (t *LogDetTensor) Visit(v TensorVisitor) { v.VisitLogDet(t) }
+
+func (e *evaluationVisitor) VisitLogDet(t *LogDetTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.LogDetInto(e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitLogDet(t *LogDetTensor) {
+	delta := g.collect(t)
+
+	// d a^-T
+	g.push(t.t, Mul(delta, batchTranspose(Inverse(t.t))))
+}
+
+// The lower triangular L with L L^T = t. Only the lower triangle of t is read.
+func Cholesky(t Tensor) Tensor {
+	checkSquare("Cholesky", t)
+	return &CholeskyTensor{
+		baseTensor: base(t.Shape(), t),
+		t:          t,
+	}
+}
+
+type CholeskyTensor struct {
+	baseTensor
+	t Tensor
+}
+
+func (t *CholeskyTensor) Visit(v TensorVisitor) { v.VisitCholesky(t) }
+
+func (e *evaluationVisitor) VisitCholesky(t *CholeskyTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.CholeskyInto(e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitCholesky(t *CholeskyTensor) {
+	delta := g.collect(t)
+
+	// sym(L^-T phi(L^T d) L^-1), where phi takes the lower triangle and halves the diagonal
+	n := t.Shape()[len(t.Shape())-1]
+	phi := constantMatrix(t, n, func(r int, c int) float64 {
+		if r == c {
+			return 0.5
+		} else if r > c {
+			return 1
+		}
+		return 0
+	})
+	inv := Inverse(t)
+	p := Mul(batchMatMul(batchTranspose(t), delta), phi)
+	g.push(t.t, symmetrize(batchMatMul(batchMatMul(batchTranspose(inv), p), inv)))
+}
+
+// The reduced QR decomposition t = q r. For m x n matrices with k = min(m, n), q is m x k with orthonormal
+// columns and r is k x n upper triangular. Both are differentiable when m >= n and r is invertible.
+func QR(t Tensor) (q Tensor, r Tensor) {
+	checkMatrices("QR", t)
+	rows, cols := t.Shape()[len(t.Shape())-2], t.Shape()[len(t.Shape())-1]
+	k := min(rows, cols)
+	q = &QRTensor{
+		baseTensor: base(linalgShape(t, rows, k), t),
+		t:          t,
+		part:       0,
+	}
+	r = &QRTensor{
+		baseTensor: base(linalgShape(t, k, cols), t),
+		t:          t,
+		part:       1,
+	}
+	return q, r
+}
+
+type QRTensor struct {
+	baseTensor
+	t Tensor
+	// 0 for q, 1 for r
+	part int
+}
+
+func (t *QRTensor) Visit(v TensorVisitor) { v.VisitQR(t) }
+
+func (e *evaluationVisitor) VisitQR(t *QRTensor) {
+	v := e.value(t.t)
+	q, r := v.QR()
+	e.values[t.ID()] = []calc.NDArray{q, r}[t.part].AsTypeInto(e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitQR(t *QRTensor) {
+	delta := g.collect(t)
+
+	shape := t.t.Shape()
+	rows, cols := shape[len(shape)-2], shape[len(shape)-1]
+	if rows < cols {
+		panic(&calc.ShapeError{Op: "QR", Axis: -1, Actual: shape, Reason: "gradient needs at least as many rows as columns"})
+	}
+
+	// (dq + q copyltu(m)) r^-T, where m = r dr^T - dq^T q and copyltu mirrors the lower triangle of m onto
+	// the upper
+	q, r := QR(t.t)
+	var m Tensor
+	if t.part == 0 {
+		m = Negate(batchMatMul(batchTranspose(delta), q))
+	} else {
+		m = batchMatMul(r, batchTranspose(delta))
+	}
+	lower := constantMatrix(t.t, cols, func(r int, c int) float64 {
+		if r >= c {
+			return 1
+		}
+		return 0
+	})
+	strictlyLower := constantMatrix(t.t, cols, func(r int, c int) float64 {
+		if r > c {
+			return 1
+		}
+		return 0
+	})
+	x := batchMatMul(q, Add(Mul(m, lower), batchTranspose(Mul(m, strictlyLower))))
+	if t.part == 0 {
+		x = Add(delta, x)
+	}
+	g.push(t.t, batchTranspose(Solve(r, batchTranspose(x))))
+}
+
+// The reduced singular value decomposition t = u diag(s) v^T. For m x n matrices with k = min(m, n), u
+// is m x k, s has the k singular values in descending order along the last axis, and v is n x k. Gradients
+// of u and v assume the singular values are distinct and nonzero, and are only meaningful for losses that
+// don't change when a column of u and the matching column of v flip sign.
+func SVD(t Tensor) (u Tensor, s Tensor, v Tensor) {
+	checkMatrices("SVD", t)
+	rows, cols := t.Shape()[len(t.Shape())-2], t.Shape()[len(t.Shape())-1]
+	k := min(rows, cols)
+	shapes := [][]int{linalgShape(t, rows, k), linalgShape(t, k), linalgShape(t, cols, k)}
+	parts := make([]Tensor, 3)
+	for i, shape := range shapes {
+		parts[i] = &SVDTensor{
+			baseTensor: base(shape, t),
+			t:          t,
+			part:       i,
+		}
+	}
+	return parts[0], parts[1], parts[2]
+}
+
+type SVDTensor struct {
+	baseTensor
+	t Tensor
+	// 0 for u, 1 for s, 2 for v
+	part int
+}
+
+func (t *SVDTensor) Visit(v TensorVisitor) { v.VisitSVD(t) }
+
+func (e *evaluationVisitor) VisitSVD(t *SVDTensor) {
+	v := e.value(t.t)
+	u, s, vs := v.SVD()
+	e.values[t.ID()] = []calc.NDArray{u, s, vs}[t.part].AsTypeInto(e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitSVD(t *SVDTensor) {
+	delta := g.collect(t)
+
+	u, s, v := SVD(t.t)
+	ut, vt, dt := batchTranspose(u), batchTranspose(v), batchTranspose(delta)
+	if t.part == 1 {
+		// u diag(d) v^T
+		g.push(t.t, batchMatMul(Mul(u, asRow(delta)), vt))
+		return
+	}
+
+	// f[i][j] = 1 / (s[j]^2 - s[i]^2) off the diagonal
+	shape := t.t.Shape()
+	f := inverseDifferences(t.t, Mul(s, s), min(shape[len(shape)-2], shape[len(shape)-1]))
+	inv := PowConstant(s, -1)
+	if t.part == 0 {
+		// u (f * (u^T d - d^T u)) diag(s) v^T + (1 - u u^T) d diag(s)^-1 v^T
+		inner := Mul(Mul(f, Sub(batchMatMul(ut, delta), batchMatMul(dt, u))), asRow(s))
+		outer := Mul(Sub(delta, batchMatMul(u, batchMatMul(ut, delta))), asRow(inv))
+		g.push(t.t, batchMatMul(Add(batchMatMul(u, inner), outer), vt))
+		return
+	}
+
+	// u diag(s) (f * (v^T d - d^T v)) v^T + u diag(s)^-1 d^T (1 - v v^T)
+	inner := Mul(asColumn(s), Mul(f, Sub(batchMatMul(vt, delta), batchMatMul(dt, v))))
+	outer := Mul(asColumn(inv), Sub(dt, batchMatMul(batchMatMul(dt, v), vt)))
+	g.push(t.t, batchMatMul(u, Add(batchMatMul(inner, vt), outer)))
+}
+
+// The eigendecomposition t = v diag(w) v^T of symmetric matrices. w has the eigenvalues in ascending
+// order along the last axis, and the columns of v are the matching eigenvectors. Only the lower triangle
+// of t is read. Gradients of v assume the eigenvalues are distinct.
+func Eigh(t Tensor) (w Tensor, v Tensor) {
+	checkSquare("Eigh", t)
+	w = &EighTensor{
+		baseTensor: base(t.Shape()[:len(t.Shape())-1], t),
+		t:          t,
+		part:       0,
+	}
+	v = &EighTensor{
+		baseTensor: base(t.Shape(), t),
+		t:          t,
+		part:       1,
+	}
+	return w, v
+}
+
+type EighTensor struct {
+	baseTensor
+	t Tensor
+	// 0 for w, 1 for v
+	part int
+}
+
+func (t *EighTensor) Visit(v TensorVisitor) { v.VisitEigh(t) }
+
+func (e *evaluationVisitor) VisitEigh(t *EighTensor) {
+	v := e.value(t.t)
+	w, vs := v.Eigh()
+	e.values[t.ID()] = []calc.NDArray{w, vs}[t.part].AsTypeInto(e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitEigh(t *EighTensor) {
+	delta := g.collect(t)
+
+	w, v := Eigh(t.t)
+	vt := batchTranspose(v)
+	if t.part == 0 {
+		// v diag(d) v^T
+		g.push(t.t, batchMatMul(Mul(v, asRow(delta)), vt))
+		return
+	}
+
+	// sym(v (f * v^T d) v^T), where f[i][j] = 1 / (w[j] - w[i]) off the diagonal
+	f := inverseDifferences(t, w, t.Shape()[len(t.Shape())-1])
+	g.push(t.t, symmetrize(batchMatMul(batchMatMul(v, Mul(f, batchMatMul(vt, delta))), vt)))
+}
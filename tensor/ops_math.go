@@ -1,13 +1,11 @@
 package tensor
 
-import (
-	"github.com/tsholmes/go-dl/calc"
-)
+import "math"
 
 func Add(as ...Tensor) Tensor {
-	shape := elementWise(as...)
+	shape := elementWise("Add", as...)
 	return &AddTensor{
-		baseTensor: base(shape, 2, as...),
+		baseTensor: base(shape, as...),
 		as:         as,
 	}
 }
@@ -20,7 +18,7 @@ type AddTensor struct {
 func (t *AddTensor) Visit(v TensorVisitor) { v.VisitAdd(t) }
 
 func (e *evaluationVisitor) VisitAdd(t *AddTensor) {
-	v, v2 := t.values[0], t.values[1]
+	v, v2 := e.alloc(t), e.alloc(t)
 	v.Fill(0.0)
 	for _, a := range t.as {
 		v2 = v.AddInto(e.value(a), v2)
@@ -42,9 +40,9 @@ func Sub(a Tensor, b Tensor) Tensor {
 }
 
 func Mul(as ...Tensor) Tensor {
-	shape := elementWise(as...)
+	shape := elementWise("Mul", as...)
 	return &MulTensor{
-		baseTensor: base(shape, 2, as...),
+		baseTensor: base(shape, as...),
 		as:         as,
 	}
 }
@@ -57,7 +55,7 @@ type MulTensor struct {
 func (t *MulTensor) Visit(v TensorVisitor) { v.VisitMul(t) }
 
 func (e *evaluationVisitor) VisitMul(t *MulTensor) {
-	v, v2 := t.values[0], t.values[1]
+	v, v2 := e.alloc(t), e.alloc(t)
 	v.Fill(1.0)
 	for _, a := range t.as {
 		v2 = v.MulInto(e.value(a), v2)
@@ -78,12 +76,12 @@ func (g *gradientVisitor) VisitMul(t *MulTensor) {
 }
 
 func Negate(t Tensor) Tensor {
-	return Mul(t, Constant(calc.Constant(-1., t.Shape()...)))
+	return Mul(t, constantLike(t, -1., t.Shape()...))
 }
 
 func Div(a Tensor, b Tensor) Tensor {
 	return &DivTensor{
-		baseTensor: base(elementWise(a, b), 0, a, b),
+		baseTensor: base(elementWise("Div", a, b), a, b),
 		a:          a,
 		b:          b,
 	}
@@ -100,8 +98,7 @@ func (t *DivTensor) Visit(v TensorVisitor) { v.VisitDiv(t) }
 func (e *evaluationVisitor) VisitDiv(t *DivTensor) {
 	a := e.value(t.a)
 	b := e.value(t.b)
-	v := a.Div(b)
-	e.values[t.ID()] = v
+	e.values[t.ID()] = a.DivInto(b, e.alloc(t))
 }
 
 func (g *gradientVisitor) VisitDiv(t *DivTensor) {
@@ -120,7 +117,7 @@ func (g *gradientVisitor) VisitDiv(t *DivTensor) {
 
 func PowConstant(t Tensor, p float64) Tensor {
 	return &PowConstantTensor{
-		baseTensor: base(t.Shape(), 0, t),
+		baseTensor: base(t.Shape(), t),
 		t:          t,
 		p:          p,
 	}
@@ -136,7 +133,7 @@ func (t *PowConstantTensor) Visit(v TensorVisitor) { v.VisitPowConstant(t) }
 
 func (e *evaluationVisitor) VisitPowConstant(t *PowConstantTensor) {
 	v := e.value(t.t)
-	e.values[t.ID()] = v.PowConstant(t.p)
+	e.values[t.ID()] = v.PowConstantInto(t.p, e.alloc(t))
 }
 
 func (g *gradientVisitor) VisitPowConstant(t *PowConstantTensor) {
@@ -144,14 +141,14 @@ func (g *gradientVisitor) VisitPowConstant(t *PowConstantTensor) {
 
 	g.push(t.t, Mul(
 		delta,
-		Constant(calc.Constant(t.p, t.Shape()...)),
+		constantLike(t.t, t.p, t.Shape()...),
 		PowConstant(t.t, t.p-1.),
 	))
 }
 
 func MatMul(a Tensor, b Tensor, a1 int, a2 int) Tensor {
 	return &MatMulTensor{
-		baseTensor: base(matMul(a, b, a1, a2), 1, a, b),
+		baseTensor: base(matMul(a, b, a1, a2), a, b),
 		a:          a,
 		b:          b,
 		a1:         a1,
@@ -173,7 +170,7 @@ func (e *evaluationVisitor) VisitMatMul(t *MatMulTensor) {
 	a := e.value(t.a)
 	b := e.value(t.b)
 
-	e.values[t.ID()] = a.MatMulInto(b, t.a1, t.a2, t.values[0])
+	e.values[t.ID()] = a.MatMulInto(b, t.a1, t.a2, e.alloc(t))
 }
 
 func (g *gradientVisitor) VisitMatMul(t *MatMulTensor) {
@@ -185,7 +182,7 @@ func (g *gradientVisitor) VisitMatMul(t *MatMulTensor) {
 
 func Log(t Tensor) Tensor {
 	return &LogTensor{
-		baseTensor: base(t.Shape(), 0, t),
+		baseTensor: base(t.Shape(), t),
 		t:          t,
 	}
 }
@@ -199,7 +196,7 @@ func (t *LogTensor) Visit(v TensorVisitor) { v.VisitLog(t) }
 
 func (e *evaluationVisitor) VisitLog(t *LogTensor) {
 	v := e.value(t.t)
-	e.values[t.ID()] = v.Log()
+	e.values[t.ID()] = v.LogInto(e.alloc(t))
 }
 
 func (g *gradientVisitor) VisitLog(t *LogTensor) {
@@ -213,7 +210,7 @@ func (g *gradientVisitor) VisitLog(t *LogTensor) {
 
 func Exp(t Tensor) Tensor {
 	return &ExpTensor{
-		baseTensor: base(t.Shape(), 0, t),
+		baseTensor: base(t.Shape(), t),
 		t:          t,
 	}
 }
@@ -227,7 +224,7 @@ func (t *ExpTensor) Visit(v TensorVisitor) { v.VisitExp(t) }
 
 func (e *evaluationVisitor) VisitExp(t *ExpTensor) {
 	v := e.value(t.t)
-	e.values[t.ID()] = v.Exp()
+	e.values[t.ID()] = v.ExpInto(e.alloc(t))
 }
 
 func (g *gradientVisitor) VisitExp(t *ExpTensor) {
@@ -241,7 +238,7 @@ func (g *gradientVisitor) VisitExp(t *ExpTensor) {
 
 func Normalize(t Tensor, axis int) Tensor {
 	return &NormalizeTensor{
-		baseTensor: base(t.Shape(), 1, t),
+		baseTensor: base(t.Shape(), t),
 		t:          t,
 		axis:       axis,
 	}
@@ -257,7 +254,7 @@ func (t *NormalizeTensor) Visit(v TensorVisitor) { v.VisitNormalize(t) }
 
 func (e *evaluationVisitor) VisitNormalize(t *NormalizeTensor) {
 	v := e.value(t.t)
-	o := t.values[0]
+	o := e.alloc(t)
 	e.values[t.ID()] = v.NormalizeInto(t.axis, o)
 }
 
@@ -269,7 +266,7 @@ func (g *gradientVisitor) VisitNormalize(t *NormalizeTensor) {
 
 func InverseNormalize(t Tensor, g Tensor, axis int) Tensor {
 	return &InverseNormalizeTensor{
-		baseTensor: base(t.Shape(), 1, t, g),
+		baseTensor: base(t.Shape(), t, g),
 		t:          t,
 		g:          g,
 		axis:       axis,
@@ -287,7 +284,7 @@ func (t *InverseNormalizeTensor) Visit(v TensorVisitor) { v.VisitInverseNormaliz
 
 func (e *evaluationVisitor) VisitInverseNormalize(t *InverseNormalizeTensor) {
 	v, g := e.value(t.t), e.value(t.g)
-	o := t.values[0]
+	o := e.alloc(t)
 	e.values[t.ID()] = v.InverseNormalizeInto(g, t.axis, o)
 }
 
@@ -296,88 +293,198 @@ func (g *gradientVisitor) VisitInverseNormalize(t *InverseNormalizeTensor) {
 	panic("InverseNormalize is not differentiable")
 }
 
-// k must be (h, w, tFilters, outFilters)
-func Conv2D(t Tensor, k Tensor, hAxis int, wAxis int, fAxis int) Tensor {
-	kh, kw := k.Shape()[0], k.Shape()[1]
-	return &Conv2DTensor{
-		baseTensor: base(conv2d(t, k, hAxis, wAxis, fAxis), 1, t, k),
+func Sqrt(t Tensor) Tensor {
+	return &SqrtTensor{
+		baseTensor: base(t.Shape(), t),
 		t:          t,
-		k:          k,
-		hAxis:      hAxis,
-		wAxis:      wAxis,
-		fAxis:      fAxis,
-		padH:       kh - 1,
-		padW:       kw - 1,
 	}
 }
 
-type Conv2DTensor struct {
+type SqrtTensor struct {
 	baseTensor
-	t     Tensor
-	k     Tensor
-	hAxis int
-	wAxis int
-	fAxis int
-	padH  int
-	padW  int
+	t Tensor
 }
 
-func (t *Conv2DTensor) Visit(v TensorVisitor) { v.VisitConv2D(t) }
+func (t *SqrtTensor) Visit(v TensorVisitor) { v.VisitSqrt(t) }
 
-func (e *evaluationVisitor) VisitConv2D(t *Conv2DTensor) {
-	i := e.value(t.t)
-	k := e.value(t.k)
+func (e *evaluationVisitor) VisitSqrt(t *SqrtTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.SqrtInto(e.alloc(t))
+}
 
-	v := i.Conv2DInto(k, t.hAxis, t.wAxis, t.fAxis, t.values[0])
+func (g *gradientVisitor) VisitSqrt(t *SqrtTensor) {
+	delta := g.collect(t)
 
-	e.values[t.ID()] = v
+	// d / 2sqrt(x)
+	g.push(t.t, Div(
+		delta,
+		Mul(constantLike(t, 2., t.Shape()...), t),
+	))
+}
+
+func Tanh(t Tensor) Tensor {
+	return &TanhTensor{
+		baseTensor: base(t.Shape(), t),
+		t:          t,
+	}
 }
 
-func (g *gradientVisitor) VisitConv2D(t *Conv2DTensor) {
+type TanhTensor struct {
+	baseTensor
+	t Tensor
+}
+
+func (t *TanhTensor) Visit(v TensorVisitor) { v.VisitTanh(t) }
+
+func (e *evaluationVisitor) VisitTanh(t *TanhTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.TanhInto(e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitTanh(t *TanhTensor) {
 	delta := g.collect(t)
 
-	kGrad := InverseConv2D(t.t, delta, t.hAxis, t.wAxis, t.fAxis)
+	// d(1 - tanh(x)^2)
+	g.push(t.t, Mul(
+		delta,
+		Sub(constantLike(t, 1., t.Shape()...), PowConstant(t, 2)),
+	))
+}
 
-	delta = Unslice(delta, t.hAxis, t.Shape()[t.hAxis]+t.padH*2, t.padH)
-	delta = Unslice(delta, t.wAxis, t.Shape()[t.wAxis]+t.padW*2, t.padW)
+func Sin(t Tensor) Tensor {
+	return &SinTensor{
+		baseTensor: base(t.Shape(), t),
+		t:          t,
+	}
+}
+
+type SinTensor struct {
+	baseTensor
+	t Tensor
+}
+
+func (t *SinTensor) Visit(v TensorVisitor) { v.VisitSin(t) }
+
+func (e *evaluationVisitor) VisitSin(t *SinTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.SinInto(e.alloc(t))
+}
 
-	tGrad := Conv2D(delta, Transpose(Reverse(t.k, 0, 1), 2, 3), t.hAxis, t.wAxis, t.fAxis)
+func (g *gradientVisitor) VisitSin(t *SinTensor) {
+	delta := g.collect(t)
 
-	g.push(t.t, tGrad)
-	g.push(t.k, kGrad)
+	g.push(t.t, Mul(delta, Cos(t.t)))
 }
 
-func InverseConv2D(t Tensor, g Tensor, hAxis int, wAxis int, fAxis int) Tensor {
-	return &InverseConv2DTensor{
-		baseTensor: base(inverseConv2d(t, g, hAxis, wAxis, fAxis), 1, t, g),
+func Cos(t Tensor) Tensor {
+	return &CosTensor{
+		baseTensor: base(t.Shape(), t),
 		t:          t,
-		g:          g,
-		hAxis:      hAxis,
-		wAxis:      wAxis,
-		fAxis:      fAxis,
 	}
 }
 
-type InverseConv2DTensor struct {
+type CosTensor struct {
 	baseTensor
-	t     Tensor
-	g     Tensor
-	hAxis int
-	wAxis int
-	fAxis int
+	t Tensor
 }
 
-func (t *InverseConv2DTensor) Visit(v TensorVisitor) { v.VisitInverseConv2D(t) }
+func (t *CosTensor) Visit(v TensorVisitor) { v.VisitCos(t) }
 
-func (e *evaluationVisitor) VisitInverseConv2D(t *InverseConv2DTensor) {
-	i := e.value(t.t)
-	g := e.value(t.g)
+func (e *evaluationVisitor) VisitCos(t *CosTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.CosInto(e.alloc(t))
+}
 
-	v := i.InverseConv2DInto(g, t.hAxis, t.wAxis, t.fAxis, t.values[0])
+func (g *gradientVisitor) VisitCos(t *CosTensor) {
+	delta := g.collect(t)
 
-	e.values[t.ID()] = v
+	g.push(t.t, Negate(Mul(delta, Sin(t.t))))
+}
+
+func Erf(t Tensor) Tensor {
+	return &ErfTensor{
+		baseTensor: base(t.Shape(), t),
+		t:          t,
+	}
+}
+
+type ErfTensor struct {
+	baseTensor
+	t Tensor
+}
+
+func (t *ErfTensor) Visit(v TensorVisitor) { v.VisitErf(t) }
+
+func (e *evaluationVisitor) VisitErf(t *ErfTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.ErfInto(e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitErf(t *ErfTensor) {
+	delta := g.collect(t)
+
+	// d * 2/sqrt(pi) * e^-x^2
+	g.push(t.t, Mul(
+		delta,
+		constantLike(t, 2./math.Sqrt(math.Pi), t.Shape()...),
+		Exp(Negate(PowConstant(t.t, 2))),
+	))
+}
+
+// log(1 + t), accurate for t near 0
+func Log1p(t Tensor) Tensor {
+	return &Log1pTensor{
+		baseTensor: base(t.Shape(), t),
+		t:          t,
+	}
+}
+
+type Log1pTensor struct {
+	baseTensor
+	t Tensor
+}
+
+func (t *Log1pTensor) Visit(v TensorVisitor) { v.VisitLog1p(t) }
+
+func (e *evaluationVisitor) VisitLog1p(t *Log1pTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.Log1pInto(e.alloc(t))
 }
 
-func (g *gradientVisitor) VisitInverseConv2D(t *InverseConv2DTensor) {
-	panic("InverseConv2D is not differentiable")
+func (g *gradientVisitor) VisitLog1p(t *Log1pTensor) {
+	delta := g.collect(t)
+
+	g.push(t.t, Div(
+		delta,
+		Add(constantLike(t.t, 1., t.Shape()...), t.t),
+	))
+}
+
+// e^t - 1, accurate for t near 0
+func Expm1(t Tensor) Tensor {
+	return &Expm1Tensor{
+		baseTensor: base(t.Shape(), t),
+		t:          t,
+	}
+}
+
+type Expm1Tensor struct {
+	baseTensor
+	t Tensor
+}
+
+func (t *Expm1Tensor) Visit(v TensorVisitor) { v.VisitExpm1(t) }
+
+func (e *evaluationVisitor) VisitExpm1(t *Expm1Tensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.Expm1Into(e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitExpm1(t *Expm1Tensor) {
+	delta := g.collect(t)
+
+	g.push(t.t, Mul(
+		delta,
+		Add(t, constantLike(t, 1., t.Shape()...)),
+	))
 }
@@ -0,0 +1,74 @@
+package tensor
+
+import "github.com/tsholmes/go-dl/calc"
+
+// Resamples t to outH x outW along hAxis and wAxis (see calc.ResizeMethod)
+func Resize2D(t Tensor, hAxis int, wAxis int, outH int, outW int, method calc.ResizeMethod) Tensor {
+	return &Resize2DTensor{
+		baseTensor: base(calc.Must(calc.CheckResize2D(t.Shape(), hAxis, wAxis, outH, outW)), t),
+		t:          t,
+		hAxis:      hAxis,
+		wAxis:      wAxis,
+		method:     method,
+	}
+}
+
+// Resizes t to scaleH times its height and scaleW times its width
+func UpSample2D(t Tensor, hAxis int, wAxis int, scaleH int, scaleW int, method calc.ResizeMethod) Tensor {
+	checkAxes("UpSample2D", t, hAxis, wAxis)
+	shape := t.Shape()
+	return Resize2D(t, hAxis, wAxis, shape[hAxis]*scaleH, shape[wAxis]*scaleW, method)
+}
+
+type Resize2DTensor struct {
+	baseTensor
+	t      Tensor
+	hAxis  int
+	wAxis  int
+	method calc.ResizeMethod
+}
+
+func (t *Resize2DTensor) Visit(v TensorVisitor) { v.VisitResize2D(t) }
+
+func (e *evaluationVisitor) VisitResize2D(t *Resize2DTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.Resize2DInto(t.hAxis, t.wAxis, t.method, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitResize2D(t *Resize2DTensor) {
+	delta := g.collect(t)
+
+	g.push(t.t, resize2DTranspose(delta, t.t.Shape(), t.hAxis, t.wAxis, t.method))
+}
+
+// the adjoint of Resize2D back to shape
+func resize2DTranspose(t Tensor, shape []int, hAxis int, wAxis int, method calc.ResizeMethod) Tensor {
+	return &Resize2DTransposeTensor{
+		baseTensor: base(shape, t),
+		t:          t,
+		hAxis:      hAxis,
+		wAxis:      wAxis,
+		method:     method,
+	}
+}
+
+type Resize2DTransposeTensor struct {
+	baseTensor
+	t      Tensor
+	hAxis  int
+	wAxis  int
+	method calc.ResizeMethod
+}
+
+func (t *Resize2DTransposeTensor) Visit(v TensorVisitor) { v.VisitResize2DTranspose(t) }
+
+func (e *evaluationVisitor) VisitResize2DTranspose(t *Resize2DTransposeTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.Resize2DTransposeInto(t.hAxis, t.wAxis, t.method, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitResize2DTranspose(t *Resize2DTransposeTensor) {
+	delta := g.collect(t)
+
+	g.push(t.t, Resize2D(delta, t.hAxis, t.wAxis, t.t.Shape()[t.hAxis], t.t.Shape()[t.wAxis], t.method))
+}
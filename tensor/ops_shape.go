@@ -1,8 +1,10 @@
 package tensor
 
+import "github.com/tsholmes/go-dl/calc"
+
 func Concat(axis int, as ...Tensor) Tensor {
 	return &ConcatTensor{
-		baseTensor: base(concat(axis, as...), 0, as...),
+		baseTensor: base(concat(axis, as...), as...),
 		axis:       axis,
 		as:         as,
 	}
@@ -17,11 +19,13 @@ type ConcatTensor struct {
 func (t *ConcatTensor) Visit(v TensorVisitor) { v.VisitConcat(t) }
 
 func (e *evaluationVisitor) VisitConcat(t *ConcatTensor) {
-	v := e.value(t.as[0])
-	for _, a := range t.as[1:] {
-		v = v.Concat(e.value(a), t.axis)
+	o := e.alloc(t)
+	offset := 0
+	for _, a := range t.as {
+		o.SetSlice(e.value(a), t.axis, offset)
+		offset += a.Shape()[t.axis]
 	}
-	e.values[t.ID()] = v
+	e.values[t.ID()] = o
 }
 
 func (g *gradientVisitor) VisitConcat(t *ConcatTensor) {
@@ -38,7 +42,7 @@ func (g *gradientVisitor) VisitConcat(t *ConcatTensor) {
 
 func Slice(t Tensor, axis int, start int, end int) Tensor {
 	return &SliceTensor{
-		baseTensor: base(resize(t, axis, end-start), 1, t),
+		baseTensor: base(slice(t, axis, start, end), t),
 		t:          t,
 		axis:       axis,
 		start:      start,
@@ -58,8 +62,7 @@ func (t *SliceTensor) Visit(v TensorVisitor) { v.VisitSlice(t) }
 
 func (e *evaluationVisitor) VisitSlice(t *SliceTensor) {
 	v := e.value(t.t)
-	o := t.values[0]
-	e.values[t.ID()] = v.SliceInto(t.axis, t.start, t.end, o)
+	e.values[t.ID()] = v.Slice(t.axis, t.start, t.end)
 }
 
 func (g *gradientVisitor) VisitSlice(t *SliceTensor) {
@@ -70,7 +73,7 @@ func (g *gradientVisitor) VisitSlice(t *SliceTensor) {
 
 func Unslice(t Tensor, axis int, size int, offset int) Tensor {
 	return &UnsliceTensor{
-		baseTensor: base(resize(t, axis, size), 1, t),
+		baseTensor: base(resize(t, axis, size), t),
 		t:          t,
 		axis:       axis,
 		size:       size,
@@ -90,7 +93,7 @@ func (t *UnsliceTensor) Visit(v TensorVisitor) { v.VisitUnslice(t) }
 
 func (e *evaluationVisitor) VisitUnslice(t *UnsliceTensor) {
 	v := e.value(t.t)
-	o := t.values[0]
+	o := e.alloc(t)
 	o.SetSlice(v, t.axis, t.offset)
 	e.values[t.ID()] = o
 }
@@ -103,7 +106,7 @@ func (g *gradientVisitor) VisitUnslice(t *UnsliceTensor) {
 
 func Transpose(t Tensor, a1 int, a2 int) Tensor {
 	return &TransposeTensor{
-		baseTensor: base(transpose(t, a1, a2), 0, t),
+		baseTensor: base(transpose(t, a1, a2), t),
 		t:          t,
 		a1:         a1,
 		a2:         a2,
@@ -132,7 +135,7 @@ func (g *gradientVisitor) VisitTranspose(t *TransposeTensor) {
 
 func Reshape(t Tensor, shape ...int) Tensor {
 	return &ReshapeTensor{
-		baseTensor: base(shape, 0, t),
+		baseTensor: base(reshape(t, shape), t),
 		t:          t,
 	}
 }
@@ -167,8 +170,9 @@ func Flatten(t Tensor, axis int) Tensor {
 
 func Reverse(t Tensor, axes ...int) Tensor {
 	return &ReverseTensor{
-		baseTensor: base(t.Shape(), 0, t),
+		baseTensor: base(t.Shape(), t),
 		t:          t,
+		axes:       axes,
 	}
 }
 
@@ -190,3 +194,204 @@ func (g *gradientVisitor) VisitReverse(t *ReverseTensor) {
 
 	g.push(t.t, Reverse(delta, t.axes...))
 }
+
+// Pads each axis of t by its {before, after} pair of widths (see calc.PadMode), filling with value in
+// calc.PadConstant mode
+func Pad(t Tensor, widths [][2]int, mode calc.PadMode, value float64) Tensor {
+	return &PadTensor{
+		baseTensor: base(calc.Must(calc.CheckPad(t.Shape(), widths, mode)), t),
+		t:          t,
+		widths:     widths,
+		mode:       mode,
+		value:      value,
+	}
+}
+
+type PadTensor struct {
+	baseTensor
+	t      Tensor
+	widths [][2]int
+	mode   calc.PadMode
+	value  float64
+}
+
+func (t *PadTensor) Visit(v TensorVisitor) { v.VisitPad(t) }
+
+func (e *evaluationVisitor) VisitPad(t *PadTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.PadInto(t.widths, t.mode, t.value, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitPad(t *PadTensor) {
+	delta := g.collect(t)
+
+	g.push(t.t, unpad(delta, t.t.Shape(), t.widths, t.mode))
+}
+
+// the adjoint of Pad, summing padding back into the elements it was copied from
+func unpad(t Tensor, shape []int, widths [][2]int, mode calc.PadMode) Tensor {
+	return &UnpadTensor{
+		baseTensor: base(shape, t),
+		t:          t,
+		widths:     widths,
+		mode:       mode,
+	}
+}
+
+type UnpadTensor struct {
+	baseTensor
+	t      Tensor
+	widths [][2]int
+	mode   calc.PadMode
+}
+
+func (t *UnpadTensor) Visit(v TensorVisitor) { v.VisitUnpad(t) }
+
+func (e *evaluationVisitor) VisitUnpad(t *UnpadTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.UnpadInto(t.widths, t.mode, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitUnpad(t *UnpadTensor) {
+	delta := g.collect(t)
+
+	g.push(t.t, Pad(delta, t.widths, t.mode, 0))
+}
+
+// Reorders the axes of t so axis i of the result is axis axes[i] of t
+func Permute(t Tensor, axes ...int) Tensor {
+	return &PermuteTensor{
+		baseTensor: base(calc.Must(calc.CheckPermute(t.Shape(), axes)), t),
+		t:          t,
+		axes:       axes,
+	}
+}
+
+type PermuteTensor struct {
+	baseTensor
+	t    Tensor
+	axes []int
+}
+
+func (t *PermuteTensor) Visit(v TensorVisitor) { v.VisitPermute(t) }
+
+func (e *evaluationVisitor) VisitPermute(t *PermuteTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.Permute(t.axes...)
+}
+
+func (g *gradientVisitor) VisitPermute(t *PermuteTensor) {
+	delta := g.collect(t)
+
+	g.push(t.t, Permute(delta, calc.InversePermutation(t.axes)...))
+}
+
+// Inserts a size 1 axis before axis, which may be the rank of t to append one
+func ExpandDims(t Tensor, axis int) Tensor {
+	return Reshape(t, calc.Must(calc.CheckExpandDims(t.Shape(), axis))...)
+}
+
+// Removes the given size 1 axes, or every size 1 axis if none are given
+func Squeeze(t Tensor, axes ...int) Tensor {
+	return Reshape(t, calc.Must(calc.CheckSqueeze(t.Shape(), axes...))...)
+}
+
+// Joins tensors of the same shape along a new axis
+func Stack(axis int, ts ...Tensor) Tensor {
+	shapes := make([][]int, len(ts))
+	for i, t := range ts {
+		shapes[i] = t.Shape()
+	}
+	calc.Must(calc.CheckStack(axis, shapes...))
+	expanded := make([]Tensor, len(ts))
+	for i, t := range ts {
+		expanded[i] = ExpandDims(t, axis)
+	}
+	return Concat(axis, expanded...)
+}
+
+// Slices t into pieces of size batch along axis
+func Split(t Tensor, axis int, batch int) []Tensor {
+	if err := calc.CheckSplit(t.Shape(), axis, batch); err != nil {
+		panic(err)
+	}
+	ts := make([]Tensor, t.Shape()[axis]/batch)
+	for i := range ts {
+		ts[i] = Slice(t, axis, i*batch, (i+1)*batch)
+	}
+	return ts
+}
+
+// Repeats the whole of t reps[i] times along each axis i, adding leading axes when reps is longer than the
+// shape like numpy.tile
+func Tile(t Tensor, reps ...int) Tensor {
+	return &TileTensor{
+		baseTensor: base(calc.Must(calc.CheckTile(t.Shape(), reps)), t),
+		t:          t,
+		reps:       reps,
+	}
+}
+
+type TileTensor struct {
+	baseTensor
+	t    Tensor
+	reps []int
+}
+
+func (t *TileTensor) Visit(v TensorVisitor) { v.VisitTile(t) }
+
+func (e *evaluationVisitor) VisitTile(t *TileTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.TileInto(t.reps, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitTile(t *TileTensor) {
+	delta := g.collect(t)
+
+	// sum the tiles, split out as (reps[0], shape[0], reps[1], shape[1], ...) with both padded to the same
+	// length by leading 1s
+	n := max(len(t.reps), len(t.t.Shape()))
+	reps, inShape := onesShape(n), onesShape(n)
+	copy(reps[n-len(t.reps):], t.reps)
+	copy(inShape[n-len(t.t.Shape()):], t.t.Shape())
+	shape := make([]int, 0, 2*n)
+	axes := make([]int, n)
+	for i, r := range reps {
+		shape = append(shape, r, inShape[i])
+		axes[i] = 2 * i
+	}
+	g.push(t.t, Reshape(Sum(Reshape(delta, shape...), axes...), t.t.Shape()...))
+}
+
+// Repeats each element of t repeats times along axis
+func Repeat(t Tensor, axis int, repeats int) Tensor {
+	return &RepeatTensor{
+		baseTensor: base(calc.Must(calc.CheckRepeat(t.Shape(), axis, repeats)), t),
+		t:          t,
+		axis:       axis,
+		repeats:    repeats,
+	}
+}
+
+type RepeatTensor struct {
+	baseTensor
+	t       Tensor
+	axis    int
+	repeats int
+}
+
+func (t *RepeatTensor) Visit(v TensorVisitor) { v.VisitRepeat(t) }
+
+func (e *evaluationVisitor) VisitRepeat(t *RepeatTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.RepeatInto(t.axis, t.repeats, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitRepeat(t *RepeatTensor) {
+	delta := g.collect(t)
+
+	// sum the repeats, split out onto an axis after axis
+	inShape := t.t.Shape()
+	shape := append(append(append([]int{}, inShape[:t.axis+1]...), t.repeats), inShape[t.axis+1:]...)
+	g.push(t.t, Reshape(Sum(Reshape(delta, shape...), t.axis+1), inShape...))
+}
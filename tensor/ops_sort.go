@@ -0,0 +1,178 @@
+package tensor
+
+import "github.com/tsholmes/go-dl/calc"
+
+// Indices of the largest elements along axis as an Int64 tensor, with axis kept at size 1
+func ArgMax(t Tensor, axis int) Tensor {
+	return &ArgMaxTensor{
+		baseTensor: baseOf(calc.Int64, aggr("ArgMax", t, axis), t),
+		t:          t,
+		axis:       axis,
+	}
+}
+
+type ArgMaxTensor struct {
+	baseTensor
+	t    Tensor
+	axis int
+}
+
+func (t *ArgMaxTensor) Visit(v TensorVisitor) { v.VisitArgMax(t) }
+
+func (e *evaluationVisitor) VisitArgMax(t *ArgMaxTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.ArgMaxInto(t.axis, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitArgMax(t *ArgMaxTensor) {
+	// indices have no gradient
+	g.collect(t)
+}
+
+// Indices of the smallest elements along axis as an Int64 tensor, with axis kept at size 1
+func ArgMin(t Tensor, axis int) Tensor {
+	return &ArgMinTensor{
+		baseTensor: baseOf(calc.Int64, aggr("ArgMin", t, axis), t),
+		t:          t,
+		axis:       axis,
+	}
+}
+
+type ArgMinTensor struct {
+	baseTensor
+	t    Tensor
+	axis int
+}
+
+func (t *ArgMinTensor) Visit(v TensorVisitor) { v.VisitArgMin(t) }
+
+func (e *evaluationVisitor) VisitArgMin(t *ArgMinTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.ArgMinInto(t.axis, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitArgMin(t *ArgMinTensor) {
+	// indices have no gradient
+	g.collect(t)
+}
+
+// Indices that stably sort t in ascending order along axis, as an Int64 tensor
+func ArgSort(t Tensor, axis int) Tensor {
+	checkAxes("ArgSort", t, axis)
+	return &ArgSortTensor{
+		baseTensor: baseOf(calc.Int64, t.Shape(), t),
+		t:          t,
+		axis:       axis,
+	}
+}
+
+type ArgSortTensor struct {
+	baseTensor
+	t    Tensor
+	axis int
+}
+
+func (t *ArgSortTensor) Visit(v TensorVisitor) { v.VisitArgSort(t) }
+
+func (e *evaluationVisitor) VisitArgSort(t *ArgSortTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.ArgSortInto(t.axis, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitArgSort(t *ArgSortTensor) {
+	// indices have no gradient
+	g.collect(t)
+}
+
+func Sort(t Tensor, axis int) Tensor {
+	checkAxes("Sort", t, axis)
+	return &SortTensor{
+		baseTensor: base(t.Shape(), t),
+		t:          t,
+		axis:       axis,
+	}
+}
+
+type SortTensor struct {
+	baseTensor
+	t    Tensor
+	axis int
+}
+
+func (t *SortTensor) Visit(v TensorVisitor) { v.VisitSort(t) }
+
+func (e *evaluationVisitor) VisitSort(t *SortTensor) {
+	v := e.value(t.t)
+	e.values[t.ID()] = v.SortInto(t.axis, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitSort(t *SortTensor) {
+	delta := g.collect(t)
+
+	// each element gets the gradient of the position it was sorted to
+	g.push(t.t, ScatterAdd(delta, ArgSort(t.t, t.axis), t.axis, t.t.Shape()[t.axis]))
+}
+
+// The k largest elements along axis in descending order
+func TopK(t Tensor, k int, axis int) Tensor {
+	return &TopKTensor{
+		baseTensor: base(calc.Must(calc.CheckTopK(t.Shape(), k, axis)), t),
+		t:          t,
+		k:          k,
+		axis:       axis,
+	}
+}
+
+type TopKTensor struct {
+	baseTensor
+	t    Tensor
+	k    int
+	axis int
+}
+
+func (t *TopKTensor) Visit(v TensorVisitor) { v.VisitTopK(t) }
+
+func (e *evaluationVisitor) VisitTopK(t *TopKTensor) {
+	v := e.value(t.t)
+	indices := calc.ZerosOf(calc.Int64, t.Shape()...)
+	vals, _ := v.TopKInto(t.axis, e.alloc(t), indices)
+	e.values[t.ID()] = vals
+}
+
+func (g *gradientVisitor) VisitTopK(t *TopKTensor) {
+	delta := g.collect(t)
+
+	// only the elements that were kept get a gradient
+	g.push(t.t, ScatterAdd(delta, TopKIndices(t.t, t.k, t.axis), t.axis, t.t.Shape()[t.axis]))
+}
+
+// Indices of the k largest elements along axis as an Int64 tensor, in the same order as TopK
+func TopKIndices(t Tensor, k int, axis int) Tensor {
+	return &TopKIndicesTensor{
+		baseTensor: baseOf(calc.Int64, calc.Must(calc.CheckTopK(t.Shape(), k, axis)), t),
+		t:          t,
+		k:          k,
+		axis:       axis,
+	}
+}
+
+type TopKIndicesTensor struct {
+	baseTensor
+	t    Tensor
+	k    int
+	axis int
+}
+
+func (t *TopKIndicesTensor) Visit(v TensorVisitor) { v.VisitTopKIndices(t) }
+
+func (e *evaluationVisitor) VisitTopKIndices(t *TopKIndicesTensor) {
+	v := e.value(t.t)
+	vals := calc.ZerosOf(t.t.DType(), t.Shape()...)
+	_, indices := v.TopKInto(t.axis, vals, e.alloc(t))
+	e.values[t.ID()] = indices
+}
+
+func (g *gradientVisitor) VisitTopKIndices(t *TopKIndicesTensor) {
+	// indices have no gradient
+	g.collect(t)
+}
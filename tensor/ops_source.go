@@ -3,8 +3,13 @@ package tensor
 import "github.com/tsholmes/go-dl/calc"
 
 func Input(shape ...int) Tensor {
+	return InputOf(calc.Float64, shape...)
+}
+
+// Provided values are converted to dtype if needed
+func InputOf(dtype calc.DType, shape ...int) Tensor {
 	return &InputTensor{
-		baseTensor: base(shape, 0),
+		baseTensor: baseOf(dtype, shape),
 		shape:      shape,
 	}
 }
@@ -17,8 +22,15 @@ type InputTensor struct {
 func (t *InputTensor) Visit(v TensorVisitor) { v.VisitInput(t) }
 
 func (e *evaluationVisitor) VisitInput(t *InputTensor) {
-	// Just assert that it was passed
-	e.value(t)
+	// Just assert that it was passed with the right shape
+	v := e.value(t)
+	if shape := v.Shape(); !calc.ShapeEqual(shape, t.shape) {
+		panic(&calc.ShapeError{Op: "Input", Axis: -1, Expected: t.shape, Actual: shape, Reason: "provided value has the wrong shape"})
+	}
+	if v.DType() != t.dtype {
+		v = v.AsTypeInto(e.alloc(t))
+	}
+	e.values[t.ID()] = v
 }
 
 func (g *gradientVisitor) VisitInput(t *InputTensor) {
@@ -27,7 +39,7 @@ func (g *gradientVisitor) VisitInput(t *InputTensor) {
 
 func Constant(value calc.NDArray) Tensor {
 	return &ConstantTensor{
-		baseTensor: base(value.Shape(), 0),
+		baseTensor: baseOf(value.DType(), value.Shape()),
 		value:      value,
 	}
 }
@@ -52,3 +64,12 @@ func (g *gradientVisitor) VisitConstant(t *ConstantTensor) {
 func Ones(shape ...int) Tensor {
 	return Constant(calc.Ones(shape...))
 }
+
+// A constant with the dtype of t, so that combining them doesn't promote t. Constants for Int64 and
+// Bool tensors are Float64 so that the value isn't truncated.
+func constantLike(t Tensor, value float64, shape ...int) Tensor {
+	if !t.DType().IsFloat() {
+		return Constant(calc.Constant(value, shape...))
+	}
+	return Constant(calc.Constant(value, shape...).AsType(t.DType()))
+}
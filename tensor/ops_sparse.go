@@ -0,0 +1,133 @@
+package tensor
+
+import (
+	"fmt"
+
+	"github.com/tsholmes/go-dl/calc"
+)
+
+// Sparse tensors have a calc.CSR value instead of an array. Only SparseMatMul and SparseTranspose can read
+// them, and they have no gradient.
+
+// A sparse rows x cols matrix, provided with ProvideSparse
+func SparseInput(rows int, cols int) Tensor {
+	return &SparseInputTensor{
+		baseTensor: base([]int{rows, cols}),
+	}
+}
+
+type SparseInputTensor struct {
+	baseTensor
+	transpose Tensor
+}
+
+func (t *SparseInputTensor) Visit(v TensorVisitor) { v.VisitSparseInput(t) }
+
+func (e *evaluationVisitor) VisitSparseInput(t *SparseInputTensor) {
+	s, ok := e.sparse[t.ID()]
+	if !ok {
+		panic(fmt.Sprintf("missing sparse value for tensor %d", t.ID()))
+	}
+	if shape := s.Shape(); !calc.ShapeEqual(shape, t.Shape()) {
+		panic(&calc.ShapeError{Op: "SparseInput", Axis: -1, Expected: t.Shape(), Actual: shape, Reason: "provided value has the wrong shape"})
+	}
+}
+
+func (g *gradientVisitor) VisitSparseInput(t *SparseInputTensor) {}
+
+func SparseConstant(value calc.CSR) Tensor {
+	return &SparseConstantTensor{
+		baseTensor: base(value.Shape()),
+		value:      value,
+	}
+}
+
+type SparseConstantTensor struct {
+	baseTensor
+	value     calc.CSR
+	transpose Tensor
+}
+
+func (t *SparseConstantTensor) Visit(v TensorVisitor) { v.VisitSparseConstant(t) }
+
+func (e *evaluationVisitor) VisitSparseConstant(t *SparseConstantTensor) {
+	e.sparse[t.ID()] = t.value
+}
+
+func (g *gradientVisitor) VisitSparseConstant(t *SparseConstantTensor) {}
+
+func IsSparse(t Tensor) bool {
+	switch t.(type) {
+	case *SparseInputTensor, *SparseConstantTensor, *SparseTransposeTensor:
+		return true
+	}
+	return false
+}
+
+// The transpose of a sparse matrix. A constant is transposed here, and the transpose of each input is only
+// built once, so the gradients of every SparseMatMul reading it share one transpose per evaluation.
+func SparseTranspose(a Tensor) Tensor {
+	switch a := a.(type) {
+	case *SparseConstantTensor:
+		if a.transpose == nil {
+			a.transpose = SparseConstant(a.value.Transpose())
+		}
+		return a.transpose
+	case *SparseInputTensor:
+		if a.transpose == nil {
+			a.transpose = &SparseTransposeTensor{
+				baseTensor: base([]int{a.Shape()[1], a.Shape()[0]}, a),
+				a:          a,
+			}
+		}
+		return a.transpose
+	case *SparseTransposeTensor:
+		return a.a
+	}
+	panic(fmt.Sprintf("SparseTranspose needs a sparse a, got %T", a))
+}
+
+type SparseTransposeTensor struct {
+	baseTensor
+	a Tensor
+}
+
+func (t *SparseTransposeTensor) Visit(v TensorVisitor) { v.VisitSparseTranspose(t) }
+
+func (e *evaluationVisitor) VisitSparseTranspose(t *SparseTransposeTensor) {
+	e.sparse[t.ID()] = e.sparse[t.a.ID()].Transpose()
+}
+
+func (g *gradientVisitor) VisitSparseTranspose(t *SparseTransposeTensor) {}
+
+// a b for a sparse m x k a and a dense k x n b, in the dtype of b. Only b is differentiable.
+func SparseMatMul(a Tensor, b Tensor) Tensor {
+	if !IsSparse(a) {
+		panic(fmt.Sprintf("SparseMatMul needs a sparse a, got %T", a))
+	}
+	return &SparseMatMulTensor{
+		baseTensor: baseOf(b.DType(), calc.Must(calc.CheckMatMul(a.Shape(), b.Shape(), 0, 1)), a, b),
+		a:          a,
+		b:          b,
+	}
+}
+
+type SparseMatMulTensor struct {
+	baseTensor
+	a Tensor
+	b Tensor
+}
+
+func (t *SparseMatMulTensor) Visit(v TensorVisitor) { v.VisitSparseMatMul(t) }
+
+func (e *evaluationVisitor) VisitSparseMatMul(t *SparseMatMulTensor) {
+	b := e.value(t.b)
+	e.values[t.ID()] = e.sparse[t.a.ID()].MatMulInto(b, e.alloc(t))
+}
+
+func (g *gradientVisitor) VisitSparseMatMul(t *SparseMatMulTensor) {
+	delta := g.collect(t)
+
+	// a^T d
+	g.push(t.b, SparseMatMul(SparseTranspose(t.a), delta))
+}
@@ -0,0 +1,28 @@
+package tensor_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+func TestReductionGradients(t *testing.T) {
+	rng := calc.NewRNG(33)
+	reduce := func(op func(t tensor.Tensor, axes ...int) tensor.Tensor, axes ...int) func(ins ...tensor.Tensor) tensor.Tensor {
+		return func(ins ...tensor.Tensor) tensor.Tensor { return op(ins[0], axes...) }
+	}
+	checkGradients(t, []gradientCase{
+		{"Min", reduce(tensor.Min, 1), []calc.NDArray{rng.Normal(0, 1, 3, 4)}},
+		{"Min of all axes", reduce(tensor.Min, 0, 1), []calc.NDArray{rng.Normal(0, 1, 3, 4)}},
+		{"Prod", reduce(tensor.Prod, 0), []calc.NDArray{rng.Normal(0, 1, 3, 4)}},
+		{"Prod of two axes", reduce(tensor.Prod, 0, 2), []calc.NDArray{rng.Normal(0, 1, 2, 3, 2)}},
+		{"LogSumExp", reduce(tensor.LogSumExp, 1), []calc.NDArray{rng.Normal(0, 3, 3, 4)}},
+		{"LogSumExp of two axes", reduce(tensor.LogSumExp, 0, 2), []calc.NDArray{rng.Normal(0, 1, 2, 3, 2)}},
+		{"Var", reduce(tensor.Var, 1), []calc.NDArray{rng.Normal(0, 1, 3, 4)}},
+		{"Var of two axes", reduce(tensor.Var, 0, 2), []calc.NDArray{rng.Normal(0, 1, 2, 3, 2)}},
+		{"Std", reduce(tensor.Std, 0), []calc.NDArray{rng.Normal(0, 1, 3, 4)}},
+		{"CumSum", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.CumSum(ins[0], 1) }, []calc.NDArray{rng.Normal(0, 1, 3, 4)}},
+		{"CumProd", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.CumProd(ins[0], 0) }, []calc.NDArray{rng.Normal(0, 1, 4, 3)}},
+	})
+}
@@ -0,0 +1,24 @@
+package tensor_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+func TestResizeGradients(t *testing.T) {
+	rng := calc.NewRNG(47)
+	resize := func(outH int, outW int, method calc.ResizeMethod) func(ins ...tensor.Tensor) tensor.Tensor {
+		return func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Resize2D(ins[0], 1, 2, outH, outW, method) }
+	}
+	checkGradients(t, []gradientCase{
+		{"Resize2D nearest up", resize(5, 7, calc.ResizeNearest), []calc.NDArray{rng.Normal(0, 1, 2, 3, 4, 2)}},
+		{"Resize2D nearest down", resize(2, 3, calc.ResizeNearest), []calc.NDArray{rng.Normal(0, 1, 2, 3, 4, 2)}},
+		{"Resize2D bilinear up", resize(5, 7, calc.ResizeBilinear), []calc.NDArray{rng.Normal(0, 1, 2, 3, 4, 2)}},
+		{"Resize2D bilinear down", resize(2, 3, calc.ResizeBilinear), []calc.NDArray{rng.Normal(0, 1, 2, 3, 4, 2)}},
+		{"UpSample2D channels first", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.UpSample2D(ins[0], 2, 3, 2, 3, calc.ResizeBilinear)
+		}, []calc.NDArray{rng.Normal(0, 1, 1, 2, 3, 2)}},
+	})
+}
@@ -1,38 +1,65 @@
 package tensor
 
-import (
-	"github.com/tsholmes/go-dl/calc"
-)
+import "github.com/tsholmes/go-dl/calc"
 
-func elementWise(as ...Tensor) []int {
-	// TODO: validate equal
+// the shape all of as broadcast to (see calc.BroadcastShape)
+func elementWise(op string, as ...Tensor) []int {
 	newShape := as[0].Shape()
 	for _, a := range as[1:] {
-		newShape = calc.BroadcastShape(newShape, a.Shape())
+		newShape = calc.Must(calc.CheckBroadcast(op, newShape, a.Shape()))
 	}
 	return newShape
 }
 
-func concat(axis int, as ...Tensor) []int {
-	// TODO validate match
-	shape := make([]int, len(as[0].Shape()))
-	copy(shape, as[0].Shape())
-	for i := 1; i < len(as); i++ {
-		shape[axis] += as[i].Shape()[axis]
+// a shape of n size 1 axes
+func onesShape(n int) []int {
+	shape := make([]int, n)
+	for i := range shape {
+		shape[i] = 1
 	}
 	return shape
 }
 
-func aggr(a Tensor, axes ...int) []int {
+func concat(axis int, as ...Tensor) []int {
+	shapes := make([][]int, len(as))
+	for i, a := range as {
+		shapes[i] = a.Shape()
+	}
+	return calc.Must(calc.CheckConcat(axis, shapes...))
+}
+
+func aggr(op string, a Tensor, axes ...int) []int {
+	checkAxes(op, a, axes...)
 	return calc.AggrShape(a.Shape(), axes)
 }
 
+func checkAxes(op string, a Tensor, axes ...int) {
+	if err := calc.CheckAxes(op, a.Shape(), axes...); err != nil {
+		panic(err)
+	}
+}
+
 func transpose(a Tensor, a1 int, a2 int) []int {
+	checkAxes("Transpose", a, a1, a2)
 	return calc.TransposeShape(a.Shape(), a1, a2)
 }
 
 func matMul(a Tensor, b Tensor, a1 int, a2 int) []int {
-	return calc.MatMulShape(a.Shape(), b.Shape(), a1, a2)
+	return calc.Must(calc.CheckMatMul(a.Shape(), b.Shape(), a1, a2))
+}
+
+func reshape(a Tensor, shape []int) []int {
+	if err := calc.CheckReshape(a.Shape(), shape); err != nil {
+		panic(err)
+	}
+	return shape
+}
+
+func slice(a Tensor, axis int, start int, end int) []int {
+	if err := calc.CheckSlice(a.Shape(), axis, start, end); err != nil {
+		panic(err)
+	}
+	return resize(a, axis, end-start)
 }
 
 func resize(a Tensor, axis int, size int) []int {
@@ -43,13 +70,40 @@ func resize(a Tensor, axis int, size int) []int {
 	return shape
 }
 
-func conv2d(a Tensor, k Tensor, hAxis int, wAxis int, fAxis int) []int {
-	kh, kw, kf := k.Shape()[0], k.Shape()[1], k.Shape()[3]
-	return calc.Conv2DShape(a.Shape(), hAxis, wAxis, fAxis, kh, kw, kf)
+func conv1d(a Tensor, k Tensor, wAxis int, fAxis int, opts calc.ConvOpts) []int {
+	return calc.Must(calc.CheckConv(a.Shape(), k.Shape(), []int{wAxis}, fAxis, opts))
+}
+
+func inverseConv1d(a Tensor, g Tensor, wAxis int, fAxis int, kernelW int, opts calc.ConvOpts) []int {
+	return calc.Must(calc.CheckInverseConv(a.Shape(), g.Shape(), []int{kernelW}, []int{wAxis}, fAxis, opts))
+}
+
+func conv1dTranspose(a Tensor, k Tensor, wAxis int, fAxis int, opts calc.ConvOpts) []int {
+	return calc.Must(calc.CheckConvTranspose(a.Shape(), k.Shape(), []int{wAxis}, fAxis, opts))
+}
+
+func conv2d(a Tensor, k Tensor, hAxis int, wAxis int, fAxis int, opts calc.ConvOpts) []int {
+	return calc.Must(calc.CheckConv(a.Shape(), k.Shape(), []int{hAxis, wAxis}, fAxis, opts))
+}
+
+func inverseConv2d(a Tensor, g Tensor, hAxis int, wAxis int, fAxis int, kernelH int, kernelW int, opts calc.ConvOpts) []int {
+	return calc.Must(calc.CheckInverseConv(a.Shape(), g.Shape(), []int{kernelH, kernelW}, []int{hAxis, wAxis}, fAxis, opts))
+}
+
+func conv2dTranspose(a Tensor, k Tensor, hAxis int, wAxis int, fAxis int, opts calc.ConvOpts) []int {
+	return calc.Must(calc.CheckConvTranspose(a.Shape(), k.Shape(), []int{hAxis, wAxis}, fAxis, opts))
+}
+
+func conv3d(a Tensor, k Tensor, dAxis int, hAxis int, wAxis int, fAxis int, opts calc.ConvOpts) []int {
+	return calc.Must(calc.CheckConv(a.Shape(), k.Shape(), []int{dAxis, hAxis, wAxis}, fAxis, opts))
+}
+
+func inverseConv3d(a Tensor, g Tensor, dAxis int, hAxis int, wAxis int, fAxis int, kernelD int, kernelH int, kernelW int, opts calc.ConvOpts) []int {
+	return calc.Must(calc.CheckInverseConv(a.Shape(), g.Shape(), []int{kernelD, kernelH, kernelW}, []int{dAxis, hAxis, wAxis}, fAxis, opts))
 }
 
-func inverseConv2d(a Tensor, g Tensor, hAxis int, wAxis int, fAxis int) []int {
-	return calc.InverseConv2DShape(a.Shape(), g.Shape(), hAxis, wAxis, fAxis)
+func conv3dTranspose(a Tensor, k Tensor, dAxis int, hAxis int, wAxis int, fAxis int, opts calc.ConvOpts) []int {
+	return calc.Must(calc.CheckConvTranspose(a.Shape(), k.Shape(), []int{dAxis, hAxis, wAxis}, fAxis, opts))
 }
 
 func shapeEq(s1 []int, s2 []int) bool {
@@ -0,0 +1,62 @@
+package tensor_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+func TestShapeGradients(t *testing.T) {
+	rng := calc.NewRNG(17)
+	tile := func(reps ...int) func(ins ...tensor.Tensor) tensor.Tensor {
+		return func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Tile(ins[0], reps...) }
+	}
+	checkGradients(t, []gradientCase{
+		{"Tile", tile(2, 3), []calc.NDArray{rng.Normal(0, 1, 2, 3)}},
+		{"Tile with leading axes", tile(2, 1, 3), []calc.NDArray{rng.Normal(0, 1, 2, 3)}},
+		{"Tile of a vector with leading axes", tile(3, 2, 2), []calc.NDArray{rng.Normal(0, 1, 4)}},
+		{"Tile with short reps", tile(3), []calc.NDArray{rng.Normal(0, 1, 2, 3)}},
+		{"Repeat", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Repeat(ins[0], 1, 3) }, []calc.NDArray{rng.Normal(0, 1, 2, 3)}},
+		{"Permute", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Permute(ins[0], 2, 0, 1) }, []calc.NDArray{rng.Normal(0, 1, 2, 3, 4)}},
+		{"ExpandDims Squeeze", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.Mul(tensor.ExpandDims(ins[0], 1), tensor.Squeeze(ins[1]))
+		}, []calc.NDArray{rng.Normal(0, 1, 2, 3), rng.Normal(0, 1, 1, 3, 1)}},
+		{"Stack", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Stack(1, ins...) }, []calc.NDArray{rng.Normal(0, 1, 2, 3), rng.Normal(0, 1, 2, 3)}},
+		{"Split", func(ins ...tensor.Tensor) tensor.Tensor {
+			parts := tensor.Split(ins[0], 1, 2)
+			return tensor.Mul(parts[0], parts[2])
+		}, []calc.NDArray{rng.Normal(0, 1, 2, 6)}},
+	})
+}
+
+func TestPadGradients(t *testing.T) {
+	rng := calc.NewRNG(45)
+	pad := func(widths [][2]int, mode calc.PadMode) func(ins ...tensor.Tensor) tensor.Tensor {
+		return func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Pad(ins[0], widths, mode, 0.5) }
+	}
+	widths := [][2]int{{1, 2}, {3, 0}}
+	checkGradients(t, []gradientCase{
+		{"Pad constant", pad(widths, calc.PadConstant), []calc.NDArray{rng.Normal(0, 1, 2, 3)}},
+		{"Pad reflect", pad(widths, calc.PadReflect), []calc.NDArray{rng.Normal(0, 1, 2, 3)}},
+		{"Pad symmetric", pad(widths, calc.PadSymmetric), []calc.NDArray{rng.Normal(0, 1, 2, 3)}},
+		{"Pad edge", pad(widths, calc.PadEdge), []calc.NDArray{rng.Normal(0, 1, 2, 3)}},
+		{"Pad reflect wider than the axis", pad([][2]int{{0, 0}, {5, 4}}, calc.PadReflect), []calc.NDArray{rng.Normal(0, 1, 2, 3)}},
+	})
+}
+
+func TestTileShapes(t *testing.T) {
+	for _, c := range []struct {
+		shape, reps, want []int
+	}{
+		{[]int{2, 3}, []int{2, 3}, []int{4, 9}},
+		{[]int{2, 3}, []int{2, 1, 3}, []int{2, 2, 9}},
+		{[]int{3}, []int{2, 2}, []int{2, 6}},
+		{[]int{2, 3}, []int{2}, []int{2, 6}},
+		{[]int{}, []int{2}, []int{2}},
+	} {
+		if got := tensor.Tile(tensor.Input(c.shape...), c.reps...).Shape(); !calc.ShapeEqual(got, c.want) || len(got) != len(c.want) {
+			t.Errorf("Tile of %v by %v has shape %v, want %v", c.shape, c.reps, got, c.want)
+		}
+	}
+}
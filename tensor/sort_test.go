@@ -0,0 +1,53 @@
+package tensor_test
+
+import (
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+func TestSortGradients(t *testing.T) {
+	rng := calc.NewRNG(31)
+	checkGradients(t, []gradientCase{
+		{"Sort", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Sort(ins[0], 1) }, []calc.NDArray{rng.Normal(0, 1, 3, 5)}},
+		{"Sort axis 0", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.Sort(ins[0], 0) }, []calc.NDArray{rng.Normal(0, 1, 4, 2, 3)}},
+		{"TopK", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.TopK(ins[0], 2, 1) }, []calc.NDArray{rng.Normal(0, 1, 3, 5)}},
+		{"TopK axis 0", func(ins ...tensor.Tensor) tensor.Tensor { return tensor.TopK(ins[0], 3, 0) }, []calc.NDArray{rng.Normal(0, 1, 4, 2)}},
+	})
+}
+
+func TestAccuracy(t *testing.T) {
+	// the second row ties its first two classes, and the last row ties its top two with the third
+	pred := calc.FromRaw([]int{4, 3}, []float64{
+		0.1, 0.7, 0.2,
+		0.4, 0.4, 0.2,
+		0.5, 0.3, 0.2,
+		0.3, 0.3, 0.3,
+	})
+	labels := calc.FromRawInt64([]int{4}, []int64{1, 1, 2, 2})
+	yPred, yTrue, ySparse := tensor.Input(4, 3), tensor.Input(4, 3), tensor.InputOf(calc.Int64, 4)
+
+	eval := tensor.MakeEvaluation(
+		tensor.CategoricalAccuracy(yTrue, yPred),
+		tensor.SparseCategoricalAccuracy(ySparse, yPred),
+		tensor.TopKCategoricalAccuracy(yTrue, yPred, 2),
+		tensor.ArgMax(yPred, 1),
+		tensor.ArgMin(yPred, 1),
+		tensor.TopKIndices(yPred, 2, 1),
+	)
+	res := eval.Evaluate(tensor.Provide(yPred, pred), tensor.Provide(yTrue, labels.OneHot(3)), tensor.Provide(ySparse, labels))
+	// only the first row is right, since ties go to the first class
+	for i, name := range []string{"CategoricalAccuracy", "SparseCategoricalAccuracy"} {
+		if got := res[i].Get([]int{0}); got != 0.25 {
+			t.Errorf("%s = %v, want 0.25", name, got)
+		}
+	}
+	// the third row's true class is ranked last, and the last row's is behind the other two in the tie
+	if got := res[2].Get([]int{0}); got != 0.5 {
+		t.Errorf("TopKCategoricalAccuracy = %v, want 0.5", got)
+	}
+	checkEqual(t, "ArgMax", res[3], calc.FromRawInt64([]int{4, 1}, []int64{1, 0, 0, 0}))
+	checkEqual(t, "ArgMin", res[4], calc.FromRawInt64([]int{4, 1}, []int64{0, 2, 2, 0}))
+	checkEqual(t, "TopKIndices", res[5], calc.FromRawInt64([]int{4, 2}, []int64{1, 2, 0, 1, 0, 1, 0, 1}))
+}
@@ -0,0 +1,79 @@
+package tensor_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/tsholmes/go-dl/calc"
+	"github.com/tsholmes/go-dl/tensor"
+)
+
+// a 6x5 matrix with about half its elements zero
+func sparseMatrix() calc.NDArray {
+	rng := calc.NewRNG(12)
+	a := rng.Normal(0, 1, 6, 5)
+	rng.Normal(0, 1, 6, 5).ForEach(func(_ int, index []int, m float64) {
+		if m < 0 {
+			a.Set(index, 0)
+		}
+	})
+	return a
+}
+
+func TestSparseMatMulGradients(t *testing.T) {
+	a := sparseMatrix()
+	rng := calc.NewRNG(13)
+	checkGradients(t, []gradientCase{
+		{"SparseMatMul", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.SparseMatMul(tensor.SparseConstant(a.CSR()), ins[0])
+		}, []calc.NDArray{rng.Normal(0, 1, 5, 3)}},
+		{"SparseMatMul transposed", func(ins ...tensor.Tensor) tensor.Tensor {
+			return tensor.SparseMatMul(tensor.SparseTranspose(tensor.SparseConstant(a.CSR())), ins[0])
+		}, []calc.NDArray{rng.Normal(0, 1, 6, 3)}},
+	})
+}
+
+func TestSparseInput(t *testing.T) {
+	a := sparseMatrix()
+	x := tensor.SparseInput(6, 5)
+	if tensor.SparseTranspose(x) != tensor.SparseTranspose(x) {
+		t.Error("SparseTranspose built a second transpose of the same input")
+	}
+	if tensor.SparseTranspose(tensor.SparseTranspose(x)) != x {
+		t.Error("SparseTranspose of a transpose isn't the original input")
+	}
+	if shape := tensor.SparseTranspose(x).Shape(); !calc.ShapeEqual(shape, []int{5, 6}) {
+		t.Errorf("SparseTranspose has shape %v, want [5 6]", shape)
+	}
+
+	rng := calc.NewRNG(14)
+	wv, cv := rng.Normal(0, 1, 5, 3), rng.Normal(0, 1, 6, 3)
+	w := tensor.Input(5, 3)
+	y := tensor.SparseMatMul(x, w)
+	// reads x through its transpose, so the gradient reads it back through the transpose of that
+	z := tensor.SparseMatMul(tensor.SparseTranspose(x), tensor.Mul(y, tensor.Constant(cv)))
+	grads := tensor.Gradients(y, z)
+
+	eval := tensor.MakeEvaluation(y, z, grads[w.ID()])
+	// evaluated twice, so a transpose left over from the first evaluation would be caught
+	for _, s := range []calc.NDArray{a, a.MulConstant(2)} {
+		res := eval.Evaluate(tensor.ProvideSparse(x, s.CSR()), tensor.Provide(w, wv))
+
+		wantY := s.MatMul(wv, 0, 1)
+		wantZ := s.Transpose(0, 1).MatMul(wantY.Mul(cv), 0, 1)
+		// d/dw of sum(y) + sum(z) = s^T 1 + s^T (c * (s 1))
+		wantGrad := s.Transpose(0, 1).MatMul(calc.Ones(6, 3).Add(cv.Mul(s.MatMul(calc.Ones(5, 3), 0, 1))), 0, 1)
+		for i, want := range []calc.NDArray{wantY, wantZ, wantGrad} {
+			want.ForEach(func(_ int, index []int, v float64) {
+				if got := res[i].Get(index); math.Abs(got-v) > 1e-9*math.Max(1, math.Abs(v)) {
+					t.Errorf("output %d at %v = %v, want %v", i, index, got, v)
+				}
+			})
+		}
+	}
+
+	_, err := tensor.Build(func() tensor.Tensor { return tensor.SparseMatMul(x, tensor.Input(6, 3)) })
+	if _, ok := err.(*calc.ShapeError); !ok {
+		t.Errorf("SparseMatMul with the wrong inner size: got %v, want a ShapeError", err)
+	}
+}
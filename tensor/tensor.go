@@ -5,6 +5,7 @@ import "github.com/tsholmes/go-dl/calc"
 type Tensor interface {
 	ID() int64
 	Shape() []int
+	DType() calc.DType
 	Inputs() []Tensor
 
 	Visit(v TensorVisitor)
@@ -13,6 +14,8 @@ type Tensor interface {
 type TensorVisitor interface {
 	VisitInput(t *InputTensor)
 	VisitConstant(t *ConstantTensor)
+	VisitCast(t *CastTensor)
+	VisitOneHot(t *OneHotTensor)
 	VisitAdd(t *AddTensor)
 	VisitMul(t *MulTensor)
 	VisitDiv(t *DivTensor)
@@ -22,23 +25,79 @@ type TensorVisitor interface {
 	VisitMatMul(t *MatMulTensor)
 	VisitLog(t *LogTensor)
 	VisitExp(t *ExpTensor)
+	VisitSqrt(t *SqrtTensor)
+	VisitTanh(t *TanhTensor)
+	VisitSin(t *SinTensor)
+	VisitCos(t *CosTensor)
+	VisitErf(t *ErfTensor)
+	VisitLog1p(t *Log1pTensor)
+	VisitExpm1(t *Expm1Tensor)
 	VisitNormalize(t *NormalizeTensor)
 	VisitInverseNormalize(t *InverseNormalizeTensor)
+	VisitConv1D(t *Conv1DTensor)
+	VisitInverseConv1D(t *InverseConv1DTensor)
+	VisitConv1DTranspose(t *Conv1DTransposeTensor)
 	VisitConv2D(t *Conv2DTensor)
 	VisitInverseConv2D(t *InverseConv2DTensor)
+	VisitConv2DTranspose(t *Conv2DTransposeTensor)
+	VisitConv3D(t *Conv3DTensor)
+	VisitInverseConv3D(t *InverseConv3DTensor)
+	VisitConv3DTranspose(t *Conv3DTransposeTensor)
+	VisitResize2D(t *Resize2DTensor)
+	VisitResize2DTranspose(t *Resize2DTransposeTensor)
 	VisitConcat(t *ConcatTensor)
 	VisitSlice(t *SliceTensor)
 	VisitUnslice(t *UnsliceTensor)
 	VisitTranspose(t *TransposeTensor)
 	VisitReshape(t *ReshapeTensor)
 	VisitReverse(t *ReverseTensor)
+	VisitPad(t *PadTensor)
+	VisitUnpad(t *UnpadTensor)
+	VisitPermute(t *PermuteTensor)
+	VisitTile(t *TileTensor)
+	VisitRepeat(t *RepeatTensor)
 	VisitSum(t *SumTensor)
 	VisitMax(t *MaxTensor)
+	VisitMin(t *MinTensor)
+	VisitProd(t *ProdTensor)
+	VisitLogSumExp(t *LogSumExpTensor)
+	VisitVar(t *VarTensor)
+	VisitStd(t *StdTensor)
+	VisitCumSum(t *CumSumTensor)
+	VisitCumProd(t *CumProdTensor)
 	VisitGreater(t *GreaterTensor)
 	VisitEqual(t *EqualTensor)
 	VisitReLU(t *ReLUTensor)
 	VisitReLUMask(t *ReLUMaskTensor)
 	VisitEqualMask(t *EqualMaskTensor)
+	VisitClip(t *ClipTensor)
+	VisitMinimum(t *MinimumTensor)
+	VisitMaximum(t *MaximumTensor)
+	VisitWhere(t *WhereTensor)
+	VisitArgMax(t *ArgMaxTensor)
+	VisitArgMin(t *ArgMinTensor)
+	VisitArgSort(t *ArgSortTensor)
+	VisitSort(t *SortTensor)
+	VisitTopK(t *TopKTensor)
+	VisitTopKIndices(t *TopKIndicesTensor)
+	VisitGather(t *GatherTensor)
+	VisitScatterAdd(t *ScatterAddTensor)
+	VisitScatter(t *ScatterTensor)
+	VisitIndexSelect(t *IndexSelectTensor)
+	VisitIndexAdd(t *IndexAddTensor)
+	VisitInverse(t *InverseTensor)
+	VisitSolve(t *SolveTensor)
+	VisitDet(t *DetTensor)
+	VisitLogDet(t *LogDetTensor)
+	VisitCholesky(t *CholeskyTensor)
+	VisitQR(t *QRTensor)
+	VisitSVD(t *SVDTensor)
+	VisitEigh(t *EighTensor)
+	VisitEinsum(t *EinsumTensor)
+	VisitSparseInput(t *SparseInputTensor)
+	VisitSparseConstant(t *SparseConstantTensor)
+	VisitSparseTranspose(t *SparseTransposeTensor)
+	VisitSparseMatMul(t *SparseMatMulTensor)
 }
 
 var nextID int64
@@ -46,9 +105,8 @@ var nextID int64
 type baseTensor struct {
 	id     int64
 	shape  []int
+	dtype  calc.DType
 	inputs []Tensor
-
-	values []calc.NDArray
 }
 
 func (b *baseTensor) ID() int64 {
@@ -59,23 +117,49 @@ func (b *baseTensor) Shape() []int {
 	return b.shape
 }
 
+func (b *baseTensor) DType() calc.DType {
+	return b.dtype
+}
+
 func (b *baseTensor) Inputs() []Tensor {
 	return b.inputs
 }
 
-func base(shape []int, tempValues int, inputs ...Tensor) baseTensor {
+// the dtype is promoted from the inputs
+func base(shape []int, inputs ...Tensor) baseTensor {
+	dtype := calc.Bool
+	for _, t := range inputs {
+		dtype = calc.PromoteTypes(dtype, t.DType())
+	}
+	if len(inputs) == 0 {
+		dtype = calc.Float64
+	}
+	return baseOf(dtype, shape, inputs...)
+}
+
+func baseOf(dtype calc.DType, shape []int, inputs ...Tensor) baseTensor {
 	// TODO: lock around nextID
 	id := nextID
 	nextID++
 
-	values := make([]calc.NDArray, tempValues)
-	for i := range values {
-		values[i] = calc.Zeros(shape...)
-	}
 	return baseTensor{
 		id:     id,
 		shape:  shape,
+		dtype:  dtype,
 		inputs: inputs,
-		values: values,
 	}
 }
+
+// Runs f to build a graph, returning the first *calc.ShapeError an op panicked with instead of panicking
+func Build[T any](f func() T) (res T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			se, ok := r.(*calc.ShapeError)
+			if !ok {
+				panic(r)
+			}
+			err = se
+		}
+	}()
+	return f(), nil
+}